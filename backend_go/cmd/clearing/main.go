@@ -0,0 +1,245 @@
+// Command clearing ingests an end-of-day clearing file of presentments,
+// settles the Pending transactions they match and writes an exceptions
+// report for the records it could not settle.
+//
+//	go run ./cmd/clearing -file clearing-2025-01-31.csv -exceptions exceptions.csv
+//
+// CSV files have the columns transaction_id,merchant,amount (header row
+// optional). Fixed-width files have one record per line:
+//
+//	cols  1-12  transaction id, zero padded
+//	cols 13-42  merchant, space padded
+//	cols 43-54  amount in cents, zero padded
+//
+// Run it with AUTO_CAPTURE_DELAY=0 on the server, otherwise the demo
+// auto-capture settles authorizations before the clearing file arrives.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"backend_go/internal/initialize"
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+)
+
+func main() {
+	file := flag.String("file", "", "clearing file to ingest (required)")
+	format := flag.String("format", "", "csv or fixed (default: by file extension, .csv is csv)")
+	exceptions := flag.String("exceptions", "-", "exceptions report path (CSV), - for stdout")
+	partial := flag.Bool("partial", false, "settle presentments below the authorized amount as partial captures")
+	flag.Parse()
+
+	env := initialize.LoadEnv()
+	logger := utils.NewLogger(os.Stderr, env.LogLevel, env.LogFormat)
+	slog.SetDefault(logger)
+
+	if *file == "" {
+		logger.Error("missing -file")
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = "fixed"
+		if strings.EqualFold(filepath.Ext(*file), ".csv") {
+			*format = "csv"
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = utils.WithLogger(ctx, logger.With("component", "clearing", "file", *file))
+
+	records, invalid, err := readClearingFile(*file, *format)
+	if err != nil {
+		logger.Error("read clearing file failed", "error", err)
+		os.Exit(1)
+	}
+
+	pool, err := initialize.NewPGPool(ctx, env.DatabaseURL, env.TraceRedactColumns)
+	if err != nil {
+		logger.Error("db connect failed", "error", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	svc := initialize.NewTransactionService(pool, env, nil, nil)
+	report, err := svc.ClearPresentments(ctx, records, *partial)
+	if err != nil {
+		// Settled records stay settled; re-running the file reports them as duplicates
+		logger.Error("clearing aborted", "error", err)
+	}
+	report.Records += len(invalid)
+	report.Exceptions = append(invalid, report.Exceptions...)
+	sort.SliceStable(report.Exceptions, func(i, j int) bool { return report.Exceptions[i].Line < report.Exceptions[j].Line })
+
+	if werr := writeExceptions(*exceptions, report.Exceptions); werr != nil {
+		logger.Error("write exceptions report failed", "error", werr)
+		os.Exit(1)
+	}
+	utils.LoggerFrom(ctx).Info("clearing finished",
+		"records", report.Records,
+		"settled", report.Settled,
+		"settled_amount", report.SettledAmount,
+		"exceptions", len(report.Exceptions),
+	)
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+/* ---------------- clearing file ---------------- */
+
+// readClearingFile returns the parsed records and, as invalid exceptions,
+// the lines that could not be parsed.
+func readClearingFile(path, format string) ([]service.Presentment, []service.ClearingException, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	switch format {
+	case "csv":
+		return readCSV(f)
+	case "fixed":
+		return readFixedWidth(f)
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q (csv or fixed)", format)
+	}
+}
+
+// readCSV reads one record per presentment; a malformed record (e.g. a
+// stray quote) is an invalid exception on its line and reading goes on.
+func readCSV(r io.Reader) ([]service.Presentment, []service.ClearingException, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+
+	var records []service.Presentment
+	var invalid []service.ClearingException
+	for first := true; ; first = false {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			invalid = append(invalid, invalidRecord(perr.StartLine, perr.Err.Error()))
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if first && row[0] == "transaction_id" {
+			continue // header
+		}
+		if len(row) != 3 {
+			invalid = append(invalid, invalidRecord(line, fmt.Sprintf("expected 3 columns, got %d", len(row))))
+			continue
+		}
+		p, err := parsePresentment(line, row[0], row[1], row[2], false)
+		if err != nil {
+			invalid = append(invalid, invalidRecord(line, err.Error()))
+			continue
+		}
+		records = append(records, p)
+	}
+	return records, invalid, nil
+}
+
+func readFixedWidth(r io.Reader) ([]service.Presentment, []service.ClearingException, error) {
+	var records []service.Presentment
+	var invalid []service.ClearingException
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if len(text) != 54 {
+			invalid = append(invalid, invalidRecord(line, fmt.Sprintf("expected 54 characters, got %d", len(text))))
+			continue
+		}
+		p, err := parsePresentment(line, text[0:12], text[12:42], text[42:54], true)
+		if err != nil {
+			invalid = append(invalid, invalidRecord(line, err.Error()))
+			continue
+		}
+		records = append(records, p)
+	}
+	return records, invalid, sc.Err()
+}
+
+func parsePresentment(line int, txID, merchant, amount string, cents bool) (service.Presentment, error) {
+	p := service.Presentment{Line: line, Merchant: strings.TrimSpace(merchant)}
+	id, err := strconv.Atoi(strings.TrimSpace(txID))
+	if err != nil || id <= 0 {
+		return p, fmt.Errorf("bad transaction id %q", txID)
+	}
+	p.TransactionID = id
+	if p.Merchant == "" {
+		return p, fmt.Errorf("missing merchant")
+	}
+	amount = strings.TrimSpace(amount)
+	if cents {
+		c, err := strconv.ParseInt(amount, 10, 64)
+		if err != nil || c <= 0 {
+			return p, fmt.Errorf("bad amount %q", amount)
+		}
+		p.Amount = float64(c) / 100
+		return p, nil
+	}
+	a, err := strconv.ParseFloat(amount, 64)
+	if err != nil || a <= 0 {
+		return p, fmt.Errorf("bad amount %q", amount)
+	}
+	p.Amount = a
+	return p, nil
+}
+
+func invalidRecord(line int, reason string) service.ClearingException {
+	return service.ClearingException{Presentment: service.Presentment{Line: line}, Type: service.ClearingInvalid, Reason: reason}
+}
+
+/* ---------------- exceptions report ---------------- */
+
+func writeExceptions(path string, exceptions []service.ClearingException) error {
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"line", "type", "transaction_id", "merchant", "amount", "reason"})
+	for _, e := range exceptions {
+		id, amount := "", ""
+		if e.TransactionID > 0 {
+			id = strconv.Itoa(e.TransactionID)
+		}
+		if e.Amount > 0 {
+			amount = fmt.Sprintf("%.2f", e.Amount)
+		}
+		_ = w.Write([]string{strconv.Itoa(e.Line), e.Type, id, e.Merchant, amount, e.Reason})
+	}
+	w.Flush()
+	return w.Error()
+}
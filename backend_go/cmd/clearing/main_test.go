@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	service "backend_go/internal/services"
+)
+
+func TestReadCSVKeepsGoingPastBadRecords(t *testing.T) {
+	file := "transaction_id,merchant,amount\n" +
+		"1,Steam,10.50\n" +
+		"2,Ste\"am,5\n" + // bare quote: malformed record
+		"3,\"Apple\nStore\",7\n" + // one record over lines 4-5
+		"4,Amazon\n" +
+		"x,Amazon,1\n" +
+		"6,7-11,2.25\n"
+	records, invalid, err := readCSV(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantRecords := []service.Presentment{
+		{Line: 2, TransactionID: 1, Merchant: "Steam", Amount: 10.5},
+		{Line: 4, TransactionID: 3, Merchant: "Apple\nStore", Amount: 7},
+		{Line: 8, TransactionID: 6, Merchant: "7-11", Amount: 2.25},
+	}
+	if len(records) != len(wantRecords) {
+		t.Fatalf("records = %+v", records)
+	}
+	for i, w := range wantRecords {
+		if records[i] != w {
+			t.Errorf("record %d = %+v, want %+v", i, records[i], w)
+		}
+	}
+
+	wantInvalid := []struct {
+		line   int
+		reason string
+	}{
+		{3, "bare \" in non-quoted-field"},
+		{6, "expected 3 columns, got 2"},
+		{7, "bad transaction id"},
+	}
+	if len(invalid) != len(wantInvalid) {
+		t.Fatalf("invalid = %+v", invalid)
+	}
+	for i, w := range wantInvalid {
+		e := invalid[i]
+		if e.Line != w.line || e.Type != service.ClearingInvalid || !strings.Contains(e.Reason, w.reason) {
+			t.Errorf("invalid %d = %+v, want line %d %q", i, e, w.line, w.reason)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const csvTime = "2006-01-02 15:04:05"
+
+// exportFilter narrows an export; zero values export everything.
+type exportFilter struct {
+	UserIDs pq.Int64Array // nil: all users
+	From    *time.Time    // created_at >= From
+	To      *time.Time    // created_at < To (the day after -to)
+}
+
+func parseExportFilter(userIDs, from, to string) (exportFilter, error) {
+	var f exportFilter
+	for _, s := range strings.Split(userIDs, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			return f, fmt.Errorf("-user-ids: %q is not a positive integer", s)
+		}
+		f.UserIDs = append(f.UserIDs, id)
+	}
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return f, fmt.Errorf("-from: want YYYY-MM-DD, got %q", from)
+		}
+		f.From = &t
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return f, fmt.Errorf("-to: want YYYY-MM-DD, got %q", to)
+		}
+		t = t.AddDate(0, 0, 1)
+		f.To = &t
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return f, fmt.Errorf("-from must not be after -to")
+	}
+	return f, nil
+}
+
+// runExport dumps the filtered Users, Transactions and Points of one
+// consistent snapshot into dir.
+func runExport(dbURL, dir, format string, f exportFilter) error {
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("-format must be csv or json, got %q", format)
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	log.Println("📤 Exporting database...")
+	ds, err := exportDataset(ctx, tx, f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if format == "json" {
+		err = writeFixtures(filepath.Join(dir, "fixtures.json"), ds)
+	} else {
+		err = writeCSVDataset(dir, ds)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("🎉 Exported %d users, %d transactions, %d points rows to %s (%s).", len(ds.Users), len(ds.Transactions), len(ds.Points), dir, format)
+	return nil
+}
+
+// exportDataset reads the filtered rows. Refund sources outside the date
+// range are included, and Points rows of transactions not exported are
+// left out, so the export loads back without dangling references.
+func exportDataset(ctx context.Context, tx *sql.Tx, f exportFilter) (*dataset, error) {
+	ds := &dataset{}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT user_id, username, balance, current_points, credit_limit
+		FROM Users
+		WHERE $1::bigint[] IS NULL OR user_id = ANY($1)
+		ORDER BY user_id
+	`, f.UserIDs)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.UserID, &u.Username, &u.Balance, &u.CurrentPoints, &u.CreditLimit); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ds.Users = append(ds.Users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		WITH picked AS (
+			SELECT transaction_id, source_transaction_id FROM Transactions
+			WHERE ($1::bigint[] IS NULL OR user_id = ANY($1))
+			  AND ($2::timestamp IS NULL OR created_at >= $2)
+			  AND ($3::timestamp IS NULL OR created_at < $3)
+		)
+		SELECT transaction_id, user_id, amount, status, point_change, source_transaction_id, COALESCE(merchant, ''), created_at
+		FROM Transactions
+		WHERE transaction_id IN (SELECT transaction_id FROM picked UNION SELECT source_transaction_id FROM picked)
+		ORDER BY transaction_id
+	`, f.UserIDs, f.From, f.To)
+	if err != nil {
+		return nil, err
+	}
+	var txIDs pq.Int64Array
+	for rows.Next() {
+		var t Transaction
+		if err := rows.Scan(&t.TransactionID, &t.UserID, &t.Amount, &t.Status, &t.PointChange, &t.SourceTransactionID, &t.Merchant, &t.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ds.Transactions = append(ds.Transactions, t)
+		txIDs = append(txIDs, int64(t.TransactionID))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if txIDs == nil {
+		txIDs = pq.Int64Array{}
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT user_id, transaction_id, change_amount, COALESCE(reason, ''), created_at
+		FROM Points
+		WHERE ($1::bigint[] IS NULL OR user_id = ANY($1))
+		  AND ($2::timestamp IS NULL OR created_at >= $2)
+		  AND ($3::timestamp IS NULL OR created_at < $3)
+		  AND (transaction_id IS NULL OR transaction_id = ANY($4))
+		ORDER BY log_id
+	`, f.UserIDs, f.From, f.To, txIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p PointsEntry
+		if err := rows.Scan(&p.UserID, &p.TransactionID, &p.ChangeAmount, &p.Reason, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		ds.Points = append(ds.Points, p)
+	}
+	return ds, rows.Err()
+}
+
+func nullID(id sql.NullInt64) string {
+	if !id.Valid {
+		return "NULL"
+	}
+	return strconv.FormatInt(id.Int64, 10)
+}
+
+func money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// writeCSVDataset writes user.csv, transaction.csv and points.csv in the
+// format the CSV seed mode reads.
+func writeCSVDataset(dir string, ds *dataset) error {
+	users := [][]string{{"user_id", "username", "balance", "current_points", "credit_limit"}}
+	for _, u := range ds.Users {
+		users = append(users, []string{strconv.Itoa(u.UserID), u.Username, money(u.Balance), strconv.Itoa(u.CurrentPoints), money(u.CreditLimit)})
+	}
+	txs := [][]string{{"transaction_id", "user_id", "amount", "status", "point_change", "source_transaction_id", "created_at", "merchant"}}
+	for _, t := range ds.Transactions {
+		txs = append(txs, []string{
+			strconv.Itoa(t.TransactionID), strconv.Itoa(t.UserID), money(t.Amount), t.Status, strconv.Itoa(t.PointChange),
+			nullID(t.SourceTransactionID), t.CreatedAt.Format(csvTime), t.Merchant,
+		})
+	}
+	points := [][]string{{"user_id", "transaction_id", "change_amount", "reason", "created_at"}}
+	for _, p := range ds.Points {
+		points = append(points, []string{strconv.Itoa(p.UserID), nullID(p.TransactionID), strconv.Itoa(p.ChangeAmount), p.Reason, p.CreatedAt.Format(csvTime)})
+	}
+
+	for name, rows := range map[string][][]string{"user.csv": users, "transaction.csv": txs, "points.csv": points} {
+		if err := writeCSVFile(filepath.Join(dir, name), rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSVFile(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(rows); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// JSON fixture rows, snake_case like the API.
+type (
+	userFixture struct {
+		UserID        int     `json:"user_id"`
+		Username      string  `json:"username"`
+		Balance       float64 `json:"balance"`
+		CurrentPoints int     `json:"current_points"`
+		CreditLimit   float64 `json:"credit_limit"`
+	}
+	transactionFixture struct {
+		TransactionID       int     `json:"transaction_id"`
+		UserID              int     `json:"user_id"`
+		Amount              float64 `json:"amount"`
+		Status              string  `json:"status"`
+		PointChange         int     `json:"point_change"`
+		SourceTransactionID *int64  `json:"source_transaction_id"`
+		Merchant            string  `json:"merchant"`
+		CreatedAt           string  `json:"created_at"`
+	}
+	pointsFixture struct {
+		UserID        int    `json:"user_id"`
+		TransactionID *int64 `json:"transaction_id"`
+		ChangeAmount  int    `json:"change_amount"`
+		Reason        string `json:"reason"`
+		CreatedAt     string `json:"created_at"`
+	}
+	fixtures struct {
+		Users        []userFixture        `json:"users"`
+		Transactions []transactionFixture `json:"transactions"`
+		Points       []pointsFixture      `json:"points"`
+	}
+)
+
+func idPtr(id sql.NullInt64) *int64 {
+	if !id.Valid {
+		return nil
+	}
+	return &id.Int64
+}
+
+// writeFixtures writes the dataset as one JSON document.
+func writeFixtures(path string, ds *dataset) error {
+	fx := fixtures{
+		Users:        make([]userFixture, 0, len(ds.Users)),
+		Transactions: make([]transactionFixture, 0, len(ds.Transactions)),
+		Points:       make([]pointsFixture, 0, len(ds.Points)),
+	}
+	for _, u := range ds.Users {
+		fx.Users = append(fx.Users, userFixture{u.UserID, u.Username, u.Balance, u.CurrentPoints, u.CreditLimit})
+	}
+	for _, t := range ds.Transactions {
+		fx.Transactions = append(fx.Transactions, transactionFixture{
+			t.TransactionID, t.UserID, t.Amount, t.Status, t.PointChange, idPtr(t.SourceTransactionID), t.Merchant, t.CreatedAt.Format(csvTime),
+		})
+	}
+	for _, p := range ds.Points {
+		fx.Points = append(fx.Points, pointsFixture{p.UserID, idPtr(p.TransactionID), p.ChangeAmount, p.Reason, p.CreatedAt.Format(csvTime)})
+	}
+
+	b, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
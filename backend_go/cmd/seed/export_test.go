@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseExportFilter(t *testing.T) {
+	day := func(s string) *time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return &d
+	}
+	cases := []struct {
+		name              string
+		userIDs, from, to string
+		ids               []int64
+		wantFrom, wantTo  *time.Time
+		err               bool
+	}{
+		{name: "no filter"},
+		{name: "user ids", userIDs: " 3, 1,,2 ", ids: []int64{3, 1, 2}},
+		{name: "-to is inclusive", from: "2024-03-01", to: "2024-03-31", wantFrom: day("2024-03-01"), wantTo: day("2024-04-01")},
+		{name: "one day", from: "2024-03-31", to: "2024-03-31", wantFrom: day("2024-03-31"), wantTo: day("2024-04-01")},
+		{name: "-to alone", to: "2024-12-31", wantTo: day("2025-01-01")},
+		{name: "-from after -to", from: "2024-04-01", to: "2024-03-31", err: true},
+		{name: "bad date", from: "2024/03/01", err: true},
+		{name: "bad user id", userIDs: "1,x", err: true},
+		{name: "zero user id", userIDs: "0", err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := parseExportFilter(c.userIDs, c.from, c.to)
+			if c.err {
+				if err == nil {
+					t.Fatalf("filter = %+v, want an error", f)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(f.UserIDs) != len(c.ids) || (len(c.ids) > 0 && !reflect.DeepEqual([]int64(f.UserIDs), c.ids)) {
+				t.Fatalf("user ids = %v, want %v", f.UserIDs, c.ids)
+			}
+			if !sameTime(f.From, c.wantFrom) || !sameTime(f.To, c.wantTo) {
+				t.Fatalf("range = [%v, %v), want [%v, %v)", f.From, f.To, c.wantFrom, c.wantTo)
+			}
+		})
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// exportTestDataset is a small generated dataset, in the shape an export
+// reads back from the database.
+func exportTestDataset() *dataset {
+	cfg := genConfig{Users: 5, Transactions: 80, Seed: 3, End: time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)}
+	ds := &dataset{}
+	_ = eachUser(cfg, txCounts(cfg), func(d genUserData) error {
+		ds.Users = append(ds.Users, d.User)
+		for _, t := range d.Txs {
+			ds.Transactions = append(ds.Transactions, t.Transaction)
+		}
+		ds.Points = append(ds.Points, d.Points...)
+		return nil
+	})
+	return ds
+}
+
+func TestExportCSVRoundTrip(t *testing.T) {
+	ds := exportTestDataset()
+	dir := t.TempDir()
+	if err := writeCSVDataset(dir, ds); err != nil {
+		t.Fatal(err)
+	}
+	// The export loads back with -strict: no errors, no warnings
+	got, err := loadCSVDataset(dir, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Transactions) != len(ds.Transactions) || len(got.Points) != len(ds.Points) {
+		t.Fatalf("%d transactions and %d points rows, want %d and %d", len(got.Transactions), len(got.Points), len(ds.Transactions), len(ds.Points))
+	}
+	for i := range got.Users {
+		got.Users[i].Line = 0
+	}
+	// The CSV format keeps whole seconds
+	for i := range got.Transactions {
+		got.Transactions[i].Line = 0
+		ds.Transactions[i].CreatedAt = ds.Transactions[i].CreatedAt.Truncate(time.Second)
+	}
+	for i := range got.Points {
+		got.Points[i].Line = 0
+		ds.Points[i].CreatedAt = ds.Points[i].CreatedAt.Truncate(time.Second)
+	}
+	if !reflect.DeepEqual(got.Users, ds.Users) {
+		t.Fatalf("users differ:\n%+v\n%+v", got.Users, ds.Users)
+	}
+	if !reflect.DeepEqual(got.Transactions, ds.Transactions) {
+		t.Fatal("transactions differ after the round trip")
+	}
+	if !reflect.DeepEqual(got.Points, ds.Points) {
+		t.Fatal("points differ after the round trip")
+	}
+}
+
+func TestExportFixtures(t *testing.T) {
+	ds := exportTestDataset()
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := writeFixtures(path, ds); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fx fixtures
+	if err := json.Unmarshal(b, &fx); err != nil {
+		t.Fatal(err)
+	}
+	if len(fx.Users) != len(ds.Users) || len(fx.Transactions) != len(ds.Transactions) || len(fx.Points) != len(ds.Points) {
+		t.Fatalf("fixtures have %d/%d/%d rows, dataset %d/%d/%d", len(fx.Users), len(fx.Transactions), len(fx.Points), len(ds.Users), len(ds.Transactions), len(ds.Points))
+	}
+	for i, tx := range ds.Transactions {
+		f := fx.Transactions[i]
+		if f.TransactionID != tx.TransactionID || (f.SourceTransactionID == nil) == tx.SourceTransactionID.Valid || f.CreatedAt != tx.CreatedAt.Format(csvTime) {
+			t.Fatalf("fixture %+v for %+v", f, tx)
+		}
+	}
+}
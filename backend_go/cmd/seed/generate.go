@@ -0,0 +1,277 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// genConfig sizes a synthetic dataset. The same Seed, Users, Transactions
+// and End always produce the same rows.
+type genConfig struct {
+	Users        int
+	Transactions int // rows, refund rows included
+	Seed         uint64
+	End          time.Time // transactions span the year before End
+}
+
+// genMerchant is a merchant's share of purchases and its amount
+// distribution (log-normal around Median).
+type genMerchant struct {
+	Name   string
+	Weight float64
+	Median float64
+	Sigma  float64
+	Rate   float64 // points per $1, as merchantRates in the service
+}
+
+var genMerchants = []genMerchant{
+	{Name: "7-11", Weight: 0.45, Median: 8, Sigma: 0.6, Rate: 1},
+	{Name: "Amazon", Weight: 0.25, Median: 45, Sigma: 0.9, Rate: 1.5},
+	{Name: "Steam", Weight: 0.18, Median: 20, Sigma: 0.7, Rate: 2},
+	{Name: "Apple Store", Weight: 0.12, Median: 250, Sigma: 0.8, Rate: 3},
+}
+
+// Share of purchases later refunded / voided; the rest stay Paid.
+const (
+	genRefundRatio = 0.05
+	genVoidRatio   = 0.03
+)
+
+// Base credit limits and their shares; a user's limit is raised to keep
+// 20% headroom over the generated balance.
+var genLimits = []struct {
+	Limit  float64
+	Weight float64
+}{{5000, 0.40}, {10000, 0.35}, {20000, 0.18}, {50000, 0.07}}
+
+var genNames = []string{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi", "Ivan", "Judy", "Mallory", "Niaj", "Olivia", "Peggy", "Rupert", "Sybil", "Trent", "Victor", "Walter", "Yuki"}
+
+type genTx struct {
+	Transaction
+	Rate float64
+}
+
+// genUserData is everything generated for one user.
+type genUserData struct {
+	User   User
+	Txs    []genTx
+	Points []PointsEntry
+}
+
+// userRand gives every user an independent stream, so a user's rows do not
+// depend on the users generated before it.
+func userRand(seed uint64, userID int, stream uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, uint64(userID)<<2|stream))
+}
+
+// txCounts spreads cfg.Transactions over the users with a heavy tail: a few
+// users make many purchases, most make a handful.
+func txCounts(cfg genConfig) []int {
+	weights := make([]float64, cfg.Users)
+	total := 0.0
+	for i := range weights {
+		r := userRand(cfg.Seed, i+1, 0)
+		weights[i] = math.Exp(r.NormFloat64() * 1.1)
+		total += weights[i]
+	}
+	counts := make([]int, cfg.Users)
+	left := cfg.Transactions
+	for i, w := range weights {
+		counts[i] = int(float64(cfg.Transactions) * w / total)
+		left -= counts[i]
+	}
+	for i := 0; left > 0; i = (i + 1) % cfg.Users {
+		counts[i]++
+		left--
+	}
+	return counts
+}
+
+func pickWeighted(r *rand.Rand, n int, weight func(int) float64) int {
+	x := r.Float64()
+	for i := 0; i < n-1; i++ {
+		if x -= weight(i); x < 0 {
+			return i
+		}
+	}
+	return n - 1
+}
+
+// genUser generates one user with rows transaction rows whose ids start at
+// firstTxID. Balance and points are the sums of those rows.
+func genUser(cfg genConfig, userID, rows, firstTxID int) genUserData {
+	r := userRand(cfg.Seed, userID, 1)
+	d := genUserData{User: User{UserID: userID, Username: fmt.Sprintf("%s%d", genNames[r.IntN(len(genNames))], userID)}}
+	base := genLimits[pickWeighted(r, len(genLimits), func(i int) float64 { return genLimits[i].Weight })].Limit
+
+	start := cfg.End.AddDate(-1, 0, 0)
+	span := cfg.End.Sub(start)
+	times := make([]time.Time, 0, rows)
+	for range rows {
+		times = append(times, start.Add(time.Duration(r.Int64N(int64(span)))).Truncate(time.Second))
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	var balanceCents int64
+	nextID := firstTxID
+	for i := 0; i < rows; i++ {
+		m := genMerchants[pickWeighted(r, len(genMerchants), func(i int) float64 { return genMerchants[i].Weight })]
+		amount := math.Exp(math.Log(m.Median) + m.Sigma*r.NormFloat64())
+		cents := int64(math.Round(math.Min(math.Max(amount, 1), 10000) * 100))
+		amount = float64(cents) / 100
+		points := int(math.Floor(amount * m.Rate))
+
+		t := genTx{Transaction: Transaction{
+			TransactionID: nextID, UserID: userID, Amount: amount, Status: "Paid",
+			PointChange: points, Merchant: m.Name, CreatedAt: times[i],
+		}, Rate: m.Rate}
+		nextID++
+		earned := PointsEntry{UserID: userID, TransactionID: txRef(t.TransactionID), ChangeAmount: points, Reason: fmt.Sprintf("Earned (%s x%g)", m.Name, m.Rate), CreatedAt: t.CreatedAt}
+		d.Points = append(d.Points, earned)
+
+		status := "Paid"
+		switch x := r.Float64(); {
+		case x < genRefundRatio:
+			status = "Refunded"
+		case x < genRefundRatio+genVoidRatio:
+			status = "Voided"
+		}
+		if status == "Refunded" && i+1 == rows {
+			status = "Paid" // no row slot left for the refund
+		}
+
+		switch status {
+		case "Refunded":
+			// The refund takes the next row slot, 1 hour to 14 days later
+			t.Status = "Refunded"
+			refundAt := t.CreatedAt.Add(time.Hour + time.Duration(r.Int64N(int64(14*24*time.Hour))))
+			if refundAt.After(cfg.End) {
+				refundAt = cfg.End
+			}
+			refund := genTx{Transaction: Transaction{
+				TransactionID: nextID, UserID: userID, Amount: -amount, Status: "Refunded", PointChange: -points,
+				SourceTransactionID: txRef(t.TransactionID), Merchant: m.Name, CreatedAt: refundAt,
+			}}
+			nextID++
+			i++
+			d.Txs = append(d.Txs, t, refund)
+			d.Points = append(d.Points, PointsEntry{UserID: userID, TransactionID: txRef(refund.TransactionID), ChangeAmount: -points, Reason: "Refund", CreatedAt: refundAt})
+			continue
+		case "Voided":
+			t.Status = "Voided"
+			d.Points = append(d.Points, PointsEntry{UserID: userID, TransactionID: txRef(t.TransactionID), ChangeAmount: -points, Reason: "Void Reversal", CreatedAt: t.CreatedAt.Add(time.Duration(1+r.IntN(60)) * time.Minute)})
+		default:
+			balanceCents += cents
+			d.User.CurrentPoints += points
+		}
+		d.Txs = append(d.Txs, t)
+	}
+	d.User.Balance = float64(balanceCents) / 100
+	d.User.CreditLimit = math.Max(base, math.Ceil(d.User.Balance*1.2/1000)*1000)
+	return d
+}
+
+// eachUser regenerates the users in order and calls fn for each; the COPY
+// passes below each walk the dataset once instead of holding it in memory.
+func eachUser(cfg genConfig, counts []int, fn func(d genUserData) error) error {
+	nextID := 1
+	for i, n := range counts {
+		d := genUser(cfg, i+1, n, nextID)
+		nextID += n
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyRows streams rows into table with COPY FROM STDIN.
+func copyRows(tx *sql.Tx, table string, columns []string, fill func(add func(args ...any) error) error) error {
+	stmt, err := tx.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	add := func(args ...any) error {
+		_, err := stmt.Exec(args...)
+		return err
+	}
+	if err := fill(add); err != nil {
+		_ = stmt.Close()
+		return err
+	}
+	if _, err := stmt.Exec(); err != nil {
+		_ = stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// generateDataset loads a synthetic dataset into the truncated tables.
+func generateDataset(tx *sql.Tx, cfg genConfig) error {
+	counts := txCounts(cfg)
+
+	log.Printf("👤 Generating Users (%d)...", cfg.Users)
+	err := copyRows(tx, "users", []string{"user_id", "username", "balance", "current_points", "credit_limit"}, func(add func(...any) error) error {
+		return eachUser(cfg, counts, func(d genUserData) error {
+			return add(d.User.UserID, d.User.Username, d.User.Balance, d.User.CurrentPoints, d.User.CreditLimit)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("💳 Generating Transactions (%d)...", cfg.Transactions)
+	err = copyRows(tx, "transactions", []string{
+		"transaction_id", "user_id", "amount", "status", "point_change", "source_transaction_id", "merchant",
+		"authorized_amount", "captured_at", "reward_multiplier", "created_at",
+	}, func(add func(...any) error) error {
+		return eachUser(cfg, counts, func(d genUserData) error {
+			for _, t := range d.Txs {
+				var authorized, captured, rate any
+				if !t.SourceTransactionID.Valid {
+					authorized, captured, rate = t.Amount, t.CreatedAt, t.Rate
+				}
+				if err := add(t.TransactionID, t.UserID, t.Amount, t.Status, t.PointChange, t.SourceTransactionID, t.Merchant, authorized, captured, rate, t.CreatedAt); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Println("⭐ Generating Points ledger...")
+	return copyRows(tx, "points", []string{"user_id", "transaction_id", "change_amount", "reason", "created_at"}, func(add func(...any) error) error {
+		return eachUser(cfg, counts, func(d genUserData) error {
+			for _, p := range d.Points {
+				if err := add(p.UserID, p.TransactionID, p.ChangeAmount, p.Reason, p.CreatedAt); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// summary counts the rows the dataset has, without a database.
+func (cfg genConfig) summary() (users, txs, points int) {
+	err := eachUser(cfg, txCounts(cfg), func(d genUserData) error {
+		users++
+		txs += len(d.Txs)
+		points += len(d.Points)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return users, txs, points
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testGenConfig = genConfig{Users: 200, Transactions: 3000, Seed: 7, End: time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	cfg := testGenConfig
+	if a, b := genUser(cfg, 3, 40, 101), genUser(cfg, 3, 40, 101); !reflect.DeepEqual(a, b) {
+		t.Fatal("genUser differs between two runs with the same seed and -end")
+	}
+	users, txs, points := cfg.summary()
+	if u, tx, p := cfg.summary(); u != users || tx != txs || p != points {
+		t.Fatalf("summary %d/%d/%d, then %d/%d/%d", users, txs, points, u, tx, p)
+	}
+	if users != cfg.Users || txs != cfg.Transactions {
+		t.Fatalf("summary = %d users, %d transactions; want %d, %d", users, txs, cfg.Users, cfg.Transactions)
+	}
+
+	// A user's rows do not depend on the users before it
+	if a, b := genUser(cfg, 3, 40, 101), genUser(genConfig{Users: 5, Seed: cfg.Seed, End: cfg.End}, 3, 40, 101); !reflect.DeepEqual(a, b) {
+		t.Fatal("genUser depends on the dataset size")
+	}
+	other := cfg
+	other.Seed++
+	if reflect.DeepEqual(genUser(cfg, 3, 40, 101), genUser(other, 3, 40, 101)) {
+		t.Fatal("another seed gave the same user")
+	}
+	other = cfg
+	other.End = other.End.AddDate(0, 0, 1)
+	if reflect.DeepEqual(genUser(cfg, 3, 40, 101), genUser(other, 3, 40, 101)) {
+		t.Fatal("another -end gave the same user")
+	}
+}
+
+func TestGeneratedUsersMatchTheirRows(t *testing.T) {
+	cfg := testGenConfig
+	ds := &dataset{}
+	nextID := 1
+	err := eachUser(cfg, txCounts(cfg), func(d genUserData) error {
+		var balance int64
+		points, ledger := 0, 0
+		for _, tx := range d.Txs {
+			if tx.TransactionID != nextID {
+				t.Fatalf("user %d: transaction id %d, want %d", d.User.UserID, tx.TransactionID, nextID)
+			}
+			nextID++
+			c, p := balanceEffect(tx.Transaction)
+			balance += c
+			points += p
+			ds.Transactions = append(ds.Transactions, tx.Transaction)
+		}
+		for _, p := range d.Points {
+			ledger += p.ChangeAmount
+		}
+		if want := int64(math.Round(d.User.Balance * 100)); balance != want {
+			t.Errorf("user %d: balance %.2f, rows sum to %.2f", d.User.UserID, d.User.Balance, float64(balance)/100)
+		}
+		if points != d.User.CurrentPoints || ledger != d.User.CurrentPoints {
+			t.Errorf("user %d: current_points %d, transactions sum to %d, ledger to %d", d.User.UserID, d.User.CurrentPoints, points, ledger)
+		}
+		if d.User.Balance > d.User.CreditLimit {
+			t.Errorf("user %d: balance %.2f over the credit limit %.2f", d.User.UserID, d.User.Balance, d.User.CreditLimit)
+		}
+		ds.Users = append(ds.Users, d.User)
+		ds.Points = append(ds.Points, d.Points...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The generated dataset passes the CSV validation without findings
+	rep := &report{}
+	validateDataset(ds, false, rep)
+	if len(rep.issues) != 0 {
+		t.Fatalf("validation issues: %+v", rep.issues[:min(len(rep.issues), 5)])
+	}
+}
+
+func TestGenerateStatusRatios(t *testing.T) {
+	// One row per user: a refund has no slot for its refund row, so the
+	// purchase stays Paid instead of turning into a void
+	cfg := genConfig{Users: 4000, Seed: 1, End: testGenConfig.End}
+	counts := map[string]int{}
+	for id := 1; id <= cfg.Users; id++ {
+		d := genUser(cfg, id, 1, id)
+		if len(d.Txs) != 1 {
+			t.Fatalf("user %d: %d rows", id, len(d.Txs))
+		}
+		counts[d.Txs[0].Status]++
+	}
+	if counts["Refunded"] != 0 {
+		t.Fatalf("refunded without a refund row: %v", counts)
+	}
+	// Expected 3% voided (120); 8% would mean refunds became voids
+	if voided := float64(counts["Voided"]) / float64(cfg.Users); voided > genVoidRatio*1.5 || voided < genVoidRatio/2 {
+		t.Fatalf("voided share %.3f, want about %.2f: %v", voided, genVoidRatio, counts)
+	}
+}
@@ -3,7 +3,10 @@ package main
 import (
 	"database/sql"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -20,6 +23,8 @@ type User struct {
 	Username      string
 	Balance       float64
 	CurrentPoints int
+	CreditLimit   float64
+	Line          int // CSV line, 0 for generated rows
 }
 
 type Transaction struct {
@@ -31,6 +36,29 @@ type Transaction struct {
 	SourceTransactionID sql.NullInt64
 	Merchant            string
 	CreatedAt           time.Time
+	Line                int // CSV line, 0 for generated rows
+}
+
+// PointsEntry is a Points ledger row; TransactionID is NULL for rows of
+// redemptions and transfers.
+type PointsEntry struct {
+	UserID        int
+	TransactionID sql.NullInt64
+	ChangeAmount  int
+	Reason        string
+	CreatedAt     time.Time
+	Line          int // CSV line, 0 for generated rows
+}
+
+// dataset is what the seed tool loads and exports.
+type dataset struct {
+	Users        []User
+	Transactions []Transaction
+	Points       []PointsEntry
+}
+
+func txRef(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: true}
 }
 
 func mustEnv(key string) string {
@@ -42,15 +70,73 @@ func mustEnv(key string) string {
 }
 
 func main() {
-	dbURL := mustEnv("DATABASE_URL")
+	generate := flag.Bool("generate", false, "load a synthetic dataset instead of the CSV files")
+	genUsers := flag.Int("users", 1000, "generate: number of users")
+	genTxs := flag.Int("transactions", 10000, "generate: number of transaction rows (refund rows included)")
+	genSeed := flag.Uint64("seed", 1, "generate: random seed; the same seed and -end give the same dataset")
+	genEnd := flag.String("end", time.Now().UTC().Format("2006-01-02"), "generate: transactions span the year before this date (YYYY-MM-DD)")
+	dryRun := flag.Bool("dry-run", false, "validate (or generate) and report, without connecting to or writing the database")
+	strict := flag.Bool("strict", false, "treat validation warnings (e.g. balance mismatches) and merge conflicts as errors")
+	mode := flag.String("mode", "replace", "replace: truncate and load; merge: upsert users and insert missing transactions (CSV only)")
+	exportDir := flag.String("export", "", "export the database into this directory instead of seeding it")
+	exportFormat := flag.String("format", "csv", "export: csv (user.csv, transaction.csv, points.csv) or json (fixtures.json)")
+	exportUsers := flag.String("user-ids", "", "export: only these comma-separated user ids")
+	exportFrom := flag.String("from", "", "export: only transactions and points created on or after this date (YYYY-MM-DD)")
+	exportTo := flag.String("to", "", "export: only transactions and points created on or before this date (YYYY-MM-DD)")
+	flag.Parse()
+
+	if *exportDir != "" {
+		filter, err := parseExportFilter(*exportUsers, *exportFrom, *exportTo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := runExport(mustEnv("DATABASE_URL"), *exportDir, *exportFormat, filter); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	merge := *mode == "merge"
+	if !merge && *mode != "replace" {
+		log.Fatalf("-mode must be replace or merge, got %q", *mode)
+	}
+	if merge && *generate {
+		log.Fatalf("-mode merge loads CSV files only; generated ids would collide with existing rows")
+	}
+
+	var gen genConfig
+	if *generate {
+		end, err := time.Parse("2006-01-02", *genEnd)
+		if err != nil || *genUsers <= 0 || *genTxs < 0 {
+			log.Fatalf("invalid generator flags: -users must be positive, -transactions >= 0, -end YYYY-MM-DD")
+		}
+		gen = genConfig{Users: *genUsers, Transactions: *genTxs, Seed: *genSeed, End: end}
+	}
 
 	seedDir := os.Getenv("SEED_DIR")
 	if seedDir == "" {
 		seedDir = "./db/seed"
 	}
 
-	userCSV := filepath.Join(seedDir, "user.csv")
-	txCSV := filepath.Join(seedDir, "transaction.csv")
+	// Validate before touching the database, so a bad file never truncates it
+	var ds *dataset
+	if !*generate {
+		var err error
+		if ds, err = loadCSVDataset(seedDir, *strict, merge); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *dryRun {
+		if *generate {
+			users, rows, points := gen.summary()
+			log.Printf("🔎 Dry run: would generate %d users, %d transactions, %d points rows (seed %d).", users, rows, points, gen.Seed)
+		} else {
+			log.Printf("🔎 Dry run: %d users, %d transactions and %d points rows are valid; nothing written.", len(ds.Users), len(ds.Transactions), len(ds.Points))
+		}
+		return
+	}
+
+	dbURL := mustEnv("DATABASE_URL")
 
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -71,39 +157,64 @@ func main() {
 
 	log.Println("🌱 Starting database seeding...")
 
-	// 1. 清空舊資料
-	log.Println("🧹 Cleaning old data...")
-	_, err = tx.Exec(`TRUNCATE TABLE Points, Transactions, Users CASCADE`)
-	if err != nil {
+	// 1. 清空舊資料（merge 模式保留現有資料）
+	if !merge {
+		log.Println("🧹 Cleaning old data...")
+		_, err = tx.Exec(`TRUNCATE TABLE Points, Transactions, Users CASCADE`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if merge {
+		if err = mergeCSV(tx, ds, *strict); err != nil {
+			log.Fatal(err)
+		}
+	} else if *generate {
+		if err = generateDataset(tx, gen); err != nil {
+			log.Fatal(err)
+		}
+	} else if err = seedFromCSV(tx, ds); err != nil {
 		log.Fatal(err)
 	}
 
-	// 2. Users
-	users, err := loadUsers(userCSV)
+	// Explicit ids bypass the identity; the next generated id follows MAX
+	// (or starts at 1 on an empty table)
+	log.Println("🔧 Aligning Transactions identity sequence...")
+	_, err = tx.Exec(`
+		SELECT setval(
+			pg_get_serial_sequence('transactions','transaction_id'),
+			COALESCE(MAX(transaction_id), 1),
+			MAX(transaction_id) IS NOT NULL
+		) FROM transactions;
+	`)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Printf("👤 Seeding Users (%d)...", len(users))
-	for _, u := range users {
-		_, err = tx.Exec(`
+	if err = tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("🎉 Seeding completed successfully!")
+}
+
+// seedFromCSV inserts the validated CSV rows one by one.
+func seedFromCSV(tx *sql.Tx, ds *dataset) error {
+	log.Printf("👤 Seeding Users (%d)...", len(ds.Users))
+	for _, u := range ds.Users {
+		_, err := tx.Exec(`
 			INSERT INTO Users (user_id, username, balance, current_points, credit_limit)
 			VALUES ($1,$2,$3,$4,$5)
-		`, u.UserID, u.Username, u.Balance, u.CurrentPoints, 10000.0)
+		`, u.UserID, u.Username, u.Balance, u.CurrentPoints, u.CreditLimit)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 	}
 
-	// 3. Transactions
-	txs, err := loadTransactions(txCSV)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	log.Printf("💳 Seeding Transactions (%d)...", len(txs))
-	for _, t := range txs {
-		_, err = tx.Exec(`
+	log.Printf("💳 Seeding Transactions (%d)...", len(ds.Transactions))
+	for _, t := range ds.Transactions {
+		_, err := tx.Exec(`
 			INSERT INTO Transactions
 			(transaction_id, user_id, amount, status, point_change, source_transaction_id, merchant, created_at)
 			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
@@ -118,112 +229,181 @@ func main() {
 			t.CreatedAt,
 		)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 	}
 
-	log.Println("🔧 Aligning Transactions identity sequence...")
-	_, err = tx.Exec(`
-		SELECT setval(
-			pg_get_serial_sequence('transactions','transaction_id'),
-			COALESCE((SELECT MAX(transaction_id) FROM transactions), 0),
-			true
-		);
-	`)
-	if err != nil {
-		log.Fatal(err)
+	if len(ds.Points) > 0 {
+		log.Printf("⭐ Seeding Points (%d)...", len(ds.Points))
 	}
-
-	if err = tx.Commit(); err != nil {
-		log.Fatal(err)
+	for _, p := range ds.Points {
+		_, err := tx.Exec(`
+			INSERT INTO Points (user_id, transaction_id, change_amount, reason, created_at)
+			VALUES ($1,$2,$3,$4,$5)
+		`, p.UserID, p.TransactionID, p.ChangeAmount, p.Reason, p.CreatedAt)
+		if err != nil {
+			return err
+		}
 	}
-
-	log.Println("🎉 Seeding completed successfully!")
+	return nil
 }
 
 /* ---------------- CSV helpers ---------------- */
 
-func loadUsers(path string) ([]User, error) {
+// readCSV reads every record of path with its line number, skipping the header.
+func readCSV(path string) (rows [][]string, lines []int, err error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer f.Close()
 
 	r := csv.NewReader(f)
 	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1 // column counts are checked per row
 
-	rows, err := r.ReadAll()
+	for i := 0; ; i++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows, lines, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if i == 0 {
+			continue // header
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+}
+
+// loadUsers parses user.csv; rows that do not parse are reported and skipped.
+func loadUsers(path string, rep *report) ([]User, error) {
+	rows, lines, err := readCSV(path)
 	if err != nil {
 		return nil, err
 	}
+	file := filepath.Base(path)
 
 	var users []User
 	for i, row := range rows {
-		if i == 0 {
-			continue // header
+		line := lines[i]
+		if len(row) < 4 {
+			rep.errorf(file, line, "%d columns, expected at least 4 (user_id,username,balance,current_points)", len(row))
+			continue
+		}
+		userID, err := strconv.Atoi(row[0])
+		if err != nil || userID <= 0 {
+			rep.errorf(file, line, "user_id %q is not a positive integer", row[0])
+			continue
+		}
+		username := strings.TrimSpace(row[1])
+		if username == "" || len(username) > 50 {
+			rep.errorf(file, line, "username must be 1-50 characters")
+			continue
+		}
+		balance, err := strconv.ParseFloat(row[2], 64)
+		if err != nil {
+			rep.errorf(file, line, "balance %q is not a number", row[2])
+			continue
+		}
+		points, err := strconv.Atoi(row[3])
+		if err != nil {
+			rep.errorf(file, line, "current_points %q is not an integer", row[3])
+			continue
+		}
+		// 第 5 欄 credit_limit 可省略（預設 10000）
+		creditLimit := 10000.0
+		if len(row) >= 5 && row[4] != "" {
+			if creditLimit, err = strconv.ParseFloat(row[4], 64); err != nil || creditLimit < 0 {
+				rep.errorf(file, line, "credit_limit %q is not a non-negative number", row[4])
+				continue
+			}
 		}
-
-		userID, _ := strconv.Atoi(row[0])
-		balance, _ := strconv.ParseFloat(row[2], 64)
-		points, _ := strconv.Atoi(row[3])
 
 		users = append(users, User{
 			UserID:        userID,
-			Username:      row[1],
+			Username:      username,
 			Balance:       balance,
 			CurrentPoints: points,
+			CreditLimit:   creditLimit,
+			Line:          line,
 		})
 	}
 
 	return users, nil
 }
 
-func loadTransactions(path string) ([]Transaction, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	r := csv.NewReader(f)
-	r.TrimLeadingSpace = true
+var txStatuses = map[string]bool{"Pending": true, "Paid": true, "Voided": true, "Refunded": true, "Disputed": true, "ChargedBack": true, "Expired": true}
 
-	rows, err := r.ReadAll()
+// loadTransactions parses transaction.csv; rows that do not parse are
+// reported and skipped.
+func loadTransactions(path string, rep *report) ([]Transaction, error) {
+	rows, lines, err := readCSV(path)
 	if err != nil {
 		return nil, err
 	}
+	file := filepath.Base(path)
 
 	var txs []Transaction
 	defaultMerchants := []string{"7-11", "Steam", "Apple Store", "Amazon"}
 
 	for i, row := range rows {
-		if i == 0 {
-			continue
-		}
+		line := lines[i]
 		// 防呆：空行或欄位不足
 		if len(row) < 7 {
-			return nil, fmt.Errorf("transaction.csv row %d has %d columns, expected at least 7", i+1, len(row))
+			rep.errorf(file, line, "%d columns, expected at least 7", len(row))
+			continue
 		}
 
-		txID, _ := strconv.Atoi(row[0])
-		userID, _ := strconv.Atoi(row[1])
-		amount, _ := strconv.ParseFloat(row[2], 64)
+		txID, err := strconv.Atoi(row[0])
+		if err != nil || txID <= 0 {
+			rep.errorf(file, line, "transaction_id %q is not a positive integer", row[0])
+			continue
+		}
+		userID, err := strconv.Atoi(row[1])
+		if err != nil || userID <= 0 {
+			rep.errorf(file, line, "user_id %q is not a positive integer", row[1])
+			continue
+		}
+		amount, err := strconv.ParseFloat(row[2], 64)
+		if err != nil || amount == 0 {
+			rep.errorf(file, line, "amount %q is not a non-zero number", row[2])
+			continue
+		}
 		status := row[3]
-		pointChange, _ := strconv.Atoi(row[4])
+		if !txStatuses[status] {
+			rep.errorf(file, line, "unknown status %q", status)
+			continue
+		}
+		pointChange, err := strconv.Atoi(row[4])
+		if err != nil {
+			rep.errorf(file, line, "point_change %q is not an integer", row[4])
+			continue
+		}
 
 		var srcID sql.NullInt64
 		if row[5] != "NULL" && row[5] != "" {
-			v, _ := strconv.Atoi(row[5])
+			v, err := strconv.Atoi(row[5])
+			if err != nil || v <= 0 {
+				rep.errorf(file, line, "source_transaction_id %q is not a positive integer or NULL", row[5])
+				continue
+			}
 			srcID = sql.NullInt64{Int64: int64(v), Valid: true}
 		}
 
-		createdAt, err := parseCreatedAt(row[6])
+		createdAt, patched, err := parseCreatedAt(row[6])
 		if err != nil {
-			return nil, fmt.Errorf("bad created_at at row %d: %v (value=%q)", i+1, err, row[6])
+			rep.errorf(file, line, "bad created_at: %v", err)
+			continue
+		}
+		if patched {
+			rep.warnf(file, line, "created_at %q is not YYYY-MM-DD HH:MM:SS, read as %s", row[6], createdAt.Format("2006-01-02 15:04:05"))
 		}
 
-		merchant := defaultMerchants[(i-1)%len(defaultMerchants)]
+		merchant := defaultMerchants[i%len(defaultMerchants)]
 		// 如果 CSV 有第 8 欄 merchant，就用它覆蓋預設值
 		if len(row) >= 8 && row[7] != "" && row[7] != "NULL" {
 			merchant = row[7]
@@ -238,39 +418,94 @@ func loadTransactions(path string) ([]Transaction, error) {
 			SourceTransactionID: srcID,
 			Merchant:            merchant,
 			CreatedAt:           createdAt,
+			Line:                line,
 		})
 	}
 
 	return txs, nil
 }
 
+// loadPoints parses the optional points.csv
+// (user_id,transaction_id,change_amount,reason,created_at); a missing file
+// loads no ledger rows.
+func loadPoints(path string, rep *report) ([]PointsEntry, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	rows, lines, err := readCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Base(path)
+
+	var points []PointsEntry
+	for i, row := range rows {
+		line := lines[i]
+		if len(row) < 5 {
+			rep.errorf(file, line, "%d columns, expected 5 (user_id,transaction_id,change_amount,reason,created_at)", len(row))
+			continue
+		}
+		userID, err := strconv.Atoi(row[0])
+		if err != nil || userID <= 0 {
+			rep.errorf(file, line, "user_id %q is not a positive integer", row[0])
+			continue
+		}
+		var txID sql.NullInt64
+		if row[1] != "NULL" && row[1] != "" {
+			v, err := strconv.Atoi(row[1])
+			if err != nil || v <= 0 {
+				rep.errorf(file, line, "transaction_id %q is not a positive integer or NULL", row[1])
+				continue
+			}
+			txID = txRef(v)
+		}
+		change, err := strconv.Atoi(row[2])
+		if err != nil {
+			rep.errorf(file, line, "change_amount %q is not an integer", row[2])
+			continue
+		}
+		if len(row[3]) > 50 {
+			rep.errorf(file, line, "reason is longer than 50 characters")
+			continue
+		}
+		createdAt, _, err := parseCreatedAt(row[4])
+		if err != nil {
+			rep.errorf(file, line, "bad created_at: %v", err)
+			continue
+		}
+		points = append(points, PointsEntry{UserID: userID, TransactionID: txID, ChangeAmount: change, Reason: row[3], CreatedAt: createdAt, Line: line})
+	}
+	return points, nil
+}
+
 var oneDigitHour = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}) (\d):(\d{2}:\d{2})$`)
 
-func parseCreatedAt(s string) (time.Time, error) {
+// parseCreatedAt also accepts a one-digit hour; patched reports that case.
+func parseCreatedAt(s string) (t time.Time, patched bool, err error) {
 	s = strings.TrimSpace(s)
 	if s == "" || s == "NULL" {
-		return time.Time{}, fmt.Errorf("empty created_at")
+		return time.Time{}, false, fmt.Errorf("empty created_at")
 	}
 
 	// 1) 先試 RFC3339（萬一未來你換格式）
 	if t, err := time.Parse(time.RFC3339, s); err == nil {
-		return t, nil
+		return t, false, nil
 	}
 
 	// 2) 你目前 CSV 的主要格式：YYYY-MM-DD HH:MM:SS
 	//    但 Go 會要求 HH 必須是兩位數
 	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.UTC); err == nil {
-		return t, nil
+		return t, false, nil
 	}
 
 	// 3) 處理單位數小時：2023-01-03 9:15:00 -> 2023-01-03 09:15:00
 	if m := oneDigitHour.FindStringSubmatch(s); m != nil {
 		fixed := fmt.Sprintf("%s 0%s:%s", m[1], m[2], m[3])
 		if t, err := time.ParseInLocation("2006-01-02 15:04:05", fixed, time.UTC); err == nil {
-			return t, nil
+			return t, true, nil
 		}
 	}
 
 	// 4) 如果還是失敗，就回報原字串方便你抓錯
-	return time.Time{}, fmt.Errorf("unsupported created_at format: %q", s)
+	return time.Time{}, false, fmt.Errorf("unsupported created_at format: %q", s)
 }
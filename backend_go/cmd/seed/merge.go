@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+)
+
+// mergeCSV loads ds on top of the existing rows instead of replacing them:
+// users are upserted by user_id, transactions missing from the database are
+// inserted and existing ones are kept. A transaction whose database row
+// differs from its CSV row is reported as a conflict, which fails the run
+// with strict.
+func mergeCSV(tx *sql.Tx, ds *dataset, strict bool) error {
+	var inserted, updated int
+	log.Printf("👤 Merging Users (%d)...", len(ds.Users))
+	for _, u := range ds.Users {
+		var isNew bool
+		err := tx.QueryRow(`
+			INSERT INTO Users (user_id, username, balance, current_points, credit_limit)
+			VALUES ($1,$2,$3,$4,$5)
+			ON CONFLICT (user_id) DO UPDATE SET
+				username = EXCLUDED.username,
+				balance = EXCLUDED.balance,
+				current_points = EXCLUDED.current_points,
+				credit_limit = EXCLUDED.credit_limit
+			RETURNING xmax = 0
+		`, u.UserID, u.Username, u.Balance, u.CurrentPoints, u.CreditLimit).Scan(&isNew)
+		if err != nil {
+			return fmt.Errorf("user.csv:%d: %w", u.Line, err)
+		}
+		if isNew {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+	log.Printf("👤 Users: %d inserted, %d updated.", inserted, updated)
+
+	rep := &report{}
+	newTx := map[int64]bool{}
+	var unchanged, conflicts int
+	log.Printf("💳 Merging Transactions (%d)...", len(ds.Transactions))
+	for _, t := range ds.Transactions {
+		res, err := tx.Exec(`
+			INSERT INTO Transactions
+			(transaction_id, user_id, amount, status, point_change, source_transaction_id, merchant, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+			ON CONFLICT (transaction_id) DO NOTHING
+		`, t.TransactionID, t.UserID, t.Amount, t.Status, t.PointChange, t.SourceTransactionID, t.Merchant, t.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("transaction.csv:%d: %w", t.Line, err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			newTx[int64(t.TransactionID)] = true
+			continue
+		}
+		field, err := transactionDiff(tx, t)
+		if err != nil {
+			return fmt.Errorf("transaction.csv:%d: %w", t.Line, err)
+		}
+		if field == "" {
+			unchanged++
+			continue
+		}
+		conflicts++
+		rep.warnf("transaction.csv", t.Line, "transaction %d exists with a different %s; kept the database row", t.TransactionID, field)
+	}
+
+	// Points of kept transactions are already in the ledger; adjustments
+	// without a transaction are added unless the same row exists
+	var points, skipped int
+	for _, p := range ds.Points {
+		if p.TransactionID.Valid && !newTx[p.TransactionID.Int64] {
+			skipped++
+			continue
+		}
+		res, err := tx.Exec(`
+			INSERT INTO Points (user_id, transaction_id, change_amount, reason, created_at)
+			SELECT $1,$2,$3,$4,$5
+			WHERE $2::bigint IS NOT NULL OR NOT EXISTS (
+				SELECT 1 FROM Points
+				WHERE user_id = $1 AND transaction_id IS NULL
+				  AND change_amount = $3 AND COALESCE(reason, '') = $4 AND created_at = $5
+			)
+		`, p.UserID, p.TransactionID, p.ChangeAmount, p.Reason, p.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("points.csv:%d: %w", p.Line, err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			points++
+		} else {
+			skipped++
+		}
+	}
+
+	rep.print()
+	log.Printf("💳 Transactions: %d inserted, %d unchanged, %d conflicts. ⭐ Points: %d inserted, %d skipped.", len(newTx), unchanged, conflicts, points, skipped)
+	if strict && conflicts > 0 {
+		return fmt.Errorf("merge failed: %d conflicts with -strict", conflicts)
+	}
+	return nil
+}
+
+// transactionDiff names the first column where the stored transaction
+// differs from t, or returns "" when they match.
+func transactionDiff(tx *sql.Tx, t Transaction) (string, error) {
+	var db Transaction
+	err := tx.QueryRow(`
+		SELECT user_id, amount, status, point_change, source_transaction_id, COALESCE(merchant, ''), created_at
+		FROM Transactions WHERE transaction_id = $1
+	`, t.TransactionID).Scan(&db.UserID, &db.Amount, &db.Status, &db.PointChange, &db.SourceTransactionID, &db.Merchant, &db.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("transaction %d was neither inserted nor found", t.TransactionID)
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case db.UserID != t.UserID:
+		return "user_id", nil
+	case math.Round(db.Amount*100) != math.Round(t.Amount*100):
+		return "amount", nil
+	case db.Status != t.Status:
+		return "status", nil
+	case db.PointChange != t.PointChange:
+		return "point_change", nil
+	case db.SourceTransactionID != t.SourceTransactionID:
+		return "source_transaction_id", nil
+	case db.Merchant != t.Merchant:
+		return "merchant", nil
+	case !db.CreatedAt.Equal(t.CreatedAt):
+		return "created_at", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Merge files may refer to users and transactions that are only in the
+// database; what the files do contain is still checked.
+func TestValidatePartialDataset(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ds := &dataset{
+		// Balance and points are not recomputed: the user has older rows
+		Users: []User{{UserID: 1, Username: "Alice", Balance: 500, CurrentPoints: 7, Line: 2}},
+		Transactions: []Transaction{
+			{TransactionID: 10, UserID: 1, Amount: 50, Status: "Paid", PointChange: 50, CreatedAt: at, Line: 2},
+			// Refund of a purchase in the database
+			{TransactionID: 11, UserID: 1, Amount: -20, Status: "Refunded", PointChange: -20, SourceTransactionID: txRef(3), CreatedAt: at, Line: 3},
+			// A user only in the database
+			{TransactionID: 12, UserID: 2, Amount: 5, Status: "Paid", PointChange: 5, CreatedAt: at, Line: 4},
+		},
+		Points: []PointsEntry{
+			{UserID: 1, TransactionID: txRef(4), ChangeAmount: 4, Line: 2},
+			{UserID: 2, TransactionID: txRef(12), ChangeAmount: 5, Line: 3},
+		},
+	}
+	rep := &report{}
+	validateDataset(ds, true, rep)
+	wantIssues(t, rep)
+
+	// Both rows in the files: the refund source is checked
+	ds.Transactions = append(ds.Transactions, Transaction{TransactionID: 13, UserID: 2, Amount: -10, Status: "Refunded", PointChange: -10, SourceTransactionID: txRef(10), CreatedAt: at, Line: 5})
+	ds.Users = append(ds.Users, User{UserID: 1, Username: "Alice again", Line: 3})
+	rep = &report{}
+	validateDataset(ds, true, rep)
+	rep.print()
+	wantIssues(t, rep,
+		"user.csv:3:duplicate user_id 1",
+		"transaction.csv:5:refund source 10 belongs to user 1",
+	)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+)
+
+// issue is one finding of the validation pass.
+type issue struct {
+	File    string
+	Line    int
+	Warning bool
+	Msg     string
+}
+
+// report collects row errors (the file is not loaded) and warnings (loaded
+// as is, unless -strict).
+type report struct {
+	issues []issue
+}
+
+func (r *report) errorf(file string, line int, format string, args ...any) {
+	r.issues = append(r.issues, issue{File: file, Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (r *report) warnf(file string, line int, format string, args ...any) {
+	r.issues = append(r.issues, issue{File: file, Line: line, Warning: true, Msg: fmt.Sprintf(format, args...)})
+}
+
+var fileOrder = map[string]int{"user.csv": 0, "transaction.csv": 1, "points.csv": 2}
+
+// print logs the issues by file and line and returns the error and warning counts.
+func (r *report) print() (errs, warns int) {
+	sort.SliceStable(r.issues, func(i, j int) bool {
+		if r.issues[i].File != r.issues[j].File {
+			return fileOrder[r.issues[i].File] < fileOrder[r.issues[j].File]
+		}
+		return r.issues[i].Line < r.issues[j].Line
+	})
+	for _, is := range r.issues {
+		if is.Warning {
+			warns++
+			log.Printf("⚠️  %s:%d: %s", is.File, is.Line, is.Msg)
+		} else {
+			errs++
+			log.Printf("❌ %s:%d: %s", is.File, is.Line, is.Msg)
+		}
+	}
+	return errs, warns
+}
+
+// loadCSVDataset parses and validates the CSV files of dir. It fails on any
+// row error, and on warnings too when strict. partial files (merge mode) may
+// refer to rows that are only in the database.
+func loadCSVDataset(dir string, strict, partial bool) (*dataset, error) {
+	rep := &report{}
+	ds := &dataset{}
+	var err error
+	if ds.Users, err = loadUsers(filepath.Join(dir, "user.csv"), rep); err != nil {
+		return nil, err
+	}
+	if ds.Transactions, err = loadTransactions(filepath.Join(dir, "transaction.csv"), rep); err != nil {
+		return nil, err
+	}
+	if ds.Points, err = loadPoints(filepath.Join(dir, "points.csv"), rep); err != nil {
+		return nil, err
+	}
+	validateDataset(ds, partial, rep)
+
+	errs, warns := rep.print()
+	log.Printf("🔎 Validated %d users, %d transactions, %d points rows: %d errors, %d warnings.", len(ds.Users), len(ds.Transactions), len(ds.Points), errs, warns)
+	if errs > 0 {
+		return nil, fmt.Errorf("validation failed: %d errors", errs)
+	}
+	if strict && warns > 0 {
+		return nil, fmt.Errorf("validation failed: %d warnings with -strict", warns)
+	}
+	return ds, nil
+}
+
+// Effects of a transaction row on the user, as the service leaves them: a
+// Paid purchase adds to balance and points; a refunded purchase does too and
+// its negative refund row takes them back; voided, pending, expired and
+// disputed purchases have no net effect.
+func balanceEffect(t Transaction) (cents int64, points int) {
+	if t.SourceTransactionID.Valid || t.Status == "Paid" || t.Status == "Refunded" {
+		return int64(math.Round(t.Amount * 100)), t.PointChange
+	}
+	return 0, 0
+}
+
+// validateDataset checks referential integrity and recomputes every user's
+// balance and points from the transactions. With partial, references to rows
+// not in the files are left to the database foreign keys and balances are
+// not recomputed.
+func validateDataset(ds *dataset, partial bool, rep *report) {
+	const userFile, txFile, pointsFile = "user.csv", "transaction.csv", "points.csv"
+	users, txs := ds.Users, ds.Transactions
+	userByID := make(map[int]User, len(users))
+	for _, u := range users {
+		if prev, ok := userByID[u.UserID]; ok {
+			rep.errorf(userFile, u.Line, "duplicate user_id %d (first on line %d)", u.UserID, prev.Line)
+			continue
+		}
+		userByID[u.UserID] = u
+	}
+
+	txByID := make(map[int]Transaction, len(txs))
+	for _, t := range txs {
+		if prev, ok := txByID[t.TransactionID]; ok {
+			rep.errorf(txFile, t.Line, "duplicate transaction_id %d (first on line %d)", t.TransactionID, prev.Line)
+			continue
+		}
+		txByID[t.TransactionID] = t
+	}
+
+	refunds := map[int]int{} // source id -> refund rows
+	for _, t := range txs {
+		if _, ok := userByID[t.UserID]; !ok && !partial {
+			rep.errorf(txFile, t.Line, "transaction %d: user %d is not in %s", t.TransactionID, t.UserID, userFile)
+		}
+		if !t.SourceTransactionID.Valid {
+			if t.Amount < 0 {
+				rep.errorf(txFile, t.Line, "transaction %d: negative amount without source_transaction_id", t.TransactionID)
+			}
+			continue
+		}
+
+		srcID := int(t.SourceTransactionID.Int64)
+		if t.Status != "Refunded" || t.Amount >= 0 {
+			rep.errorf(txFile, t.Line, "transaction %d: a refund row (with source_transaction_id) must be Refunded with a negative amount", t.TransactionID)
+		}
+		src, ok := txByID[srcID]
+		if !ok {
+			if !partial {
+				rep.errorf(txFile, t.Line, "transaction %d: refund source %d does not exist", t.TransactionID, srcID)
+			}
+			continue
+		}
+		switch {
+		case src.SourceTransactionID.Valid:
+			rep.errorf(txFile, t.Line, "transaction %d: refund source %d is itself a refund", t.TransactionID, srcID)
+		case src.UserID != t.UserID:
+			rep.errorf(txFile, t.Line, "transaction %d: refund source %d belongs to user %d", t.TransactionID, srcID, src.UserID)
+		case src.Status != "Paid" && src.Status != "Refunded":
+			rep.errorf(txFile, t.Line, "transaction %d: refund source %d is %s, only Paid purchases can be refunded", t.TransactionID, srcID, src.Status)
+		case -t.Amount > src.Amount:
+			rep.errorf(txFile, t.Line, "transaction %d: refund %.2f exceeds source %d amount %.2f", t.TransactionID, -t.Amount, srcID, src.Amount)
+		case src.Status == "Paid":
+			rep.warnf(txFile, src.Line, "transaction %d is still Paid although refund %d points to it (refunded purchases are Refunded)", srcID, t.TransactionID)
+		}
+		if refunds[srcID]++; refunds[srcID] == 2 {
+			rep.errorf(txFile, t.Line, "transaction %d: source %d is refunded more than once", t.TransactionID, srcID)
+		}
+	}
+	for _, t := range txs {
+		if !partial && !t.SourceTransactionID.Valid && t.Status == "Refunded" && refunds[t.TransactionID] == 0 {
+			rep.warnf(txFile, t.Line, "transaction %d is Refunded but has no refund row", t.TransactionID)
+		}
+	}
+
+	ledger := map[int]int{}
+	for _, p := range ds.Points {
+		if _, ok := userByID[p.UserID]; !ok && !partial {
+			rep.errorf(pointsFile, p.Line, "user %d is not in %s", p.UserID, userFile)
+		}
+		if !p.TransactionID.Valid {
+			ledger[p.UserID] += p.ChangeAmount
+			continue
+		}
+		if t, ok := txByID[int(p.TransactionID.Int64)]; !ok {
+			if !partial {
+				rep.errorf(pointsFile, p.Line, "transaction %d is not in %s", p.TransactionID.Int64, txFile)
+			}
+		} else if t.UserID != p.UserID {
+			rep.errorf(pointsFile, p.Line, "transaction %d belongs to user %d, not %d", t.TransactionID, t.UserID, p.UserID)
+		}
+		ledger[p.UserID] += p.ChangeAmount
+	}
+	if partial {
+		return
+	}
+	balances := map[int]int64{}
+	points := map[int]int{}
+	for _, t := range txByID {
+		c, p := balanceEffect(t)
+		balances[t.UserID] += c
+		points[t.UserID] += p
+	}
+	for _, u := range users {
+		if userByID[u.UserID].Line != u.Line {
+			continue // duplicate, already reported
+		}
+		if want := int64(math.Round(u.Balance * 100)); balances[u.UserID] != want {
+			rep.warnf(userFile, u.Line, "user %d: balance %.2f, transactions sum to %.2f", u.UserID, u.Balance, float64(balances[u.UserID])/100)
+		}
+		if points[u.UserID] != u.CurrentPoints {
+			rep.warnf(userFile, u.Line, "user %d: current_points %d, transactions sum to %d", u.UserID, u.CurrentPoints, points[u.UserID])
+		}
+		if len(ds.Points) > 0 && ledger[u.UserID] != u.CurrentPoints {
+			rep.warnf(userFile, u.Line, "user %d: current_points %d, %s sums to %d", u.UserID, u.CurrentPoints, pointsFile, ledger[u.UserID])
+		}
+	}
+}
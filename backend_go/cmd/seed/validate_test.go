@@ -0,0 +1,209 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSeedFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// wantIssues checks the report has exactly the issues of want, each given
+// as "file:line:substring", prefixed with "warn " for warnings.
+func wantIssues(t *testing.T, rep *report, want ...string) {
+	t.Helper()
+	if len(rep.issues) != len(want) {
+		t.Fatalf("%d issues, want %d: %+v", len(rep.issues), len(want), rep.issues)
+	}
+	for i, w := range want {
+		is := rep.issues[i]
+		warning := strings.HasPrefix(w, "warn ")
+		parts := strings.SplitN(strings.TrimPrefix(w, "warn "), ":", 3)
+		if is.File != parts[0] || strconv.Itoa(is.Line) != parts[1] || is.Warning != warning || !strings.Contains(is.Msg, parts[2]) {
+			t.Errorf("issue %d = %+v, want %s", i, is, w)
+		}
+	}
+}
+
+func TestLoadCSVReportsLineNumbers(t *testing.T) {
+	dir := writeSeedFiles(t, map[string]string{
+		"user.csv": "user_id,username,balance,current_points\n" +
+			"1,\"Alice\nof Wonderland\",0,0\n" + // one record over lines 2-3
+			"x,Bob,0,0\n" +
+			"3,Carol,lots,0\n",
+		"transaction.csv": "transaction_id,user_id,amount,status,point_change,source_transaction_id,created_at\n" +
+			"1,1,10,Paid,10,NULL,2024-01-01T09:00:00Z\n" +
+			"2,1,10,Lost,10,NULL,2024-01-01 10:00:00\n" +
+			"3,1,10,Paid\n",
+	})
+	rep := &report{}
+	users, err := loadUsers(filepath.Join(dir, "user.csv"), rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs, err := loadTransactions(filepath.Join(dir, "transaction.csv"), rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Line != 2 || users[0].Username != "Alice\nof Wonderland" {
+		t.Fatalf("users = %+v", users)
+	}
+	if len(txs) != 1 || txs[0].Line != 2 || !txs[0].CreatedAt.Equal(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("transactions = %+v", txs)
+	}
+	wantIssues(t, rep,
+		"user.csv:4:user_id \"x\"",
+		"user.csv:5:balance \"lots\"",
+		"transaction.csv:3:unknown status \"Lost\"",
+		"transaction.csv:4:4 columns",
+	)
+}
+
+func TestValidateRefundSources(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	purchase := func(id, userID int, status string, line int) Transaction {
+		return Transaction{TransactionID: id, UserID: userID, Amount: 50, Status: status, PointChange: 50, Merchant: "7-11", CreatedAt: at, Line: line}
+	}
+	refund := func(id, userID, src int, amount float64, line int) Transaction {
+		return Transaction{TransactionID: id, UserID: userID, Amount: -amount, Status: "Refunded", PointChange: -int(amount), SourceTransactionID: txRef(src), Merchant: "7-11", CreatedAt: at, Line: line}
+	}
+	cases := []struct {
+		name string
+		txs  []Transaction
+		want []string
+	}{
+		{
+			name: "valid refund",
+			txs:  []Transaction{purchase(1, 1, "Refunded", 2), refund(2, 1, 1, 50, 3)},
+		},
+		{
+			name: "missing source",
+			txs:  []Transaction{refund(2, 1, 9, 50, 2)},
+			want: []string{"transaction.csv:2:refund source 9 does not exist"},
+		},
+		{
+			name: "source of another user",
+			txs:  []Transaction{purchase(1, 2, "Refunded", 2), refund(2, 1, 1, 50, 3)},
+			want: []string{"transaction.csv:3:refund source 1 belongs to user 2"},
+		},
+		{
+			name: "voided source",
+			txs:  []Transaction{purchase(1, 1, "Voided", 2), refund(2, 1, 1, 50, 3)},
+			want: []string{"transaction.csv:3:refund source 1 is Voided"},
+		},
+		{
+			name: "refund above the source",
+			txs:  []Transaction{purchase(1, 1, "Refunded", 2), refund(2, 1, 1, 60, 3)},
+			want: []string{"transaction.csv:3:refund 60.00 exceeds source 1"},
+		},
+		{
+			name: "refund of a refund",
+			txs:  []Transaction{purchase(1, 1, "Refunded", 2), refund(2, 1, 1, 50, 3), refund(3, 1, 2, 50, 4)},
+			want: []string{"transaction.csv:4:refund source 2 is itself a refund"},
+		},
+		{
+			name: "refunded twice",
+			txs:  []Transaction{purchase(1, 1, "Refunded", 2), refund(2, 1, 1, 20, 3), refund(3, 1, 1, 20, 4)},
+			want: []string{"transaction.csv:4:source 1 is refunded more than once"},
+		},
+		{
+			name: "source still Paid",
+			txs:  []Transaction{purchase(1, 1, "Paid", 2), refund(2, 1, 1, 50, 3)},
+			want: []string{"warn transaction.csv:2:transaction 1 is still Paid"},
+		},
+		{
+			name: "refund row not Refunded",
+			txs:  []Transaction{purchase(1, 1, "Refunded", 2), {TransactionID: 2, UserID: 1, Amount: 50, Status: "Paid", SourceTransactionID: txRef(1), CreatedAt: at, Line: 3}},
+			want: []string{"transaction.csv:3:must be Refunded with a negative amount"},
+		},
+		{
+			name: "Refunded without refund row",
+			txs:  []Transaction{purchase(1, 1, "Refunded", 2)},
+			want: []string{"warn transaction.csv:2:Refunded but has no refund row"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// Balances are left out of this test: give the users what the rows sum to
+			users := []User{{UserID: 1, Username: "Alice", Line: 2}, {UserID: 2, Username: "Bob", Line: 3}}
+			for _, tx := range c.txs {
+				cents, points := balanceEffect(tx)
+				users[tx.UserID-1].Balance += float64(cents) / 100
+				users[tx.UserID-1].CurrentPoints += points
+			}
+			ds := &dataset{Users: users, Transactions: c.txs}
+			rep := &report{}
+			validateDataset(ds, false, rep)
+			rep.print()
+			wantIssues(t, rep, c.want...)
+		})
+	}
+}
+
+func TestValidateRecomputesBalances(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ds := &dataset{
+		Users: []User{
+			{UserID: 1, Username: "Alice", Balance: 50, CurrentPoints: 50, Line: 2},
+			{UserID: 2, Username: "Bob", Balance: 10, CurrentPoints: 0, Line: 3},
+			{UserID: 1, Username: "Alice again", Line: 4},
+		},
+		Transactions: []Transaction{
+			{TransactionID: 1, UserID: 1, Amount: 50, Status: "Paid", PointChange: 50, CreatedAt: at, Line: 2},
+			{TransactionID: 2, UserID: 1, Amount: 30, Status: "Voided", PointChange: 30, CreatedAt: at, Line: 3},
+			{TransactionID: 3, UserID: 3, Amount: 5, Status: "Paid", PointChange: 5, CreatedAt: at, Line: 4},
+		},
+		Points: []PointsEntry{
+			{UserID: 1, TransactionID: txRef(1), ChangeAmount: 50, Line: 2},
+			{UserID: 2, TransactionID: txRef(1), ChangeAmount: 5, Line: 3},
+		},
+	}
+	rep := &report{}
+	validateDataset(ds, false, rep)
+	rep.print()
+	wantIssues(t, rep,
+		"warn user.csv:3:balance 10.00, transactions sum to 0.00",
+		"warn user.csv:3:current_points 0, points.csv sums to 5",
+		"user.csv:4:duplicate user_id 1 (first on line 2)",
+		"transaction.csv:4:user 3 is not in user.csv",
+		"points.csv:3:transaction 1 belongs to user 1, not 2",
+	)
+}
+
+func TestLoadCSVDatasetStrict(t *testing.T) {
+	const txs = "transaction_id,user_id,amount,status,point_change,source_transaction_id,created_at,merchant\n" +
+		"1,1,10,Paid,10,NULL,2024-01-01 09:00:00,7-11\n"
+	// The balance does not match the transactions: a warning
+	dir := writeSeedFiles(t, map[string]string{
+		"user.csv":        "user_id,username,balance,current_points\n1,Alice,99,10\n",
+		"transaction.csv": txs,
+	})
+	ds, err := loadCSVDataset(dir, false, false)
+	if err != nil || len(ds.Users) != 1 || len(ds.Transactions) != 1 || len(ds.Points) != 0 {
+		t.Fatalf("loadCSVDataset = %+v, %v", ds, err)
+	}
+	if _, err := loadCSVDataset(dir, true, false); err == nil || !strings.Contains(err.Error(), "1 warnings with -strict") {
+		t.Fatalf("strict: %v", err)
+	}
+
+	// Row errors fail without -strict
+	dir = writeSeedFiles(t, map[string]string{
+		"user.csv":        "user_id,username,balance,current_points\n1,Alice,10,10\n2,,0,0\n",
+		"transaction.csv": txs,
+	})
+	if _, err := loadCSVDataset(dir, false, false); err == nil || !strings.Contains(err.Error(), "1 errors") {
+		t.Fatalf("row error: %v", err)
+	}
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"backend_go/internal/initialize"
+	"backend_go/internal/utils"
 )
 
 func main() {
@@ -18,9 +19,21 @@ func main() {
 
 	env := initialize.LoadEnv()
 
+	logger := utils.NewLogger(os.Stdout, env.LogLevel, env.LogFormat)
+	slog.SetDefault(logger)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, env, os.Args[2:]); err != nil {
+			logger.Error("migrate failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	app, err := initialize.Build(ctx, env)
 	if err != nil {
-		log.Fatalf("init failed: %v", err)
+		logger.Error("init failed", "error", err)
+		os.Exit(1)
 	}
 	defer app.Close()
 
@@ -30,9 +43,10 @@ func main() {
 	}
 
 	go func() {
-		log.Printf("server listening on %s", srv.Addr)
+		logger.Info("server listening", "addr", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
+			logger.Error("server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -41,5 +55,5 @@ func main() {
 	ctxShutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	_ = srv.Shutdown(ctxShutdown)
-	log.Printf("server stopped")
+	logger.Info("server stopped")
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"backend_go/internal/initialize"
+	"backend_go/internal/migrations"
+)
+
+// runMigrate implements `server migrate <up|down|status>`:
+//
+//	server migrate up [-to VERSION]   apply pending migrations (all by default)
+//	server migrate down [-steps N]    revert the last N applied migrations (1)
+//	server migrate status             list migrations and when they were applied
+func runMigrate(ctx context.Context, env initialize.Env, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: server migrate <up|down|status> [flags]")
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	to := fs.Int64("to", 0, "up: stop after this version (0: latest)")
+	steps := fs.Int("steps", 1, "down: number of migrations to revert")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	pool, err := initialize.NewPGPool(ctx, env.DatabaseURL, env.TraceRedactColumns)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	runner, err := migrations.New(pool)
+	if err != nil {
+		return err
+	}
+
+	var ran []migrations.Migration
+	switch args[0] {
+	case "up":
+		ran, err = runner.Up(ctx, *to)
+	case "down":
+		if *steps <= 0 {
+			return fmt.Errorf("-steps must be positive")
+		}
+		ran, err = runner.Down(ctx, *steps)
+	case "status":
+		st, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(st)
+	default:
+		return fmt.Errorf("unknown migrate command %q (up, down or status)", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	for _, m := range ran {
+		fmt.Printf("%s %d_%s\n", args[0], m.Version, m.Name)
+	}
+	if len(ran) == 0 {
+		fmt.Println("nothing to do")
+	}
+	return nil
+}
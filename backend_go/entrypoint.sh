@@ -3,7 +3,7 @@ set -eu
 
 echo "[entrypoint] Starting backend_go..."
 echo "[entrypoint] PORT=${PORT:-3000}"
-echo "[entrypoint] RUN_SEED=${RUN_SEED:-0} WAIT_FOR_DEPS=${WAIT_FOR_DEPS:-1}"
+echo "[entrypoint] RUN_MIGRATIONS=${RUN_MIGRATIONS:-1} RUN_SEED=${RUN_SEED:-0} WAIT_FOR_DEPS=${WAIT_FOR_DEPS:-1}"
 
 if [ "${WAIT_FOR_DEPS:-1}" = "1" ]; then
   DB_HOSTPORT="$(echo "${DATABASE_URL:-}" | sed -n 's|.*@||; s|/.*||p')"
@@ -42,6 +42,18 @@ if [ "${WAIT_FOR_DEPS:-1}" = "1" ]; then
   echo "[entrypoint] Redis is reachable."
 fi
 
+# ---- Migrations ----
+if [ "${RUN_MIGRATIONS:-1}" = "1" ]; then
+  echo "[entrypoint] Running migrations..."
+  /app/server migrate up || {
+    echo "[entrypoint] ERROR: migrations failed"
+    exit 1
+  }
+  echo "[entrypoint] Migrations done."
+else
+  echo "[entrypoint] Migrations skipped (RUN_MIGRATIONS!=1)."
+fi
+
 # ---- Seed (optional) ----
 if [ "${RUN_SEED:-0}" = "1" ]; then
   echo "[entrypoint] Running seed..."
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"net/http"
+
+	"backend_go/internal/middlewares"
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+)
+
+type captureReq struct {
+	TargetTransactionID int      `json:"target_transaction_id"`
+	Amount              *float64 `json:"amount,omitempty"` // omit to capture the full authorized amount
+}
+
+type incrementReq struct {
+	TargetTransactionID int     `json:"target_transaction_id"`
+	Amount              float64 `json:"amount"`
+}
+
+// CaptureTx captures an authorization of the authenticated merchant.
+func (a *API) CaptureTx(w http.ResponseWriter, r *http.Request) {
+	var req captureReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.TargetTransactionID <= 0 || (req.Amount != nil && *req.Amount <= 0) {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.CaptureTransaction(ctx, service.CaptureRequest{
+		Merchant:      middlewares.MerchantFrom(ctx).Name,
+		TransactionID: req.TargetTransactionID,
+		Amount:        req.Amount,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
+
+// IncrementAuth raises an authorization of the authenticated merchant.
+func (a *API) IncrementAuth(w http.ResponseWriter, r *http.Request) {
+	var req incrementReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.TargetTransactionID <= 0 || req.Amount <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.IncrementAuthorization(ctx, service.IncrementRequest{
+		Merchant:      middlewares.MerchantFrom(ctx).Name,
+		TransactionID: req.TargetTransactionID,
+		Amount:        req.Amount,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type issueCardReq struct {
+	CardType     string   `json:"card_type"`
+	CreditLimit  float64  `json:"credit_limit"`
+	SingleUse    bool     `json:"single_use"`
+	AmountCap    *float64 `json:"amount_cap"`
+	MerchantLock string   `json:"merchant_lock"`
+}
+
+type cardActionReq struct {
+	UserID int    `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+func (a *API) IssueCard(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	var req issueCardReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.CardType == "" || req.CreditLimit < 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.IssueCard(ctx, service.IssueCardRequest{
+		UserID:       id,
+		CardType:     req.CardType,
+		CreditLimit:  req.CreditLimit,
+		SingleUse:    req.SingleUse,
+		AmountCap:    req.AmountCap,
+		MerchantLock: req.MerchantLock,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 201, res)
+}
+
+func (a *API) ListCards(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	cards, err := a.Svc.ListCards(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, cards)
+}
+
+func (a *API) FreezeCard(w http.ResponseWriter, r *http.Request) {
+	a.changeCardStatus(w, r, "freeze")
+}
+
+func (a *API) UnfreezeCard(w http.ResponseWriter, r *http.Request) {
+	a.changeCardStatus(w, r, "unfreeze")
+}
+
+func (a *API) CloseCard(w http.ResponseWriter, r *http.Request) {
+	a.changeCardStatus(w, r, "close")
+}
+
+// ReportLostCard blocks a lost or stolen card; only an admin can reverse it.
+func (a *API) ReportLostCard(w http.ResponseWriter, r *http.Request) {
+	a.changeCardStatus(w, r, "report_lost")
+}
+
+func (a *API) changeCardStatus(w http.ResponseWriter, r *http.Request, action string) {
+	cardID, err := strconv.ParseInt(chi.URLParam(r, "card_id"), 10, 64)
+	if err != nil || cardID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	var req cardActionReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.UserID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.ChangeCardStatus(ctx, req.UserID, cardID, action, req.Reason)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type creditLimitReq struct {
+	NewLimit   *float64   `json:"new_limit"`
+	Boost      *float64   `json:"boost"`
+	BoostUntil *time.Time `json:"boost_until"`
+	Reason     string     `json:"reason"`
+}
+
+type decisionReq struct {
+	Reason string `json:"reason"`
+}
+
+func (a *API) RequestCreditLimitChange(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	var req creditLimitReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.Reason == "" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.RequestCreditLimitChange(ctx, service.CreditLimitChangeRequest{
+		UserID:     id,
+		NewLimit:   req.NewLimit,
+		Boost:      req.Boost,
+		BoostUntil: req.BoostUntil,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 201, res)
+}
+
+func (a *API) ListCreditLimitRequests(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	reqs, err := a.Svc.ListCreditLimitRequests(r.Context(), id, "")
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, reqs)
+}
+
+func (a *API) GetCreditLimitHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	history, err := a.Svc.CreditLimitHistory(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, history)
+}
+
+// ---- Admin (behind middlewares.AdminOnly) ----
+
+// AdminListCreditLimitRequests lists requests, optionally ?status=Pending.
+func (a *API) AdminListCreditLimitRequests(w http.ResponseWriter, r *http.Request) {
+	reqs, err := a.Svc.ListCreditLimitRequests(r.Context(), 0, r.URL.Query().Get("status"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, reqs)
+}
+
+func (a *API) ApproveCreditLimitRequest(w http.ResponseWriter, r *http.Request) {
+	a.decideCreditLimitRequest(w, r, true)
+}
+
+func (a *API) RejectCreditLimitRequest(w http.ResponseWriter, r *http.Request) {
+	a.decideCreditLimitRequest(w, r, false)
+}
+
+func (a *API) decideCreditLimitRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	requestID, err := strconv.ParseInt(chi.URLParam(r, "request_id"), 10, 64)
+	if err != nil || requestID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	var req decisionReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.Reason == "" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.DecideCreditLimitRequest(ctx, requestID, approve, req.Reason)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
+
+func (a *API) ApplyCreditLimitRequest(w http.ResponseWriter, r *http.Request) {
+	requestID, err := strconv.ParseInt(chi.URLParam(r, "request_id"), 10, 64)
+	if err != nil || requestID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.ApplyCreditLimitRequest(ctx, requestID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
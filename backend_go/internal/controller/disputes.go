@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend_go/internal/middlewares"
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type openDisputeReq struct {
+	UserID        int    `json:"user_id"`
+	TransactionID int    `json:"transaction_id"`
+	ReasonCode    string `json:"reason_code"`
+	Description   string `json:"description"`
+}
+
+type merchantResponseReq struct {
+	Accept bool   `json:"accept"`
+	Note   string `json:"note"`
+}
+
+type resolveDisputeReq struct {
+	Outcome string `json:"outcome"` // won / lost
+	Reason  string `json:"reason"`
+}
+
+func (a *API) OpenDispute(w http.ResponseWriter, r *http.Request) {
+	var req openDisputeReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.UserID <= 0 || req.TransactionID <= 0 || req.ReasonCode == "" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.OpenDispute(ctx, service.OpenDisputeRequest{
+		UserID:        req.UserID,
+		TransactionID: req.TransactionID,
+		ReasonCode:    req.ReasonCode,
+		Description:   req.Description,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 201, res)
+}
+
+// GetDispute returns the dispute with its recorded state transitions.
+func (a *API) GetDispute(w http.ResponseWriter, r *http.Request) {
+	disputeID, ok := disputeIDParam(w, r)
+	if !ok {
+		return
+	}
+	d, err := a.Svc.GetDispute(r.Context(), disputeID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, d)
+}
+
+func (a *API) ListUserDisputes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	disputes, err := a.Svc.ListDisputes(r.Context(), id, "")
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, disputes)
+}
+
+// ---- Admin (behind middlewares.AdminOnly) ----
+
+// AdminListDisputes lists disputes, optionally ?status=UnderReview.
+func (a *API) AdminListDisputes(w http.ResponseWriter, r *http.Request) {
+	disputes, err := a.Svc.ListDisputes(r.Context(), 0, r.URL.Query().Get("status"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, disputes)
+}
+
+func (a *API) ResolveDispute(w http.ResponseWriter, r *http.Request) {
+	disputeID, ok := disputeIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req resolveDisputeReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if (req.Outcome != "won" && req.Outcome != "lost") || req.Reason == "" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.ResolveDispute(ctx, disputeID, req.Outcome == "won", req.Reason)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
+
+// ---- Merchant (behind middlewares.MerchantOnly) ----
+
+// MerchantRespondToDispute records the authenticated merchant's answer to an
+// Open dispute on one of its sales.
+func (a *API) MerchantRespondToDispute(w http.ResponseWriter, r *http.Request) {
+	disputeID, ok := disputeIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req merchantResponseReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.Note == "" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.RespondToDispute(ctx, middlewares.MerchantFrom(ctx).Name, disputeID, req.Accept, req.Note)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
+
+func disputeIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	disputeID, err := strconv.ParseInt(chi.URLParam(r, "dispute_id"), 10, 64)
+	if err != nil || disputeID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return 0, false
+	}
+	return disputeID, true
+}
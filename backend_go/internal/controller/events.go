@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	sseHeartbeatInterval = 15 * time.Second
+	sseRetryMillis       = 3000
+	sseReplayLimit       = 500
+)
+
+// sseTruncated is the data of the "truncated" event: only the oldest
+// Replayed missed events were sent, so the client should rebuild its state
+// from the snapshot that follows.
+type sseTruncated struct {
+	Replayed int `json:"replayed"`
+}
+
+// StreamUserEvents is a Server-Sent Events stream of the user's account
+// events (transaction.*, points.*, account.* ... with balance/points snapshot).
+//
+// A fresh connection first receives a "snapshot" event with the current user
+// row. A reconnect with Last-Event-ID (header, or ?lastEventId= for clients
+// that cannot set headers) replays missed events from the outbox instead; if
+// more than sseReplayLimit were missed, the replay ends with a "truncated"
+// event followed by a fresh "snapshot".
+func (a *API) StreamUserEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	lastID := int64(0)
+	lastRaw := r.Header.Get("Last-Event-ID")
+	if lastRaw == "" {
+		lastRaw = r.URL.Query().Get("lastEventId")
+	}
+	if lastRaw != "" {
+		if lastID, err = strconv.ParseInt(lastRaw, 10, 64); err != nil || lastID < 0 {
+			writeError(w, utils.CodeValidationFailed)
+			return
+		}
+	}
+
+	// Subscribe before reading the snapshot and the replay so nothing
+	// committed in between is lost; replayed events that also arrive live
+	// are dropped below.
+	ctx := r.Context()
+	live, unsubscribe := a.Events.Subscribe(id)
+	defer unsubscribe()
+
+	u, err := a.Svc.GetUserDetails(ctx, id)
+	if err != nil {
+		if err.Error() == "User not found" {
+			writeError(w, utils.CodeUserNotFound)
+			return
+		}
+		writeServiceError(w, r, err)
+		return
+	}
+
+	var replay []models.EventEnvelope
+	truncated := false
+	if lastRaw != "" {
+		if replay, err = a.Events.EventsSince(ctx, id, lastID, sseReplayLimit+1); err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		if len(replay) > sseReplayLimit {
+			replay, truncated = replay[:sseReplayLimit], true
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis); err != nil {
+		return
+	}
+	if lastRaw == "" {
+		if err := writeSSE(w, "", "snapshot", u); err != nil {
+			return
+		}
+	}
+	// Outbox ids are allocated at insert, not commit, so a live event can
+	// have a lower id than one already sent; only exact replays are skipped.
+	replayed := make(map[int64]struct{}, len(replay))
+	for _, e := range replay {
+		if err := writeSSE(w, strconv.FormatInt(e.ID, 10), e.Type, e); err != nil {
+			return
+		}
+		replayed[e.ID] = struct{}{}
+	}
+	if truncated {
+		if err := writeSSE(w, "", "truncated", sseTruncated{Replayed: len(replay)}); err != nil {
+			return
+		}
+		if err := writeSSE(w, "", "snapshot", u); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-live:
+			if _, ok := replayed[e.ID]; ok {
+				delete(replayed, e.ID)
+				continue
+			}
+			if err := writeSSE(w, strconv.FormatInt(e.ID, 10), e.Type, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, id, event string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	return err
+}
@@ -20,7 +20,10 @@ import (
 )
 
 type API struct {
-	Svc *service.TransactionService
+	Svc       *service.TransactionService
+	Webhooks  *service.WebhookService
+	Merchants *service.MerchantService
+	Events    *service.EventHub
 }
 
 type healthResp struct {
@@ -32,21 +35,27 @@ func (a *API) Health(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, 200, healthResp{Status: "ok", Time: time.Now().UTC().Format(time.RFC3339Nano)})
 }
 
+// ErrorCatalog lists the public error codes and messages.
+func (a *API) ErrorCatalog(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, 200, utils.ErrorCatalog())
+}
+
 func (a *API) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		utils.WriteJSON(w, 400, utils.APIError{Code: "INVALID_USER_ID", Error: "Invalid user ID format"})
+		writeError(w, utils.CodeInvalidUserID)
 		return
 	}
 	ctx := r.Context()
 	u, err := a.Svc.GetUserDetails(ctx, id)
 	if err != nil {
 		if err.Error() == "User not found" {
-			utils.WriteJSON(w, 404, utils.APIError{Code: "USER_NOT_FOUND", Error: "User not found"})
+			writeError(w, utils.CodeUserNotFound)
 			return
 		}
-		utils.WriteJSON(w, 500, utils.APIError{Code: "INTERNAL_ERROR", Error: "Internal Server Error"})
+		utils.LoggerFrom(ctx).Error("get user failed", utils.LogKeyUserID, id, utils.LogKeyErrorCode, utils.CodeInternalError, "error", err)
+		writeError(w, utils.CodeInternalError)
 		return
 	}
 	utils.WriteJSON(w, 200, u)
@@ -56,22 +65,30 @@ func (a *API) GetUserTransactions(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "user_id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id <= 0 {
-		utils.WriteJSON(w, 400, utils.APIError{Code: "INVALID_USER_ID", Error: "Invalid user ID format"})
+		writeError(w, utils.CodeInvalidUserID)
 		return
 	}
 	txs, err := a.Svc.GetTransactionHistory(r.Context(), id)
 	if err != nil {
-		utils.WriteJSON(w, 500, utils.APIError{Code: "INTERNAL_ERROR", Error: "Internal Server Error"})
+		utils.LoggerFrom(r.Context()).Error("get transactions failed", utils.LogKeyUserID, id, utils.LogKeyErrorCode, utils.CodeInternalError, "error", err)
+		writeError(w, utils.CodeInternalError)
 		return
 	}
 	utils.WriteJSON(w, 200, txs)
 }
 
 type payReq struct {
-	UserID    int     `json:"user_id"`
-	Amount    float64 `json:"amount"`
-	Merchant  string  `json:"merchant"`
-	UsePoints bool    `json:"use_points"`
+	UserID       int     `json:"user_id"`
+	Amount       float64 `json:"amount"`
+	Merchant     string  `json:"merchant"`
+	UsePoints    bool    `json:"use_points"`
+	CardID       *int64  `json:"card_id,omitempty"`
+	Currency     string  `json:"currency,omitempty"`
+	Installments int     `json:"installments,omitempty"` // term in months; omit to pay in full
+
+	// Partial redemption; either implies use_points
+	Points          int     `json:"points,omitempty"`            // points to spend at most
+	MaxRedeemAmount float64 `json:"max_redeem_amount,omitempty"` // discount cap in the billing currency
 }
 
 type actionReq struct {
@@ -79,7 +96,15 @@ type actionReq struct {
 	TargetTransactionID int `json:"target_transaction_id"`
 }
 
-func writeTxError(w http.ResponseWriter, te *service.TxError) {
+// writeError writes a catalogued error (see utils.ErrorCatalog).
+func writeError(w http.ResponseWriter, code string) {
+	spec := utils.LookupError(code)
+	utils.WriteJSON(w, spec.HTTP, utils.APIError{Code: spec.Code, Error: spec.Message})
+}
+
+// writeTxError writes a service error. The step trace and internal detail are
+// only included when the request verbosity allows it.
+func writeTxError(w http.ResponseWriter, r *http.Request, te *service.TxError) {
 	status := te.HTTP
 	if status == 0 {
 		status = 400
@@ -88,78 +113,112 @@ func writeTxError(w http.ResponseWriter, te *service.TxError) {
 	if code == "" {
 		code = "TX_ERROR"
 	}
-	utils.WriteJSON(w, status, utils.APIError{
-		Code:  code,
-		Error: te.Msg,
-		Logs:  te.Logs,
-	})
+	body := utils.APIError{Code: code, Error: te.Msg}
+	if utils.ShowSteps(r.Context()) {
+		body.Detail = te.Detail
+		body.Steps = te.Steps
+	}
+	utils.WriteJSON(w, status, body)
+}
+
+// writeServiceError writes err as a TxError when it is one, INTERNAL_ERROR otherwise.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	if te, ok := err.(*service.TxError); ok {
+		writeTxError(w, r, te)
+		return
+	}
+	utils.LoggerFrom(r.Context()).Error("request failed", utils.LogKeyErrorCode, utils.CodeInternalError, "error", err)
+	writeError(w, utils.CodeInternalError)
 }
 
 func (a *API) Pay(w http.ResponseWriter, r *http.Request) {
 	var req payReq
 	if err := utils.ReadJSON(r, &req); err != nil {
-		utils.WriteJSON(w, 400, utils.APIError{Code: "BAD_JSON", Error: "Invalid JSON body"})
+		writeError(w, utils.CodeBadJSON)
 		return
 	}
-	if req.UserID <= 0 || req.Amount <= 0 || req.Merchant == "" {
-		utils.WriteJSON(w, 400, utils.APIError{Code: "VALIDATION_FAILED", Error: "Validation failed"})
+	if req.UserID <= 0 || req.Amount <= 0 || req.Merchant == "" || (req.CardID != nil && *req.CardID <= 0) || req.Installments < 0 || req.Points < 0 || req.MaxRedeemAmount < 0 {
+		writeError(w, utils.CodeValidationFailed)
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	res, err := a.Svc.ProcessPayment(ctx, req.UserID, req.Amount, req.Merchant, req.UsePoints)
+	res, err := a.Svc.ProcessPayment(ctx, service.PaymentRequest{
+		UserID:    req.UserID,
+		Amount:    req.Amount,
+		Merchant:  req.Merchant,
+		UsePoints: req.UsePoints,
+		CardID:    req.CardID,
+		Currency:  req.Currency,
+
+		Installments: req.Installments,
+
+		RedeemPoints:    req.Points,
+		MaxRedeemAmount: req.MaxRedeemAmount,
+	})
 	if err != nil {
 		if te, ok := err.(*service.TxError); ok {
-			writeTxError(w, te)
+			writeTxError(w, r, te)
 			return
 		}
-		utils.WriteJSON(w, 500, utils.APIError{Code: "INTERNAL_ERROR", Error: "Internal Server Error"})
+		writeError(w, utils.CodeInternalError)
 		return
 	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
 	utils.WriteJSON(w, 201, res)
 }
 
 func (a *API) VoidTx(w http.ResponseWriter, r *http.Request) {
 	var req actionReq
 	if err := utils.ReadJSON(r, &req); err != nil {
-		utils.WriteJSON(w, 400, utils.APIError{Code: "BAD_JSON", Error: "Invalid JSON body"})
+		writeError(w, utils.CodeBadJSON)
 		return
 	}
 	if req.UserID <= 0 || req.TargetTransactionID <= 0 {
-		utils.WriteJSON(w, 400, utils.APIError{Code: "VALIDATION_FAILED", Error: "Validation failed"})
+		writeError(w, utils.CodeValidationFailed)
 		return
 	}
-	res, err := a.Svc.VoidTransaction(r.Context(), req.UserID, req.TargetTransactionID)
+	ctx := r.Context()
+	res, err := a.Svc.VoidTransaction(ctx, req.UserID, req.TargetTransactionID)
 	if err != nil {
 		if te, ok := err.(*service.TxError); ok {
-			writeTxError(w, te)
+			writeTxError(w, r, te)
 			return
 		}
-		utils.WriteJSON(w, 500, utils.APIError{Code: "INTERNAL_ERROR", Error: "Internal Server Error"})
+		writeError(w, utils.CodeInternalError)
 		return
 	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
 	utils.WriteJSON(w, 200, res)
 }
 
 func (a *API) RefundTx(w http.ResponseWriter, r *http.Request) {
 	var req actionReq
 	if err := utils.ReadJSON(r, &req); err != nil {
-		utils.WriteJSON(w, 400, utils.APIError{Code: "BAD_JSON", Error: "Invalid JSON body"})
+		writeError(w, utils.CodeBadJSON)
 		return
 	}
 	if req.UserID <= 0 || req.TargetTransactionID <= 0 {
-		utils.WriteJSON(w, 400, utils.APIError{Code: "VALIDATION_FAILED", Error: "Validation failed"})
+		writeError(w, utils.CodeValidationFailed)
 		return
 	}
-	res, err := a.Svc.RefundTransaction(r.Context(), req.UserID, req.TargetTransactionID)
+	ctx := r.Context()
+	res, err := a.Svc.RefundTransaction(ctx, req.UserID, req.TargetTransactionID)
 	if err != nil {
 		if te, ok := err.(*service.TxError); ok {
-			writeTxError(w, te)
+			writeTxError(w, r, te)
 			return
 		}
-		utils.WriteJSON(w, 500, utils.APIError{Code: "INTERNAL_ERROR", Error: "Internal Server Error"})
+		writeError(w, utils.CodeInternalError)
 		return
 	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
 	utils.WriteJSON(w, 200, res)
 }
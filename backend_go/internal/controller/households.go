@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createHouseholdReq struct {
+	Name        string `json:"name"`
+	OwnerUserID int    `json:"owner_user_id"`
+}
+
+type householdMemberReq struct {
+	OwnerUserID int `json:"owner_user_id"`
+	UserID      int `json:"user_id"`
+}
+
+type contributionReq struct {
+	UserID int    `json:"user_id"`
+	Points int    `json:"points"`
+	Note   string `json:"note"`
+}
+
+func (a *API) CreateHousehold(w http.ResponseWriter, r *http.Request) {
+	var req createHouseholdReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.OwnerUserID <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	res, err := a.Svc.CreateHousehold(r.Context(), req.OwnerUserID, req.Name)
+	writeHouseholdResult(w, r, 201, res, err)
+}
+
+func (a *API) GetHousehold(w http.ResponseWriter, r *http.Request) {
+	householdID, ok := householdIDParam(w, r)
+	if !ok {
+		return
+	}
+	h, err := a.Svc.GetHousehold(r.Context(), householdID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, h)
+}
+
+// AddHouseholdMember adds a user to the household; owner only.
+func (a *API) AddHouseholdMember(w http.ResponseWriter, r *http.Request) {
+	householdID, ok := householdIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req householdMemberReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.OwnerUserID <= 0 || req.UserID <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	res, err := a.Svc.AddHouseholdMember(r.Context(), householdID, req.OwnerUserID, req.UserID)
+	writeHouseholdResult(w, r, 200, res, err)
+}
+
+// RemoveHouseholdMember removes a member; acting_user_id is the owner or
+// the member leaving.
+func (a *API) RemoveHouseholdMember(w http.ResponseWriter, r *http.Request) {
+	householdID, ok := householdIDParam(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	actingUserID, err := strconv.Atoi(r.URL.Query().Get("acting_user_id"))
+	if err != nil || actingUserID <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	res, err := a.Svc.RemoveHouseholdMember(r.Context(), householdID, actingUserID, userID)
+	writeHouseholdResult(w, r, 200, res, err)
+}
+
+// ContributeToHousehold moves a member's points into the household pool.
+func (a *API) ContributeToHousehold(w http.ResponseWriter, r *http.Request) {
+	householdID, ok := householdIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req contributionReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.UserID <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.ContributeToHousehold(ctx, householdID, req.UserID, req.Points, req.Note)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 201, res)
+}
+
+func writeHouseholdResult(w http.ResponseWriter, r *http.Request, status int, res *service.HouseholdResult, err error) {
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(r.Context()) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, status, res)
+}
+
+func householdIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	householdID, err := strconv.ParseInt(chi.URLParam(r, "household_id"), 10, 64)
+	if err != nil || householdID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return 0, false
+	}
+	return householdID, true
+}
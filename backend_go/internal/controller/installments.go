@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *API) ListInstallmentPlans(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	plans, err := a.Svc.ListInstallmentPlans(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, plans)
+}
@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend_go/internal/middlewares"
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createMerchantReq struct {
+	Name string `json:"name"`
+}
+
+type merchantStatusReq struct {
+	Active bool `json:"active"`
+}
+
+type merchantRefundReq struct {
+	TargetTransactionID int `json:"target_transaction_id"`
+}
+
+// ---- Admin ----
+
+// AdminCreateMerchant opens a merchant account; the response carries the only copy of its API key.
+func (a *API) AdminCreateMerchant(w http.ResponseWriter, r *http.Request) {
+	var req createMerchantReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.Name == "" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	m, err := a.Merchants.Create(r.Context(), req.Name)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 201, m)
+}
+
+func (a *API) AdminListMerchants(w http.ResponseWriter, r *http.Request) {
+	list, err := a.Merchants.List(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, list)
+}
+
+func (a *API) RotateMerchantKey(w http.ResponseWriter, r *http.Request) {
+	merchantID, ok := merchantIDParam(w, r)
+	if !ok {
+		return
+	}
+	m, err := a.Merchants.RotateKey(r.Context(), merchantID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, m)
+}
+
+// AdminSetMerchantStatus enables or disables a merchant's API access.
+func (a *API) AdminSetMerchantStatus(w http.ResponseWriter, r *http.Request) {
+	merchantID, ok := merchantIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req merchantStatusReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	m, err := a.Merchants.SetActive(r.Context(), merchantID, req.Active)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, m)
+}
+
+// ---- Merchant API (X-Merchant-Key) ----
+
+// MerchantListTransactions lists the authenticated merchant's transactions,
+// optionally ?status=Paid&from=2025-01-01&to=2025-01-31 (to inclusive)&limit=100.
+func (a *API) MerchantListTransactions(w http.ResponseWriter, r *http.Request) {
+	m := middlewares.MerchantFrom(r.Context())
+	q := r.URL.Query()
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			writeError(w, utils.CodeValidationFailed)
+			return
+		}
+		limit = n
+	}
+	var from, to *time.Time
+	if v := q.Get("from"); v != "" {
+		d, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			writeError(w, utils.CodeInvalidReportDate)
+			return
+		}
+		from = &d
+	}
+	if v := q.Get("to"); v != "" {
+		d, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			writeError(w, utils.CodeInvalidReportDate)
+			return
+		}
+		d = d.AddDate(0, 0, 1)
+		to = &d
+	}
+	txs, err := a.Merchants.ListTransactions(r.Context(), m.Name, q.Get("status"), from, to, limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, txs)
+}
+
+// MerchantRefund refunds one of the authenticated merchant's sales.
+func (a *API) MerchantRefund(w http.ResponseWriter, r *http.Request) {
+	var req merchantRefundReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.TargetTransactionID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.MerchantRefund(ctx, middlewares.MerchantFrom(ctx).Name, req.TargetTransactionID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
+
+// MerchantSettlementReport returns the daily settlement report of the
+// authenticated merchant as JSON, or as a CSV download with ?format=csv.
+func (a *API) MerchantSettlementReport(w http.ResponseWriter, r *http.Request) {
+	day, err := time.Parse(time.DateOnly, chi.URLParam(r, "date"))
+	if err != nil {
+		writeError(w, utils.CodeInvalidReportDate)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	m := middlewares.MerchantFrom(r.Context())
+	report, err := a.Merchants.SettlementReport(r.Context(), m.Name, day)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if format != "csv" {
+		utils.WriteJSON(w, 200, report)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="settlement-%d-%s.csv"`, m.MerchantID, report.Date))
+	w.WriteHeader(200)
+	if err := writeSettlementCSV(w, report); err != nil {
+		utils.LoggerFrom(r.Context()).Warn("settlement csv write failed", "error", err)
+	}
+}
+
+// writeSettlementCSV writes one row per line followed by the totals, which
+// use the line columns with an empty transaction id.
+func writeSettlementCSV(w io.Writer, report *models.SettlementReport) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"type", "transaction_id", "source_transaction_id", "user_id", "amount", "occurred_at"})
+	for _, l := range report.Lines {
+		source := ""
+		if l.SourceTransactionID != nil {
+			source = strconv.FormatInt(*l.SourceTransactionID, 10)
+		}
+		_ = cw.Write([]string{l.Type, strconv.FormatInt(l.TransactionID, 10), source, strconv.Itoa(l.UserID), fmt.Sprintf("%.2f", l.Amount), l.OccurredAt.Format(time.RFC3339)})
+	}
+	for _, total := range []struct {
+		name   string
+		amount float64
+	}{
+		{"gross_sales", report.GrossSales},
+		{"refunds", report.Refunds},
+		{"chargebacks", report.Chargebacks},
+		{"net_payable", report.NetPayable},
+	} {
+		_ = cw.Write([]string{total.name, "", "", "", fmt.Sprintf("%.2f", total.amount), ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func merchantIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	merchantID, err := strconv.ParseInt(chi.URLParam(r, "merchant_id"), 10, 64)
+	if err != nil || merchantID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return 0, false
+	}
+	return merchantID, true
+}
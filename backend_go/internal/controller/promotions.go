@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createPromotionReq struct {
+	Name           string     `json:"name"`
+	Kind           string     `json:"kind"`
+	Merchant       string     `json:"merchant"`
+	Category       string     `json:"category"`
+	Multiplier     *float64   `json:"multiplier"`
+	BonusPoints    *int       `json:"bonus_points"`
+	UserMonthlyCap *int       `json:"user_monthly_cap"`
+	Stackable      *bool      `json:"stackable"` // default true
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+}
+
+// ListPromotions lists the promotions running now.
+func (a *API) ListPromotions(w http.ResponseWriter, r *http.Request) {
+	list, err := a.Svc.ListPromotions(r.Context(), true)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, list)
+}
+
+// ---- Admin ----
+
+func (a *API) AdminCreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var req createPromotionReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	stackable := req.Stackable == nil || *req.Stackable
+	p, err := a.Svc.CreatePromotion(r.Context(), service.CreatePromotionRequest{
+		Name: req.Name, Kind: req.Kind, Merchant: req.Merchant, Category: req.Category,
+		Multiplier: req.Multiplier, BonusPoints: req.BonusPoints, UserMonthlyCap: req.UserMonthlyCap,
+		Stackable: stackable, StartsAt: req.StartsAt, EndsAt: req.EndsAt,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 201, p)
+}
+
+// AdminListPromotions lists every promotion, including ended and deactivated ones.
+func (a *API) AdminListPromotions(w http.ResponseWriter, r *http.Request) {
+	list, err := a.Svc.ListPromotions(r.Context(), false)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, list)
+}
+
+// DeactivatePromotion stops a promotion; bonuses already granted stay.
+func (a *API) DeactivatePromotion(w http.ResponseWriter, r *http.Request) {
+	promotionID, err := strconv.ParseInt(chi.URLParam(r, "promotion_id"), 10, 64)
+	if err != nil || promotionID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	p, err := a.Svc.DeactivatePromotion(r.Context(), promotionID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, p)
+}
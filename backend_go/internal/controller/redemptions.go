@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type redeemReq struct {
+	Kind   string `json:"kind"`   // statement_credit or catalogue
+	Points int    `json:"points"` // statement_credit
+	Item   string `json:"item"`   // catalogue SKU
+	// FromHousehold spends the pool of the user's household
+	FromHousehold bool `json:"from_household"`
+}
+
+// RedeemPoints spends points without a purchase.
+func (a *API) RedeemPoints(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	var req redeemReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.RedeemPoints(ctx, service.RedeemRequest{UserID: id, Kind: req.Kind, Points: req.Points, Item: req.Item, FromHousehold: req.FromHousehold})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 201, res)
+}
+
+func (a *API) ListRedemptions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	list, err := a.Svc.ListRedemptions(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, list)
+}
+
+// ListRewardCatalogue lists the items that can be redeemed with points.
+func (a *API) ListRewardCatalogue(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, 200, a.Svc.ListCatalogue())
+}
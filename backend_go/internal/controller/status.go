@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type accountActionReq struct {
+	Reason string `json:"reason"`
+}
+
+type adminStatusReq struct {
+	Status      string     `json:"status"`
+	Reason      string     `json:"reason"`
+	FrozenUntil *time.Time `json:"frozen_until"`
+}
+
+func (a *API) FreezeAccount(w http.ResponseWriter, r *http.Request) {
+	a.changeAccountStatus(w, r, "freeze")
+}
+
+func (a *API) UnfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	a.changeAccountStatus(w, r, "unfreeze")
+}
+
+func (a *API) changeAccountStatus(w http.ResponseWriter, r *http.Request, action string) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	var req accountActionReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.ChangeAccountStatus(ctx, id, action, req.Reason)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
+
+func (a *API) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	changes, err := a.Svc.StatusHistory(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, changes)
+}
+
+// ---- Admin (behind middlewares.AdminOnly) ----
+
+func (a *API) AdminSetAccountStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	var req adminStatusReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.Status == "" || req.Reason == "" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.AdminSetAccountStatus(ctx, id, req.Status, req.Reason, req.FrozenUntil)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
+
+func (a *API) AdminSetCardStatus(w http.ResponseWriter, r *http.Request) {
+	cardID, err := strconv.ParseInt(chi.URLParam(r, "card_id"), 10, 64)
+	if err != nil || cardID <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	var req adminStatusReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.Status == "" || req.Reason == "" || req.FrozenUntil != nil {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.AdminSetCardStatus(ctx, cardID, req.Status, req.Reason)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 200, res)
+}
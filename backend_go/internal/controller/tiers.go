@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetTier shows the user's loyalty tier and progress to the next tier.
+func (a *API) GetTier(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	st, err := a.Svc.GetTierStatus(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, st)
+}
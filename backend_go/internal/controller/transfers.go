@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type transferReq struct {
+	FromUserID int    `json:"from_user_id"`
+	ToUserID   int    `json:"to_user_id"`
+	Points     int    `json:"points"`
+	Note       string `json:"note"`
+}
+
+// TransferPoints moves points from one user to another.
+func (a *API) TransferPoints(w http.ResponseWriter, r *http.Request) {
+	var req transferReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.FromUserID <= 0 || req.ToUserID <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	ctx := r.Context()
+	res, err := a.Svc.TransferPoints(ctx, service.TransferRequest{FromUserID: req.FromUserID, ToUserID: req.ToUserID, Points: req.Points, Note: req.Note})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !utils.ShowSteps(ctx) {
+		res.Steps = nil
+	}
+	utils.WriteJSON(w, 201, res)
+}
+
+// ListPointTransfers lists the transfers a user sent or received.
+func (a *API) ListPointTransfers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeInvalidUserID)
+		return
+	}
+	list, err := a.Svc.ListPointTransfers(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, list)
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type registerWebhookReq struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+type successResp struct {
+	Success bool `json:"success"`
+}
+
+func (a *API) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	var req registerWebhookReq
+	if err := utils.ReadJSON(r, &req); err != nil {
+		writeError(w, utils.CodeBadJSON)
+		return
+	}
+	if req.URL == "" {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	wh, err := a.Webhooks.Register(r.Context(), req.URL, req.Secret, req.EventTypes)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 201, wh)
+}
+
+func (a *API) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	list, err := a.Webhooks.List(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, list)
+}
+
+func (a *API) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	if err := a.Webhooks.Deactivate(r.Context(), id); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, successResp{Success: true})
+}
+
+func (a *API) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			writeError(w, utils.CodeValidationFailed)
+			return
+		}
+		limit = n
+	}
+	list, err := a.Webhooks.DeadLetters(r.Context(), limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, list)
+}
+
+func (a *API) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, utils.CodeValidationFailed)
+		return
+	}
+	if err := a.Webhooks.Retry(r.Context(), id); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, 200, successResp{Success: true})
+}
@@ -2,9 +2,12 @@ package initialize
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"backend_go/internal/controller"
+	"backend_go/internal/middlewares"
+	"backend_go/internal/repo"
 	"backend_go/internal/routers"
 	service "backend_go/internal/services"
 
@@ -18,15 +21,25 @@ type App struct {
 	DB    *pgxpool.Pool
 	Redis *redis.Client
 
-	Svc *service.TransactionService
+	Svc       *service.TransactionService
+	Webhooks  *service.WebhookService
+	Merchants *service.MerchantService
+	Events    *service.EventHub
 }
 
 func Build(ctx context.Context, env Env) (*App, error) {
-	pool, err := NewPGPool(ctx, env.DatabaseURL)
+	pool, err := NewPGPool(ctx, env.DatabaseURL, env.TraceRedactColumns)
 	if err != nil {
 		return nil, err
 	}
 
+	if env.MigrateOnStart {
+		if err := Migrate(ctx, pool); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
 	rdb, err := NewRedis(ctx, env.RedisAddr, env.RedisPassword, env.RedisDB)
 	if err != nil {
 		pool.Close()
@@ -34,23 +47,68 @@ func Build(ctx context.Context, env Env) (*App, error) {
 	}
 
 	risk := &service.RiskEngine{
-		Redis: rdb,
-		Rules: service.DefaultRules(env.LoadTest),
+		Redis:        rdb,
+		Rules:        service.DefaultRules(env.LoadTest),
+		Transactions: repo.Postgres().Transactions,
+		Transfers:    repo.Postgres().Transfers,
 	}
 
-	svc := &service.TransactionService{
-		Pool: pool,
-		Risk: risk,
+	events := service.NewEventHub(rdb, pool)
+	// Every background job started below runs until ctx is cancelled
+	// (process shutdown).
+	go events.Run(ctx)
+
+	svc := NewTransactionService(pool, env, risk, events)
+
+	if env.InstallmentBilling {
+		biller := &service.InstallmentBiller{Pool: pool, Svc: svc, Interval: env.InstallmentPollInterval, Batch: 100}
+		go biller.Run(ctx)
+	}
+	if env.AuthExpirySweep {
+		sweeper := &service.AuthorizationSweeper{Pool: pool, Svc: svc, Interval: env.AuthExpirySweepInterval, Batch: 100}
+		go sweeper.Run(ctx)
+	}
+	if env.DisputeSweep {
+		sweeper := &service.DisputeSweeper{Pool: pool, Svc: svc, Interval: env.DisputeSweepInterval, Batch: 100}
+		go sweeper.Run(ctx)
+	}
+	if env.TierReview {
+		reviewer := &service.TierReviewer{Pool: pool, Svc: svc, Interval: env.TierReviewInterval, MaxAge: env.TierReviewMaxAge, Batch: 100}
+		go reviewer.Run(ctx)
 	}
 
-	api := &controller.API{Svc: svc}
-	h := routers.NewRouter(api)
+	webhooks := &service.WebhookService{Pool: pool, AllowPrivateTargets: env.WebhookAllowPrivate}
+	merchants := &service.MerchantService{Pool: pool}
+
+	if env.WebhookDispatch {
+		cfg := service.DefaultWebhookConfig()
+		cfg.PollInterval = env.WebhookPollInterval
+		cfg.MaxAttempts = env.WebhookMaxAttempts
+		cfg.BackoffBase = env.WebhookBackoffBase
+		cfg.Timeout = env.WebhookTimeout
+		dispatcher := &service.WebhookDispatcher{
+			Pool:   pool,
+			Client: service.NewWebhookClient(cfg.Timeout, env.WebhookAllowPrivate),
+			Config: cfg,
+		}
+		go dispatcher.Run(ctx)
+	}
+
+	api := &controller.API{Svc: svc, Webhooks: webhooks, Merchants: merchants, Events: events}
+	h := routers.NewRouter(api, slog.Default(), middlewares.VerbosityPolicy{
+		Default:    env.Verbosity,
+		Routes:     env.VerbosityRoutes,
+		DebugToken: env.DebugToken,
+	}, env.AdminToken, merchants)
 
 	return &App{
-		Handler: h,
-		DB:      pool,
-		Redis:   rdb,
-		Svc:     svc,
+		Handler:   h,
+		DB:        pool,
+		Redis:     rdb,
+		Svc:       svc,
+		Webhooks:  webhooks,
+		Merchants: merchants,
+		Events:    events,
 	}, nil
 }
 
@@ -66,3 +124,38 @@ func (a *App) Close() {
 		a.DB.Close()
 	}
 }
+
+// NewTransactionService configures the transaction service from env without
+// starting any of its background jobs. risk and events may be nil for tools
+// that only settle or capture (e.g. cmd/clearing).
+func NewTransactionService(pool *pgxpool.Pool, env Env, risk *service.RiskEngine, events *service.EventHub) *service.TransactionService {
+	return &service.TransactionService{
+		Pool:   pool,
+		Repos:  repo.Postgres(),
+		Risk:   risk,
+		Events: events,
+		Rates:  &service.FileRateProvider{Path: env.FXRatesFile},
+		FX: service.FXConfig{
+			BillingCurrency:     env.BillingCurrency,
+			FeeRate:             env.FXFeePercent / 100,
+			RefundAtCurrentRate: env.FXRefundRate == "current",
+		},
+		Installments: service.InstallmentConfig{
+			MinAmount: env.InstallmentMinAmount,
+			Cycle:     env.InstallmentCycle,
+		},
+		Capture: service.CaptureConfig{
+			AuthExpiry:       env.AuthExpiry,
+			AutoCaptureDelay: env.AutoCaptureDelay,
+		},
+		Disputes: service.DisputeConfig{
+			FilingWindow:   env.DisputeFilingWindow,
+			ResponseWindow: env.DisputeResponseWindow,
+		},
+		Promotions: service.PromotionConfig{MonthlyCap: env.PromoMonthlyCap},
+		Redemption: service.RedemptionConfig{
+			TierRates:     env.PointsTierRates,
+			MerchantRates: env.PointsMerchantRates,
+		},
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"time"
 
+	"backend_go/internal/migrations"
+	"backend_go/internal/repo"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewPGPool(ctx context.Context, connString string) (*pgxpool.Pool, error) {
+func NewPGPool(ctx context.Context, connString string, redactColumns []string) (*pgxpool.Pool, error) {
 	cfg, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, err
@@ -18,6 +21,11 @@ func NewPGPool(ctx context.Context, connString string) (*pgxpool.Pool, error) {
 	cfg.MaxConnIdleTime = 5 * time.Minute
 	cfg.MaxConnLifetime = 30 * time.Minute
 
+	// Record the statements of service transactions into their TxLogger
+	cfg.ConnConfig.Tracer = &repo.StepTracer{
+		Redactor: repo.NewRedactor(append(repo.DefaultRedactedColumns, redactColumns...)...),
+	}
+
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
@@ -31,3 +39,13 @@ func NewPGPool(ctx context.Context, connString string) (*pgxpool.Pool, error) {
 	}
 	return pool, nil
 }
+
+// Migrate applies every pending embedded migration.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	runner, err := migrations.New(pool)
+	if err != nil {
+		return err
+	}
+	_, err = runner.Up(ctx, 0)
+	return err
+}
@@ -4,6 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	service "backend_go/internal/services"
+	"backend_go/internal/utils"
 )
 
 type Env struct {
@@ -20,8 +25,78 @@ type Env struct {
 	RedisPassword string
 	RedisDB       int
 
+	// Apply pending schema migrations in Build (see `server migrate`)
+	MigrateOnStart bool
+
 	// Optional: if true, relax risk rules for load testing
 	LoadTest bool
+
+	// Structured logging: level (debug/info/warn/error) and format (json/text)
+	LogLevel  string
+	LogFormat string
+
+	// Extra column names whose bound args are masked in the SQL step trace
+	TraceRedactColumns []string
+
+	// Response verbosity: default mode, per-route-prefix overrides and the
+	// operator token that forces debug output (see middlewares.VerbosityPolicy)
+	Verbosity       utils.Verbosity
+	VerbosityRoutes map[string]utils.Verbosity
+	DebugToken      string
+
+	// Token required in X-Admin-Token for /api/admin/*; empty disables the admin API
+	AdminToken string
+
+	// Foreign-currency payments: billing currency, rate file (FileRateProvider),
+	// foreign transaction fee in percent and refund rate ("original" or "current")
+	BillingCurrency string
+	FXRatesFile     string
+	FXFeePercent    float64
+	FXRefundRate    string
+
+	// Installment purchases: minimum billed amount, billing cycle and the
+	// background job that posts due installments
+	InstallmentMinAmount    float64
+	InstallmentCycle        time.Duration
+	InstallmentBilling      bool
+	InstallmentPollInterval time.Duration
+
+	// Authorizations: expiry of uncaptured authorizations, demo auto-capture
+	// delay (0: merchants must capture) and the expiry job
+	AuthExpiry              time.Duration
+	AutoCaptureDelay        time.Duration
+	AuthExpirySweep         bool
+	AuthExpirySweepInterval time.Duration
+
+	// Disputes: filing window after the purchase, merchant response window
+	// and the background job that resolves unanswered disputes
+	DisputeFilingWindow   time.Duration
+	DisputeResponseWindow time.Duration
+	DisputeSweep          bool
+	DisputeSweepInterval  time.Duration
+
+	// Promotions: bonus points per user and month over all promotions (0: no global cap)
+	PromoMonthlyCap int
+
+	// Loyalty tiers: the background job that re-evaluates tiers not refreshed
+	// within TierReviewMaxAge (tiers also refresh on every capture)
+	TierReview         bool
+	TierReviewInterval time.Duration
+	TierReviewMaxAge   time.Duration
+
+	// Points redemption rates (points per $1) by tier and by merchant, as
+	// comma-separated name=points lists; unset tiers keep their default rate
+	PointsTierRates     map[string]int
+	PointsMerchantRates map[string]int
+
+	// Webhook dispatcher (outbox -> registered endpoints)
+	WebhookDispatch     bool
+	WebhookPollInterval time.Duration
+	WebhookMaxAttempts  int
+	WebhookBackoffBase  time.Duration
+	WebhookTimeout      time.Duration
+	// Accept loopback / private webhook targets (local development only)
+	WebhookAllowPrivate bool
 }
 
 func LoadEnv() Env {
@@ -41,6 +116,66 @@ func LoadEnv() Env {
 
 	loadTest := getenvBool("LOADTEST", false)
 
+	migrateOnStart := getenvBool("MIGRATE_ON_START", false)
+
+	logLevel := getenv("LOG_LEVEL", "info")
+	logFormat := getenv("LOG_FORMAT", "json")
+
+	traceRedact := getenvList("TRACE_REDACT_COLUMNS")
+
+	verbosity := utils.ParseVerbosity(os.Getenv("API_VERBOSITY"), utils.VerbosityProduction)
+	verbosityRoutes := make(map[string]utils.Verbosity)
+	for _, kv := range getenvList("API_VERBOSITY_ROUTES") {
+		prefix, mode, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		verbosityRoutes[strings.TrimSpace(prefix)] = utils.ParseVerbosity(mode, verbosity)
+	}
+	debugToken := os.Getenv("DEBUG_TOKEN")
+	adminToken := os.Getenv("ADMIN_TOKEN")
+
+	fxDefaults := service.DefaultFXConfig()
+	billingCurrency := strings.ToUpper(getenv("BILLING_CURRENCY", fxDefaults.BillingCurrency))
+	fxRatesFile := getenv("FX_RATES_FILE", "db/fx/rates.json")
+	fxFeePercent := getenvFloat("FX_FEE_PERCENT", fxDefaults.FeeRate*100)
+	fxRefundRate := getenv("FX_REFUND_RATE", "original")
+
+	instDefaults := service.DefaultInstallmentConfig()
+	installmentMin := getenvFloat("INSTALLMENT_MIN_AMOUNT", instDefaults.MinAmount)
+	installmentCycle := getenvDuration("INSTALLMENT_CYCLE", instDefaults.Cycle)
+	installmentBilling := getenvBool("INSTALLMENT_BILLING", true)
+	installmentPoll := getenvDuration("INSTALLMENT_POLL_INTERVAL", time.Minute)
+
+	captureDefaults := service.DefaultCaptureConfig()
+	authExpiry := getenvDuration("AUTH_EXPIRY", captureDefaults.AuthExpiry)
+	autoCaptureDelay := getenvDuration("AUTO_CAPTURE_DELAY", captureDefaults.AutoCaptureDelay)
+	authExpirySweep := getenvBool("AUTH_EXPIRY_SWEEP", true)
+	authExpirySweepInterval := getenvDuration("AUTH_EXPIRY_SWEEP_INTERVAL", time.Minute)
+
+	disputeDefaults := service.DefaultDisputeConfig()
+	disputeFiling := getenvDuration("DISPUTE_FILING_WINDOW", disputeDefaults.FilingWindow)
+	disputeResponse := getenvDuration("DISPUTE_RESPONSE_WINDOW", disputeDefaults.ResponseWindow)
+	disputeSweep := getenvBool("DISPUTE_SWEEP", true)
+	disputeSweepInterval := getenvDuration("DISPUTE_SWEEP_INTERVAL", time.Minute)
+
+	promoMonthlyCap := getenvInt("PROMO_MONTHLY_CAP", service.DefaultPromotionConfig().MonthlyCap)
+
+	tierReview := getenvBool("TIER_REVIEW", true)
+	tierReviewInterval := getenvDuration("TIER_REVIEW_INTERVAL", 10*time.Minute)
+	tierReviewMaxAge := getenvDuration("TIER_REVIEW_MAX_AGE", 24*time.Hour)
+
+	pointsTierRates := getenvIntMap("POINTS_TIER_RATES")
+	pointsMerchantRates := getenvIntMap("POINTS_MERCHANT_RATES")
+
+	whDefaults := service.DefaultWebhookConfig()
+	webhookDispatch := getenvBool("WEBHOOK_DISPATCH", true)
+	webhookPoll := getenvDuration("WEBHOOK_POLL_INTERVAL", whDefaults.PollInterval)
+	webhookMaxAttempts := getenvInt("WEBHOOK_MAX_ATTEMPTS", whDefaults.MaxAttempts)
+	webhookBackoff := getenvDuration("WEBHOOK_BACKOFF_BASE", whDefaults.BackoffBase)
+	webhookTimeout := getenvDuration("WEBHOOK_TIMEOUT", whDefaults.Timeout)
+	webhookAllowPrivate := getenvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false)
+
 	return Env{
 		Port:          port,
 		DatabaseURL:   databaseURL,
@@ -53,6 +188,54 @@ func LoadEnv() Env {
 		RedisPassword: redisPass,
 		RedisDB:       redisDB,
 		LoadTest:      loadTest,
+		LogLevel:      logLevel,
+		LogFormat:     logFormat,
+
+		MigrateOnStart: migrateOnStart,
+
+		TraceRedactColumns: traceRedact,
+
+		Verbosity:       verbosity,
+		VerbosityRoutes: verbosityRoutes,
+		DebugToken:      debugToken,
+
+		AdminToken: adminToken,
+
+		BillingCurrency: billingCurrency,
+		FXRatesFile:     fxRatesFile,
+		FXFeePercent:    fxFeePercent,
+		FXRefundRate:    fxRefundRate,
+
+		InstallmentMinAmount:    installmentMin,
+		InstallmentCycle:        installmentCycle,
+		InstallmentBilling:      installmentBilling,
+		InstallmentPollInterval: installmentPoll,
+
+		AuthExpiry:              authExpiry,
+		AutoCaptureDelay:        autoCaptureDelay,
+		AuthExpirySweep:         authExpirySweep,
+		AuthExpirySweepInterval: authExpirySweepInterval,
+
+		DisputeFilingWindow:   disputeFiling,
+		DisputeResponseWindow: disputeResponse,
+		DisputeSweep:          disputeSweep,
+		DisputeSweepInterval:  disputeSweepInterval,
+
+		PromoMonthlyCap: promoMonthlyCap,
+
+		TierReview:         tierReview,
+		TierReviewInterval: tierReviewInterval,
+		TierReviewMaxAge:   tierReviewMaxAge,
+
+		PointsTierRates:     pointsTierRates,
+		PointsMerchantRates: pointsMerchantRates,
+
+		WebhookDispatch:     webhookDispatch,
+		WebhookPollInterval: webhookPoll,
+		WebhookMaxAttempts:  webhookMaxAttempts,
+		WebhookBackoffBase:  webhookBackoff,
+		WebhookTimeout:      webhookTimeout,
+		WebhookAllowPrivate: webhookAllowPrivate,
 	}
 }
 
@@ -82,6 +265,18 @@ func getenvInt(key string, def int) int {
 	return i
 }
 
+func getenvFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
 func getenvBool(key string, def bool) bool {
 	v := os.Getenv(key)
 	if v == "" {
@@ -93,3 +288,44 @@ func getenvBool(key string, def bool) bool {
 	}
 	return b
 }
+
+func getenvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+func getenvList(key string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	out := make([]string, 0)
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// getenvIntMap parses "name=int,name=int"; malformed or non-positive entries are skipped.
+func getenvIntMap(key string) map[string]int {
+	out := make(map[string]int)
+	for _, kv := range getenvList(key) {
+		name, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && n > 0 {
+			out[strings.TrimSpace(name)] = n
+		}
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"backend_go/internal/utils"
+)
+
+// AdminTokenHeader carries the admin API token.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminOnly rejects requests without the configured admin token with
+// ADMIN_FORBIDDEN. An empty token disables the admin API entirely.
+func AdminOnly(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tok := r.Header.Get(AdminTokenHeader)
+			if token == "" || tok == "" || subtle.ConstantTimeCompare([]byte(tok), []byte(token)) != 1 {
+				writeCatalogued(w, utils.CodeAdminForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -10,8 +10,8 @@ func CORS() func(http.Handler) http.Handler {
 	return cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"}, // TODO: restrict in production (e.g. https://example.com)
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Debug-Token", "X-Admin-Token", "X-Merchant-Key", "Last-Event-ID"},
+		ExposedHeaders:   []string{"Link", "X-Request-Id"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	})
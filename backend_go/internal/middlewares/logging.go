@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"backend_go/internal/utils"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestLogger attaches a request-scoped slog logger (with request_id) to the
+// context and writes one access log line per request.
+// It must run after middleware.RequestID.
+func RequestLogger(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqID := middleware.GetReqID(r.Context())
+			logger := base.With(utils.LogKeyRequestID, reqID)
+			if reqID != "" {
+				w.Header().Set(middleware.RequestIDHeader, reqID)
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r.WithContext(utils.WithLogger(r.Context(), logger)))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			logger.Log(r.Context(), level, "http request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+)
+
+// MerchantKeyHeader carries a merchant API key.
+const MerchantKeyHeader = "X-Merchant-Key"
+
+// MerchantAuthenticator resolves an API key to its active merchant, or nil.
+type MerchantAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.Merchant, error)
+}
+
+type merchantCtxKey struct{}
+
+// MerchantFrom returns the merchant authenticated by MerchantOnly.
+func MerchantFrom(ctx context.Context) *models.Merchant {
+	m, _ := ctx.Value(merchantCtxKey{}).(*models.Merchant)
+	return m
+}
+
+// MerchantOnly rejects requests without the API key of an active merchant
+// with MERCHANT_UNAUTHORIZED and stores the merchant in the request context.
+func MerchantOnly(auth MerchantAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			var m *models.Merchant
+			if key := r.Header.Get(MerchantKeyHeader); key != "" {
+				var err error
+				if m, err = auth.Authenticate(ctx, key); err != nil {
+					utils.LoggerFrom(ctx).Error("merchant authentication failed", utils.LogKeyErrorCode, utils.CodeInternalError, "error", err)
+					writeCatalogued(w, utils.CodeInternalError)
+					return
+				}
+			}
+			if m == nil {
+				writeCatalogued(w, utils.CodeMerchantUnauthorized)
+				return
+			}
+			ctx = utils.WithLogFields(context.WithValue(ctx, merchantCtxKey{}, m), utils.LogKeyMerchant, m.Name)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func writeCatalogued(w http.ResponseWriter, code string) {
+	spec := utils.LookupError(code)
+	utils.WriteJSON(w, spec.HTTP, utils.APIError{Code: spec.Code, Error: spec.Message})
+}
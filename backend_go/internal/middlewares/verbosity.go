@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"backend_go/internal/utils"
+)
+
+// DebugTokenHeader lets an operator request debug output from a production
+// deployment by presenting the configured debug token.
+const DebugTokenHeader = "X-Debug-Token"
+
+// VerbosityPolicy decides the response verbosity of a request.
+// Resolution order: debug token (operator role) > longest matching route prefix > Default.
+type VerbosityPolicy struct {
+	Default    utils.Verbosity
+	Routes     map[string]utils.Verbosity
+	DebugToken string
+}
+
+func (p VerbosityPolicy) resolve(r *http.Request) utils.Verbosity {
+	if p.DebugToken != "" {
+		tok := r.Header.Get(DebugTokenHeader)
+		if tok != "" && subtle.ConstantTimeCompare([]byte(tok), []byte(p.DebugToken)) == 1 {
+			return utils.VerbosityDebug
+		}
+	}
+	v, best := p.Default, -1
+	for prefix, rv := range p.Routes {
+		if strings.HasPrefix(r.URL.Path, prefix) && len(prefix) > best {
+			v, best = rv, len(prefix)
+		}
+	}
+	return v
+}
+
+// Verbosity stores the resolved utils.Verbosity in the request context.
+func Verbosity(p VerbosityPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := utils.WithVerbosity(r.Context(), p.resolve(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
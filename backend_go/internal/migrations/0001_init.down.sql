@@ -0,0 +1,4 @@
+-- Drops the initial schema, children before parents.
+DROP TABLE IF EXISTS Points;
+DROP TABLE IF EXISTS Transactions;
+DROP TABLE IF EXISTS Users;
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS CreditLimitHistory;
+DROP TABLE IF EXISTS CreditLimitRequests;
+DROP TABLE IF EXISTS StatusChanges;
+DROP TABLE IF EXISTS Cards;
+
+ALTER TABLE Users
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS status_changed_by,
+    DROP COLUMN IF EXISTS frozen_until,
+    DROP COLUMN IF EXISTS temp_limit_boost,
+    DROP COLUMN IF EXISTS temp_limit_boost_until,
+    DROP COLUMN IF EXISTS installment_reserved,
+    DROP COLUMN IF EXISTS auth_hold,
+    DROP COLUMN IF EXISTS tier,
+    DROP COLUMN IF EXISTS tier_spend,
+    DROP COLUMN IF EXISTS tier_evaluated_at;
@@ -0,0 +1,108 @@
+-- Account status, limit boosts, credit reservations and loyalty tiers on
+-- Users; cards, the status audit trail and the credit limit workflow.
+ALTER TABLE Users
+    -- Account status; only Active accounts can pay or refund. frozen_until
+    -- makes a Frozen status temporary (lifted on the next payment attempt).
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'Active' CHECK (status IN ('Active','Frozen','Blocked','Closed')),
+    ADD COLUMN IF NOT EXISTS status_reason VARCHAR(200),
+    ADD COLUMN IF NOT EXISTS status_changed_by VARCHAR(20) CHECK (status_changed_by IN ('cardholder','admin','system')),
+    ADD COLUMN IF NOT EXISTS frozen_until TIMESTAMP DEFAULT NULL,
+    -- Temporary limit boost on top of credit_limit, ignored after its expiry
+    ADD COLUMN IF NOT EXISTS temp_limit_boost DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    ADD COLUMN IF NOT EXISTS temp_limit_boost_until TIMESTAMP DEFAULT NULL,
+    -- Unposted installments of settled installment purchases; counts against
+    -- credit_limit together with balance
+    ADD COLUMN IF NOT EXISTS installment_reserved DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    -- Open (uncaptured) authorizations; also counts against credit_limit
+    ADD COLUMN IF NOT EXISTS auth_hold DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    -- Loyalty tier from the rolling 12-month settled spend, refreshed on
+    -- capture and by the tier review job
+    ADD COLUMN IF NOT EXISTS tier VARCHAR(10) NOT NULL DEFAULT 'Standard' CHECK (tier IN ('Standard','Silver','Gold','Platinum')),
+    ADD COLUMN IF NOT EXISTS tier_spend DECIMAL(12, 2) NOT NULL DEFAULT 0.00,
+    ADD COLUMN IF NOT EXISTS tier_evaluated_at TIMESTAMP DEFAULT NULL;
+
+-- Cards share the account limit (Users.credit_limit / Users.balance) and each
+-- has its own limit and balance on top of it. The PAN itself is never stored.
+CREATE TABLE IF NOT EXISTS Cards (
+    card_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INT NOT NULL,
+    card_type VARCHAR(20) NOT NULL CHECK (card_type IN ('Primary','Virtual','Supplementary')),
+    pan_token VARCHAR(64) NOT NULL UNIQUE,
+    last4 CHAR(4) NOT NULL,
+    expires_at DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'Active' CHECK (status IN ('Active','Frozen','Blocked','Closed')),
+    credit_limit DECIMAL(10, 2) NOT NULL,
+    balance DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    -- Virtual card controls: closed automatically once used (single_use) or
+    -- once spent reaches amount_cap; merchant_lock restricts to one merchant.
+    single_use BOOLEAN NOT NULL DEFAULT FALSE,
+    amount_cap DECIMAL(10, 2) DEFAULT NULL,
+    merchant_lock VARCHAR(50) DEFAULT NULL,
+    spent DECIMAL(10, 2) NOT NULL DEFAULT 0.00, -- authorized total, counted against amount_cap
+    installment_reserved DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    auth_hold DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id)
+);
+
+-- At most one open primary card per user (a Blocked lost/stolen card can be replaced)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cards_one_primary
+ON Cards (user_id) WHERE card_type = 'Primary' AND status IN ('Active','Frozen');
+
+-- Audit trail of account and card status changes
+CREATE TABLE IF NOT EXISTS StatusChanges (
+    change_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INT NOT NULL,
+    card_id BIGINT DEFAULT NULL, -- NULL: account status change
+    old_status VARCHAR(20) NOT NULL,
+    new_status VARCHAR(20) NOT NULL,
+    reason VARCHAR(200) NOT NULL,
+    actor VARCHAR(20) NOT NULL CHECK (actor IN ('cardholder','admin','system')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (card_id) REFERENCES Cards(card_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_changes_user
+ON StatusChanges (user_id, created_at);
+
+-- Credit limit change workflow: Pending -> Approved -> Applied, or Rejected
+CREATE TABLE IF NOT EXISTS CreditLimitRequests (
+    request_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INT NOT NULL,
+    change_type VARCHAR(20) NOT NULL CHECK (change_type IN ('Permanent','TemporaryBoost')),
+    current_limit DECIMAL(10, 2) NOT NULL, -- credit_limit when requested
+    requested_limit DECIMAL(10, 2),        -- Permanent
+    boost_amount DECIMAL(10, 2),           -- TemporaryBoost
+    boost_until TIMESTAMP,                 -- TemporaryBoost
+    status VARCHAR(20) NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending','Approved','Rejected','Applied')),
+    reason VARCHAR(200) NOT NULL,
+    decision_reason VARCHAR(200),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP,
+    applied_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_limit_requests_status
+ON CreditLimitRequests (status, request_id);
+
+-- Every applied credit limit / boost change
+CREATE TABLE IF NOT EXISTS CreditLimitHistory (
+    history_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INT NOT NULL,
+    request_id BIGINT,
+    old_limit DECIMAL(10, 2) NOT NULL,
+    new_limit DECIMAL(10, 2) NOT NULL,
+    old_boost DECIMAL(10, 2) NOT NULL,
+    new_boost DECIMAL(10, 2) NOT NULL,
+    boost_until TIMESTAMP,
+    reason VARCHAR(200) NOT NULL,
+    actor VARCHAR(20) NOT NULL CHECK (actor IN ('cardholder','admin','system')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (request_id) REFERENCES CreditLimitRequests(request_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_limit_history_user
+ON CreditLimitHistory (user_id, created_at);
@@ -0,0 +1,19 @@
+-- Fails while Disputed, ChargedBack or Expired transactions exist.
+DROP INDEX IF EXISTS idx_transactions_merchant;
+DROP INDEX IF EXISTS idx_transactions_auth_expiry;
+
+ALTER TABLE Transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE Transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('Pending','Paid','Voided','Refunded'));
+
+ALTER TABLE Transactions
+    DROP COLUMN IF EXISTS card_id,
+    DROP COLUMN IF EXISTS original_amount,
+    DROP COLUMN IF EXISTS original_currency,
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS fx_fee,
+    DROP COLUMN IF EXISTS authorized_amount,
+    DROP COLUMN IF EXISTS auth_expires_at,
+    DROP COLUMN IF EXISTS captured_at,
+    DROP COLUMN IF EXISTS points_redeemed,
+    DROP COLUMN IF EXISTS reward_multiplier;
@@ -0,0 +1,31 @@
+-- Card, foreign-currency and authorization columns of Transactions, and the
+-- statuses added by disputes and authorization expiry.
+ALTER TABLE Transactions
+    ADD COLUMN IF NOT EXISTS card_id BIGINT DEFAULT NULL REFERENCES Cards(card_id),
+    -- Foreign-currency payments: amount is in the billing currency and
+    -- includes fx_fee; original_amount/original_currency are what was charged.
+    ADD COLUMN IF NOT EXISTS original_amount DECIMAL(12, 2) DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS original_currency CHAR(3) DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS fx_rate DECIMAL(18, 8) DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS fx_fee DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    -- Authorizations: amount is the authorized amount while Pending and the
+    -- captured amount once Paid; authorized_amount includes increments.
+    ADD COLUMN IF NOT EXISTS authorized_amount DECIMAL(10, 2) DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS auth_expires_at TIMESTAMP DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS captured_at TIMESTAMP DEFAULT NULL,
+    -- Points redeemed as a discount; point_change = earned + bonus - redeemed
+    ADD COLUMN IF NOT EXISTS points_redeemed INT NOT NULL DEFAULT 0,
+    -- Points per $1 at authorization (merchant rate x tier multiplier);
+    -- capture and increments keep earning at this rate
+    ADD COLUMN IF NOT EXISTS reward_multiplier DECIMAL(6, 3) DEFAULT NULL;
+
+-- init.sql named the inline status check transactions_status_check
+ALTER TABLE Transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE Transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('Pending','Paid','Voided','Refunded','Disputed','ChargedBack','Expired'));
+
+CREATE INDEX IF NOT EXISTS idx_transactions_auth_expiry
+ON Transactions (auth_expires_at) WHERE status = 'Pending';
+
+CREATE INDEX IF NOT EXISTS idx_transactions_merchant
+ON Transactions (merchant, created_at);
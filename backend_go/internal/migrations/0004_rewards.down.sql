@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS TransactionPromotions;
+
+ALTER TABLE Points
+    DROP COLUMN IF EXISTS promotion_id,
+    DROP COLUMN IF EXISTS redemption_id,
+    DROP COLUMN IF EXISTS transfer_id;
+
+DROP TABLE IF EXISTS Redemptions;
+DROP TABLE IF EXISTS PointTransfers;
+DROP TABLE IF EXISTS HouseholdMembers;
+DROP TABLE IF EXISTS Households;
+DROP TABLE IF EXISTS Promotions;
@@ -0,0 +1,101 @@
+-- Promotions, household pooling, points transfers and redemptions, and the
+-- Points columns linking ledger rows to them.
+
+-- Rewards promotions: bonus points on top of the merchant rate. multiplier
+-- is the total points per $1 while the promotion applies (the bonus is the
+-- part above the merchant rate); bonus_points is a fixed bonus per purchase.
+CREATE TABLE IF NOT EXISTS Promotions (
+    promotion_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name VARCHAR(40) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('campaign','category','first_purchase')),
+    merchant VARCHAR(50),
+    category VARCHAR(30),
+    multiplier DECIMAL(6, 2),
+    bonus_points INT,
+    user_monthly_cap INT, -- bonus points per user per calendar month
+    stackable BOOLEAN NOT NULL DEFAULT TRUE,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Household points pooling: members contribute points to pool_points and
+-- any member can redeem from it. A user belongs to at most one household.
+CREATE TABLE IF NOT EXISTS Households (
+    household_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    owner_user_id INT NOT NULL,
+    pool_points INT NOT NULL DEFAULT 0 CHECK (pool_points >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_user_id) REFERENCES Users(user_id)
+);
+
+CREATE TABLE IF NOT EXISTS HouseholdMembers (
+    user_id INT PRIMARY KEY,
+    household_id BIGINT NOT NULL,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (household_id) REFERENCES Households(household_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_household_members_household
+ON HouseholdMembers (household_id);
+
+-- Points moved from a user to another user or into a household pool
+-- (exactly one of to_user_id / household_id is set).
+CREATE TABLE IF NOT EXISTS PointTransfers (
+    transfer_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    from_user_id INT NOT NULL,
+    to_user_id INT DEFAULT NULL,
+    household_id BIGINT DEFAULT NULL,
+    points INT NOT NULL CHECK (points > 0),
+    note VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((to_user_id IS NULL) <> (household_id IS NULL)),
+    FOREIGN KEY (from_user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (to_user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (household_id) REFERENCES Households(household_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_point_transfers_from
+ON PointTransfers (from_user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_point_transfers_to
+ON PointTransfers (to_user_id, created_at);
+
+-- Points-only redemptions: a statement credit against the balance or a
+-- catalogue item; credit_amount is 0 for catalogue items. household_id is
+-- set when the points came from the household pool.
+CREATE TABLE IF NOT EXISTS Redemptions (
+    redemption_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INT NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('statement_credit','catalogue')),
+    item VARCHAR(40),
+    points INT NOT NULL CHECK (points > 0),
+    credit_amount DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    household_id BIGINT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (household_id) REFERENCES Households(household_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_redemptions_user
+ON Redemptions (user_id, created_at);
+
+ALTER TABLE Points
+    ADD COLUMN IF NOT EXISTS promotion_id BIGINT DEFAULT NULL REFERENCES Promotions(promotion_id), -- set on promotion bonus rows
+    ADD COLUMN IF NOT EXISTS redemption_id BIGINT DEFAULT NULL REFERENCES Redemptions(redemption_id), -- set on points-only redemption rows
+    ADD COLUMN IF NOT EXISTS transfer_id BIGINT DEFAULT NULL REFERENCES PointTransfers(transfer_id); -- set on both rows of a points transfer
+
+-- Bonus points granted to a purchase at authorization, awarded at capture
+-- and counted against the monthly caps.
+CREATE TABLE IF NOT EXISTS TransactionPromotions (
+    transaction_id BIGINT NOT NULL,
+    promotion_id BIGINT NOT NULL,
+    points INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (transaction_id, promotion_id),
+    FOREIGN KEY (transaction_id) REFERENCES Transactions(transaction_id),
+    FOREIGN KEY (promotion_id) REFERENCES Promotions(promotion_id)
+);
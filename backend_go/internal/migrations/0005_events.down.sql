@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS WebhookDeliveries;
+DROP TABLE IF EXISTS Webhooks;
+DROP TABLE IF EXISTS Outbox;
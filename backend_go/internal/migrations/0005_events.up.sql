@@ -0,0 +1,43 @@
+-- Transactional outbox: lifecycle events written in the same DB transaction
+-- as the money movement, delivered later by the webhook dispatcher.
+CREATE TABLE IF NOT EXISTS Outbox (
+    event_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    user_id INT NOT NULL,
+    transaction_id BIGINT,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (transaction_id) REFERENCES Transactions(transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_undispatched
+ON Outbox (event_id) WHERE dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS Webhooks (
+    webhook_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    url VARCHAR(500) NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS WebhookDeliveries (
+    delivery_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_id BIGINT NOT NULL,
+    webhook_id BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending','Delivered','Dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT,
+    last_error TEXT,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, webhook_id),
+    FOREIGN KEY (event_id) REFERENCES Outbox(event_id),
+    FOREIGN KEY (webhook_id) REFERENCES Webhooks(webhook_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+ON WebhookDeliveries (next_attempt_at) WHERE status = 'Pending';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS DisputeEvents;
+DROP TABLE IF EXISTS Disputes;
+DROP TABLE IF EXISTS Installments;
+DROP TABLE IF EXISTS InstallmentPlans;
@@ -0,0 +1,74 @@
+-- Installment purchases: the parent transaction carries the full amount; the
+-- schedule posts one installment to balance per billing cycle.
+CREATE TABLE IF NOT EXISTS InstallmentPlans (
+    plan_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    transaction_id BIGINT NOT NULL UNIQUE,
+    user_id INT NOT NULL,
+    card_id BIGINT,
+    total_amount DECIMAL(10, 2) NOT NULL,
+    term INT NOT NULL CHECK (term IN (3, 6, 12)),
+    posted_count INT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'Active' CHECK (status IN ('Active','Completed','Cancelled')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (transaction_id) REFERENCES Transactions(transaction_id),
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (card_id) REFERENCES Cards(card_id)
+);
+
+CREATE TABLE IF NOT EXISTS Installments (
+    installment_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    plan_id BIGINT NOT NULL,
+    seq INT NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    due_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'Scheduled' CHECK (status IN ('Scheduled','Posted','Cancelled')),
+    posted_at TIMESTAMP,
+    UNIQUE (plan_id, seq),
+    FOREIGN KEY (plan_id) REFERENCES InstallmentPlans(plan_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_installments_due
+ON Installments (due_at) WHERE status = 'Scheduled';
+
+-- Disputes: opening one gives the cardholder a provisional credit (amount and
+-- reversed points) and moves the transaction to Disputed; Won keeps the
+-- credit (transaction ChargedBack), Lost reverses it (transaction Paid again).
+CREATE TABLE IF NOT EXISTS Disputes (
+    dispute_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    transaction_id BIGINT NOT NULL,
+    user_id INT NOT NULL,
+    card_id BIGINT,
+    reason_code VARCHAR(30) NOT NULL,
+    description VARCHAR(500),
+    amount DECIMAL(10, 2) NOT NULL,
+    point_change INT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'Open' CHECK (status IN ('Open','UnderReview','Won','Lost')),
+    respond_by TIMESTAMP NOT NULL,
+    merchant_response VARCHAR(500),
+    resolution_reason VARCHAR(200),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (transaction_id) REFERENCES Transactions(transaction_id),
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (card_id) REFERENCES Cards(card_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_one_active
+ON Disputes (transaction_id) WHERE status IN ('Open','UnderReview');
+
+CREATE INDEX IF NOT EXISTS idx_disputes_respond_by
+ON Disputes (respond_by) WHERE status = 'Open';
+
+CREATE TABLE IF NOT EXISTS DisputeEvents (
+    event_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    dispute_id BIGINT NOT NULL,
+    old_status VARCHAR(20),
+    new_status VARCHAR(20) NOT NULL,
+    note VARCHAR(500),
+    actor VARCHAR(20) NOT NULL CHECK (actor IN ('cardholder','merchant','admin','system')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (dispute_id) REFERENCES Disputes(dispute_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_events_dispute
+ON DisputeEvents (dispute_id, event_id);
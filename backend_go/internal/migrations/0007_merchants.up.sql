@@ -0,0 +1,10 @@
+-- Merchant accounts for the merchant API. name matches Transactions.merchant;
+-- only the SHA-256 of the API key is stored, api_key_prefix identifies it.
+CREATE TABLE IF NOT EXISTS Merchants (
+    merchant_id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    api_key_hash CHAR(64) NOT NULL UNIQUE,
+    api_key_prefix VARCHAR(16) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,272 @@
+// Package migrations applies the versioned schema embedded in the binary.
+//
+// Each migration is a pair of files NNNN_name.up.sql / NNNN_name.down.sql
+// (the down file is optional). Applied versions are recorded in
+// schema_migrations together with the SHA-256 of their up script; an
+// applied migration whose file changed afterwards stops the runner. Never
+// edit an applied migration: add a new one instead.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed *.sql
+var embedded embed.FS
+
+// lockKey is the pg_advisory_lock key held while migrating, so instances
+// starting together apply each migration once.
+const lockKey int64 = 0x6363745f6d6967 // "cct_mig"
+
+const createVersionTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up
+}
+
+// Status is one migration with its applied time (nil while pending).
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads the migrations of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		if version <= 0 {
+			return nil, fmt.Errorf("migration %s: version must be positive", e.Name())
+		}
+		b, err := fs.ReadFile(fsys, path.Join(".", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(b)
+			sum := sha256.Sum256(b)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(b)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Runner applies migrations to one database.
+type Runner struct {
+	Pool       *pgxpool.Pool
+	Migrations []Migration
+}
+
+// New returns a Runner for the migrations embedded in the binary.
+func New(pool *pgxpool.Pool) (*Runner, error) {
+	migs, err := Load(embedded)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{Pool: pool, Migrations: migs}, nil
+}
+
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// withLock runs fn on one connection holding the migration advisory lock.
+func (r *Runner) withLock(ctx context.Context, fn func(conn *pgx.Conn, done map[int64]applied) error) error {
+	c, err := r.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+	conn := c.Conn()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return err
+	}
+	defer func() {
+		// Unlock even when ctx is cancelled, the connection goes back to the pool
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+	}()
+
+	if _, err := conn.Exec(ctx, createVersionTable); err != nil {
+		return err
+	}
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := r.verify(ctx, done); err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+func appliedVersions(ctx context.Context, conn *pgx.Conn) (map[int64]applied, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := map[int64]applied{}
+	for rows.Next() {
+		var v int64
+		var a applied
+		if err := rows.Scan(&v, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		done[v] = a
+	}
+	return done, rows.Err()
+}
+
+// verify fails if an applied migration was edited after it ran. Applied
+// versions this binary does not know (a newer release ran) are only logged.
+func (r *Runner) verify(ctx context.Context, done map[int64]applied) error {
+	known := map[int64]bool{}
+	for _, m := range r.Migrations {
+		known[m.Version] = true
+		if a, ok := done[m.Version]; ok && a.checksum != m.Checksum {
+			return fmt.Errorf("%w: %d_%s was applied as %s, the file is now %s", ErrChecksumMismatch, m.Version, m.Name, a.checksum, m.Checksum)
+		}
+	}
+	for v, a := range done {
+		if !known[v] {
+			utils.LoggerFrom(ctx).Warn("applied migration unknown to this binary", "version", v, "name", a.name)
+		}
+	}
+	return nil
+}
+
+// Up applies the pending migrations up to and including target (0: all),
+// each in its own transaction, and returns the ones it applied.
+func (r *Runner) Up(ctx context.Context, target int64) ([]Migration, error) {
+	var ran []Migration
+	err := r.withLock(ctx, func(conn *pgx.Conn, done map[int64]applied) error {
+		for _, m := range r.Migrations {
+			if _, ok := done[m.Version]; ok || (target > 0 && m.Version > target) {
+				continue
+			}
+			start := time.Now()
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, m.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`, m.Version, m.Name, m.Checksum)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+			}
+			utils.LoggerFrom(ctx).Info("migration applied", "version", m.Version, "name", m.Name, "duration_ms", time.Since(start).Milliseconds())
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted.
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var ran []Migration
+	err := r.withLock(ctx, func(conn *pgx.Conn, done map[int64]applied) error {
+		for i := len(r.Migrations) - 1; i >= 0 && len(ran) < steps; i-- {
+			m := r.Migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+			}
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, m.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+			}
+			utils.LoggerFrom(ctx).Info("migration reverted", "version", m.Version, "name", m.Name)
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Status lists every known migration with its applied time.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	out := make([]Status, 0, len(r.Migrations))
+	err := r.withLock(ctx, func(_ *pgx.Conn, done map[int64]applied) error {
+		for _, m := range r.Migrations {
+			st := Status{Version: m.Version, Name: m.Name}
+			if a, ok := done[m.Version]; ok {
+				at := a.appliedAt
+				st.AppliedAt = &at
+			}
+			out = append(out, st)
+		}
+		return nil
+	})
+	return out, err
+}
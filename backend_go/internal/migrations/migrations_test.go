@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testdata/init.sql is db/init.sql as deployed before migrations existed.
+func baselineInit(t *testing.T) string {
+	t.Helper()
+	b, err := os.ReadFile("testdata/init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migs, err := Load(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migs {
+		if m.Version != int64(i+1) {
+			t.Fatalf("migration %d_%s: want version %d", m.Version, m.Name, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+	// Databases created from init.sql must be able to adopt 0001 as applied
+	if migs[0].Up != baselineInit(t) {
+		t.Fatal("0001_init.up.sql differs from the baseline init.sql")
+	}
+}
+
+// TestUpgradeFromBaseline upgrades a database initialized with the old
+// init.sql, then reverts and re-applies everything. It needs a scratch
+// Postgres in TEST_DATABASE_URL and runs in a throwaway schema.
+func TestUpgradeFromBaseline(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+
+	admin, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _, _ = admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE") }()
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.Exec(ctx, baselineInit(t)); err != nil {
+		t.Fatalf("baseline init.sql: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO Users (user_id, username) VALUES (1, 'alice');
+		INSERT INTO Transactions (user_id, amount, status, merchant) VALUES (1, 12.50, 'Paid', 'Steam');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	migs, err := Load(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{Pool: pool, Migrations: migs}
+	if ran, err := r.Up(ctx, 0); err != nil {
+		t.Fatalf("up: %v", err)
+	} else if len(ran) != len(migs) {
+		t.Fatalf("up applied %d of %d migrations", len(ran), len(migs))
+	}
+
+	// Existing rows get the column defaults
+	var status, tier string
+	var authHold float64
+	if err := pool.QueryRow(ctx, `SELECT status, tier, auth_hold FROM Users WHERE user_id = 1`).Scan(&status, &tier, &authHold); err != nil {
+		t.Fatal(err)
+	}
+	if status != "Active" || tier != "Standard" || authHold != 0 {
+		t.Fatalf("upgraded user = %s/%s/%v", status, tier, authHold)
+	}
+	var capturedAt *time.Time
+	var pointsRedeemed int
+	if err := pool.QueryRow(ctx, `SELECT captured_at, points_redeemed FROM Transactions WHERE user_id = 1`).Scan(&capturedAt, &pointsRedeemed); err != nil {
+		t.Fatal(err)
+	}
+	if capturedAt != nil || pointsRedeemed != 0 {
+		t.Fatalf("upgraded transaction = %v/%d", capturedAt, pointsRedeemed)
+	}
+	// The widened status check accepts the new statuses
+	if _, err := pool.Exec(ctx, `UPDATE Transactions SET status = 'Disputed' WHERE user_id = 1`); err != nil {
+		t.Fatalf("Disputed after upgrade: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE Transactions SET status = 'Paid' WHERE user_id = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Down(ctx, len(migs)-1); err != nil {
+		t.Fatalf("down to 0001: %v", err)
+	}
+	var n int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM Transactions`).Scan(&n); err != nil || n != 1 {
+		t.Fatalf("baseline rows after down: %d, %v", n, err)
+	}
+	if _, err := r.Up(ctx, 0); err != nil {
+		t.Fatalf("re-up: %v", err)
+	}
+}
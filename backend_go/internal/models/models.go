@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
 	UserID        int     `json:"user_id"`
@@ -8,6 +11,26 @@ type User struct {
 	Balance       float64 `json:"balance"`
 	CurrentPoints int     `json:"current_points"`
 	CreditLimit   float64 `json:"credit_limit"`
+
+	// Account status: Active, Frozen, Blocked or Closed
+	Status          string     `json:"status"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+	StatusChangedBy string     `json:"status_changed_by,omitempty"`
+	FrozenUntil     *time.Time `json:"frozen_until,omitempty"`
+
+	// Temporary limit boost; only counts until TempLimitBoostUntil
+	TempLimitBoost      float64    `json:"temp_limit_boost"`
+	TempLimitBoostUntil *time.Time `json:"temp_limit_boost_until,omitempty"`
+
+	// Unposted installments; credit used = Balance + InstallmentReserved + AuthHold
+	InstallmentReserved float64 `json:"installment_reserved"`
+	// Uncaptured authorizations
+	AuthHold float64 `json:"auth_hold"`
+
+	// Loyalty tier and the rolling 12-month settled spend it was computed from
+	Tier            string     `json:"tier"`
+	TierSpend       float64    `json:"tier_spend"`
+	TierEvaluatedAt *time.Time `json:"tier_evaluated_at,omitempty"`
 }
 
 type Transaction struct {
@@ -18,5 +41,335 @@ type Transaction struct {
 	PointChange         int        `json:"point_change"`
 	Merchant            string     `json:"merchant,omitempty"`
 	SourceTransactionID *int       `json:"source_transaction_id,omitempty"`
+	CardID              *int64     `json:"card_id,omitempty"`
 	CreatedAt           time.Time  `json:"created_at"`
+
+	// Set for foreign-currency payments; Amount is the billed amount incl. FXFee
+	OriginalAmount   *float64 `json:"original_amount,omitempty"`
+	OriginalCurrency *string  `json:"original_currency,omitempty"`
+	FXRate           *float64 `json:"fx_rate,omitempty"`
+	FXFee            float64  `json:"fx_fee,omitempty"`
+
+	// Authorization: AuthorizedAmount includes increments; Amount is the
+	// captured amount once Paid
+	AuthorizedAmount float64    `json:"authorized_amount,omitempty"`
+	AuthExpiresAt    *time.Time `json:"auth_expires_at,omitempty"`
+	CapturedAt       *time.Time `json:"captured_at,omitempty"`
+
+	// Points redeemed as a discount; PointChange = earned + bonus - redeemed
+	PointsRedeemed int `json:"points_redeemed,omitempty"`
+	// Points per $1 fixed at authorization (0 for rows without one)
+	RewardMultiplier float64 `json:"reward_multiplier,omitempty"`
+}
+
+// Card is a payment card on the user's account. Payments must fit both the
+// card limit (Balance/CreditLimit here) and the account limit on User.
+type Card struct {
+	CardID      int64     `json:"card_id"`
+	UserID      int       `json:"user_id"`
+	CardType    string    `json:"card_type"`
+	PANToken    string    `json:"-"`
+	Last4       string    `json:"last4"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Status      string    `json:"status"`
+	CreditLimit float64   `json:"credit_limit"`
+	Balance     float64   `json:"balance"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Virtual card controls (zero values: unrestricted)
+	SingleUse    bool     `json:"single_use"`
+	AmountCap    *float64 `json:"amount_cap,omitempty"`
+	MerchantLock string   `json:"merchant_lock,omitempty"`
+	Spent        float64  `json:"spent"`
+
+	InstallmentReserved float64 `json:"installment_reserved"`
+	AuthHold            float64 `json:"auth_hold"`
+}
+
+// TransactionEvent is the "data" of transaction.* webhook events.
+type TransactionEvent struct {
+	TransactionID       int64   `json:"transaction_id"`
+	UserID              int     `json:"user_id"`
+	Amount              float64 `json:"amount"`
+	Status              string  `json:"status"`
+	PointChange         int     `json:"point_change"`
+	Merchant            string  `json:"merchant,omitempty"`
+	SourceTransactionID *int64  `json:"source_transaction_id,omitempty"`
+	CardID              *int64  `json:"card_id,omitempty"`
+
+	OriginalAmount   *float64 `json:"original_amount,omitempty"`
+	OriginalCurrency *string  `json:"original_currency,omitempty"`
+
+	// Set on installment.posted events
+	Installment *InstallmentRef `json:"installment,omitempty"`
+	// Set on dispute.* events
+	Dispute *DisputeRef `json:"dispute,omitempty"`
+
+	// Account state right after the change (same DB transaction)
+	Account *AccountSnapshot `json:"account,omitempty"`
+}
+
+type InstallmentRef struct {
+	PlanID int64 `json:"plan_id"`
+	Seq    int   `json:"seq"`
+	Term   int   `json:"term"`
+}
+
+type DisputeRef struct {
+	DisputeID  int64  `json:"dispute_id"`
+	Status     string `json:"status"`
+	ReasonCode string `json:"reason_code"`
+}
+
+type AccountSnapshot struct {
+	Balance       float64 `json:"balance"`
+	CurrentPoints int     `json:"current_points"`
+}
+
+// AccountEvent is the "data" of account events that are not transactions
+// (points.*, account.*, card.*); one of the detail fields is set.
+type AccountEvent struct {
+	UserID       int                `json:"user_id"`
+	Transfer     *PointTransfer     `json:"transfer,omitempty"`
+	Redemption   *Redemption        `json:"redemption,omitempty"`
+	StatusChange *StatusChange      `json:"status_change,omitempty"`
+	CreditLimit  *CreditLimitChange `json:"credit_limit_change,omitempty"`
+
+	// Account state right after the change (same DB transaction)
+	Account *AccountSnapshot `json:"account,omitempty"`
+}
+
+// EventEnvelope is the wire format of an outbox event, shared by webhooks and
+// the SSE account stream. ID is the Outbox event_id.
+type EventEnvelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	UserID    int             `json:"user_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type Webhook struct {
+	WebhookID  int64     `json:"webhook_id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	DeliveryID     int64     `json:"delivery_id"`
+	EventID        int64     `json:"event_id"`
+	WebhookID      int64     `json:"webhook_id"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	LastStatusCode *int      `json:"last_status_code,omitempty"`
+	LastError      *string   `json:"last_error,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// StatusChange is one audited account (CardID nil) or card status change.
+type StatusChange struct {
+	ChangeID  int64     `json:"change_id"`
+	UserID    int       `json:"user_id"`
+	CardID    *int64    `json:"card_id,omitempty"`
+	OldStatus string    `json:"old_status"`
+	NewStatus string    `json:"new_status"`
+	Reason    string    `json:"reason"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreditLimitRequest is a requested credit limit change (Permanent) or
+// temporary boost (TemporaryBoost) and its approval state.
+type CreditLimitRequest struct {
+	RequestID      int64      `json:"request_id"`
+	UserID         int        `json:"user_id"`
+	ChangeType     string     `json:"change_type"`
+	CurrentLimit   float64    `json:"current_limit"`
+	RequestedLimit *float64   `json:"requested_limit,omitempty"`
+	BoostAmount    *float64   `json:"boost_amount,omitempty"`
+	BoostUntil     *time.Time `json:"boost_until,omitempty"`
+	Status         string     `json:"status"`
+	Reason         string     `json:"reason"`
+	DecisionReason string     `json:"decision_reason,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	AppliedAt      *time.Time `json:"applied_at,omitempty"`
+}
+
+// CreditLimitChange is one CreditLimitHistory row.
+type CreditLimitChange struct {
+	HistoryID  int64      `json:"history_id"`
+	UserID     int        `json:"user_id"`
+	RequestID  *int64     `json:"request_id,omitempty"`
+	OldLimit   float64    `json:"old_limit"`
+	NewLimit   float64    `json:"new_limit"`
+	OldBoost   float64    `json:"old_boost"`
+	NewBoost   float64    `json:"new_boost"`
+	BoostUntil *time.Time `json:"boost_until,omitempty"`
+	Reason     string     `json:"reason"`
+	Actor      string     `json:"actor"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// InstallmentPlan splits a purchase (TransactionID) into Term installments.
+type InstallmentPlan struct {
+	PlanID        int64         `json:"plan_id"`
+	TransactionID int64         `json:"transaction_id"`
+	UserID        int           `json:"user_id"`
+	CardID        *int64        `json:"card_id,omitempty"`
+	TotalAmount   float64       `json:"total_amount"`
+	Term          int           `json:"term"`
+	PostedCount   int           `json:"posted_count"`
+	Status        string        `json:"status"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Installments  []Installment `json:"installments,omitempty"`
+}
+
+type Installment struct {
+	InstallmentID int64      `json:"installment_id"`
+	PlanID        int64      `json:"plan_id"`
+	Seq           int        `json:"seq"`
+	Amount        float64    `json:"amount"`
+	DueAt         time.Time  `json:"due_at"`
+	Status        string     `json:"status"`
+	PostedAt      *time.Time `json:"posted_at,omitempty"`
+}
+
+// Dispute is a cardholder dispute of a Paid transaction. Amount and
+// PointChange are the provisional credit applied when it was opened.
+type Dispute struct {
+	DisputeID        int64          `json:"dispute_id"`
+	TransactionID    int64          `json:"transaction_id"`
+	UserID           int            `json:"user_id"`
+	CardID           *int64         `json:"card_id,omitempty"`
+	ReasonCode       string         `json:"reason_code"`
+	Description      string         `json:"description,omitempty"`
+	Amount           float64        `json:"amount"`
+	PointChange      int            `json:"point_change"`
+	Status           string         `json:"status"`
+	RespondBy        time.Time      `json:"respond_by"`
+	MerchantResponse string         `json:"merchant_response,omitempty"`
+	ResolutionReason string         `json:"resolution_reason,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	ResolvedAt       *time.Time     `json:"resolved_at,omitempty"`
+	Events           []DisputeEvent `json:"events,omitempty"`
+}
+
+// DisputeEvent is one recorded state transition; OldStatus is empty for the opening.
+type DisputeEvent struct {
+	EventID   int64     `json:"event_id"`
+	DisputeID int64     `json:"dispute_id"`
+	OldStatus string    `json:"old_status,omitempty"`
+	NewStatus string    `json:"new_status"`
+	Note      string    `json:"note,omitempty"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Merchant is a merchant API account. APIKey is only set when the key is
+// issued or rotated.
+type Merchant struct {
+	MerchantID   int64     `json:"merchant_id"`
+	Name         string    `json:"name"`
+	APIKey       string    `json:"api_key,omitempty"`
+	APIKeyPrefix string    `json:"api_key_prefix"`
+	Active       bool      `json:"active"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SettlementLine is one entry of a merchant settlement report. Amount is
+// positive in the billing currency; Type is sale, refund or chargeback.
+type SettlementLine struct {
+	Type                string    `json:"type"`
+	TransactionID       int64     `json:"transaction_id"`
+	SourceTransactionID *int64    `json:"source_transaction_id,omitempty"`
+	UserID              int       `json:"user_id"`
+	Amount              float64   `json:"amount"`
+	OccurredAt          time.Time `json:"occurred_at"`
+}
+
+// SettlementReport aggregates a merchant's day:
+// NetPayable = GrossSales - Refunds - Chargebacks.
+type SettlementReport struct {
+	Merchant        string           `json:"merchant"`
+	Date            string           `json:"date"`
+	SalesCount      int              `json:"sales_count"`
+	GrossSales      float64          `json:"gross_sales"`
+	RefundCount     int              `json:"refund_count"`
+	Refunds         float64          `json:"refunds"`
+	ChargebackCount int              `json:"chargeback_count"`
+	Chargebacks     float64          `json:"chargebacks"`
+	NetPayable      float64          `json:"net_payable"`
+	Lines           []SettlementLine `json:"lines"`
+}
+
+// Promotion is a rewards promotion. Multiplier is the total points per $1
+// while it applies (the bonus is the part above the merchant rate);
+// BonusPoints is a fixed bonus per purchase.
+type Promotion struct {
+	PromotionID    int64      `json:"promotion_id"`
+	Name           string     `json:"name"`
+	Kind           string     `json:"kind"`
+	Merchant       string     `json:"merchant,omitempty"`
+	Category       string     `json:"category,omitempty"`
+	Multiplier     *float64   `json:"multiplier,omitempty"`
+	BonusPoints    *int       `json:"bonus_points,omitempty"`
+	UserMonthlyCap *int       `json:"user_monthly_cap,omitempty"`
+	Stackable      bool       `json:"stackable"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// AppliedPromotion is the bonus a promotion granted to one purchase.
+type AppliedPromotion struct {
+	PromotionID int64  `json:"promotion_id"`
+	Name        string `json:"name"`
+	Points      int    `json:"points"`
+}
+
+// Redemption is a points-only redemption: a statement credit against the
+// balance or a catalogue item.
+type Redemption struct {
+	RedemptionID int64     `json:"redemption_id"`
+	UserID       int       `json:"user_id"`
+	Kind         string    `json:"kind"`
+	Item         string    `json:"item,omitempty"`
+	Points       int       `json:"points"`
+	CreditAmount float64   `json:"credit_amount,omitempty"`
+	HouseholdID  *int64    `json:"household_id,omitempty"` // redeemed from the household pool
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PointTransfer moves points from a user to another user (ToUserID) or into
+// a household pool (HouseholdID).
+type PointTransfer struct {
+	TransferID  int64     `json:"transfer_id"`
+	FromUserID  int       `json:"from_user_id"`
+	ToUserID    *int      `json:"to_user_id,omitempty"`
+	HouseholdID *int64    `json:"household_id,omitempty"`
+	Points      int       `json:"points"`
+	Note        string    `json:"note,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Household pools points of its members.
+type Household struct {
+	HouseholdID int64             `json:"household_id"`
+	Name        string            `json:"name"`
+	OwnerUserID int               `json:"owner_user_id"`
+	PoolPoints  int               `json:"pool_points"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Members     []HouseholdMember `json:"members,omitempty"`
+}
+
+type HouseholdMember struct {
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	JoinedAt time.Time `json:"joined_at"`
 }
@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"context"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const cardColumns = `card_id, user_id, card_type, pan_token, last4, expires_at, status, credit_limit, balance, created_at, single_use, amount_cap, COALESCE(merchant_lock, ''), spent, installment_reserved, auth_hold`
+
+func scanCard(row pgx.Row) (*models.Card, error) {
+	var c models.Card
+	if err := row.Scan(&c.CardID, &c.UserID, &c.CardType, &c.PANToken, &c.Last4, &c.ExpiresAt, &c.Status, &c.CreditLimit, &c.Balance, &c.CreatedAt, &c.SingleUse, &c.AmountCap, &c.MerchantLock, &c.Spent, &c.InstallmentReserved, &c.AuthHold); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// pgCards is the pgx CardRepo.
+type pgCards struct{}
+
+func (pgCards) Create(ctx context.Context, q Querier, c *models.Card) (*models.Card, error) {
+	return scanCard(q.QueryRow(ctx, `
+		INSERT INTO Cards (user_id, card_type, pan_token, last4, expires_at, credit_limit, single_use, amount_cap, merchant_lock)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NULLIF($9, ''))
+		RETURNING `+cardColumns, c.UserID, c.CardType, c.PANToken, c.Last4, c.ExpiresAt, c.CreditLimit, c.SingleUse, c.AmountCap, c.MerchantLock))
+}
+
+func (pgCards) GetByIDForUpdate(ctx context.Context, q Querier, cardID int64) (*models.Card, error) {
+	return scanCard(q.QueryRow(ctx, `SELECT `+cardColumns+` FROM Cards WHERE card_id=$1 FOR UPDATE`, cardID))
+}
+
+func (pgCards) GetPrimaryForUpdate(ctx context.Context, q Querier, userID int) (*models.Card, error) {
+	return scanCard(q.QueryRow(ctx, `SELECT `+cardColumns+` FROM Cards WHERE user_id=$1 AND card_type='Primary' AND status IN ('Active','Frozen') FOR UPDATE`, userID))
+}
+
+func (pgCards) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Card, error) {
+	rows, err := q.Query(ctx, `SELECT `+cardColumns+` FROM Cards WHERE user_id=$1 ORDER BY card_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Card, 0)
+	for rows.Next() {
+		c, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+func (pgCards) UpdateStatus(ctx context.Context, q Querier, cardID int64, status string) error {
+	_, err := q.Exec(ctx, `UPDATE Cards SET status=$1 WHERE card_id=$2`, status, cardID)
+	return err
+}
+
+func (pgCards) UpdateBalance(ctx context.Context, q Querier, cardID int64, balanceChange float64) error {
+	_, err := q.Exec(ctx, `UPDATE Cards SET balance = balance + $1 WHERE card_id=$2`, balanceChange, cardID)
+	return err
+}
+
+func (pgCards) AddSpend(ctx context.Context, q Querier, cardID int64, amount float64) error {
+	_, err := q.Exec(ctx, `UPDATE Cards SET spent = spent + $1 WHERE card_id=$2`, amount, cardID)
+	return err
+}
+
+func (pgCards) UpdateBalanceAndReserved(ctx context.Context, q Querier, cardID int64, balanceChange, reservedChange float64) error {
+	_, err := q.Exec(ctx, `UPDATE Cards SET balance = balance + $1, installment_reserved = installment_reserved + $2 WHERE card_id=$3`, balanceChange, reservedChange, cardID)
+	return err
+}
+
+func (pgCards) UpdateAuthHold(ctx context.Context, q Querier, cardID int64, change float64) error {
+	_, err := q.Exec(ctx, `UPDATE Cards SET auth_hold = auth_hold + $1 WHERE card_id=$2`, change, cardID)
+	return err
+}
@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"context"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const creditLimitRequestColumns = `request_id, user_id, change_type, current_limit, requested_limit, boost_amount, boost_until, status, reason, COALESCE(decision_reason, ''), created_at, decided_at, applied_at`
+
+func scanCreditLimitRequest(row pgx.Row) (*models.CreditLimitRequest, error) {
+	var r models.CreditLimitRequest
+	if err := row.Scan(&r.RequestID, &r.UserID, &r.ChangeType, &r.CurrentLimit, &r.RequestedLimit, &r.BoostAmount, &r.BoostUntil, &r.Status, &r.Reason, &r.DecisionReason, &r.CreatedAt, &r.DecidedAt, &r.AppliedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// pgCreditLimits is the pgx CreditLimitRepo.
+type pgCreditLimits struct{}
+
+func (pgCreditLimits) CreateRequest(ctx context.Context, q Querier, r *models.CreditLimitRequest) (*models.CreditLimitRequest, error) {
+	return scanCreditLimitRequest(q.QueryRow(ctx, `
+		INSERT INTO CreditLimitRequests (user_id, change_type, current_limit, requested_limit, boost_amount, boost_until, reason)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		RETURNING `+creditLimitRequestColumns, r.UserID, r.ChangeType, r.CurrentLimit, r.RequestedLimit, r.BoostAmount, r.BoostUntil, r.Reason))
+}
+
+func (pgCreditLimits) GetRequestForUpdate(ctx context.Context, q Querier, requestID int64) (*models.CreditLimitRequest, error) {
+	return scanCreditLimitRequest(q.QueryRow(ctx, `SELECT `+creditLimitRequestColumns+` FROM CreditLimitRequests WHERE request_id=$1 FOR UPDATE`, requestID))
+}
+
+func (pgCreditLimits) ListRequests(ctx context.Context, q Querier, userID int, status string, limit int) ([]models.CreditLimitRequest, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+creditLimitRequestColumns+` FROM CreditLimitRequests
+		WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY request_id DESC LIMIT $3`, userID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.CreditLimitRequest, 0)
+	for rows.Next() {
+		r, err := scanCreditLimitRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
+
+func (pgCreditLimits) DecideRequest(ctx context.Context, q Querier, requestID int64, status, reason string) error {
+	_, err := q.Exec(ctx, `UPDATE CreditLimitRequests SET status=$1, decision_reason=$2, decided_at=NOW() WHERE request_id=$3`, status, reason, requestID)
+	return err
+}
+
+func (pgCreditLimits) MarkRequestApplied(ctx context.Context, q Querier, requestID int64) error {
+	_, err := q.Exec(ctx, `UPDATE CreditLimitRequests SET status='Applied', applied_at=NOW() WHERE request_id=$1`, requestID)
+	return err
+}
+
+func (pgCreditLimits) InsertChange(ctx context.Context, q Querier, c models.CreditLimitChange) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO CreditLimitHistory (user_id, request_id, old_limit, new_limit, old_boost, new_boost, boost_until, reason, actor)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		c.UserID, c.RequestID, c.OldLimit, c.NewLimit, c.OldBoost, c.NewBoost, c.BoostUntil, c.Reason, c.Actor)
+	return err
+}
+
+func (pgCreditLimits) ListHistory(ctx context.Context, q Querier, userID int, limit int) ([]models.CreditLimitChange, error) {
+	rows, err := q.Query(ctx, `
+		SELECT history_id, user_id, request_id, old_limit, new_limit, old_boost, new_boost, boost_until, reason, actor, created_at
+		FROM CreditLimitHistory WHERE user_id=$1
+		ORDER BY history_id DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.CreditLimitChange, 0)
+	for rows.Next() {
+		var c models.CreditLimitChange
+		if err := rows.Scan(&c.HistoryID, &c.UserID, &c.RequestID, &c.OldLimit, &c.NewLimit, &c.OldBoost, &c.NewBoost, &c.BoostUntil, &c.Reason, &c.Actor, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
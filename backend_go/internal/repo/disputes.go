@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const disputeColumns = `dispute_id, transaction_id, user_id, card_id, reason_code, COALESCE(description, ''), amount, point_change, status, respond_by, COALESCE(merchant_response, ''), COALESCE(resolution_reason, ''), created_at, resolved_at`
+
+func scanDispute(row pgx.Row) (*models.Dispute, error) {
+	var d models.Dispute
+	if err := row.Scan(&d.DisputeID, &d.TransactionID, &d.UserID, &d.CardID, &d.ReasonCode, &d.Description, &d.Amount, &d.PointChange, &d.Status, &d.RespondBy, &d.MerchantResponse, &d.ResolutionReason, &d.CreatedAt, &d.ResolvedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// pgDisputes is the pgx DisputeRepo.
+type pgDisputes struct{}
+
+func (pgDisputes) Create(ctx context.Context, q Querier, d *models.Dispute, responseWindowSeconds float64) (*models.Dispute, error) {
+	return scanDispute(q.QueryRow(ctx, `
+		INSERT INTO Disputes (transaction_id, user_id, card_id, reason_code, description, amount, point_change, respond_by)
+		VALUES ($1,$2,$3,$4,NULLIF($5, ''),$6,$7, NOW() + $8::float8 * INTERVAL '1 second')
+		RETURNING `+disputeColumns, d.TransactionID, d.UserID, d.CardID, d.ReasonCode, d.Description, d.Amount, d.PointChange, responseWindowSeconds))
+}
+
+func (pgDisputes) GetByID(ctx context.Context, q Querier, disputeID int64) (*models.Dispute, error) {
+	return scanDispute(q.QueryRow(ctx, `SELECT `+disputeColumns+` FROM Disputes WHERE dispute_id=$1`, disputeID))
+}
+
+func (pgDisputes) GetByIDForUpdate(ctx context.Context, q Querier, disputeID int64) (*models.Dispute, error) {
+	return scanDispute(q.QueryRow(ctx, `SELECT `+disputeColumns+` FROM Disputes WHERE dispute_id=$1 FOR UPDATE`, disputeID))
+}
+
+func (pgDisputes) List(ctx context.Context, q Querier, userID int, status string, limit int) ([]models.Dispute, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+disputeColumns+` FROM Disputes
+		WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY dispute_id DESC LIMIT $3`, userID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Dispute, 0)
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+// ListExpired uses the database clock, as respond_by was set with it.
+func (pgDisputes) ListExpired(ctx context.Context, q Querier, limit int) ([]models.Dispute, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+disputeColumns+` FROM Disputes
+		WHERE status = 'Open' AND respond_by <= NOW()
+		ORDER BY respond_by LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Dispute, 0)
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+func (pgDisputes) HasResolved(ctx context.Context, q Querier, txID int64) (bool, error) {
+	var resolved bool
+	err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM Disputes WHERE transaction_id=$1 AND status IN ('Won','Lost'))`, txID).Scan(&resolved)
+	return resolved, err
+}
+
+func (pgDisputes) SetMerchantResponse(ctx context.Context, q Querier, disputeID int64, response string) error {
+	_, err := q.Exec(ctx, `UPDATE Disputes SET merchant_response=$1 WHERE dispute_id=$2`, response, disputeID)
+	return err
+}
+
+func (pgDisputes) UpdateStatus(ctx context.Context, q Querier, disputeID int64, status, resolutionReason string) error {
+	_, err := q.Exec(ctx, `
+		UPDATE Disputes SET status=$1::varchar,
+			resolution_reason = CASE WHEN $1::varchar IN ('Won','Lost') THEN NULLIF($2, '') ELSE resolution_reason END,
+			resolved_at = CASE WHEN $1::varchar IN ('Won','Lost') THEN NOW() ELSE resolved_at END
+		WHERE dispute_id=$3`, status, resolutionReason, disputeID)
+	return err
+}
+
+func (pgDisputes) InsertEvent(ctx context.Context, q Querier, e models.DisputeEvent) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO DisputeEvents (dispute_id, old_status, new_status, note, actor)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5)`, e.DisputeID, e.OldStatus, e.NewStatus, e.Note, e.Actor)
+	return err
+}
+
+func (pgDisputes) ListEvents(ctx context.Context, q Querier, disputeID int64) ([]models.DisputeEvent, error) {
+	rows, err := q.Query(ctx, `
+		SELECT event_id, dispute_id, COALESCE(old_status, ''), new_status, COALESCE(note, ''), actor, created_at
+		FROM DisputeEvents WHERE dispute_id=$1 ORDER BY event_id`, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.DisputeEvent, 0)
+	for rows.Next() {
+		var e models.DisputeEvent
+		if err := rows.Scan(&e.EventID, &e.DisputeID, &e.OldStatus, &e.NewStatus, &e.Note, &e.Actor, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"context"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const householdColumns = `household_id, name, owner_user_id, pool_points, created_at`
+
+func scanHousehold(row pgx.Row) (*models.Household, error) {
+	var h models.Household
+	if err := row.Scan(&h.HouseholdID, &h.Name, &h.OwnerUserID, &h.PoolPoints, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// pgHouseholds is the pgx HouseholdRepo.
+type pgHouseholds struct{}
+
+func (pgHouseholds) Create(ctx context.Context, q Querier, name string, ownerUserID int) (*models.Household, error) {
+	return scanHousehold(q.QueryRow(ctx, `INSERT INTO Households (name, owner_user_id) VALUES ($1,$2) RETURNING `+householdColumns, name, ownerUserID))
+}
+
+func (pgHouseholds) GetByID(ctx context.Context, q Querier, householdID int64) (*models.Household, error) {
+	return scanHousehold(q.QueryRow(ctx, `SELECT `+householdColumns+` FROM Households WHERE household_id=$1`, householdID))
+}
+
+func (pgHouseholds) GetByIDForUpdate(ctx context.Context, q Querier, householdID int64) (*models.Household, error) {
+	return scanHousehold(q.QueryRow(ctx, `SELECT `+householdColumns+` FROM Households WHERE household_id=$1 FOR UPDATE`, householdID))
+}
+
+func (pgHouseholds) GetUserHouseholdID(ctx context.Context, q Querier, userID int) (int64, error) {
+	var id int64
+	err := q.QueryRow(ctx, `SELECT household_id FROM HouseholdMembers WHERE user_id=$1`, userID).Scan(&id)
+	return id, err
+}
+
+func (pgHouseholds) AddMember(ctx context.Context, q Querier, householdID int64, userID int) error {
+	_, err := q.Exec(ctx, `INSERT INTO HouseholdMembers (household_id, user_id) VALUES ($1,$2)`, householdID, userID)
+	return err
+}
+
+func (pgHouseholds) RemoveMember(ctx context.Context, q Querier, householdID int64, userID int) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM HouseholdMembers WHERE household_id=$1 AND user_id=$2`, householdID, userID)
+	return tag.RowsAffected() == 1, err
+}
+
+func (pgHouseholds) ListMembers(ctx context.Context, q Querier, householdID int64) ([]models.HouseholdMember, error) {
+	rows, err := q.Query(ctx, `
+		SELECT m.user_id, u.username, m.joined_at
+		FROM HouseholdMembers m JOIN Users u ON u.user_id = m.user_id
+		WHERE m.household_id=$1
+		ORDER BY m.joined_at, m.user_id`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.HouseholdMember, 0)
+	for rows.Next() {
+		var m models.HouseholdMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (pgHouseholds) AddPoolPoints(ctx context.Context, q Querier, householdID int64, change int) (int, error) {
+	var pool int
+	err := q.QueryRow(ctx, `UPDATE Households SET pool_points = pool_points + $1 WHERE household_id=$2 RETURNING pool_points`, change, householdID).Scan(&pool)
+	return pool, err
+}
@@ -0,0 +1,158 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const installmentPlanColumns = `plan_id, transaction_id, user_id, card_id, total_amount, term, posted_count, status, created_at`
+
+func scanInstallmentPlan(row pgx.Row) (*models.InstallmentPlan, error) {
+	var p models.InstallmentPlan
+	if err := row.Scan(&p.PlanID, &p.TransactionID, &p.UserID, &p.CardID, &p.TotalAmount, &p.Term, &p.PostedCount, &p.Status, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+const installmentColumns = `installment_id, plan_id, seq, amount, due_at, status, posted_at`
+
+func scanInstallment(row pgx.Row) (*models.Installment, error) {
+	var i models.Installment
+	if err := row.Scan(&i.InstallmentID, &i.PlanID, &i.Seq, &i.Amount, &i.DueAt, &i.Status, &i.PostedAt); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// pgInstallments is the pgx InstallmentRepo.
+type pgInstallments struct{}
+
+func (pgInstallments) Create(ctx context.Context, q Querier, p *models.InstallmentPlan) (*models.InstallmentPlan, error) {
+	created, err := scanInstallmentPlan(q.QueryRow(ctx, `
+		INSERT INTO InstallmentPlans (transaction_id, user_id, card_id, total_amount, term)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING `+installmentPlanColumns, p.TransactionID, p.UserID, p.CardID, p.TotalAmount, p.Term))
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range p.Installments {
+		i, err := scanInstallment(q.QueryRow(ctx, `
+			INSERT INTO Installments (plan_id, seq, amount, due_at)
+			VALUES ($1,$2,$3,$4)
+			RETURNING `+installmentColumns, created.PlanID, inst.Seq, inst.Amount, inst.DueAt))
+		if err != nil {
+			return nil, err
+		}
+		created.Installments = append(created.Installments, *i)
+	}
+	return created, nil
+}
+
+func (pgInstallments) GetByTransactionForUpdate(ctx context.Context, q Querier, txID int64) (*models.InstallmentPlan, error) {
+	p, err := scanInstallmentPlan(q.QueryRow(ctx, `SELECT `+installmentPlanColumns+` FROM InstallmentPlans WHERE transaction_id=$1 FOR UPDATE`, txID))
+	if err != nil {
+		return nil, err
+	}
+	p.Installments, err = listInstallments(ctx, q, `SELECT `+installmentColumns+` FROM Installments WHERE plan_id=$1 ORDER BY seq FOR UPDATE`, p.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (pgInstallments) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.InstallmentPlan, error) {
+	rows, err := q.Query(ctx, `SELECT `+installmentPlanColumns+` FROM InstallmentPlans WHERE user_id=$1 ORDER BY plan_id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	plans := make([]models.InstallmentPlan, 0)
+	for rows.Next() {
+		p, err := scanInstallmentPlan(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		plans = append(plans, *p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		plans[i].Installments, err = listInstallments(ctx, q, `SELECT `+installmentColumns+` FROM Installments WHERE plan_id=$1 ORDER BY seq`, plans[i].PlanID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+func listInstallments(ctx context.Context, q Querier, sql string, planID int64) ([]models.Installment, error) {
+	rows, err := q.Query(ctx, sql, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Installment, 0)
+	for rows.Next() {
+		i, err := scanInstallment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *i)
+	}
+	return out, rows.Err()
+}
+
+// DueInstallment is a Scheduled installment of a settled (Paid) purchase.
+type DueInstallment struct {
+	InstallmentID int64
+	TransactionID int64
+	UserID        int
+}
+
+// ListDue compares due_at with the database clock, which also set it at
+// settlement.
+func (pgInstallments) ListDue(ctx context.Context, q Querier, limit int) ([]DueInstallment, error) {
+	rows, err := q.Query(ctx, `
+		SELECT i.installment_id, p.transaction_id, p.user_id
+		FROM Installments i
+		JOIN InstallmentPlans p ON p.plan_id = i.plan_id
+		JOIN Transactions t ON t.transaction_id = p.transaction_id
+		WHERE i.status = 'Scheduled' AND i.due_at <= NOW() AND p.status = 'Active' AND t.status = 'Paid'
+		ORDER BY i.due_at
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]DueInstallment, 0)
+	for rows.Next() {
+		var d DueInstallment
+		if err := rows.Scan(&d.InstallmentID, &d.TransactionID, &d.UserID); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (pgInstallments) Rebase(ctx context.Context, q Querier, planID int64, cycle time.Duration) error {
+	_, err := q.Exec(ctx, `UPDATE Installments SET due_at = NOW() + (seq - 1) * $1::float8 * INTERVAL '1 second' WHERE plan_id=$2`, cycle.Seconds(), planID)
+	return err
+}
+
+func (pgInstallments) UpdateInstallmentStatus(ctx context.Context, q Querier, installmentID int64, status string) error {
+	_, err := q.Exec(ctx, `UPDATE Installments SET status=$1::varchar, posted_at = CASE WHEN $1::varchar = 'Posted' THEN NOW() ELSE posted_at END WHERE installment_id=$2`, status, installmentID)
+	return err
+}
+
+func (pgInstallments) UpdatePlan(ctx context.Context, q Querier, planID int64, postedCount int, status string) error {
+	_, err := q.Exec(ctx, `UPDATE InstallmentPlans SET posted_count=$1, status=$2 WHERE plan_id=$3`, postedCount, status, planID)
+	return err
+}
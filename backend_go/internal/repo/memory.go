@@ -0,0 +1,867 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrMemorySQL is returned for raw SQL run on a Memory transaction: only
+// the repositories of Repos are held in memory.
+var ErrMemorySQL = errors.New("repo: SQL is not supported by the in-memory store")
+
+// PointsRow is one Points ledger row of a Memory store.
+type PointsRow struct {
+	UserID        int
+	TransactionID int64
+	ChangeAmount  int
+	Reason        string
+	PromotionID   int64 // set on promotion bonus rows
+	TransferID    int64 // set on transfer rows
+	RedemptionID  int64 // set on redemption rows
+	CreatedAt     time.Time
+}
+
+// Memory keeps the tables behind Repos in memory for service tests that
+// need no Postgres. It behaves like the pgx repositories under READ
+// COMMITTED: rows written in a transaction are only visible to it until
+// Commit; ...ForUpdate reads and updates take a row lock held until Commit
+// or Rollback, blocking other transactions (a lock cycle fails one of them
+// with deadlock_detected, 40P01); reads never block. Calls outside a Memory
+// transaction autocommit.
+type Memory struct {
+	// Now is the clock of NOW() (default time.Now).
+	Now func() time.Time
+
+	mu       sync.Mutex
+	users    map[int]models.User
+	txs      map[int]models.Transaction
+	points   []PointsRow
+	nextTxID int
+	locks    map[rowKey]*rowLock
+	// rows holds the tables of memory_tables.go, seqs their id sequences
+	rows map[rowKey]any
+	seqs map[byte]int
+}
+
+// rowKey names a row: 'u' Users, 't' Transactions, and the tables of
+// memory_tables.go.
+type rowKey struct {
+	table byte
+	id    int
+}
+
+type rowLock struct {
+	owner    *memTx
+	released chan struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		Now:      time.Now,
+		users:    map[int]models.User{},
+		txs:      map[int]models.Transaction{},
+		nextTxID: 1,
+		locks:    map[rowKey]*rowLock{},
+		rows:     map[rowKey]any{},
+		seqs:     map[byte]int{},
+	}
+}
+
+// Repos returns the repositories backed by m.
+func (m *Memory) Repos() Repos {
+	return Repos{
+		Users: memUsers{m}, Transactions: memTransactions{m}, Points: memPoints{m},
+		Cards: memCards{m}, StatusChanges: memStatusChanges{m}, Outbox: memOutbox{m},
+		Promotions: memPromotions{m}, Installments: memInstallments{m}, Disputes: memDisputes{m},
+		Transfers: memTransfers{m}, Redemptions: memRedemptions{m}, Households: memHouseholds{m}, CreditLimits: memCreditLimits{m},
+	}
+}
+
+// PutUser stores u as committed, replacing the user with the same id.
+func (m *Memory) PutUser(u models.User) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u.Status == "" {
+		u.Status = "Active"
+	}
+	if u.Tier == "" {
+		u.Tier = "Standard"
+	}
+	m.users[u.UserID] = u
+}
+
+// PutTransaction stores t as committed; later Creates get higher ids.
+func (m *Memory) PutTransaction(t models.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = m.Now()
+	}
+	m.txs[t.TransactionID] = t
+	m.nextTxID = max(m.nextTxID, t.TransactionID+1)
+}
+
+// PointsOf returns the user's committed ledger rows in insertion order.
+func (m *Memory) PointsOf(userID int) []PointsRow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]PointsRow, 0)
+	for _, p := range m.points {
+		if p.UserID == userID {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Begin starts a transaction; it implements Beginner.
+func (m *Memory) Begin(ctx context.Context) (pgx.Tx, error) {
+	return m.begin(), nil
+}
+
+func (m *Memory) begin() *memTx {
+	return &memTx{m: m, users: map[int]models.User{}, txs: map[int]models.Transaction{}, rows: map[rowKey]any{}}
+}
+
+var (
+	_ Beginner = (*Memory)(nil)
+	_ pgx.Tx   = (*memTx)(nil)
+)
+
+// memTx is a Memory transaction. Raw SQL fails with ErrMemorySQL.
+type memTx struct {
+	m      *Memory
+	users  map[int]models.User // rows written, visible to this transaction only
+	txs    map[int]models.Transaction
+	points []PointsRow
+	rows   map[rowKey]any
+	held   []rowKey
+	// waiting is the transaction whose lock this one waits for (deadlock detection)
+	waiting *memTx
+	closed  bool
+}
+
+func (t *memTx) Begin(ctx context.Context) (pgx.Tx, error) { return nil, ErrMemorySQL }
+
+func (t *memTx) Commit(ctx context.Context) error {
+	m := t.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	m.commit(t)
+	return nil
+}
+
+func (t *memTx) Rollback(ctx context.Context) error {
+	m := t.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	m.release(t)
+	return nil
+}
+
+func (t *memTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return 0, ErrMemorySQL
+}
+
+func (t *memTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults { return memBatch{} }
+
+func (t *memTx) LargeObjects() pgx.LargeObjects { return pgx.LargeObjects{} }
+
+func (t *memTx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return nil, ErrMemorySQL
+}
+
+func (t *memTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, ErrMemorySQL
+}
+
+func (t *memTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, ErrMemorySQL
+}
+
+func (t *memTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return memRow{ErrMemorySQL}
+}
+
+func (t *memTx) Conn() *pgx.Conn { return nil }
+
+type memRow struct{ err error }
+
+func (r memRow) Scan(dest ...any) error { return r.err }
+
+type memBatch struct{}
+
+func (memBatch) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, ErrMemorySQL }
+func (memBatch) Query() (pgx.Rows, error)         { return nil, ErrMemorySQL }
+func (memBatch) QueryRow() pgx.Row                { return memRow{ErrMemorySQL} }
+func (memBatch) Close() error                     { return ErrMemorySQL }
+
+// commit publishes t's rows and releases its locks. m.mu must be held.
+func (m *Memory) commit(t *memTx) {
+	for id, u := range t.users {
+		m.users[id] = u
+	}
+	for id, tr := range t.txs {
+		m.txs[id] = tr
+	}
+	m.points = append(m.points, t.points...)
+	for k, v := range t.rows {
+		m.rows[k] = v
+	}
+	m.release(t)
+}
+
+// release drops t's locks and wakes the transactions waiting for them.
+// m.mu must be held.
+func (m *Memory) release(t *memTx) {
+	t.closed = true
+	for _, k := range t.held {
+		if l := m.locks[k]; l != nil && l.owner == t {
+			delete(m.locks, k)
+			close(l.released)
+		}
+	}
+	t.held = nil
+}
+
+// lock takes the row lock k for t, waiting while another transaction holds
+// it. m.mu must be held; it is released while waiting.
+func (m *Memory) lock(ctx context.Context, t *memTx, k rowKey) error {
+	for {
+		l := m.locks[k]
+		if l == nil {
+			m.locks[k] = &rowLock{owner: t, released: make(chan struct{})}
+			t.held = append(t.held, k)
+			return nil
+		}
+		if l.owner == t {
+			return nil
+		}
+		for o := l.owner; o != nil; o = o.waiting {
+			if o == t {
+				return &pgconn.PgError{Severity: "ERROR", Code: "40P01", Message: "deadlock detected"}
+			}
+		}
+		t.waiting = l.owner
+		m.mu.Unlock()
+		select {
+		case <-l.released:
+		case <-ctx.Done():
+		}
+		m.mu.Lock()
+		t.waiting = nil
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// txOf returns the Memory transaction q is, or nil for reads outside one.
+func (m *Memory) txOf(q Querier) (*memTx, error) {
+	t, ok := q.(*memTx)
+	if !ok || t.m != m {
+		return nil, nil
+	}
+	if t.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return t, nil
+}
+
+// read runs fn with the rows visible to q. m.mu is held.
+func (m *Memory) read(q Querier, fn func(t *memTx) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.txOf(q)
+	if err != nil {
+		return err
+	}
+	return fn(t)
+}
+
+// write runs fn in q's transaction, or in one committed (or rolled back on
+// error) right after fn when q is not a Memory transaction. m.mu is held.
+func (m *Memory) write(q Querier, fn func(t *memTx) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.txOf(q)
+	if err != nil {
+		return err
+	}
+	if t != nil {
+		return fn(t)
+	}
+	t = m.begin()
+	if err := fn(t); err != nil {
+		m.release(t)
+		return err
+	}
+	m.commit(t)
+	return nil
+}
+
+func (m *Memory) user(t *memTx, id int) (models.User, bool) {
+	if t != nil {
+		if u, ok := t.users[id]; ok {
+			return u, true
+		}
+	}
+	u, ok := m.users[id]
+	return u, ok
+}
+
+func (m *Memory) transaction(t *memTx, id int) (models.Transaction, bool) {
+	if t != nil {
+		if tr, ok := t.txs[id]; ok {
+			return tr, true
+		}
+	}
+	tr, ok := m.txs[id]
+	return tr, ok
+}
+
+// visibleTransactions returns every transaction t sees, unordered.
+func (m *Memory) visibleTransactions(t *memTx) []models.Transaction {
+	out := make([]models.Transaction, 0, len(m.txs))
+	for id, tr := range m.txs {
+		if t != nil {
+			if own, ok := t.txs[id]; ok {
+				tr = own
+			}
+		}
+		out = append(out, tr)
+	}
+	if t != nil {
+		for id, tr := range t.txs {
+			if _, ok := m.txs[id]; !ok {
+				out = append(out, tr)
+			}
+		}
+	}
+	return out
+}
+
+// updateUser locks and changes user id in t; found is false if it does not exist.
+func (m *Memory) updateUser(ctx context.Context, t *memTx, id int, fn func(u *models.User)) (u models.User, found bool, err error) {
+	if _, ok := m.user(t, id); !ok {
+		return u, false, nil
+	}
+	if err := m.lock(ctx, t, rowKey{'u', id}); err != nil {
+		return u, false, err
+	}
+	// Re-read: the lock holder may have committed a newer version
+	u, _ = m.user(t, id)
+	fn(&u)
+	t.users[id] = u
+	return u, true, nil
+}
+
+func (m *Memory) updateTransaction(ctx context.Context, t *memTx, id int, fn func(tr *models.Transaction)) (tr models.Transaction, found bool, err error) {
+	if _, ok := m.transaction(t, id); !ok {
+		return tr, false, nil
+	}
+	if err := m.lock(ctx, t, rowKey{'t', id}); err != nil {
+		return tr, false, err
+	}
+	tr, _ = m.transaction(t, id)
+	fn(&tr)
+	t.txs[id] = tr
+	return tr, true, nil
+}
+
+func foreignKeyError(detail string) error {
+	return &pgconn.PgError{Severity: "ERROR", Code: "23503", Message: "violates foreign key constraint", Detail: detail}
+}
+
+func uniqueViolation(constraint string) error {
+	return &pgconn.PgError{Severity: "ERROR", Code: "23505", Message: "duplicate key value violates unique constraint", ConstraintName: constraint}
+}
+
+func checkViolation(constraint string) error {
+	return &pgconn.PgError{Severity: "ERROR", Code: "23514", Message: "new row violates check constraint", ConstraintName: constraint}
+}
+
+// checkUser and checkTransaction enforce the foreign keys to Users and Transactions.
+func (m *Memory) checkUser(t *memTx, userID int) error {
+	if _, ok := m.user(t, userID); !ok {
+		return foreignKeyError(fmt.Sprintf("Key (user_id)=(%d) is not present in table \"users\".", userID))
+	}
+	return nil
+}
+
+func (m *Memory) checkTransaction(t *memTx, txID int64) error {
+	if _, ok := m.transaction(t, int(txID)); !ok {
+		return foreignKeyError(fmt.Sprintf("Key (transaction_id)=(%d) is not present in table \"transactions\".", txID))
+	}
+	return nil
+}
+
+// getRow returns the version of row k that t sees.
+func getRow[V any](m *Memory, t *memTx, k rowKey) (V, bool) {
+	if t != nil {
+		if v, ok := t.rows[k]; ok {
+			return v.(V), true
+		}
+	}
+	v, ok := m.rows[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return v.(V), true
+}
+
+// tableRows returns the rows of table that t sees, by id.
+func tableRows[V any](m *Memory, t *memTx, table byte) []V {
+	ids := make([]int, 0)
+	for k := range m.rows {
+		if k.table == table {
+			ids = append(ids, k.id)
+		}
+	}
+	if t != nil {
+		for k := range t.rows {
+			if _, ok := m.rows[k]; !ok && k.table == table {
+				ids = append(ids, k.id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	out := make([]V, 0, len(ids))
+	for _, id := range ids {
+		v, _ := getRow[V](m, t, rowKey{table, id})
+		out = append(out, v)
+	}
+	return out
+}
+
+// updateRow locks row k and applies fn to t's version of it; found is
+// false if it does not exist.
+func updateRow[V any](ctx context.Context, m *Memory, t *memTx, k rowKey, fn func(v *V)) (v V, found bool, err error) {
+	if _, ok := getRow[V](m, t, k); !ok {
+		return v, false, nil
+	}
+	if err := m.lock(ctx, t, k); err != nil {
+		return v, false, err
+	}
+	v, _ = getRow[V](m, t, k)
+	fn(&v)
+	t.rows[k] = v
+	return v, true, nil
+}
+
+// insertRow stores v under the next id of table, locked by t, and returns
+// the id. Like a sequence, ids are not given back on rollback.
+func insertRow[V any](ctx context.Context, m *Memory, t *memTx, table byte, v func(id int) V) (int, error) {
+	m.seqs[table]++
+	id := m.seqs[table]
+	k := rowKey{table, id}
+	t.rows[k] = v(id)
+	return id, m.lock(ctx, t, k)
+}
+
+// parseInterval reads the "<n> <unit>" intervals of the risk rules.
+func parseInterval(s string) (time.Duration, error) {
+	f := strings.Fields(s)
+	if len(f) != 2 {
+		return 0, fmt.Errorf("repo: unsupported interval %q", s)
+	}
+	n, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("repo: unsupported interval %q", s)
+	}
+	units := map[string]time.Duration{"second": time.Second, "minute": time.Minute, "hour": time.Hour, "day": 24 * time.Hour}
+	unit, ok := units[strings.TrimSuffix(strings.ToLower(f[1]), "s")]
+	if !ok {
+		return 0, fmt.Errorf("repo: unsupported interval %q", s)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+/* ---------------- UserRepo ---------------- */
+
+type memUsers struct{ m *Memory }
+
+func (r memUsers) GetByID(ctx context.Context, q Querier, userID int) (*models.User, error) {
+	var out *models.User
+	err := r.m.read(q, func(t *memTx) error {
+		u, ok := r.m.user(t, userID)
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		out = &u
+		return nil
+	})
+	return out, err
+}
+
+func (r memUsers) GetByIDForUpdate(ctx context.Context, q Querier, userID int) (*models.User, error) {
+	var out *models.User
+	err := r.m.write(q, func(t *memTx) error {
+		u, found, err := r.m.updateUser(ctx, t, userID, func(*models.User) {})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		out = &u
+		return nil
+	})
+	return out, err
+}
+
+// update applies fn to the user; a missing user is not an error, like an
+// UPDATE matching no row.
+func (r memUsers) update(ctx context.Context, q Querier, userID int, fn func(u *models.User)) error {
+	return r.m.write(q, func(t *memTx) error {
+		_, _, err := r.m.updateUser(ctx, t, userID, fn)
+		return err
+	})
+}
+
+func (r memUsers) UpdateBalanceAndPoints(ctx context.Context, q Querier, userID int, balanceChange float64, pointChange int) (*models.User, error) {
+	var out *models.User
+	err := r.m.write(q, func(t *memTx) error {
+		u, found, err := r.m.updateUser(ctx, t, userID, func(u *models.User) {
+			u.Balance += balanceChange
+			u.CurrentPoints += pointChange
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		out = &u
+		return nil
+	})
+	return out, err
+}
+
+func (r memUsers) AddPoints(ctx context.Context, q Querier, userID int, pointChange int) error {
+	return r.update(ctx, q, userID, func(u *models.User) { u.CurrentPoints += pointChange })
+}
+
+func (r memUsers) UpdateStatus(ctx context.Context, q Querier, userID int, status, reason, changedBy string, frozenUntil *time.Time) error {
+	return r.update(ctx, q, userID, func(u *models.User) {
+		u.Status, u.StatusReason, u.StatusChangedBy, u.FrozenUntil = status, reason, changedBy, frozenUntil
+	})
+}
+
+func (r memUsers) UpdateCreditLimit(ctx context.Context, q Querier, userID int, creditLimit float64) error {
+	return r.update(ctx, q, userID, func(u *models.User) { u.CreditLimit = creditLimit })
+}
+
+func (r memUsers) UpdateLimitBoost(ctx context.Context, q Querier, userID int, boost float64, until *time.Time) error {
+	return r.update(ctx, q, userID, func(u *models.User) { u.TempLimitBoost, u.TempLimitBoostUntil = boost, until })
+}
+
+func (r memUsers) UpdateBalanceAndReserved(ctx context.Context, q Querier, userID int, balanceChange, reservedChange float64) error {
+	return r.update(ctx, q, userID, func(u *models.User) {
+		u.Balance += balanceChange
+		u.InstallmentReserved += reservedChange
+	})
+}
+
+func (r memUsers) UpdateAuthHold(ctx context.Context, q Querier, userID int, change float64) error {
+	return r.update(ctx, q, userID, func(u *models.User) { u.AuthHold += change })
+}
+
+func (r memUsers) UpdateTier(ctx context.Context, q Querier, userID int, tier string, spend float64) error {
+	now := r.m.Now()
+	return r.update(ctx, q, userID, func(u *models.User) { u.Tier, u.TierSpend, u.TierEvaluatedAt = tier, spend, &now })
+}
+
+func (r memUsers) ListForTierReview(ctx context.Context, q Querier, maxAgeSeconds float64, limit int) ([]int, error) {
+	var due []models.User
+	cutoff := r.m.Now().Add(-time.Duration(maxAgeSeconds * float64(time.Second)))
+	err := r.m.read(q, func(t *memTx) error {
+		for id := range r.m.users {
+			if u, _ := r.m.user(t, id); u.TierEvaluatedAt == nil || !u.TierEvaluatedAt.After(cutoff) {
+				due = append(due, u)
+			}
+		}
+		return nil
+	})
+	sort.Slice(due, func(i, j int) bool {
+		a, b := due[i].TierEvaluatedAt, due[j].TierEvaluatedAt
+		switch {
+		case a == nil || b == nil:
+			if (a == nil) != (b == nil) {
+				return a == nil
+			}
+		case !a.Equal(*b):
+			return a.Before(*b)
+		}
+		return due[i].UserID < due[j].UserID
+	})
+	out := make([]int, 0)
+	for _, u := range due {
+		if len(out) == limit {
+			break
+		}
+		out = append(out, u.UserID)
+	}
+	return out, err
+}
+
+/* ---------------- TransactionRepo ---------------- */
+
+type memTransactions struct{ m *Memory }
+
+func (r memTransactions) Create(ctx context.Context, q Querier, userID int, amount float64, status string, pointChange int, merchant string, sourceID, cardID *int64) (int64, error) {
+	var id int
+	err := r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, userID); err != nil {
+			return err
+		}
+		tr := models.Transaction{UserID: userID, Amount: amount, Status: status, PointChange: pointChange, Merchant: merchant, CardID: cardID, CreatedAt: r.m.Now()}
+		if sourceID != nil {
+			if _, ok := r.m.transaction(t, int(*sourceID)); !ok {
+				return foreignKeyError(fmt.Sprintf("Key (source_transaction_id)=(%d) is not present in table \"transactions\".", *sourceID))
+			}
+			src := int(*sourceID)
+			tr.SourceTransactionID = &src
+		}
+		// Like a sequence, ids are not given back on rollback
+		id = r.m.nextTxID
+		r.m.nextTxID++
+		tr.TransactionID = id
+		t.txs[id] = tr
+		return r.m.lock(ctx, t, rowKey{'t', id})
+	})
+	return int64(id), err
+}
+
+func (r memTransactions) GetByID(ctx context.Context, q Querier, txID int) (*models.Transaction, error) {
+	var out *models.Transaction
+	err := r.m.read(q, func(t *memTx) error {
+		tr, ok := r.m.transaction(t, txID)
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		out = &tr
+		return nil
+	})
+	return out, err
+}
+
+func (r memTransactions) GetByIDForUpdate(ctx context.Context, q Querier, txID int) (*models.Transaction, error) {
+	var out *models.Transaction
+	err := r.m.write(q, func(t *memTx) error {
+		tr, found, err := r.m.updateTransaction(ctx, t, txID, func(*models.Transaction) {})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		out = &tr
+		return nil
+	})
+	return out, err
+}
+
+func (r memTransactions) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Transaction, error) {
+	out := make([]models.Transaction, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		for _, tr := range r.m.visibleTransactions(t) {
+			if tr.UserID == userID {
+				out = append(out, tr)
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].TransactionID > out[j].TransactionID
+	})
+	return out, err
+}
+
+func (r memTransactions) update(ctx context.Context, q Querier, txID int, fn func(tr *models.Transaction)) error {
+	return r.m.write(q, func(t *memTx) error {
+		_, _, err := r.m.updateTransaction(ctx, t, txID, fn)
+		return err
+	})
+}
+
+func (r memTransactions) UpdateStatus(ctx context.Context, q Querier, txID int, status string) error {
+	return r.update(ctx, q, txID, func(tr *models.Transaction) { tr.Status = status })
+}
+
+func (r memTransactions) SetFX(ctx context.Context, q Querier, txID int64, originalAmount float64, currency string, rate, fee float64) error {
+	return r.update(ctx, q, int(txID), func(tr *models.Transaction) {
+		tr.OriginalAmount, tr.OriginalCurrency, tr.FXRate, tr.FXFee = &originalAmount, &currency, &rate, fee
+	})
+}
+
+func (r memTransactions) SetAuthorization(ctx context.Context, q Querier, txID int64, expirySeconds float64, pointsRedeemed int, rewardMultiplier float64) (*time.Time, error) {
+	expiresAt := r.m.Now().Add(time.Duration(expirySeconds * float64(time.Second)))
+	err := r.m.write(q, func(t *memTx) error {
+		_, found, err := r.m.updateTransaction(ctx, t, int(txID), func(tr *models.Transaction) {
+			tr.AuthorizedAmount, tr.AuthExpiresAt = tr.Amount, &expiresAt
+			tr.PointsRedeemed, tr.RewardMultiplier = pointsRedeemed, rewardMultiplier
+		})
+		if err == nil && !found {
+			err = pgx.ErrNoRows
+		}
+		return err
+	})
+	return &expiresAt, err
+}
+
+func (r memTransactions) IncrementAuthorization(ctx context.Context, q Querier, txID int, increment float64, pointChange int) error {
+	return r.update(ctx, q, txID, func(tr *models.Transaction) {
+		if tr.AuthorizedAmount == 0 {
+			tr.AuthorizedAmount = tr.Amount
+		}
+		tr.Amount += increment
+		tr.AuthorizedAmount += increment
+		tr.PointChange = pointChange
+	})
+}
+
+func (r memTransactions) Capture(ctx context.Context, q Querier, txID int, amount float64, pointChange int) error {
+	now := r.m.Now()
+	return r.update(ctx, q, txID, func(tr *models.Transaction) {
+		if tr.AuthorizedAmount == 0 {
+			tr.AuthorizedAmount = tr.Amount
+		}
+		tr.Amount, tr.PointChange, tr.Status, tr.CapturedAt = amount, pointChange, "Paid", &now
+	})
+}
+
+func (r memTransactions) ListExpiredAuthorizations(ctx context.Context, q Querier, limit int) ([]int64, error) {
+	var expired []models.Transaction
+	now := r.m.Now()
+	err := r.m.read(q, func(t *memTx) error {
+		for _, tr := range r.m.visibleTransactions(t) {
+			if tr.Status == "Pending" && tr.AuthExpiresAt != nil && !tr.AuthExpiresAt.After(now) {
+				expired = append(expired, tr)
+			}
+		}
+		return nil
+	})
+	sort.Slice(expired, func(i, j int) bool { return expired[i].AuthExpiresAt.Before(*expired[j].AuthExpiresAt) })
+	out := make([]int64, 0)
+	for _, tr := range expired {
+		if len(out) == limit {
+			break
+		}
+		out = append(out, int64(tr.TransactionID))
+	}
+	return out, err
+}
+
+// countRecent counts the transactions match accepts created within window.
+func (r memTransactions) countRecent(q Querier, window string, match func(tr models.Transaction) bool) (int64, error) {
+	d, err := parseInterval(window)
+	if err != nil {
+		return 0, err
+	}
+	since := r.m.Now().Add(-d)
+	var n int64
+	err = r.m.read(q, func(t *memTx) error {
+		for _, tr := range r.m.visibleTransactions(t) {
+			if tr.CreatedAt.After(since) && match(tr) {
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (r memTransactions) CountRecentDuplicates(ctx context.Context, q Querier, userID int, merchant string, amount float64, window string) (int64, error) {
+	return r.countRecent(q, window, func(tr models.Transaction) bool {
+		return tr.UserID == userID && tr.Merchant == merchant && tr.Amount == amount
+	})
+}
+
+func (r memTransactions) CountRecentRefunds(ctx context.Context, q Querier, userID int, window string) (int64, error) {
+	return r.countRecent(q, window, func(tr models.Transaction) bool {
+		return tr.UserID == userID && tr.Status == "Refunded" && tr.SourceTransactionID != nil
+	})
+}
+
+func (r memTransactions) RollingSettledSpend(ctx context.Context, q Querier, userID int) (float64, error) {
+	since := r.m.Now().AddDate(-1, 0, 0)
+	var spend float64
+	err := r.m.read(q, func(t *memTx) error {
+		for _, tr := range r.m.visibleTransactions(t) {
+			at := tr.CreatedAt
+			if tr.CapturedAt != nil {
+				at = *tr.CapturedAt
+			}
+			if tr.UserID == userID && tr.SourceTransactionID == nil && (tr.Status == "Paid" || tr.Status == "Disputed") && !at.Before(since) {
+				spend += tr.Amount
+			}
+		}
+		return nil
+	})
+	return spend, err
+}
+
+func (r memTransactions) PendingRedeemedPoints(ctx context.Context, q Querier, userID int) (int, error) {
+	points := 0
+	err := r.m.read(q, func(t *memTx) error {
+		for _, tr := range r.m.visibleTransactions(t) {
+			if tr.UserID == userID && tr.Status == "Pending" {
+				points += tr.PointsRedeemed
+			}
+		}
+		return nil
+	})
+	return points, err
+}
+
+func (r memTransactions) HasPurchase(ctx context.Context, q Querier, userID int) (bool, error) {
+	found := false
+	err := r.m.read(q, func(t *memTx) error {
+		for _, tr := range r.m.visibleTransactions(t) {
+			if tr.UserID == userID && tr.SourceTransactionID == nil && tr.Status != "Voided" && tr.Status != "Expired" {
+				found = true
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+/* ---------------- PointsRepo ---------------- */
+
+type memPoints struct{ m *Memory }
+
+func (r memPoints) Insert(ctx context.Context, q Querier, userID int, txID int64, change int, reason string) error {
+	return r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, userID); err != nil {
+			return err
+		}
+		if err := r.m.checkTransaction(t, txID); err != nil {
+			return err
+		}
+		t.points = append(t.points, PointsRow{UserID: userID, TransactionID: txID, ChangeAmount: change, Reason: reason, CreatedAt: r.m.Now()})
+		return nil
+	})
+}
@@ -0,0 +1,1138 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Memory tables kept in Memory.rows, by rowKey.table.
+const (
+	memCardTable         byte = 'c' // models.Card
+	memStatusChangeTable byte = 's' // models.StatusChange
+	memOutboxTable       byte = 'o' // memEvent
+	memPromotionTable    byte = 'p' // models.Promotion
+	memGrantTable        byte = 'g' // []memGrant by transaction id
+	memPlanTable         byte = 'i' // models.InstallmentPlan with its Installments
+	memInstallmentTable  byte = 'n' // plan id (int64) by installment id
+	memDisputeTable      byte = 'd' // models.Dispute
+	memDisputeEventTable byte = 'e' // models.DisputeEvent
+	memTransferTable     byte = 'x' // models.PointTransfer
+	memRedemptionTable   byte = 'r' // models.Redemption
+	memHouseholdTable    byte = 'h' // models.Household without Members
+	memMemberTable       byte = 'm' // memMember by user id
+	memLimitRequestTable byte = 'q' // models.CreditLimitRequest
+	memLimitChangeTable  byte = 'l' // models.CreditLimitChange
+)
+
+// memEvent is an Outbox row.
+type memEvent struct {
+	models.EventEnvelope
+	TransactionID *int64
+}
+
+// memGrant is a TransactionPromotions row.
+type memGrant struct {
+	PromotionID int64
+	Points      int
+	CreatedAt   time.Time
+}
+
+// memMember is a HouseholdMembers row; HouseholdID 0 is a deleted row.
+type memMember struct {
+	UserID      int
+	HouseholdID int64
+	JoinedAt    time.Time
+}
+
+// EventsOf returns the user's committed outbox events, oldest first.
+func (m *Memory) EventsOf(userID int) []models.EventEnvelope {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]models.EventEnvelope, 0)
+	for _, e := range tableRows[memEvent](m, nil, memOutboxTable) {
+		if e.UserID == userID {
+			out = append(out, e.EventEnvelope)
+		}
+	}
+	return out
+}
+
+/* ---------------- CardRepo ---------------- */
+
+type memCards struct{ m *Memory }
+
+func (r memCards) Create(ctx context.Context, q Querier, c *models.Card) (*models.Card, error) {
+	var out models.Card
+	err := r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, c.UserID); err != nil {
+			return err
+		}
+		if c.CardType == "Primary" {
+			for _, o := range tableRows[models.Card](r.m, t, memCardTable) {
+				if o.UserID == c.UserID && o.CardType == "Primary" && (o.Status == "Active" || o.Status == "Frozen") {
+					return uniqueViolation("idx_cards_one_primary")
+				}
+			}
+		}
+		_, err := insertRow(ctx, r.m, t, memCardTable, func(id int) models.Card {
+			out = models.Card{
+				CardID: int64(id), UserID: c.UserID, CardType: c.CardType, PANToken: c.PANToken, Last4: c.Last4,
+				ExpiresAt: c.ExpiresAt, Status: "Active", CreditLimit: c.CreditLimit, CreatedAt: r.m.Now(),
+				SingleUse: c.SingleUse, AmountCap: c.AmountCap, MerchantLock: c.MerchantLock,
+			}
+			return out
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r memCards) GetByIDForUpdate(ctx context.Context, q Querier, cardID int64) (*models.Card, error) {
+	var out *models.Card
+	err := r.m.write(q, func(t *memTx) error {
+		c, found, err := updateRow(ctx, r.m, t, rowKey{memCardTable, int(cardID)}, func(*models.Card) {})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		out = &c
+		return nil
+	})
+	return out, err
+}
+
+func (r memCards) GetPrimaryForUpdate(ctx context.Context, q Querier, userID int) (*models.Card, error) {
+	var out *models.Card
+	err := r.m.write(q, func(t *memTx) error {
+		for _, c := range tableRows[models.Card](r.m, t, memCardTable) {
+			if c.UserID != userID || c.CardType != "Primary" {
+				continue
+			}
+			// Re-check after the lock, like Postgres does for FOR UPDATE
+			c, _, err := updateRow(ctx, r.m, t, rowKey{memCardTable, int(c.CardID)}, func(*models.Card) {})
+			if err != nil {
+				return err
+			}
+			if c.Status == "Active" || c.Status == "Frozen" {
+				out = &c
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+	return out, err
+}
+
+func (r memCards) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Card, error) {
+	out := make([]models.Card, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		for _, c := range tableRows[models.Card](r.m, t, memCardTable) {
+			if c.UserID == userID {
+				out = append(out, c)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// update applies fn to the card; a missing card is not an error, like an
+// UPDATE matching no row.
+func (r memCards) update(ctx context.Context, q Querier, cardID int64, fn func(c *models.Card)) error {
+	return r.m.write(q, func(t *memTx) error {
+		_, _, err := updateRow(ctx, r.m, t, rowKey{memCardTable, int(cardID)}, fn)
+		return err
+	})
+}
+
+func (r memCards) UpdateStatus(ctx context.Context, q Querier, cardID int64, status string) error {
+	return r.update(ctx, q, cardID, func(c *models.Card) { c.Status = status })
+}
+
+func (r memCards) UpdateBalance(ctx context.Context, q Querier, cardID int64, balanceChange float64) error {
+	return r.update(ctx, q, cardID, func(c *models.Card) { c.Balance += balanceChange })
+}
+
+func (r memCards) AddSpend(ctx context.Context, q Querier, cardID int64, amount float64) error {
+	return r.update(ctx, q, cardID, func(c *models.Card) { c.Spent += amount })
+}
+
+func (r memCards) UpdateBalanceAndReserved(ctx context.Context, q Querier, cardID int64, balanceChange, reservedChange float64) error {
+	return r.update(ctx, q, cardID, func(c *models.Card) {
+		c.Balance += balanceChange
+		c.InstallmentReserved += reservedChange
+	})
+}
+
+func (r memCards) UpdateAuthHold(ctx context.Context, q Querier, cardID int64, change float64) error {
+	return r.update(ctx, q, cardID, func(c *models.Card) { c.AuthHold += change })
+}
+
+/* ---------------- StatusChangeRepo ---------------- */
+
+type memStatusChanges struct{ m *Memory }
+
+func (r memStatusChanges) Insert(ctx context.Context, q Querier, c models.StatusChange) error {
+	return r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, c.UserID); err != nil {
+			return err
+		}
+		_, err := insertRow(ctx, r.m, t, memStatusChangeTable, func(id int) models.StatusChange {
+			c.ChangeID, c.CreatedAt = int64(id), r.m.Now()
+			return c
+		})
+		return err
+	})
+}
+
+func (r memStatusChanges) ListByUserID(ctx context.Context, q Querier, userID int, limit int) ([]models.StatusChange, error) {
+	out := make([]models.StatusChange, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		rows := tableRows[models.StatusChange](r.m, t, memStatusChangeTable)
+		for i := len(rows) - 1; i >= 0 && len(out) < limit; i-- {
+			if rows[i].UserID == userID {
+				out = append(out, rows[i])
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r memStatusChanges) LastForCard(ctx context.Context, q Querier, cardID int64) (*models.StatusChange, error) {
+	var out *models.StatusChange
+	err := r.m.read(q, func(t *memTx) error {
+		rows := tableRows[models.StatusChange](r.m, t, memStatusChangeTable)
+		for i := len(rows) - 1; i >= 0; i-- {
+			if rows[i].CardID != nil && *rows[i].CardID == cardID {
+				out = &rows[i]
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+	return out, err
+}
+
+/* ---------------- OutboxRepo ---------------- */
+
+type memOutbox struct{ m *Memory }
+
+func (r memOutbox) Insert(ctx context.Context, q Querier, eventType string, userID int, txID *int64, payload []byte) (*models.EventEnvelope, error) {
+	var out models.EventEnvelope
+	err := r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, userID); err != nil {
+			return err
+		}
+		if txID != nil {
+			if err := r.m.checkTransaction(t, *txID); err != nil {
+				return err
+			}
+		}
+		_, err := insertRow(ctx, r.m, t, memOutboxTable, func(id int) memEvent {
+			out = models.EventEnvelope{ID: int64(id), Type: eventType, UserID: userID, CreatedAt: r.m.Now(), Data: slices.Clone(payload)}
+			return memEvent{EventEnvelope: out, TransactionID: txID}
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+/* ---------------- PromotionRepo ---------------- */
+
+type memPromotions struct{ m *Memory }
+
+func (r memPromotions) Create(ctx context.Context, q Querier, p *models.Promotion) (*models.Promotion, error) {
+	var out models.Promotion
+	err := r.m.write(q, func(t *memTx) error {
+		_, err := insertRow(ctx, r.m, t, memPromotionTable, func(id int) models.Promotion {
+			out = *p
+			out.PromotionID, out.Active, out.CreatedAt = int64(id), true, r.m.Now()
+			return out
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r memPromotions) List(ctx context.Context, q Querier, runningOnly bool) ([]models.Promotion, error) {
+	now := r.m.Now()
+	out := make([]models.Promotion, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		for _, p := range tableRows[models.Promotion](r.m, t, memPromotionTable) {
+			running := p.Active && (p.StartsAt == nil || !p.StartsAt.After(now)) && (p.EndsAt == nil || p.EndsAt.After(now))
+			if !runningOnly || running {
+				out = append(out, p)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r memPromotions) Deactivate(ctx context.Context, q Querier, promotionID int64) (*models.Promotion, error) {
+	var out *models.Promotion
+	err := r.m.write(q, func(t *memTx) error {
+		p, found, err := updateRow(ctx, r.m, t, rowKey{memPromotionTable, int(promotionID)}, func(p *models.Promotion) { p.Active = false })
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		out = &p
+		return nil
+	})
+	return out, err
+}
+
+func (r memPromotions) MonthlyPoints(ctx context.Context, q Querier, userID int) (map[int64]int, error) {
+	now := r.m.Now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	out := make(map[int64]int)
+	err := r.m.read(q, func(t *memTx) error {
+		for _, tr := range r.m.visibleTransactions(t) {
+			if tr.UserID != userID {
+				continue
+			}
+			switch tr.Status {
+			case "Voided", "Expired", "Refunded", "ChargedBack":
+				continue
+			}
+			grants, _ := getRow[[]memGrant](r.m, t, rowKey{memGrantTable, tr.TransactionID})
+			for _, g := range grants {
+				if !g.CreatedAt.Before(month) {
+					out[g.PromotionID] += g.Points
+				}
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r memPromotions) Grant(ctx context.Context, q Querier, txID int64, a models.AppliedPromotion) error {
+	return r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkTransaction(t, txID); err != nil {
+			return err
+		}
+		if _, ok := getRow[models.Promotion](r.m, t, rowKey{memPromotionTable, int(a.PromotionID)}); !ok {
+			return foreignKeyError("Key (promotion_id) is not present in table \"promotions\".")
+		}
+		k := rowKey{memGrantTable, int(txID)}
+		if err := r.m.lock(ctx, t, k); err != nil {
+			return err
+		}
+		grants, _ := getRow[[]memGrant](r.m, t, k)
+		for _, g := range grants {
+			if g.PromotionID == a.PromotionID {
+				return uniqueViolation("transactionpromotions_pkey")
+			}
+		}
+		t.rows[k] = append(slices.Clone(grants), memGrant{PromotionID: a.PromotionID, Points: a.Points, CreatedAt: r.m.Now()})
+		return nil
+	})
+}
+
+func (r memPromotions) ListGranted(ctx context.Context, q Querier, txID int64) ([]models.AppliedPromotion, error) {
+	out := make([]models.AppliedPromotion, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		grants, _ := getRow[[]memGrant](r.m, t, rowKey{memGrantTable, int(txID)})
+		for _, g := range grants {
+			p, _ := getRow[models.Promotion](r.m, t, rowKey{memPromotionTable, int(g.PromotionID)})
+			out = append(out, models.AppliedPromotion{PromotionID: g.PromotionID, Name: p.Name, Points: g.Points})
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].PromotionID < out[j].PromotionID })
+	return out, err
+}
+
+func (r memPromotions) UpdateGranted(ctx context.Context, q Querier, txID, promotionID int64, points int) error {
+	return r.m.write(q, func(t *memTx) error {
+		_, _, err := updateRow(ctx, r.m, t, rowKey{memGrantTable, int(txID)}, func(grants *[]memGrant) {
+			*grants = slices.Clone(*grants)
+			for i := range *grants {
+				if (*grants)[i].PromotionID == promotionID {
+					(*grants)[i].Points = points
+				}
+			}
+		})
+		return err
+	})
+}
+
+func (r memPromotions) InsertPoints(ctx context.Context, q Querier, userID int, txID int64, a models.AppliedPromotion) error {
+	return r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, userID); err != nil {
+			return err
+		}
+		if err := r.m.checkTransaction(t, txID); err != nil {
+			return err
+		}
+		t.points = append(t.points, PointsRow{UserID: userID, TransactionID: txID, ChangeAmount: a.Points, Reason: "Promo: " + a.Name, PromotionID: a.PromotionID, CreatedAt: r.m.Now()})
+		return nil
+	})
+}
+
+/* ---------------- InstallmentRepo ---------------- */
+
+type memInstallments struct{ m *Memory }
+
+func (r memInstallments) Create(ctx context.Context, q Querier, p *models.InstallmentPlan) (*models.InstallmentPlan, error) {
+	var out models.InstallmentPlan
+	err := r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkTransaction(t, p.TransactionID); err != nil {
+			return err
+		}
+		if err := r.m.checkUser(t, p.UserID); err != nil {
+			return err
+		}
+		for _, o := range tableRows[models.InstallmentPlan](r.m, t, memPlanTable) {
+			if o.TransactionID == p.TransactionID {
+				return uniqueViolation("installmentplans_transaction_id_key")
+			}
+		}
+		planID, err := insertRow(ctx, r.m, t, memPlanTable, func(id int) models.InstallmentPlan {
+			out = models.InstallmentPlan{
+				PlanID: int64(id), TransactionID: p.TransactionID, UserID: p.UserID, CardID: p.CardID,
+				TotalAmount: p.TotalAmount, Term: p.Term, Status: "Active", CreatedAt: r.m.Now(),
+			}
+			return out
+		})
+		if err != nil {
+			return err
+		}
+		for _, inst := range p.Installments {
+			id, err := insertRow(ctx, r.m, t, memInstallmentTable, func(int) int64 { return int64(planID) })
+			if err != nil {
+				return err
+			}
+			out.Installments = append(out.Installments, models.Installment{
+				InstallmentID: int64(id), PlanID: int64(planID), Seq: inst.Seq, Amount: inst.Amount, DueAt: inst.DueAt, Status: "Scheduled",
+			})
+		}
+		t.rows[rowKey{memPlanTable, planID}] = out
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out.Installments = slices.Clone(out.Installments)
+	return &out, nil
+}
+
+func (r memInstallments) GetByTransactionForUpdate(ctx context.Context, q Querier, txID int64) (*models.InstallmentPlan, error) {
+	var out *models.InstallmentPlan
+	err := r.m.write(q, func(t *memTx) error {
+		for _, p := range tableRows[models.InstallmentPlan](r.m, t, memPlanTable) {
+			if p.TransactionID != txID {
+				continue
+			}
+			p, _, err := updateRow(ctx, r.m, t, rowKey{memPlanTable, int(p.PlanID)}, func(*models.InstallmentPlan) {})
+			if err != nil {
+				return err
+			}
+			p.Installments = slices.Clone(p.Installments)
+			out = &p
+			return nil
+		}
+		return pgx.ErrNoRows
+	})
+	return out, err
+}
+
+func (r memInstallments) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.InstallmentPlan, error) {
+	out := make([]models.InstallmentPlan, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		plans := tableRows[models.InstallmentPlan](r.m, t, memPlanTable)
+		for i := len(plans) - 1; i >= 0; i-- {
+			if plans[i].UserID == userID {
+				p := plans[i]
+				p.Installments = slices.Clone(p.Installments)
+				out = append(out, p)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r memInstallments) ListDue(ctx context.Context, q Querier, limit int) ([]DueInstallment, error) {
+	type due struct {
+		DueInstallment
+		at time.Time
+	}
+	var all []due
+	now := r.m.Now()
+	err := r.m.read(q, func(t *memTx) error {
+		for _, p := range tableRows[models.InstallmentPlan](r.m, t, memPlanTable) {
+			tr, _ := r.m.transaction(t, int(p.TransactionID))
+			if p.Status != "Active" || tr.Status != "Paid" {
+				continue
+			}
+			for _, i := range p.Installments {
+				if i.Status == "Scheduled" && !i.DueAt.After(now) {
+					all = append(all, due{DueInstallment{InstallmentID: i.InstallmentID, TransactionID: p.TransactionID, UserID: p.UserID}, i.DueAt})
+				}
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(all, func(i, j int) bool { return all[i].at.Before(all[j].at) })
+	out := make([]DueInstallment, 0)
+	for _, d := range all {
+		if len(out) == limit {
+			break
+		}
+		out = append(out, d.DueInstallment)
+	}
+	return out, err
+}
+
+// updatePlan applies fn to a copy of the plan's installments; a missing
+// plan is not an error, like an UPDATE matching no row.
+func (r memInstallments) updatePlan(ctx context.Context, q Querier, planID int64, fn func(p *models.InstallmentPlan)) error {
+	return r.m.write(q, func(t *memTx) error {
+		_, _, err := updateRow(ctx, r.m, t, rowKey{memPlanTable, int(planID)}, func(p *models.InstallmentPlan) {
+			p.Installments = slices.Clone(p.Installments)
+			fn(p)
+		})
+		return err
+	})
+}
+
+func (r memInstallments) Rebase(ctx context.Context, q Querier, planID int64, cycle time.Duration) error {
+	now := r.m.Now()
+	return r.updatePlan(ctx, q, planID, func(p *models.InstallmentPlan) {
+		for i := range p.Installments {
+			p.Installments[i].DueAt = now.Add(time.Duration(p.Installments[i].Seq-1) * cycle)
+		}
+	})
+}
+
+func (r memInstallments) UpdateInstallmentStatus(ctx context.Context, q Querier, installmentID int64, status string) error {
+	var planID int64
+	err := r.m.read(q, func(t *memTx) error {
+		planID, _ = getRow[int64](r.m, t, rowKey{memInstallmentTable, int(installmentID)})
+		return nil
+	})
+	if err != nil || planID == 0 {
+		return err
+	}
+	now := r.m.Now()
+	return r.updatePlan(ctx, q, planID, func(p *models.InstallmentPlan) {
+		for i := range p.Installments {
+			if p.Installments[i].InstallmentID != installmentID {
+				continue
+			}
+			p.Installments[i].Status = status
+			if status == "Posted" {
+				p.Installments[i].PostedAt = &now
+			}
+		}
+	})
+}
+
+func (r memInstallments) UpdatePlan(ctx context.Context, q Querier, planID int64, postedCount int, status string) error {
+	return r.updatePlan(ctx, q, planID, func(p *models.InstallmentPlan) { p.PostedCount, p.Status = postedCount, status })
+}
+
+/* ---------------- DisputeRepo ---------------- */
+
+type memDisputes struct{ m *Memory }
+
+func (r memDisputes) Create(ctx context.Context, q Querier, d *models.Dispute, responseWindowSeconds float64) (*models.Dispute, error) {
+	var out models.Dispute
+	err := r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkTransaction(t, d.TransactionID); err != nil {
+			return err
+		}
+		if err := r.m.checkUser(t, d.UserID); err != nil {
+			return err
+		}
+		for _, o := range tableRows[models.Dispute](r.m, t, memDisputeTable) {
+			if o.TransactionID == d.TransactionID && (o.Status == "Open" || o.Status == "UnderReview") {
+				return uniqueViolation("idx_disputes_one_active")
+			}
+		}
+		now := r.m.Now()
+		_, err := insertRow(ctx, r.m, t, memDisputeTable, func(id int) models.Dispute {
+			out = models.Dispute{
+				DisputeID: int64(id), TransactionID: d.TransactionID, UserID: d.UserID, CardID: d.CardID,
+				ReasonCode: d.ReasonCode, Description: d.Description, Amount: d.Amount, PointChange: d.PointChange,
+				Status: "Open", RespondBy: now.Add(time.Duration(responseWindowSeconds * float64(time.Second))), CreatedAt: now,
+			}
+			return out
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r memDisputes) GetByID(ctx context.Context, q Querier, disputeID int64) (*models.Dispute, error) {
+	var out *models.Dispute
+	err := r.m.read(q, func(t *memTx) error {
+		d, ok := getRow[models.Dispute](r.m, t, rowKey{memDisputeTable, int(disputeID)})
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		out = &d
+		return nil
+	})
+	return out, err
+}
+
+func (r memDisputes) GetByIDForUpdate(ctx context.Context, q Querier, disputeID int64) (*models.Dispute, error) {
+	var out *models.Dispute
+	err := r.m.write(q, func(t *memTx) error {
+		d, found, err := updateRow(ctx, r.m, t, rowKey{memDisputeTable, int(disputeID)}, func(*models.Dispute) {})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		out = &d
+		return nil
+	})
+	return out, err
+}
+
+func (r memDisputes) List(ctx context.Context, q Querier, userID int, status string, limit int) ([]models.Dispute, error) {
+	out := make([]models.Dispute, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		rows := tableRows[models.Dispute](r.m, t, memDisputeTable)
+		for i := len(rows) - 1; i >= 0 && len(out) < limit; i-- {
+			if (userID == 0 || rows[i].UserID == userID) && (status == "" || rows[i].Status == status) {
+				out = append(out, rows[i])
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r memDisputes) ListExpired(ctx context.Context, q Querier, limit int) ([]models.Dispute, error) {
+	var expired []models.Dispute
+	now := r.m.Now()
+	err := r.m.read(q, func(t *memTx) error {
+		for _, d := range tableRows[models.Dispute](r.m, t, memDisputeTable) {
+			if d.Status == "Open" && !d.RespondBy.After(now) {
+				expired = append(expired, d)
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(expired, func(i, j int) bool { return expired[i].RespondBy.Before(expired[j].RespondBy) })
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return append(make([]models.Dispute, 0, len(expired)), expired...), err
+}
+
+func (r memDisputes) HasResolved(ctx context.Context, q Querier, txID int64) (bool, error) {
+	var resolved bool
+	err := r.m.read(q, func(t *memTx) error {
+		for _, d := range tableRows[models.Dispute](r.m, t, memDisputeTable) {
+			if d.TransactionID == txID && (d.Status == "Won" || d.Status == "Lost") {
+				resolved = true
+			}
+		}
+		return nil
+	})
+	return resolved, err
+}
+
+// update applies fn to the dispute; a missing dispute is not an error,
+// like an UPDATE matching no row.
+func (r memDisputes) update(ctx context.Context, q Querier, disputeID int64, fn func(d *models.Dispute)) error {
+	return r.m.write(q, func(t *memTx) error {
+		_, _, err := updateRow(ctx, r.m, t, rowKey{memDisputeTable, int(disputeID)}, fn)
+		return err
+	})
+}
+
+func (r memDisputes) SetMerchantResponse(ctx context.Context, q Querier, disputeID int64, response string) error {
+	return r.update(ctx, q, disputeID, func(d *models.Dispute) { d.MerchantResponse = response })
+}
+
+func (r memDisputes) UpdateStatus(ctx context.Context, q Querier, disputeID int64, status, resolutionReason string) error {
+	now := r.m.Now()
+	return r.update(ctx, q, disputeID, func(d *models.Dispute) {
+		d.Status = status
+		if status == "Won" || status == "Lost" {
+			d.ResolutionReason, d.ResolvedAt = resolutionReason, &now
+		}
+	})
+}
+
+func (r memDisputes) InsertEvent(ctx context.Context, q Querier, e models.DisputeEvent) error {
+	return r.m.write(q, func(t *memTx) error {
+		if _, ok := getRow[models.Dispute](r.m, t, rowKey{memDisputeTable, int(e.DisputeID)}); !ok {
+			return foreignKeyError("Key (dispute_id) is not present in table \"disputes\".")
+		}
+		_, err := insertRow(ctx, r.m, t, memDisputeEventTable, func(id int) models.DisputeEvent {
+			e.EventID, e.CreatedAt = int64(id), r.m.Now()
+			return e
+		})
+		return err
+	})
+}
+
+func (r memDisputes) ListEvents(ctx context.Context, q Querier, disputeID int64) ([]models.DisputeEvent, error) {
+	out := make([]models.DisputeEvent, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		for _, e := range tableRows[models.DisputeEvent](r.m, t, memDisputeEventTable) {
+			if e.DisputeID == disputeID {
+				out = append(out, e)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+/* ---------------- TransferRepo ---------------- */
+
+type memTransfers struct{ m *Memory }
+
+func (r memTransfers) Create(ctx context.Context, q Querier, fromUserID int, toUserID *int, householdID *int64, points int, note string) (*models.PointTransfer, error) {
+	var out models.PointTransfer
+	err := r.m.write(q, func(t *memTx) error {
+		if points <= 0 {
+			return checkViolation("pointtransfers_points_check")
+		}
+		if (toUserID == nil) == (householdID == nil) {
+			return checkViolation("pointtransfers_check")
+		}
+		if err := r.m.checkUser(t, fromUserID); err != nil {
+			return err
+		}
+		if toUserID != nil {
+			if err := r.m.checkUser(t, *toUserID); err != nil {
+				return err
+			}
+		}
+		if householdID != nil {
+			if err := r.m.checkHousehold(t, *householdID); err != nil {
+				return err
+			}
+		}
+		_, err := insertRow(ctx, r.m, t, memTransferTable, func(id int) models.PointTransfer {
+			out = models.PointTransfer{
+				TransferID: int64(id), FromUserID: fromUserID, ToUserID: toUserID, HouseholdID: householdID,
+				Points: points, Note: note, CreatedAt: r.m.Now(),
+			}
+			return out
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r memTransfers) InsertPoints(ctx context.Context, q Querier, userID int, transferID int64, change int, reason string) error {
+	return r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, userID); err != nil {
+			return err
+		}
+		if _, ok := getRow[models.PointTransfer](r.m, t, rowKey{memTransferTable, int(transferID)}); !ok {
+			return foreignKeyError(fmt.Sprintf("Key (transfer_id)=(%d) is not present in table \"pointtransfers\".", transferID))
+		}
+		t.points = append(t.points, PointsRow{UserID: userID, TransferID: transferID, ChangeAmount: change, Reason: reason, CreatedAt: r.m.Now()})
+		return nil
+	})
+}
+
+func (r memTransfers) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.PointTransfer, error) {
+	out := make([]models.PointTransfer, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		rows := tableRows[models.PointTransfer](r.m, t, memTransferTable)
+		for i := len(rows) - 1; i >= 0; i-- {
+			if rows[i].FromUserID == userID || (rows[i].ToUserID != nil && *rows[i].ToUserID == userID) {
+				out = append(out, rows[i])
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, err
+}
+
+func (r memTransfers) CountRecent(ctx context.Context, q Querier, fromUserID int, window string) (count, points int64, err error) {
+	d, err := parseInterval(window)
+	if err != nil {
+		return 0, 0, err
+	}
+	since := r.m.Now().Add(-d)
+	err = r.m.read(q, func(t *memTx) error {
+		for _, tr := range tableRows[models.PointTransfer](r.m, t, memTransferTable) {
+			if tr.FromUserID == fromUserID && tr.CreatedAt.After(since) {
+				count++
+				points += int64(tr.Points)
+			}
+		}
+		return nil
+	})
+	return count, points, err
+}
+
+func (r memTransfers) CountRecentSenders(ctx context.Context, q Querier, toUserID, exceptUserID int, window string) (int64, error) {
+	d, err := parseInterval(window)
+	if err != nil {
+		return 0, err
+	}
+	since := r.m.Now().Add(-d)
+	senders := map[int]bool{}
+	err = r.m.read(q, func(t *memTx) error {
+		for _, tr := range tableRows[models.PointTransfer](r.m, t, memTransferTable) {
+			if tr.ToUserID != nil && *tr.ToUserID == toUserID && tr.FromUserID != exceptUserID && tr.CreatedAt.After(since) {
+				senders[tr.FromUserID] = true
+			}
+		}
+		return nil
+	})
+	return int64(len(senders)), err
+}
+
+/* ---------------- RedemptionRepo ---------------- */
+
+type memRedemptions struct{ m *Memory }
+
+func (r memRedemptions) Create(ctx context.Context, q Querier, userID int, kind, item string, points int, creditAmount float64, householdID *int64) (*models.Redemption, error) {
+	var out models.Redemption
+	err := r.m.write(q, func(t *memTx) error {
+		if points <= 0 {
+			return checkViolation("redemptions_points_check")
+		}
+		if err := r.m.checkUser(t, userID); err != nil {
+			return err
+		}
+		if householdID != nil {
+			if err := r.m.checkHousehold(t, *householdID); err != nil {
+				return err
+			}
+		}
+		_, err := insertRow(ctx, r.m, t, memRedemptionTable, func(id int) models.Redemption {
+			out = models.Redemption{
+				RedemptionID: int64(id), UserID: userID, Kind: kind, Item: item, Points: points,
+				CreditAmount: creditAmount, HouseholdID: householdID, CreatedAt: r.m.Now(),
+			}
+			return out
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r memRedemptions) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Redemption, error) {
+	out := make([]models.Redemption, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		rows := tableRows[models.Redemption](r.m, t, memRedemptionTable)
+		for i := len(rows) - 1; i >= 0; i-- {
+			if rows[i].UserID == userID {
+				out = append(out, rows[i])
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, err
+}
+
+func (r memRedemptions) InsertPoints(ctx context.Context, q Querier, red *models.Redemption, reason string) error {
+	return r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, red.UserID); err != nil {
+			return err
+		}
+		if _, ok := getRow[models.Redemption](r.m, t, rowKey{memRedemptionTable, int(red.RedemptionID)}); !ok {
+			return foreignKeyError(fmt.Sprintf("Key (redemption_id)=(%d) is not present in table \"redemptions\".", red.RedemptionID))
+		}
+		t.points = append(t.points, PointsRow{UserID: red.UserID, RedemptionID: red.RedemptionID, ChangeAmount: -red.Points, Reason: reason, CreatedAt: r.m.Now()})
+		return nil
+	})
+}
+
+/* ---------------- HouseholdRepo ---------------- */
+
+type memHouseholds struct{ m *Memory }
+
+// checkHousehold enforces the foreign keys to Households.
+func (m *Memory) checkHousehold(t *memTx, householdID int64) error {
+	if _, ok := getRow[models.Household](m, t, rowKey{memHouseholdTable, int(householdID)}); !ok {
+		return foreignKeyError(fmt.Sprintf("Key (household_id)=(%d) is not present in table \"households\".", householdID))
+	}
+	return nil
+}
+
+func (r memHouseholds) Create(ctx context.Context, q Querier, name string, ownerUserID int) (*models.Household, error) {
+	var out models.Household
+	err := r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, ownerUserID); err != nil {
+			return err
+		}
+		_, err := insertRow(ctx, r.m, t, memHouseholdTable, func(id int) models.Household {
+			out = models.Household{HouseholdID: int64(id), Name: name, OwnerUserID: ownerUserID, CreatedAt: r.m.Now()}
+			return out
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r memHouseholds) GetByID(ctx context.Context, q Querier, householdID int64) (*models.Household, error) {
+	var out *models.Household
+	err := r.m.read(q, func(t *memTx) error {
+		h, ok := getRow[models.Household](r.m, t, rowKey{memHouseholdTable, int(householdID)})
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		out = &h
+		return nil
+	})
+	return out, err
+}
+
+func (r memHouseholds) GetByIDForUpdate(ctx context.Context, q Querier, householdID int64) (*models.Household, error) {
+	var out *models.Household
+	err := r.m.write(q, func(t *memTx) error {
+		h, found, err := updateRow(ctx, r.m, t, rowKey{memHouseholdTable, int(householdID)}, func(*models.Household) {})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		out = &h
+		return nil
+	})
+	return out, err
+}
+
+func (r memHouseholds) GetUserHouseholdID(ctx context.Context, q Querier, userID int) (int64, error) {
+	var id int64
+	err := r.m.read(q, func(t *memTx) error {
+		m, ok := getRow[memMember](r.m, t, rowKey{memMemberTable, userID})
+		if !ok || m.HouseholdID == 0 {
+			return pgx.ErrNoRows
+		}
+		id = m.HouseholdID
+		return nil
+	})
+	return id, err
+}
+
+func (r memHouseholds) AddMember(ctx context.Context, q Querier, householdID int64, userID int) error {
+	return r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, userID); err != nil {
+			return err
+		}
+		if err := r.m.checkHousehold(t, householdID); err != nil {
+			return err
+		}
+		k := rowKey{memMemberTable, userID}
+		if err := r.m.lock(ctx, t, k); err != nil {
+			return err
+		}
+		if m, ok := getRow[memMember](r.m, t, k); ok && m.HouseholdID != 0 {
+			return uniqueViolation("householdmembers_pkey")
+		}
+		t.rows[k] = memMember{UserID: userID, HouseholdID: householdID, JoinedAt: r.m.Now()}
+		return nil
+	})
+}
+
+func (r memHouseholds) RemoveMember(ctx context.Context, q Querier, householdID int64, userID int) (bool, error) {
+	removed := false
+	err := r.m.write(q, func(t *memTx) error {
+		k := rowKey{memMemberTable, userID}
+		if m, ok := getRow[memMember](r.m, t, k); !ok || m.HouseholdID != householdID {
+			return nil
+		}
+		_, _, err := updateRow(ctx, r.m, t, k, func(m *memMember) {
+			// Re-checked under the lock, like DELETE ... WHERE household_id
+			if m.HouseholdID == householdID {
+				m.HouseholdID, removed = 0, true
+			}
+		})
+		return err
+	})
+	return removed, err
+}
+
+func (r memHouseholds) ListMembers(ctx context.Context, q Querier, householdID int64) ([]models.HouseholdMember, error) {
+	out := make([]models.HouseholdMember, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		for _, m := range tableRows[memMember](r.m, t, memMemberTable) {
+			if m.HouseholdID == householdID {
+				u, _ := r.m.user(t, m.UserID)
+				out = append(out, models.HouseholdMember{UserID: m.UserID, Username: u.Username, JoinedAt: m.JoinedAt})
+			}
+		}
+		return nil
+	})
+	// tableRows is in user id order, the tie-break of joined_at
+	sort.SliceStable(out, func(i, j int) bool { return out[i].JoinedAt.Before(out[j].JoinedAt) })
+	return out, err
+}
+
+func (r memHouseholds) AddPoolPoints(ctx context.Context, q Querier, householdID int64, change int) (int, error) {
+	pool := 0
+	err := r.m.write(q, func(t *memTx) error {
+		k := rowKey{memHouseholdTable, int(householdID)}
+		h, found, err := updateRow(ctx, r.m, t, k, func(*models.Household) {})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		if h.PoolPoints+change < 0 {
+			return checkViolation("households_pool_points_check")
+		}
+		h.PoolPoints += change
+		t.rows[k] = h
+		pool = h.PoolPoints
+		return nil
+	})
+	return pool, err
+}
+
+/* ---------------- CreditLimitRepo ---------------- */
+
+type memCreditLimits struct{ m *Memory }
+
+func (r memCreditLimits) CreateRequest(ctx context.Context, q Querier, req *models.CreditLimitRequest) (*models.CreditLimitRequest, error) {
+	var out models.CreditLimitRequest
+	err := r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, req.UserID); err != nil {
+			return err
+		}
+		_, err := insertRow(ctx, r.m, t, memLimitRequestTable, func(id int) models.CreditLimitRequest {
+			out = models.CreditLimitRequest{
+				RequestID: int64(id), UserID: req.UserID, ChangeType: req.ChangeType, CurrentLimit: req.CurrentLimit,
+				RequestedLimit: req.RequestedLimit, BoostAmount: req.BoostAmount, BoostUntil: req.BoostUntil,
+				Status: "Pending", Reason: req.Reason, CreatedAt: r.m.Now(),
+			}
+			return out
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r memCreditLimits) GetRequestForUpdate(ctx context.Context, q Querier, requestID int64) (*models.CreditLimitRequest, error) {
+	var out *models.CreditLimitRequest
+	err := r.m.write(q, func(t *memTx) error {
+		req, found, err := updateRow(ctx, r.m, t, rowKey{memLimitRequestTable, int(requestID)}, func(*models.CreditLimitRequest) {})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return pgx.ErrNoRows
+		}
+		out = &req
+		return nil
+	})
+	return out, err
+}
+
+func (r memCreditLimits) ListRequests(ctx context.Context, q Querier, userID int, status string, limit int) ([]models.CreditLimitRequest, error) {
+	out := make([]models.CreditLimitRequest, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		rows := tableRows[models.CreditLimitRequest](r.m, t, memLimitRequestTable)
+		for i := len(rows) - 1; i >= 0 && len(out) < limit; i-- {
+			if (userID == 0 || rows[i].UserID == userID) && (status == "" || rows[i].Status == status) {
+				out = append(out, rows[i])
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// update applies fn to the request; a missing request is not an error,
+// like an UPDATE matching no row.
+func (r memCreditLimits) update(ctx context.Context, q Querier, requestID int64, fn func(req *models.CreditLimitRequest)) error {
+	return r.m.write(q, func(t *memTx) error {
+		_, _, err := updateRow(ctx, r.m, t, rowKey{memLimitRequestTable, int(requestID)}, fn)
+		return err
+	})
+}
+
+func (r memCreditLimits) DecideRequest(ctx context.Context, q Querier, requestID int64, status, reason string) error {
+	now := r.m.Now()
+	return r.update(ctx, q, requestID, func(req *models.CreditLimitRequest) {
+		req.Status, req.DecisionReason, req.DecidedAt = status, reason, &now
+	})
+}
+
+func (r memCreditLimits) MarkRequestApplied(ctx context.Context, q Querier, requestID int64) error {
+	now := r.m.Now()
+	return r.update(ctx, q, requestID, func(req *models.CreditLimitRequest) { req.Status, req.AppliedAt = "Applied", &now })
+}
+
+func (r memCreditLimits) InsertChange(ctx context.Context, q Querier, c models.CreditLimitChange) error {
+	return r.m.write(q, func(t *memTx) error {
+		if err := r.m.checkUser(t, c.UserID); err != nil {
+			return err
+		}
+		if c.RequestID != nil {
+			if _, ok := getRow[models.CreditLimitRequest](r.m, t, rowKey{memLimitRequestTable, int(*c.RequestID)}); !ok {
+				return foreignKeyError(fmt.Sprintf("Key (request_id)=(%d) is not present in table \"creditlimitrequests\".", *c.RequestID))
+			}
+		}
+		_, err := insertRow(ctx, r.m, t, memLimitChangeTable, func(id int) models.CreditLimitChange {
+			c.HistoryID, c.CreatedAt = int64(id), r.m.Now()
+			return c
+		})
+		return err
+	})
+}
+
+func (r memCreditLimits) ListHistory(ctx context.Context, q Querier, userID int, limit int) ([]models.CreditLimitChange, error) {
+	out := make([]models.CreditLimitChange, 0)
+	err := r.m.read(q, func(t *memTx) error {
+		rows := tableRows[models.CreditLimitChange](r.m, t, memLimitChangeTable)
+		for i := len(rows) - 1; i >= 0 && len(out) < limit; i-- {
+			if rows[i].UserID == userID {
+				out = append(out, rows[i])
+			}
+		}
+		return nil
+	})
+	return out, err
+}
@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const merchantColumns = `merchant_id, name, api_key_prefix, active, created_at`
+
+func scanMerchant(row pgx.Row) (*models.Merchant, error) {
+	var m models.Merchant
+	if err := row.Scan(&m.MerchantID, &m.Name, &m.APIKeyPrefix, &m.Active, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func CreateMerchant(ctx context.Context, q Querier, name, keyHash, keyPrefix string) (*models.Merchant, error) {
+	return scanMerchant(q.QueryRow(ctx, `
+		INSERT INTO Merchants (name, api_key_hash, api_key_prefix)
+		VALUES ($1,$2,$3)
+		RETURNING `+merchantColumns, name, keyHash, keyPrefix))
+}
+
+// GetMerchantByKeyHash looks up an active merchant by the SHA-256 of its API key.
+func GetMerchantByKeyHash(ctx context.Context, q Querier, keyHash string) (*models.Merchant, error) {
+	return scanMerchant(q.QueryRow(ctx, `SELECT `+merchantColumns+` FROM Merchants WHERE api_key_hash=$1 AND active`, keyHash))
+}
+
+func ListMerchants(ctx context.Context, q Querier) ([]models.Merchant, error) {
+	rows, err := q.Query(ctx, `SELECT `+merchantColumns+` FROM Merchants ORDER BY merchant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Merchant, 0)
+	for rows.Next() {
+		m, err := scanMerchant(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *m)
+	}
+	return out, rows.Err()
+}
+
+// UpdateMerchantKey replaces the API key; pgx.ErrNoRows if the merchant does not exist.
+func UpdateMerchantKey(ctx context.Context, q Querier, merchantID int64, keyHash, keyPrefix string) (*models.Merchant, error) {
+	return scanMerchant(q.QueryRow(ctx, `
+		UPDATE Merchants SET api_key_hash=$1, api_key_prefix=$2 WHERE merchant_id=$3
+		RETURNING `+merchantColumns, keyHash, keyPrefix, merchantID))
+}
+
+// SetMerchantActive enables or disables API access; pgx.ErrNoRows if the merchant does not exist.
+func SetMerchantActive(ctx context.Context, q Querier, merchantID int64, active bool) (*models.Merchant, error) {
+	return scanMerchant(q.QueryRow(ctx, `
+		UPDATE Merchants SET active=$1 WHERE merchant_id=$2
+		RETURNING `+merchantColumns, active, merchantID))
+}
+
+// ListMerchantTransactions returns the merchant's transactions (refunds
+// included), newest first. status and the [from, to) created_at range are
+// optional.
+func ListMerchantTransactions(ctx context.Context, q Querier, merchant, status string, from, to *time.Time, limit int) ([]models.Transaction, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+transactionColumns+` FROM Transactions
+		WHERE merchant=$1
+		  AND ($2 = '' OR status = $2)
+		  AND ($3::timestamp IS NULL OR created_at >= $3)
+		  AND ($4::timestamp IS NULL OR created_at < $4)
+		ORDER BY transaction_id DESC
+		LIMIT $5`, merchant, status, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Transaction, 0)
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+// ListSettlementLines returns the merchant's sales (captured that day and
+// not voided since), refunds (issued that day) and chargebacks (disputes the
+// cardholder won that day) for the calendar date of day, by the database
+// clock. The date goes in as YYYY-MM-DD text: a time.Time would be cast to
+// a date in the session TimeZone and could land on the day before.
+func ListSettlementLines(ctx context.Context, q Querier, merchant string, day time.Time) ([]models.SettlementLine, error) {
+	rows, err := q.Query(ctx, `
+		SELECT 'sale', t.transaction_id, NULL::bigint, t.user_id, t.amount, t.captured_at
+		FROM Transactions t
+		WHERE t.merchant=$1 AND t.source_transaction_id IS NULL AND t.status <> 'Voided'
+		  AND t.captured_at >= $2::date AND t.captured_at < $2::date + 1
+		UNION ALL
+		SELECT 'refund', r.transaction_id, r.source_transaction_id, r.user_id, -r.amount, r.created_at
+		FROM Transactions r
+		WHERE r.merchant=$1 AND r.source_transaction_id IS NOT NULL AND r.status='Refunded'
+		  AND r.created_at >= $2::date AND r.created_at < $2::date + 1
+		UNION ALL
+		SELECT 'chargeback', d.transaction_id, NULL::bigint, d.user_id, d.amount, d.resolved_at
+		FROM Disputes d
+		JOIN Transactions t ON t.transaction_id = d.transaction_id
+		WHERE t.merchant=$1 AND d.status='Won'
+		  AND d.resolved_at >= $2::date AND d.resolved_at < $2::date + 1
+		ORDER BY 6, 2`, merchant, day.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.SettlementLine, 0)
+	for rows.Next() {
+		var l models.SettlementLine
+		if err := rows.Scan(&l.Type, &l.TransactionID, &l.SourceTransactionID, &l.UserID, &l.Amount, &l.OccurredAt); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,11 @@
+package repo
+
+import "context"
+
+// pgPoints is the pgx PointsRepo.
+type pgPoints struct{}
+
+func (pgPoints) Insert(ctx context.Context, q Querier, userID int, txID int64, change int, reason string) error {
+	_, err := q.Exec(ctx, `INSERT INTO Points (user_id, transaction_id, change_amount, reason) VALUES ($1,$2,$3,$4)`, userID, txID, change, reason)
+	return err
+}
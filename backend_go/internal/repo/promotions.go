@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const promotionColumns = `promotion_id, name, kind, COALESCE(merchant, ''), COALESCE(category, ''), multiplier::float8, bonus_points, user_monthly_cap, stackable, starts_at, ends_at, active, created_at`
+
+func scanPromotion(row pgx.Row) (*models.Promotion, error) {
+	var p models.Promotion
+	if err := row.Scan(&p.PromotionID, &p.Name, &p.Kind, &p.Merchant, &p.Category, &p.Multiplier, &p.BonusPoints, &p.UserMonthlyCap, &p.Stackable, &p.StartsAt, &p.EndsAt, &p.Active, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// pgPromotions is the pgx PromotionRepo.
+type pgPromotions struct{}
+
+func (pgPromotions) Create(ctx context.Context, q Querier, p *models.Promotion) (*models.Promotion, error) {
+	return scanPromotion(q.QueryRow(ctx, `
+		INSERT INTO Promotions (name, kind, merchant, category, multiplier, bonus_points, user_monthly_cap, stackable, starts_at, ends_at)
+		VALUES ($1,$2,NULLIF($3, ''),NULLIF($4, ''),$5,$6,$7,$8,$9,$10)
+		RETURNING `+promotionColumns,
+		p.Name, p.Kind, p.Merchant, p.Category, p.Multiplier, p.BonusPoints, p.UserMonthlyCap, p.Stackable, p.StartsAt, p.EndsAt))
+}
+
+// List uses the database clock for the promotion windows.
+func (pgPromotions) List(ctx context.Context, q Querier, runningOnly bool) ([]models.Promotion, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+promotionColumns+` FROM Promotions
+		WHERE NOT $1::boolean OR (active AND (starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW()))
+		ORDER BY promotion_id`, runningOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Promotion, 0)
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+func (pgPromotions) Deactivate(ctx context.Context, q Querier, promotionID int64) (*models.Promotion, error) {
+	return scanPromotion(q.QueryRow(ctx, `UPDATE Promotions SET active=FALSE WHERE promotion_id=$1 RETURNING `+promotionColumns, promotionID))
+}
+
+func (pgPromotions) MonthlyPoints(ctx context.Context, q Querier, userID int) (map[int64]int, error) {
+	rows, err := q.Query(ctx, `
+		SELECT tp.promotion_id, SUM(tp.points)
+		FROM TransactionPromotions tp
+		JOIN Transactions t ON t.transaction_id = tp.transaction_id
+		WHERE t.user_id=$1 AND tp.created_at >= date_trunc('month', NOW())
+		  AND t.status NOT IN ('Voided','Expired','Refunded','ChargedBack')
+		GROUP BY tp.promotion_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var points int
+		if err := rows.Scan(&id, &points); err != nil {
+			return nil, err
+		}
+		out[id] = points
+	}
+	return out, rows.Err()
+}
+
+func (pgPromotions) Grant(ctx context.Context, q Querier, txID int64, a models.AppliedPromotion) error {
+	_, err := q.Exec(ctx, `INSERT INTO TransactionPromotions (transaction_id, promotion_id, points) VALUES ($1,$2,$3)`, txID, a.PromotionID, a.Points)
+	return err
+}
+
+func (pgPromotions) ListGranted(ctx context.Context, q Querier, txID int64) ([]models.AppliedPromotion, error) {
+	rows, err := q.Query(ctx, `
+		SELECT tp.promotion_id, p.name, tp.points
+		FROM TransactionPromotions tp
+		JOIN Promotions p ON p.promotion_id = tp.promotion_id
+		WHERE tp.transaction_id=$1
+		ORDER BY tp.promotion_id`, txID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.AppliedPromotion, 0)
+	for rows.Next() {
+		var a models.AppliedPromotion
+		if err := rows.Scan(&a.PromotionID, &a.Name, &a.Points); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (pgPromotions) UpdateGranted(ctx context.Context, q Querier, txID, promotionID int64, points int) error {
+	_, err := q.Exec(ctx, `UPDATE TransactionPromotions SET points=$1 WHERE transaction_id=$2 AND promotion_id=$3`, points, txID, promotionID)
+	return err
+}
+
+func (pgPromotions) InsertPoints(ctx context.Context, q Querier, userID int, txID int64, a models.AppliedPromotion) error {
+	reason := "Promo: " + a.Name
+	_, err := q.Exec(ctx, `INSERT INTO Points (user_id, transaction_id, change_amount, reason, promotion_id) VALUES ($1,$2,$3,$4,$5)`, userID, txID, a.Points, reason, a.PromotionID)
+	return err
+}
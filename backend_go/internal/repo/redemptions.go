@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const redemptionColumns = `redemption_id, user_id, kind, COALESCE(item, ''), points, credit_amount::float8, household_id, created_at`
+
+func scanRedemption(row pgx.Row) (*models.Redemption, error) {
+	var r models.Redemption
+	if err := row.Scan(&r.RedemptionID, &r.UserID, &r.Kind, &r.Item, &r.Points, &r.CreditAmount, &r.HouseholdID, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// pgRedemptions is the pgx RedemptionRepo.
+type pgRedemptions struct{}
+
+func (pgRedemptions) Create(ctx context.Context, q Querier, userID int, kind, item string, points int, creditAmount float64, householdID *int64) (*models.Redemption, error) {
+	return scanRedemption(q.QueryRow(ctx, `
+		INSERT INTO Redemptions (user_id, kind, item, points, credit_amount, household_id)
+		VALUES ($1,$2,NULLIF($3, ''),$4,$5,$6)
+		RETURNING `+redemptionColumns, userID, kind, item, points, creditAmount, householdID))
+}
+
+func (pgRedemptions) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Redemption, error) {
+	rows, err := q.Query(ctx, `SELECT `+redemptionColumns+` FROM Redemptions WHERE user_id=$1 ORDER BY created_at DESC, redemption_id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Redemption, 0)
+	for rows.Next() {
+		r, err := scanRedemption(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
+
+func (pgRedemptions) InsertPoints(ctx context.Context, q Querier, r *models.Redemption, reason string) error {
+	_, err := q.Exec(ctx, `INSERT INTO Points (user_id, change_amount, reason, redemption_id) VALUES ($1,$2,$3,$4)`, r.UserID, -r.Points, reason, r.RedemptionID)
+	return err
+}
@@ -0,0 +1,269 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UserRepo, TransactionRepo and PointsRepo store the core ledger. Every
+// repository method runs on q: the caller's transaction, or the pool for
+// reads outside one. Missing rows are pgx.ErrNoRows. ...ForUpdate reads and
+// all updates lock the row until q commits or rolls back; lock users before
+// their transactions.
+type UserRepo interface {
+	GetByID(ctx context.Context, q Querier, userID int) (*models.User, error)
+	GetByIDForUpdate(ctx context.Context, q Querier, userID int) (*models.User, error)
+	UpdateBalanceAndPoints(ctx context.Context, q Querier, userID int, balanceChange float64, pointChange int) (*models.User, error)
+	// AddPoints adds pointChange (negative to take back) to current_points.
+	AddPoints(ctx context.Context, q Querier, userID int, pointChange int) error
+	// UpdateStatus sets the account status; frozenUntil is only kept for Frozen.
+	UpdateStatus(ctx context.Context, q Querier, userID int, status, reason, changedBy string, frozenUntil *time.Time) error
+	UpdateCreditLimit(ctx context.Context, q Querier, userID int, creditLimit float64) error
+	// UpdateLimitBoost replaces the temporary limit boost.
+	UpdateLimitBoost(ctx context.Context, q Querier, userID int, boost float64, until *time.Time) error
+	// UpdateBalanceAndReserved moves amounts between balance and installment_reserved.
+	UpdateBalanceAndReserved(ctx context.Context, q Querier, userID int, balanceChange, reservedChange float64) error
+	// UpdateAuthHold adds change to the held (authorized, uncaptured) amount.
+	UpdateAuthHold(ctx context.Context, q Querier, userID int, change float64) error
+	// UpdateTier stores the tier and the spend it was computed from.
+	UpdateTier(ctx context.Context, q Querier, userID int, tier string, spend float64) error
+	// ListForTierReview returns users whose tier was last evaluated more
+	// than maxAgeSeconds ago (or never), least recently evaluated first.
+	ListForTierReview(ctx context.Context, q Querier, maxAgeSeconds float64, limit int) ([]int, error)
+}
+
+type TransactionRepo interface {
+	// Create inserts a transaction and returns its id; sourceID links a
+	// refund to its purchase.
+	Create(ctx context.Context, q Querier, userID int, amount float64, status string, pointChange int, merchant string, sourceID, cardID *int64) (int64, error)
+	GetByID(ctx context.Context, q Querier, txID int) (*models.Transaction, error)
+	GetByIDForUpdate(ctx context.Context, q Querier, txID int) (*models.Transaction, error)
+	// ListByUserID returns the user's transactions, newest first.
+	ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Transaction, error)
+	UpdateStatus(ctx context.Context, q Querier, txID int, status string) error
+	// SetFX records the original currency side of a foreign-currency transaction.
+	SetFX(ctx context.Context, q Querier, txID int64, originalAmount float64, currency string, rate, fee float64) error
+	// SetAuthorization starts the authorization window of a Pending
+	// transaction: authorized_amount = amount, expiring expirySeconds from
+	// now. It also records the points redeemed and the reward rate.
+	SetAuthorization(ctx context.Context, q Querier, txID int64, expirySeconds float64, pointsRedeemed int, rewardMultiplier float64) (*time.Time, error)
+	// IncrementAuthorization raises the authorized amount of a Pending transaction.
+	IncrementAuthorization(ctx context.Context, q Querier, txID int, increment float64, pointChange int) error
+	// Capture marks a Pending transaction Paid with the captured amount.
+	Capture(ctx context.Context, q Querier, txID int, amount float64, pointChange int) error
+	// ListExpiredAuthorizations returns Pending transactions past
+	// auth_expires_at, oldest first.
+	ListExpiredAuthorizations(ctx context.Context, q Querier, limit int) ([]int64, error)
+	// CountRecentDuplicates counts the user's transactions with the same
+	// merchant and amount created within window (an SQL interval, e.g. "5 minutes").
+	CountRecentDuplicates(ctx context.Context, q Querier, userID int, merchant string, amount float64, window string) (int64, error)
+	// CountRecentRefunds counts the user's refund rows created within window.
+	CountRecentRefunds(ctx context.Context, q Querier, userID int, window string) (int64, error)
+	// RollingSettledSpend is the user's settled purchase spend over the last
+	// 12 months. Refunded and charged-back purchases do not count.
+	RollingSettledSpend(ctx context.Context, q Querier, userID int) (float64, error)
+	// PendingRedeemedPoints is the points the user's Pending authorizations
+	// will redeem when captured.
+	PendingRedeemedPoints(ctx context.Context, q Querier, userID int) (int, error)
+	// HasPurchase reports whether the user has a purchase that was not voided or expired.
+	HasPurchase(ctx context.Context, q Querier, userID int) (bool, error)
+}
+
+type PointsRepo interface {
+	// Insert appends a Points ledger row for transaction txID.
+	Insert(ctx context.Context, q Querier, userID int, txID int64, change int, reason string) error
+}
+
+// CardRepo stores cards. A card's balance, reservation and hold move
+// together with the same columns of its user.
+type CardRepo interface {
+	// Create inserts c (user, type, token, last4, expiry, limit and controls).
+	Create(ctx context.Context, q Querier, c *models.Card) (*models.Card, error)
+	GetByIDForUpdate(ctx context.Context, q Querier, cardID int64) (*models.Card, error)
+	// GetPrimaryForUpdate returns the user's open (Active or Frozen) primary card.
+	GetPrimaryForUpdate(ctx context.Context, q Querier, userID int) (*models.Card, error)
+	ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Card, error)
+	UpdateStatus(ctx context.Context, q Querier, cardID int64, status string) error
+	UpdateBalance(ctx context.Context, q Querier, cardID int64, balanceChange float64) error
+	// AddSpend adds an authorized amount to the card's spent total.
+	AddSpend(ctx context.Context, q Querier, cardID int64, amount float64) error
+	UpdateBalanceAndReserved(ctx context.Context, q Querier, cardID int64, balanceChange, reservedChange float64) error
+	// UpdateAuthHold adds change to the held (authorized, uncaptured) amount.
+	UpdateAuthHold(ctx context.Context, q Querier, cardID int64, change float64) error
+}
+
+// StatusChangeRepo is the audit trail of account and card status changes.
+type StatusChangeRepo interface {
+	Insert(ctx context.Context, q Querier, c models.StatusChange) error
+	// ListByUserID returns the user's account and card changes, newest first.
+	ListByUserID(ctx context.Context, q Querier, userID int, limit int) ([]models.StatusChange, error)
+	// LastForCard returns the card's latest change; pgx.ErrNoRows if it has none.
+	LastForCard(ctx context.Context, q Querier, cardID int64) (*models.StatusChange, error)
+}
+
+// OutboxRepo writes account events; the webhook dispatcher and the SSE
+// replay read them back with the Outbox functions of webhooks.go.
+type OutboxRepo interface {
+	// Insert appends an event; txID is nil for events without a transaction.
+	Insert(ctx context.Context, q Querier, eventType string, userID int, txID *int64, payload []byte) (*models.EventEnvelope, error)
+}
+
+// PromotionRepo stores promotions and the bonuses granted to purchases.
+type PromotionRepo interface {
+	Create(ctx context.Context, q Querier, p *models.Promotion) (*models.Promotion, error)
+	// List returns all promotions, or only those running now (active and
+	// inside their window), oldest first.
+	List(ctx context.Context, q Querier, runningOnly bool) ([]models.Promotion, error)
+	// Deactivate returns pgx.ErrNoRows if the promotion does not exist.
+	Deactivate(ctx context.Context, q Querier, promotionID int64) (*models.Promotion, error)
+	// MonthlyPoints returns the user's bonus points per promotion granted
+	// this calendar month, not counting purchases that were voided,
+	// expired, refunded or charged back.
+	MonthlyPoints(ctx context.Context, q Querier, userID int) (map[int64]int, error)
+	// Grant records the bonus a purchase earns, awarded at capture.
+	Grant(ctx context.Context, q Querier, txID int64, a models.AppliedPromotion) error
+	// ListGranted returns the bonuses granted to a purchase.
+	ListGranted(ctx context.Context, q Querier, txID int64) ([]models.AppliedPromotion, error)
+	UpdateGranted(ctx context.Context, q Querier, txID, promotionID int64, points int) error
+	// InsertPoints writes the Points row of a promotion bonus.
+	InsertPoints(ctx context.Context, q Querier, userID int, txID int64, a models.AppliedPromotion) error
+}
+
+// InstallmentRepo stores installment plans with their schedules.
+type InstallmentRepo interface {
+	// Create inserts the plan and its schedule (p.Installments).
+	Create(ctx context.Context, q Querier, p *models.InstallmentPlan) (*models.InstallmentPlan, error)
+	// GetByTransactionForUpdate locks the plan of a parent transaction and
+	// its installments; pgx.ErrNoRows if the purchase has none.
+	GetByTransactionForUpdate(ctx context.Context, q Querier, txID int64) (*models.InstallmentPlan, error)
+	// ListByUserID returns the user's plans with their schedules, newest first.
+	ListByUserID(ctx context.Context, q Querier, userID int) ([]models.InstallmentPlan, error)
+	// ListDue returns Scheduled installments past due_at of settled
+	// (Paid) purchases with an Active plan, oldest due first.
+	ListDue(ctx context.Context, q Querier, limit int) ([]DueInstallment, error)
+	// Rebase sets the plan's due dates from now: installment n is due
+	// (n-1) cycles after settlement.
+	Rebase(ctx context.Context, q Querier, planID int64, cycle time.Duration) error
+	// UpdateInstallmentStatus also stamps posted_at when status is Posted.
+	UpdateInstallmentStatus(ctx context.Context, q Querier, installmentID int64, status string) error
+	UpdatePlan(ctx context.Context, q Querier, planID int64, postedCount int, status string) error
+}
+
+// DisputeRepo stores disputes and their state transitions.
+type DisputeRepo interface {
+	// Create inserts an Open dispute responding by responseWindowSeconds from now.
+	Create(ctx context.Context, q Querier, d *models.Dispute, responseWindowSeconds float64) (*models.Dispute, error)
+	GetByID(ctx context.Context, q Querier, disputeID int64) (*models.Dispute, error)
+	GetByIDForUpdate(ctx context.Context, q Querier, disputeID int64) (*models.Dispute, error)
+	// List filters by user (userID > 0) and/or status (non-empty), newest first.
+	List(ctx context.Context, q Querier, userID int, status string, limit int) ([]models.Dispute, error)
+	// ListExpired returns Open disputes whose merchant response window has passed.
+	ListExpired(ctx context.Context, q Querier, limit int) ([]models.Dispute, error)
+	// HasResolved reports whether the transaction already had a dispute
+	// resolved (Won or Lost).
+	HasResolved(ctx context.Context, q Querier, txID int64) (bool, error)
+	SetMerchantResponse(ctx context.Context, q Querier, disputeID int64, response string) error
+	// UpdateStatus sets the status; Won/Lost also record the resolution.
+	UpdateStatus(ctx context.Context, q Querier, disputeID int64, status, resolutionReason string) error
+	InsertEvent(ctx context.Context, q Querier, e models.DisputeEvent) error
+	// ListEvents returns the state transitions of a dispute, oldest first.
+	ListEvents(ctx context.Context, q Querier, disputeID int64) ([]models.DisputeEvent, error)
+}
+
+// TransferRepo stores points transfers between users and into household pools.
+type TransferRepo interface {
+	// Create records a transfer to toUserID or, with householdID, into that
+	// household's pool.
+	Create(ctx context.Context, q Querier, fromUserID int, toUserID *int, householdID *int64, points int, note string) (*models.PointTransfer, error)
+	// InsertPoints writes one side of a transfer to the Points ledger.
+	InsertPoints(ctx context.Context, q Querier, userID int, transferID int64, change int, reason string) error
+	// ListByUserID returns the transfers a user sent or received, newest first.
+	ListByUserID(ctx context.Context, q Querier, userID int) ([]models.PointTransfer, error)
+	// CountRecent counts the transfers fromUserID sent within window (an
+	// SQL interval) and their points.
+	CountRecent(ctx context.Context, q Querier, fromUserID int, window string) (count, points int64, err error)
+	// CountRecentSenders counts the distinct users other than exceptUserID
+	// who sent points to toUserID within window.
+	CountRecentSenders(ctx context.Context, q Querier, toUserID, exceptUserID int, window string) (int64, error)
+}
+
+// RedemptionRepo stores points-only redemptions.
+type RedemptionRepo interface {
+	Create(ctx context.Context, q Querier, userID int, kind, item string, points int, creditAmount float64, householdID *int64) (*models.Redemption, error)
+	// ListByUserID returns the user's redemptions, newest first.
+	ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Redemption, error)
+	// InsertPoints writes the Points row of a redemption from the user's points.
+	InsertPoints(ctx context.Context, q Querier, r *models.Redemption, reason string) error
+}
+
+// HouseholdRepo stores households, their members and points pools. Lock
+// users before their household.
+type HouseholdRepo interface {
+	Create(ctx context.Context, q Querier, name string, ownerUserID int) (*models.Household, error)
+	GetByID(ctx context.Context, q Querier, householdID int64) (*models.Household, error)
+	GetByIDForUpdate(ctx context.Context, q Querier, householdID int64) (*models.Household, error)
+	// GetUserHouseholdID returns pgx.ErrNoRows if the user is in no household.
+	GetUserHouseholdID(ctx context.Context, q Querier, userID int) (int64, error)
+	// AddMember fails with a unique violation if the user already belongs
+	// to a household.
+	AddMember(ctx context.Context, q Querier, householdID int64, userID int) error
+	// RemoveMember reports whether the user was a member.
+	RemoveMember(ctx context.Context, q Querier, householdID int64, userID int) (bool, error)
+	// ListMembers returns the members in joining order.
+	ListMembers(ctx context.Context, q Querier, householdID int64) ([]models.HouseholdMember, error)
+	// AddPoolPoints changes the pool by change and returns the new pool.
+	AddPoolPoints(ctx context.Context, q Querier, householdID int64, change int) (int, error)
+}
+
+// CreditLimitRepo stores credit limit change requests and the history of
+// applied changes.
+type CreditLimitRepo interface {
+	CreateRequest(ctx context.Context, q Querier, r *models.CreditLimitRequest) (*models.CreditLimitRequest, error)
+	GetRequestForUpdate(ctx context.Context, q Querier, requestID int64) (*models.CreditLimitRequest, error)
+	// ListRequests filters by user (userID > 0) and/or status (non-empty), newest first.
+	ListRequests(ctx context.Context, q Querier, userID int, status string, limit int) ([]models.CreditLimitRequest, error)
+	// DecideRequest sets Approved/Rejected and the decision reason.
+	DecideRequest(ctx context.Context, q Querier, requestID int64, status, reason string) error
+	MarkRequestApplied(ctx context.Context, q Querier, requestID int64) error
+	InsertChange(ctx context.Context, q Querier, c models.CreditLimitChange) error
+	// ListHistory returns the user's applied changes, newest first.
+	ListHistory(ctx context.Context, q Querier, userID int, limit int) ([]models.CreditLimitChange, error)
+}
+
+// Repos bundles the repositories of the account services. Merchants and
+// webhook delivery are not part of it: they stay Querier functions and
+// need Postgres.
+type Repos struct {
+	Users         UserRepo
+	Transactions  TransactionRepo
+	Points        PointsRepo
+	Cards         CardRepo
+	StatusChanges StatusChangeRepo
+	Outbox        OutboxRepo
+	Promotions    PromotionRepo
+	Installments  InstallmentRepo
+	Disputes      DisputeRepo
+	Transfers     TransferRepo
+	Redemptions   RedemptionRepo
+	Households    HouseholdRepo
+	CreditLimits  CreditLimitRepo
+}
+
+// Postgres returns the pgx implementation.
+func Postgres() Repos {
+	return Repos{
+		Users: pgUsers{}, Transactions: pgTransactions{}, Points: pgPoints{},
+		Cards: pgCards{}, StatusChanges: pgStatusChanges{}, Outbox: pgOutbox{},
+		Promotions: pgPromotions{}, Installments: pgInstallments{}, Disputes: pgDisputes{},
+		Transfers: pgTransfers{}, Redemptions: pgRedemptions{}, Households: pgHouseholds{}, CreditLimits: pgCreditLimits{},
+	}
+}
+
+// Beginner starts a transaction; *pgxpool.Pool and *Memory implement it.
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+
+	"backend_go/internal/models"
+)
+
+// pgStatusChanges is the pgx StatusChangeRepo.
+type pgStatusChanges struct{}
+
+func (pgStatusChanges) Insert(ctx context.Context, q Querier, c models.StatusChange) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO StatusChanges (user_id, card_id, old_status, new_status, reason, actor)
+		VALUES ($1,$2,$3,$4,$5,$6)`, c.UserID, c.CardID, c.OldStatus, c.NewStatus, c.Reason, c.Actor)
+	return err
+}
+
+func (pgStatusChanges) ListByUserID(ctx context.Context, q Querier, userID int, limit int) ([]models.StatusChange, error) {
+	rows, err := q.Query(ctx, `
+		SELECT change_id, user_id, card_id, old_status, new_status, reason, actor, created_at
+		FROM StatusChanges WHERE user_id=$1
+		ORDER BY change_id DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.StatusChange, 0)
+	for rows.Next() {
+		var c models.StatusChange
+		if err := rows.Scan(&c.ChangeID, &c.UserID, &c.CardID, &c.OldStatus, &c.NewStatus, &c.Reason, &c.Actor, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (pgStatusChanges) LastForCard(ctx context.Context, q Querier, cardID int64) (*models.StatusChange, error) {
+	var c models.StatusChange
+	err := q.QueryRow(ctx, `
+		SELECT change_id, user_id, card_id, old_status, new_status, reason, actor, created_at
+		FROM StatusChanges WHERE card_id=$1
+		ORDER BY change_id DESC LIMIT 1`, cardID).Scan(&c.ChangeID, &c.UserID, &c.CardID, &c.OldStatus, &c.NewStatus, &c.Reason, &c.Actor, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
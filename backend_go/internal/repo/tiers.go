@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+)
+
+func (pgUsers) ListForTierReview(ctx context.Context, q Querier, maxAgeSeconds float64, limit int) ([]int, error) {
+	rows, err := q.Query(ctx, `
+		SELECT user_id FROM Users
+		WHERE tier_evaluated_at IS NULL OR tier_evaluated_at <= NOW() - $1::float8 * INTERVAL '1 second'
+		ORDER BY tier_evaluated_at NULLS FIRST, user_id LIMIT $2`, maxAgeSeconds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
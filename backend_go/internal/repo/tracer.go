@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultRedactedColumns are never shown in the SQL trace.
+var DefaultRedactedColumns = []string{"pan_token", "card_number", "cvv", "password", "secret", "api_key", "api_key_hash", "api_secret"}
+
+// StepTracer is a pgx.QueryTracer installed on the pool. Statements executed
+// with a ctx that carries a utils.TxLogger (see utils.WithTxLogger) are
+// recorded as "sql" steps with the real SQL, bound args, rows affected and
+// duration. Other statements are ignored.
+type StepTracer struct {
+	Redactor *Redactor
+}
+
+type traceStartKey struct{}
+
+type traceStart struct {
+	sql  string
+	args []any
+	at   time.Time
+}
+
+func (t *StepTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if utils.TxLoggerFrom(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceStartKey{}, &traceStart{sql: data.SQL, args: data.Args, at: time.Now()})
+}
+
+func (t *StepTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	log := utils.TxLoggerFrom(ctx)
+	st, _ := ctx.Value(traceStartKey{}).(*traceStart)
+	if log == nil || st == nil {
+		return
+	}
+	q := utils.QueryTrace{
+		SQL:          compactSQL(st.sql),
+		Args:         t.Redactor.Render(st.sql, st.args),
+		RowsAffected: data.CommandTag.RowsAffected(),
+		DurationMs:   float64(time.Since(st.at).Microseconds()) / 1000,
+	}
+	if data.Err != nil {
+		q.Error = data.Err.Error()
+	}
+	log.Query(q)
+}
+
+// Redactor masks bound args whose placeholder is bound to a sensitive column.
+// The column is inferred from "col = $n" style predicates / assignments and
+// from "INSERT INTO t (cols) VALUES ($1, ...)" column lists.
+type Redactor struct {
+	columns map[string]bool
+}
+
+func NewRedactor(columns ...string) *Redactor {
+	r := &Redactor{columns: make(map[string]bool, len(columns))}
+	for _, c := range columns {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			r.columns[c] = true
+		}
+	}
+	return r
+}
+
+var (
+	reCompare = regexp.MustCompile(`(?i)(\w+)\s*(?:=|<>|!=|<=|>=|<|>)\s*\$(\d+)`)
+	reInsert  = regexp.MustCompile(`(?is)INSERT\s+INTO\s+\w+\s*\(([^)]*)\)\s*VALUES\s*\(([^)]*)\)`)
+	reSpace   = regexp.MustCompile(`\s+`)
+)
+
+// Render formats args as "$n=value", masking sensitive ones.
+func (r *Redactor) Render(sql string, args []any) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	sensitive := r.sensitivePlaceholders(sql)
+	out := make([]string, len(args))
+	for i, a := range args {
+		if sensitive[i+1] {
+			out[i] = fmt.Sprintf("$%d=[REDACTED]", i+1)
+			continue
+		}
+		out[i] = fmt.Sprintf("$%d=%s", i+1, formatArg(a))
+	}
+	return out
+}
+
+func (r *Redactor) sensitivePlaceholders(sql string) map[int]bool {
+	res := map[int]bool{}
+	if r == nil || len(r.columns) == 0 {
+		return res
+	}
+	for _, m := range reCompare.FindAllStringSubmatch(sql, -1) {
+		if r.columns[strings.ToLower(m[1])] {
+			n, _ := strconv.Atoi(m[2])
+			res[n] = true
+		}
+	}
+	for _, m := range reInsert.FindAllStringSubmatch(sql, -1) {
+		cols := strings.Split(m[1], ",")
+		vals := strings.Split(m[2], ",")
+		for i := 0; i < len(cols) && i < len(vals); i++ {
+			col := strings.ToLower(strings.TrimSpace(cols[i]))
+			val := strings.TrimSpace(vals[i])
+			if !r.columns[col] || !strings.HasPrefix(val, "$") {
+				continue
+			}
+			if n, err := strconv.Atoi(val[1:]); err == nil {
+				res[n] = true
+			}
+		}
+	}
+	return res
+}
+
+func formatArg(a any) string {
+	switch v := a.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + v + "'"
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	case time.Time:
+		return "'" + v.Format(time.RFC3339) + "'"
+	case *int64:
+		if v == nil {
+			return "NULL"
+		}
+		return strconv.FormatInt(*v, 10)
+	case *int:
+		if v == nil {
+			return "NULL"
+		}
+		return strconv.Itoa(*v)
+	case *string:
+		if v == nil {
+			return "NULL"
+		}
+		return "'" + *v + "'"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func compactSQL(sql string) string {
+	return strings.TrimSpace(reSpace.ReplaceAllString(sql, " "))
+}
@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRedactorRender(t *testing.T) {
+	cases := []struct {
+		name string
+		r    *Redactor
+		sql  string
+		args []any
+		want []string
+	}{
+		{
+			name: "insert column list",
+			r:    NewRedactor(DefaultRedactedColumns...),
+			sql:  `INSERT INTO Merchants (name, api_key_hash, api_key_prefix) VALUES ($1,$2,$3)`,
+			args: []any{"Steam", "3f2a9c", "mk_3f2a"},
+			want: []string{"$1='Steam'", "$2=[REDACTED]", "$3='mk_3f2a'"},
+		},
+		{
+			name: "predicate",
+			r:    NewRedactor(DefaultRedactedColumns...),
+			sql:  `SELECT merchant_id FROM Merchants WHERE api_key_hash=$1 AND active`,
+			args: []any{"3f2a9c"},
+			want: []string{"$1=[REDACTED]"},
+		},
+		{
+			name: "assignment",
+			r:    NewRedactor(DefaultRedactedColumns...),
+			sql:  `UPDATE Merchants SET api_key_hash=$1, api_key_prefix=$2 WHERE merchant_id=$3`,
+			args: []any{"3f2a9c", "mk_3f2a", int64(7)},
+			want: []string{"$1=[REDACTED]", "$2='mk_3f2a'", "$3=7"},
+		},
+		{
+			name: "multi-line insert",
+			r:    NewRedactor(DefaultRedactedColumns...),
+			sql:  "INSERT INTO Webhooks (url, secret, event_types)\n\t\tVALUES ($1, $2, $3)",
+			args: []any{"https://hooks.example.com", "whsec_1", []byte("{}")},
+			want: []string{"$1='https://hooks.example.com'", "$2=[REDACTED]", "$3=<2 bytes>"},
+		},
+		{
+			name: "configured column, any case",
+			r:    NewRedactor(" Username "),
+			sql:  `SELECT user_id FROM Users WHERE USERNAME = $1 AND user_id <> $2`,
+			args: []any{"alice", 1},
+			want: []string{"$1=[REDACTED]", "$2=1"},
+		},
+		{
+			name: "unlisted columns",
+			r:    NewRedactor(DefaultRedactedColumns...),
+			sql:  `SELECT user_id FROM Users WHERE username = $1 AND secret_question IS NULL`,
+			args: []any{"alice"},
+			want: []string{"$1='alice'"},
+		},
+		{
+			name: "no redactor",
+			sql:  `SELECT url FROM Webhooks WHERE secret = $1`,
+			args: []any{"whsec_1"},
+			want: []string{"$1='whsec_1'"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.r.Render(c.sql, c.args); !slices.Equal(got, c.want) {
+				t.Fatalf("Render = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestStepTracerRedactsSteps(t *testing.T) {
+	tracer := &StepTracer{Redactor: NewRedactor(DefaultRedactedColumns...)}
+	trace := func(ctx context.Context, sql string, args ...any) {
+		ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql, Args: args})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("INSERT 0 1")})
+	}
+	log := utils.NewTxLogger()
+	ctx := utils.WithTxLogger(context.Background(), log)
+
+	trace(ctx, "INSERT INTO Webhooks (url, secret, event_types)\n\t\tVALUES ($1, $2, $3)", "https://hooks.example.com", "whsec_1", "{}")
+	trace(ctx, `UPDATE Merchants SET api_key_hash=$1 WHERE merchant_id=$2`, "3f2a9c", int64(7))
+	// Statements outside a traced transaction are not recorded
+	trace(context.Background(), `SELECT 1 WHERE secret = $1`, "whsec_2")
+
+	if len(log.Steps) != 2 {
+		t.Fatalf("steps = %+v", log.Steps)
+	}
+	want := []struct {
+		sql  string
+		args []string
+	}{
+		{"INSERT INTO Webhooks (url, secret, event_types) VALUES ($1, $2, $3)", []string{"$1='https://hooks.example.com'", "$2=[REDACTED]", "$3='{}'"}},
+		{"UPDATE Merchants SET api_key_hash=$1 WHERE merchant_id=$2", []string{"$1=[REDACTED]", "$2=7"}},
+	}
+	for i, w := range want {
+		s := log.Steps[i]
+		if s.Type != utils.StepSQL || s.Query == nil || s.Query.SQL != w.sql || !slices.Equal(s.Query.Args, w.args) || s.Query.RowsAffected != 1 {
+			t.Fatalf("step %d = %+v (query %+v)", i, s, s.Query)
+		}
+		// The rendered line shows the same masked args
+		if !strings.Contains(s.Message, strings.Join(w.args, ", ")) {
+			t.Errorf("step %d message %q", i, s.Message)
+		}
+		for _, secret := range []string{"whsec_1", "3f2a9c"} {
+			if strings.Contains(s.Message, secret) {
+				t.Errorf("step %d leaks %q: %s", i, secret, s.Message)
+			}
+		}
+	}
+}
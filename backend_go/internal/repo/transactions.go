@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"backend_go/internal/models"
 
@@ -16,35 +17,12 @@ type Querier interface {
 	Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error)
 }
 
-func CreateTransactionReturningID(
-	ctx context.Context,
-	q Querier,
-	userID int,
-	amount float64,
-	status string,
-	pointChange int,
-	merchant string,
-	sourceID *int64,
-) (int64, error) {
-	var newID int64
-	err := q.QueryRow(ctx, `
-		INSERT INTO Transactions (user_id, amount, status, point_change, merchant, source_transaction_id)
-		VALUES ($1,$2,$3,$4,$5,$6)
-		RETURNING transaction_id
-	`, userID, amount, status, pointChange, merchant, sourceID).Scan(&newID)
-	return newID, err
-}
-
-func CreateTransaction(ctx context.Context, q Querier, txID, userID int, amount float64, status string, pointChange int, merchant string, sourceID *int) error {
-	_, err := q.Exec(ctx, `INSERT INTO Transactions (transaction_id, user_id, amount, status, point_change, merchant, source_transaction_id) VALUES ($1,$2,$3,$4,$5,$6,$7)`, txID, userID, amount, status, pointChange, merchant, sourceID)
-	return err
-}
+const transactionColumns = `transaction_id, user_id, amount, status, point_change, merchant, source_transaction_id, card_id, created_at, original_amount, original_currency, fx_rate, fx_fee, COALESCE(authorized_amount, 0), auth_expires_at, captured_at, points_redeemed, COALESCE(reward_multiplier, 0)::float8`
 
-func GetTransactionByIDForUpdate(ctx context.Context, q Querier, txID int) (*models.Transaction, error) {
-	row := q.QueryRow(ctx, `SELECT transaction_id, user_id, amount, status, point_change, merchant, source_transaction_id, created_at FROM Transactions WHERE transaction_id=$1 FOR UPDATE`, txID)
+func scanTransaction(row pgx.Row) (*models.Transaction, error) {
 	var t models.Transaction
 	var source sql.NullInt64
-	if err := row.Scan(&t.TransactionID, &t.UserID, &t.Amount, &t.Status, &t.PointChange, &t.Merchant, &source, &t.CreatedAt); err != nil {
+	if err := row.Scan(&t.TransactionID, &t.UserID, &t.Amount, &t.Status, &t.PointChange, &t.Merchant, &source, &t.CardID, &t.CreatedAt, &t.OriginalAmount, &t.OriginalCurrency, &t.FXRate, &t.FXFee, &t.AuthorizedAmount, &t.AuthExpiresAt, &t.CapturedAt, &t.PointsRedeemed, &t.RewardMultiplier); err != nil {
 		return nil, err
 	}
 	if source.Valid {
@@ -54,38 +32,135 @@ func GetTransactionByIDForUpdate(ctx context.Context, q Querier, txID int) (*mod
 	return &t, nil
 }
 
-func GetTransactionsByUserID(ctx context.Context, q Querier, userID int) ([]models.Transaction, error) {
-	rows, err := q.Query(ctx, `SELECT transaction_id, user_id, amount, status, point_change, merchant, source_transaction_id, created_at FROM Transactions WHERE user_id=$1 ORDER BY created_at DESC`, userID)
+// pgTransactions is the pgx TransactionRepo.
+type pgTransactions struct{}
+
+func (pgTransactions) Create(ctx context.Context, q Querier, userID int, amount float64, status string, pointChange int, merchant string, sourceID, cardID *int64) (int64, error) {
+	var newID int64
+	err := q.QueryRow(ctx, `
+		INSERT INTO Transactions (user_id, amount, status, point_change, merchant, source_transaction_id, card_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		RETURNING transaction_id
+	`, userID, amount, status, pointChange, merchant, sourceID, cardID).Scan(&newID)
+	return newID, err
+}
+
+func (pgTransactions) GetByID(ctx context.Context, q Querier, txID int) (*models.Transaction, error) {
+	return scanTransaction(q.QueryRow(ctx, `SELECT `+transactionColumns+` FROM Transactions WHERE transaction_id=$1`, txID))
+}
+
+func (pgTransactions) GetByIDForUpdate(ctx context.Context, q Querier, txID int) (*models.Transaction, error) {
+	return scanTransaction(q.QueryRow(ctx, `SELECT `+transactionColumns+` FROM Transactions WHERE transaction_id=$1 FOR UPDATE`, txID))
+}
+
+func (pgTransactions) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.Transaction, error) {
+	rows, err := q.Query(ctx, `SELECT `+transactionColumns+` FROM Transactions WHERE user_id=$1 ORDER BY created_at DESC`, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	out := make([]models.Transaction, 0)
 	for rows.Next() {
-		var t models.Transaction
-		var source sql.NullInt64
-		if err := rows.Scan(&t.TransactionID, &t.UserID, &t.Amount, &t.Status, &t.PointChange, &t.Merchant, &source, &t.CreatedAt); err != nil {
+		t, err := scanTransaction(rows)
+		if err != nil {
 			return nil, err
 		}
-		if source.Valid {
-			v := int(source.Int64)
-			t.SourceTransactionID = &v
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+func (pgTransactions) SetFX(ctx context.Context, q Querier, txID int64, originalAmount float64, currency string, rate, fee float64) error {
+	_, err := q.Exec(ctx, `UPDATE Transactions SET original_amount=$1, original_currency=$2, fx_rate=$3, fx_fee=$4 WHERE transaction_id=$5`, originalAmount, currency, rate, fee, txID)
+	return err
+}
+
+func (pgTransactions) SetAuthorization(ctx context.Context, q Querier, txID int64, expirySeconds float64, pointsRedeemed int, rewardMultiplier float64) (*time.Time, error) {
+	var expiresAt time.Time
+	err := q.QueryRow(ctx, `
+		UPDATE Transactions SET authorized_amount = amount, auth_expires_at = NOW() + $1::float8 * INTERVAL '1 second', points_redeemed=$2, reward_multiplier=$3
+		WHERE transaction_id=$4 RETURNING auth_expires_at`, expirySeconds, pointsRedeemed, rewardMultiplier, txID).Scan(&expiresAt)
+	return &expiresAt, err
+}
+
+func (pgTransactions) IncrementAuthorization(ctx context.Context, q Querier, txID int, increment float64, pointChange int) error {
+	_, err := q.Exec(ctx, `
+		UPDATE Transactions SET amount = amount + $1, authorized_amount = COALESCE(authorized_amount, amount) + $1, point_change=$2
+		WHERE transaction_id=$3`, increment, pointChange, txID)
+	return err
+}
+
+func (pgTransactions) Capture(ctx context.Context, q Querier, txID int, amount float64, pointChange int) error {
+	_, err := q.Exec(ctx, `
+		UPDATE Transactions SET amount=$1, point_change=$2, status='Paid', captured_at=NOW(), authorized_amount = COALESCE(authorized_amount, amount)
+		WHERE transaction_id=$3`, amount, pointChange, txID)
+	return err
+}
+
+// ListExpiredAuthorizations uses the database clock, as auth_expires_at was set with it.
+func (pgTransactions) ListExpiredAuthorizations(ctx context.Context, q Querier, limit int) ([]int64, error) {
+	rows, err := q.Query(ctx, `
+		SELECT transaction_id FROM Transactions
+		WHERE status = 'Pending' AND auth_expires_at <= NOW()
+		ORDER BY auth_expires_at LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
-		out = append(out, t)
+		out = append(out, id)
 	}
 	return out, rows.Err()
 }
 
-func UpdateTransactionStatus(ctx context.Context, q Querier, txID int, newStatus string) error {
+func (pgTransactions) UpdateStatus(ctx context.Context, q Querier, txID int, newStatus string) error {
 	_, err := q.Exec(ctx, `UPDATE Transactions SET status=$1 WHERE transaction_id=$2`, newStatus, txID)
 	return err
 }
 
-func GetMaxTransactionID(ctx context.Context, q Querier) (int, error) {
-	row := q.QueryRow(ctx, `SELECT COALESCE(MAX(transaction_id), 0) FROM Transactions`)
-	var max int
-	if err := row.Scan(&max); err != nil {
-		return 0, err
-	}
-	return max, nil
+func (pgTransactions) CountRecentDuplicates(ctx context.Context, q Querier, userID int, merchant string, amount float64, window string) (int64, error) {
+	var n int64
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*) FROM Transactions
+		WHERE user_id = $1 AND merchant = $2 AND amount = $3 AND created_at > NOW() - $4::interval`, userID, merchant, amount, window).Scan(&n)
+	return n, err
+}
+
+func (pgTransactions) CountRecentRefunds(ctx context.Context, q Querier, userID int, window string) (int64, error) {
+	var n int64
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*) FROM Transactions
+		WHERE user_id = $1 AND status = 'Refunded' AND source_transaction_id IS NOT NULL AND created_at > NOW() - $2::interval`, userID, window).Scan(&n)
+	return n, err
+}
+
+// RollingSettledSpend uses the database clock: purchases captured in the
+// last 12 months that are still Paid or Disputed. Rows paid before
+// authorizations existed (seeded or legacy) have no captured_at and count
+// by created_at.
+func (pgTransactions) RollingSettledSpend(ctx context.Context, q Querier, userID int) (float64, error) {
+	var spend float64
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0)::float8 FROM Transactions
+		WHERE user_id=$1 AND source_transaction_id IS NULL AND status IN ('Paid','Disputed')
+		  AND COALESCE(captured_at, created_at) >= NOW() - INTERVAL '12 months'`, userID).Scan(&spend)
+	return spend, err
+}
+
+func (pgTransactions) PendingRedeemedPoints(ctx context.Context, q Querier, userID int) (int, error) {
+	var points int
+	err := q.QueryRow(ctx, `SELECT COALESCE(SUM(points_redeemed), 0) FROM Transactions WHERE user_id=$1 AND status='Pending'`, userID).Scan(&points)
+	return points, err
+}
+
+func (pgTransactions) HasPurchase(ctx context.Context, q Querier, userID int) (bool, error) {
+	var ok bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM Transactions WHERE user_id=$1 AND source_transaction_id IS NULL AND status NOT IN ('Voided','Expired'))`, userID).Scan(&ok)
+	return ok, err
 }
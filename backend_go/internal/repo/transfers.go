@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"context"
+
+	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const pointTransferColumns = `transfer_id, from_user_id, to_user_id, household_id, points, COALESCE(note, ''), created_at`
+
+func scanPointTransfer(row pgx.Row) (*models.PointTransfer, error) {
+	var t models.PointTransfer
+	if err := row.Scan(&t.TransferID, &t.FromUserID, &t.ToUserID, &t.HouseholdID, &t.Points, &t.Note, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// pgTransfers is the pgx TransferRepo.
+type pgTransfers struct{}
+
+func (pgTransfers) Create(ctx context.Context, q Querier, fromUserID int, toUserID *int, householdID *int64, points int, note string) (*models.PointTransfer, error) {
+	return scanPointTransfer(q.QueryRow(ctx, `
+		INSERT INTO PointTransfers (from_user_id, to_user_id, household_id, points, note)
+		VALUES ($1,$2,$3,$4,NULLIF($5, ''))
+		RETURNING `+pointTransferColumns, fromUserID, toUserID, householdID, points, note))
+}
+
+func (pgTransfers) InsertPoints(ctx context.Context, q Querier, userID int, transferID int64, change int, reason string) error {
+	_, err := q.Exec(ctx, `INSERT INTO Points (user_id, change_amount, reason, transfer_id) VALUES ($1,$2,$3,$4)`, userID, change, reason, transferID)
+	return err
+}
+
+func (pgTransfers) ListByUserID(ctx context.Context, q Querier, userID int) ([]models.PointTransfer, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+pointTransferColumns+` FROM PointTransfers
+		WHERE from_user_id=$1 OR to_user_id=$1
+		ORDER BY created_at DESC, transfer_id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.PointTransfer, 0)
+	for rows.Next() {
+		t, err := scanPointTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+func (pgTransfers) CountRecent(ctx context.Context, q Querier, fromUserID int, window string) (count, points int64, err error) {
+	err = q.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(points), 0) FROM PointTransfers
+		WHERE from_user_id = $1 AND created_at > NOW() - $2::interval`, fromUserID, window).Scan(&count, &points)
+	return count, points, err
+}
+
+func (pgTransfers) CountRecentSenders(ctx context.Context, q Querier, toUserID, exceptUserID int, window string) (int64, error) {
+	var n int64
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT from_user_id) FROM PointTransfers
+		WHERE to_user_id = $1 AND from_user_id <> $2 AND created_at > NOW() - $3::interval`, toUserID, exceptUserID, window).Scan(&n)
+	return n, err
+}
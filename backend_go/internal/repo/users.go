@@ -2,24 +2,71 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"backend_go/internal/models"
+
+	"github.com/jackc/pgx/v5"
 )
 
-func GetUserByID(ctx context.Context, q Querier, userID int) (*models.User, error) {
-	row := q.QueryRow(ctx, `SELECT user_id, username, balance, current_points, credit_limit FROM Users WHERE user_id=$1`, userID)
+const userColumns = `user_id, username, balance, current_points, credit_limit, status, COALESCE(status_reason, ''), COALESCE(status_changed_by, ''), frozen_until, temp_limit_boost, temp_limit_boost_until, installment_reserved, auth_hold, tier, tier_spend, tier_evaluated_at`
+
+func scanUser(row pgx.Row) (*models.User, error) {
 	var u models.User
-	if err := row.Scan(&u.UserID, &u.Username, &u.Balance, &u.CurrentPoints, &u.CreditLimit); err != nil {
+	if err := row.Scan(&u.UserID, &u.Username, &u.Balance, &u.CurrentPoints, &u.CreditLimit, &u.Status, &u.StatusReason, &u.StatusChangedBy, &u.FrozenUntil, &u.TempLimitBoost, &u.TempLimitBoostUntil, &u.InstallmentReserved, &u.AuthHold, &u.Tier, &u.TierSpend, &u.TierEvaluatedAt); err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
-func UpdateUserBalanceAndPoints(ctx context.Context, q Querier, userID int, balanceChange float64, pointChange int) (*models.User, error) {
-	row := q.QueryRow(ctx, `UPDATE Users SET balance = balance + $1, current_points = current_points + $2 WHERE user_id = $3 RETURNING user_id, username, balance, current_points, credit_limit`, balanceChange, pointChange, userID)
-	var u models.User
-	if err := row.Scan(&u.UserID, &u.Username, &u.Balance, &u.CurrentPoints, &u.CreditLimit); err != nil {
-		return nil, err
-	}
-	return &u, nil
+// pgUsers is the pgx UserRepo.
+type pgUsers struct{}
+
+func (pgUsers) GetByID(ctx context.Context, q Querier, userID int) (*models.User, error) {
+	return scanUser(q.QueryRow(ctx, `SELECT `+userColumns+` FROM Users WHERE user_id=$1`, userID))
+}
+
+func (pgUsers) UpdateBalanceAndPoints(ctx context.Context, q Querier, userID int, balanceChange float64, pointChange int) (*models.User, error) {
+	return scanUser(q.QueryRow(ctx, `UPDATE Users SET balance = balance + $1, current_points = current_points + $2 WHERE user_id = $3 RETURNING `+userColumns, balanceChange, pointChange, userID))
+}
+
+func (pgUsers) AddPoints(ctx context.Context, q Querier, userID int, pointChange int) error {
+	_, err := q.Exec(ctx, `UPDATE Users SET current_points = current_points + $1 WHERE user_id=$2`, pointChange, userID)
+	return err
+}
+
+func (pgUsers) GetByIDForUpdate(ctx context.Context, q Querier, userID int) (*models.User, error) {
+	return scanUser(q.QueryRow(ctx, `SELECT `+userColumns+` FROM Users WHERE user_id=$1 FOR UPDATE`, userID))
+}
+
+func (pgUsers) UpdateStatus(ctx context.Context, q Querier, userID int, status, reason, changedBy string, frozenUntil *time.Time) error {
+	_, err := q.Exec(ctx, `
+		UPDATE Users SET status=$1, status_reason=$2, status_changed_by=$3, frozen_until=$4
+		WHERE user_id=$5`, status, reason, changedBy, frozenUntil, userID)
+	return err
+}
+
+func (pgUsers) UpdateCreditLimit(ctx context.Context, q Querier, userID int, creditLimit float64) error {
+	_, err := q.Exec(ctx, `UPDATE Users SET credit_limit=$1 WHERE user_id=$2`, creditLimit, userID)
+	return err
+}
+
+func (pgUsers) UpdateLimitBoost(ctx context.Context, q Querier, userID int, boost float64, until *time.Time) error {
+	_, err := q.Exec(ctx, `UPDATE Users SET temp_limit_boost=$1, temp_limit_boost_until=$2 WHERE user_id=$3`, boost, until, userID)
+	return err
+}
+
+func (pgUsers) UpdateBalanceAndReserved(ctx context.Context, q Querier, userID int, balanceChange, reservedChange float64) error {
+	_, err := q.Exec(ctx, `UPDATE Users SET balance = balance + $1, installment_reserved = installment_reserved + $2 WHERE user_id=$3`, balanceChange, reservedChange, userID)
+	return err
+}
+
+func (pgUsers) UpdateAuthHold(ctx context.Context, q Querier, userID int, change float64) error {
+	_, err := q.Exec(ctx, `UPDATE Users SET auth_hold = auth_hold + $1 WHERE user_id=$2`, change, userID)
+	return err
+}
+
+func (pgUsers) UpdateTier(ctx context.Context, q Querier, userID int, tier string, spend float64) error {
+	_, err := q.Exec(ctx, `UPDATE Users SET tier=$1, tier_spend=$2, tier_evaluated_at=NOW() WHERE user_id=$3`, tier, spend, userID)
+	return err
 }
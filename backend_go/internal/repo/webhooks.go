@@ -0,0 +1,229 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"backend_go/internal/models"
+)
+
+// ---- Outbox ----
+
+// pgOutbox is the pgx OutboxRepo.
+type pgOutbox struct{}
+
+func (pgOutbox) Insert(ctx context.Context, q Querier, eventType string, userID int, txID *int64, payload []byte) (*models.EventEnvelope, error) {
+	e := models.EventEnvelope{Type: eventType, UserID: userID, Data: payload}
+	err := q.QueryRow(ctx, `
+		INSERT INTO Outbox (event_type, user_id, transaction_id, payload)
+		VALUES ($1,$2,$3,$4)
+		RETURNING event_id, created_at
+	`, eventType, userID, txID, payload).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ListOutboxEventsForUser returns the user's events with event_id > afterID, oldest first.
+func ListOutboxEventsForUser(ctx context.Context, q Querier, userID int, afterID int64, limit int) ([]models.EventEnvelope, error) {
+	rows, err := q.Query(ctx, `
+		SELECT event_id, event_type, user_id, created_at, payload
+		FROM Outbox
+		WHERE user_id=$1 AND event_id > $2
+		ORDER BY event_id
+		LIMIT $3
+	`, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.EventEnvelope, 0)
+	for rows.Next() {
+		var e models.EventEnvelope
+		if err := rows.Scan(&e.ID, &e.Type, &e.UserID, &e.CreatedAt, &e.Data); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// FanOutOutbox creates one delivery per (undispatched event, subscribed active
+// webhook) and marks the events dispatched. SKIP LOCKED lets several
+// instances run it concurrently. Returns the number of events processed.
+func FanOutOutbox(ctx context.Context, q Querier, limit int) (int64, error) {
+	tag, err := q.Exec(ctx, `
+		WITH batch AS (
+			SELECT event_id, event_type FROM Outbox
+			WHERE dispatched_at IS NULL
+			ORDER BY event_id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), fan AS (
+			INSERT INTO WebhookDeliveries (event_id, webhook_id)
+			SELECT b.event_id, w.webhook_id
+			FROM batch b
+			JOIN Webhooks w ON w.active AND (cardinality(w.event_types) = 0 OR b.event_type = ANY(w.event_types))
+			ON CONFLICT (event_id, webhook_id) DO NOTHING
+		)
+		UPDATE Outbox SET dispatched_at = NOW()
+		WHERE event_id IN (SELECT event_id FROM batch)
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ---- Webhooks ----
+
+func CreateWebhook(ctx context.Context, q Querier, url, secret string, eventTypes []string) (*models.Webhook, error) {
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	row := q.QueryRow(ctx, `
+		INSERT INTO Webhooks (url, secret, event_types)
+		VALUES ($1,$2,$3)
+		RETURNING webhook_id, url, secret, event_types, active, created_at
+	`, url, secret, eventTypes)
+	var w models.Webhook
+	if err := row.Scan(&w.WebhookID, &w.URL, &w.Secret, &w.EventTypes, &w.Active, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// ListWebhooks returns all webhooks without their secrets.
+func ListWebhooks(ctx context.Context, q Querier) ([]models.Webhook, error) {
+	rows, err := q.Query(ctx, `SELECT webhook_id, url, event_types, active, created_at FROM Webhooks ORDER BY webhook_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.Webhook, 0)
+	for rows.Next() {
+		var w models.Webhook
+		if err := rows.Scan(&w.WebhookID, &w.URL, &w.EventTypes, &w.Active, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func DeactivateWebhook(ctx context.Context, q Querier, webhookID int64) (bool, error) {
+	tag, err := q.Exec(ctx, `UPDATE Webhooks SET active = FALSE WHERE webhook_id=$1`, webhookID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ---- Deliveries ----
+
+// DueDelivery is a claimed delivery with everything needed to send it.
+type DueDelivery struct {
+	DeliveryID int64
+	EventID    int64
+	EventType  string
+	UserID     int
+	Payload    []byte
+	CreatedAt  time.Time
+	Attempts   int
+	URL        string
+	Secret     string
+}
+
+// ClaimDueDeliveries leases up to limit due deliveries by pushing their
+// next_attempt_at forward by lease and counting the attempt, so other
+// dispatcher instances skip them while this one is sending.
+func ClaimDueDeliveries(ctx context.Context, q Querier, limit int, lease time.Duration) ([]DueDelivery, error) {
+	rows, err := q.Query(ctx, `
+		UPDATE WebhookDeliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2), attempts = d.attempts + 1, updated_at = NOW()
+		FROM Outbox o, Webhooks w
+		WHERE d.delivery_id IN (
+			SELECT delivery_id FROM WebhookDeliveries
+			WHERE status = 'Pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		AND o.event_id = d.event_id AND w.webhook_id = d.webhook_id
+		RETURNING d.delivery_id, d.event_id, o.event_type, o.user_id, o.payload, o.created_at, d.attempts, w.url, w.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]DueDelivery, 0)
+	for rows.Next() {
+		var d DueDelivery
+		if err := rows.Scan(&d.DeliveryID, &d.EventID, &d.EventType, &d.UserID, &d.Payload, &d.CreatedAt, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func MarkDeliveryDelivered(ctx context.Context, q Querier, deliveryID int64, statusCode int) error {
+	_, err := q.Exec(ctx, `
+		UPDATE WebhookDeliveries
+		SET status='Delivered', last_status_code=$2, last_error=NULL, updated_at=NOW()
+		WHERE delivery_id=$1
+	`, deliveryID, statusCode)
+	return err
+}
+
+// MarkDeliveryFailed schedules a retry at nextAttempt, or moves the delivery
+// to the dead-letter list when dead is true.
+func MarkDeliveryFailed(ctx context.Context, q Querier, deliveryID int64, statusCode *int, errMsg string, nextAttempt time.Time, dead bool) error {
+	status := "Pending"
+	if dead {
+		status = "Dead"
+	}
+	_, err := q.Exec(ctx, `
+		UPDATE WebhookDeliveries
+		SET status=$2, last_status_code=$3, last_error=$4, next_attempt_at=$5, updated_at=NOW()
+		WHERE delivery_id=$1
+	`, deliveryID, status, statusCode, errMsg, nextAttempt)
+	return err
+}
+
+func ListDeadDeliveries(ctx context.Context, q Querier, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := q.Query(ctx, `
+		SELECT d.delivery_id, d.event_id, d.webhook_id, o.event_type, d.status, d.attempts, d.last_status_code, d.last_error, d.updated_at
+		FROM WebhookDeliveries d JOIN Outbox o ON o.event_id = d.event_id
+		WHERE d.status = 'Dead'
+		ORDER BY d.updated_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.DeliveryID, &d.EventID, &d.WebhookID, &d.EventType, &d.Status, &d.Attempts, &d.LastStatusCode, &d.LastError, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RequeueDeadDelivery moves a dead-lettered delivery back to Pending with a fresh attempt budget.
+func RequeueDeadDelivery(ctx context.Context, q Querier, deliveryID int64) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE WebhookDeliveries
+		SET status='Pending', attempts=0, next_attempt_at=NOW(), updated_at=NOW()
+		WHERE delivery_id=$1 AND status='Dead'
+	`, deliveryID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
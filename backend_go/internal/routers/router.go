@@ -1,33 +1,165 @@
 package routers
 
 import (
+	"log/slog"
 	"net/http"
 
 	"backend_go/internal/middlewares"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Handlers interface {
 	Health(w http.ResponseWriter, r *http.Request)
+	ErrorCatalog(w http.ResponseWriter, r *http.Request)
 	GetUserInfo(w http.ResponseWriter, r *http.Request)
+	StreamUserEvents(w http.ResponseWriter, r *http.Request)
+	IssueCard(w http.ResponseWriter, r *http.Request)
+	ListCards(w http.ResponseWriter, r *http.Request)
+	FreezeCard(w http.ResponseWriter, r *http.Request)
+	UnfreezeCard(w http.ResponseWriter, r *http.Request)
+	CloseCard(w http.ResponseWriter, r *http.Request)
+	ReportLostCard(w http.ResponseWriter, r *http.Request)
+	FreezeAccount(w http.ResponseWriter, r *http.Request)
+	UnfreezeAccount(w http.ResponseWriter, r *http.Request)
+	GetStatusHistory(w http.ResponseWriter, r *http.Request)
+	RequestCreditLimitChange(w http.ResponseWriter, r *http.Request)
+	ListCreditLimitRequests(w http.ResponseWriter, r *http.Request)
+	GetCreditLimitHistory(w http.ResponseWriter, r *http.Request)
+	ListInstallmentPlans(w http.ResponseWriter, r *http.Request)
+	ListUserDisputes(w http.ResponseWriter, r *http.Request)
+	GetTier(w http.ResponseWriter, r *http.Request)
+	RedeemPoints(w http.ResponseWriter, r *http.Request)
+	ListRedemptions(w http.ResponseWriter, r *http.Request)
+	ListPointTransfers(w http.ResponseWriter, r *http.Request)
 	GetUserTransactions(w http.ResponseWriter, r *http.Request)
 	Pay(w http.ResponseWriter, r *http.Request)
 	VoidTx(w http.ResponseWriter, r *http.Request)
 	RefundTx(w http.ResponseWriter, r *http.Request)
+	CaptureTx(w http.ResponseWriter, r *http.Request)
+	IncrementAuth(w http.ResponseWriter, r *http.Request)
+	OpenDispute(w http.ResponseWriter, r *http.Request)
+	GetDispute(w http.ResponseWriter, r *http.Request)
+	ListPromotions(w http.ResponseWriter, r *http.Request)
+	ListRewardCatalogue(w http.ResponseWriter, r *http.Request)
+	TransferPoints(w http.ResponseWriter, r *http.Request)
+	CreateHousehold(w http.ResponseWriter, r *http.Request)
+	GetHousehold(w http.ResponseWriter, r *http.Request)
+	AddHouseholdMember(w http.ResponseWriter, r *http.Request)
+	RemoveHouseholdMember(w http.ResponseWriter, r *http.Request)
+	ContributeToHousehold(w http.ResponseWriter, r *http.Request)
+
+	RegisterWebhook(w http.ResponseWriter, r *http.Request)
+	ListWebhooks(w http.ResponseWriter, r *http.Request)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request)
+	ListDeadLetters(w http.ResponseWriter, r *http.Request)
+	RetryDelivery(w http.ResponseWriter, r *http.Request)
+
+	AdminSetAccountStatus(w http.ResponseWriter, r *http.Request)
+	AdminSetCardStatus(w http.ResponseWriter, r *http.Request)
+	AdminListCreditLimitRequests(w http.ResponseWriter, r *http.Request)
+	ApproveCreditLimitRequest(w http.ResponseWriter, r *http.Request)
+	RejectCreditLimitRequest(w http.ResponseWriter, r *http.Request)
+	ApplyCreditLimitRequest(w http.ResponseWriter, r *http.Request)
+	AdminListDisputes(w http.ResponseWriter, r *http.Request)
+	ResolveDispute(w http.ResponseWriter, r *http.Request)
+	AdminCreateMerchant(w http.ResponseWriter, r *http.Request)
+	AdminListMerchants(w http.ResponseWriter, r *http.Request)
+	RotateMerchantKey(w http.ResponseWriter, r *http.Request)
+	AdminSetMerchantStatus(w http.ResponseWriter, r *http.Request)
+	AdminCreatePromotion(w http.ResponseWriter, r *http.Request)
+	AdminListPromotions(w http.ResponseWriter, r *http.Request)
+	DeactivatePromotion(w http.ResponseWriter, r *http.Request)
+
+	MerchantListTransactions(w http.ResponseWriter, r *http.Request)
+	MerchantRefund(w http.ResponseWriter, r *http.Request)
+	MerchantSettlementReport(w http.ResponseWriter, r *http.Request)
+	MerchantRespondToDispute(w http.ResponseWriter, r *http.Request)
 }
 
-func NewRouter(h Handlers) http.Handler {
+func NewRouter(h Handlers, logger *slog.Logger, verbosity middlewares.VerbosityPolicy, adminToken string, merchants middlewares.MerchantAuthenticator) http.Handler {
 	r := chi.NewRouter()
 
+	r.Use(middleware.RequestID)
+	r.Use(middlewares.RequestLogger(logger))
 	r.Use(middlewares.CORS())
+	r.Use(middlewares.Verbosity(verbosity))
 
 	r.Get("/api/health", h.Health)
+	r.Get("/api/errors", h.ErrorCatalog)
 	r.Get("/api/users/{id}", h.GetUserInfo)
+	r.Get("/api/users/{id}/events", h.StreamUserEvents)
+	r.Post("/api/users/{id}/freeze", h.FreezeAccount)
+	r.Post("/api/users/{id}/unfreeze", h.UnfreezeAccount)
+	r.Get("/api/users/{id}/status-history", h.GetStatusHistory)
+	r.Post("/api/users/{id}/credit-limit-requests", h.RequestCreditLimitChange)
+	r.Get("/api/users/{id}/credit-limit-requests", h.ListCreditLimitRequests)
+	r.Get("/api/users/{id}/credit-limit-history", h.GetCreditLimitHistory)
+	r.Get("/api/users/{id}/installment-plans", h.ListInstallmentPlans)
+	r.Get("/api/users/{id}/disputes", h.ListUserDisputes)
+	r.Get("/api/users/{id}/tier", h.GetTier)
+	r.Post("/api/users/{id}/redemptions", h.RedeemPoints)
+	r.Get("/api/users/{id}/redemptions", h.ListRedemptions)
+	r.Get("/api/users/{id}/point-transfers", h.ListPointTransfers)
+	r.Post("/api/users/{id}/cards", h.IssueCard)
+	r.Get("/api/users/{id}/cards", h.ListCards)
+	r.Post("/api/cards/{card_id}/freeze", h.FreezeCard)
+	r.Post("/api/cards/{card_id}/unfreeze", h.UnfreezeCard)
+	r.Post("/api/cards/{card_id}/close", h.CloseCard)
+	r.Post("/api/cards/{card_id}/report-lost", h.ReportLostCard)
 	r.Get("/api/transactions/{user_id}", h.GetUserTransactions)
 	r.Post("/api/transactions/pay", h.Pay)
 	r.Post("/api/transactions/void", h.VoidTx)
 	r.Post("/api/transactions/refund", h.RefundTx)
+	r.Post("/api/disputes", h.OpenDispute)
+	r.Get("/api/disputes/{dispute_id}", h.GetDispute)
+	r.Get("/api/promotions", h.ListPromotions)
+	r.Get("/api/rewards/catalogue", h.ListRewardCatalogue)
+	r.Post("/api/points/transfers", h.TransferPoints)
+	r.Post("/api/households", h.CreateHousehold)
+	r.Get("/api/households/{household_id}", h.GetHousehold)
+	r.Post("/api/households/{household_id}/members", h.AddHouseholdMember)
+	r.Delete("/api/households/{household_id}/members/{user_id}", h.RemoveHouseholdMember)
+	r.Post("/api/households/{household_id}/contributions", h.ContributeToHousehold)
+
+	r.Route("/api/webhooks", func(r chi.Router) {
+		r.Use(middlewares.AdminOnly(adminToken))
+		r.Post("/", h.RegisterWebhook)
+		r.Get("/", h.ListWebhooks)
+		r.Delete("/{id}", h.DeleteWebhook)
+		r.Get("/dead-letters", h.ListDeadLetters)
+		r.Post("/deliveries/{id}/retry", h.RetryDelivery)
+	})
+
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(middlewares.AdminOnly(adminToken))
+		r.Post("/users/{id}/status", h.AdminSetAccountStatus)
+		r.Post("/cards/{card_id}/status", h.AdminSetCardStatus)
+		r.Get("/credit-limit-requests", h.AdminListCreditLimitRequests)
+		r.Post("/credit-limit-requests/{request_id}/approve", h.ApproveCreditLimitRequest)
+		r.Post("/credit-limit-requests/{request_id}/reject", h.RejectCreditLimitRequest)
+		r.Post("/credit-limit-requests/{request_id}/apply", h.ApplyCreditLimitRequest)
+		r.Get("/disputes", h.AdminListDisputes)
+		r.Post("/disputes/{dispute_id}/resolve", h.ResolveDispute)
+		r.Post("/merchants", h.AdminCreateMerchant)
+		r.Get("/merchants", h.AdminListMerchants)
+		r.Post("/merchants/{merchant_id}/rotate-key", h.RotateMerchantKey)
+		r.Post("/merchants/{merchant_id}/status", h.AdminSetMerchantStatus)
+		r.Post("/promotions", h.AdminCreatePromotion)
+		r.Get("/promotions", h.AdminListPromotions)
+		r.Post("/promotions/{promotion_id}/deactivate", h.DeactivatePromotion)
+	})
+
+	r.Route("/api/merchant", func(r chi.Router) {
+		r.Use(middlewares.MerchantOnly(merchants))
+		r.Get("/transactions", h.MerchantListTransactions)
+		r.Post("/refunds", h.MerchantRefund)
+		r.Post("/captures", h.CaptureTx)
+		r.Post("/increments", h.IncrementAuth)
+		r.Get("/settlements/{date}", h.MerchantSettlementReport)
+		r.Post("/disputes/{dispute_id}/response", h.MerchantRespondToDispute)
+	})
 
 	return r
 }
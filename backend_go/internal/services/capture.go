@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	EventTransactionIncremented = "transaction.incremented"
+	EventTransactionExpired     = "transaction.expired"
+)
+
+// CaptureConfig controls authorizations.
+type CaptureConfig struct {
+	// AuthExpiry is how long an authorization can be captured; after that the
+	// hold is released and the transaction is Expired
+	AuthExpiry time.Duration
+	// AutoCaptureDelay > 0 captures the full amount that long after
+	// authorization unless the merchant captured first; 0 leaves it to the merchant
+	AutoCaptureDelay time.Duration
+}
+
+func DefaultCaptureConfig() CaptureConfig {
+	return CaptureConfig{AuthExpiry: 7 * 24 * time.Hour, AutoCaptureDelay: 10 * time.Second}
+}
+
+// CaptureRequest is the input of CaptureTransaction. A nil Amount captures
+// the full authorized amount.
+type CaptureRequest struct {
+	Merchant      string
+	TransactionID int
+	Amount        *float64
+}
+
+// IncrementRequest is the input of IncrementAuthorization.
+type IncrementRequest struct {
+	Merchant      string
+	TransactionID int
+	Amount        float64
+}
+
+type CaptureResult struct {
+	TransactionID    int64        `json:"transactionId"`
+	AuthorizedAmount float64      `json:"authorizedAmount"`
+	CapturedAmount   float64      `json:"capturedAmount"`
+	ReleasedAmount   float64      `json:"releasedAmount"`
+	PointsEarned     int          `json:"pointsEarned"`
+	BonusPoints      int          `json:"bonusPoints,omitempty"`
+	PointsRedeemed   int          `json:"pointsRedeemed"`
+	Steps            []utils.Step `json:"steps,omitempty"`
+}
+
+type AuthorizationResult struct {
+	TransactionID    int64        `json:"transactionId"`
+	AuthorizedAmount float64      `json:"authorizedAmount"`
+	Steps            []utils.Step `json:"steps,omitempty"`
+}
+
+// ---- CAPTURE (merchant) ----
+func (s *TransactionService) CaptureTransaction(ctx context.Context, req CaptureRequest) (*CaptureResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyTxID, req.TransactionID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		return s.capture(ctx, tx, req, false, log)
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "capture", err, steps)
+	}
+	res := anyRes.(*CaptureResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("transaction captured", "captured_amount", res.CapturedAmount, "released_amount", res.ReleasedAmount)
+	return res, nil
+}
+
+// SettleTransaction auto-captures the full amount after AutoCaptureDelay
+// (demo behaviour). Transactions the merchant already captured, or that
+// were voided or expired in the meantime, are skipped.
+func (s *TransactionService) SettleTransaction(txID int64) {
+	time.Sleep(s.Capture.AutoCaptureDelay)
+
+	ctx := utils.WithLogFields(context.Background(), utils.LogKeyTxID, txID)
+	_, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		return s.capture(ctx, tx, CaptureRequest{TransactionID: int(txID)}, true, log)
+	})
+	if err != nil {
+		utils.LoggerFrom(ctx).Error("auto-capture failed", "error", err, "steps", steps)
+		return
+	}
+	utils.LoggerFrom(ctx).Debug("auto-capture finished", "steps", steps)
+}
+
+// capture moves the captured amount from the hold to the balance and
+// releases the rest. Points are recomputed on the captured amount; points
+// redeemed at authorization stay redeemed.
+func (s *TransactionService) capture(ctx context.Context, tx pgx.Tx, req CaptureRequest, auto bool, log *utils.TxLogger) (any, error) {
+	log.Raw(fmt.Sprintf("> Processing: CAPTURE Transaction: %d", req.TransactionID))
+
+	user, t, err := s.lockAuthorization(ctx, tx, req.TransactionID, req.Merchant)
+	if err != nil {
+		if auto && errors.Is(err, pgx.ErrNoRows) {
+			log.Info("Transaction not found during auto-capture.")
+			return nil, nil
+		}
+		return nil, err
+	}
+	if t.Status != "Pending" {
+		if auto {
+			log.Info(fmt.Sprintf("Transaction %d is '%s', skipping auto-capture.", t.TransactionID, t.Status))
+			return nil, nil
+		}
+		return nil, Failf(utils.CodeTxInvalidStatus, "Cannot capture transaction with status: %s", t.Status)
+	}
+	// The sweeper may not have released it yet, but the hold is gone
+	if authorizationExpired(t, time.Now()) {
+		if auto {
+			log.Info(fmt.Sprintf("Authorization %d expired at %s, skipping auto-capture.", t.TransactionID, t.AuthExpiresAt.Format(time.RFC3339)))
+			return nil, nil
+		}
+		return nil, Failf(utils.CodeAuthExpired, "authorization expired at %s", t.AuthExpiresAt.Format(time.RFC3339))
+	}
+
+	authorized := t.Amount
+	captured := authorized
+	if req.Amount != nil {
+		captured = round2(*req.Amount)
+	}
+	if captured <= 0 || captured > authorized {
+		return nil, Failf(utils.CodeInvalidAuthAmount, "capture amount must be > 0 and <= authorized $%.2f", authorized)
+	}
+
+	var card *models.Card
+	if t.CardID != nil {
+		if card, err = s.Cards.GetByIDForUpdate(ctx, tx, *t.CardID); err != nil {
+			return nil, err
+		}
+	}
+	plan, err := s.lockInstallmentPlan(ctx, tx, int64(t.TransactionID))
+	if err != nil {
+		return nil, err
+	}
+	if plan != nil && captured != authorized {
+		return nil, Failf(utils.CodeInvalidAuthAmount, "installment purchases must be captured in full")
+	}
+
+	// The hold already counts against the limits, so this only fails if a
+	// limit was lowered after authorization
+	limit := effectiveLimit(user, time.Now())
+	if creditUsed(user.Balance, user.InstallmentReserved, user.AuthHold-authorized)+captured > limit {
+		log.Info(fmt.Sprintf("[CAPTURE] FAIL: capture $%.2f exceeds the credit limit $%.2f.", captured, limit))
+		return nil, Fail(utils.CodeInsufficientCredit)
+	}
+	if card != nil && creditUsed(card.Balance, card.InstallmentReserved, card.AuthHold-authorized)+captured > card.CreditLimit {
+		log.Info(fmt.Sprintf("[CAPTURE] FAIL: capture $%.2f exceeds card limit $%.2f.", captured, card.CreditLimit))
+		return nil, Fail(utils.CodeCardLimitExceeded)
+	}
+
+	mult := rewardRate(t)
+	pointsRedeemed := t.PointsRedeemed
+	fee := t.FXFee
+	released := round2(authorized - captured)
+	if released > 0 {
+		log.Info(fmt.Sprintf("[CAPTURE] Partial capture $%.2f of $%.2f; releasing $%.2f.", captured, authorized, released))
+		if t.OriginalCurrency != nil {
+			fee = round2(t.FXFee * captured / authorized)
+			orig := round2(*t.OriginalAmount * captured / authorized)
+			if err := s.Transactions.SetFX(ctx, tx, int64(t.TransactionID), orig, *t.OriginalCurrency, *t.FXRate, fee); err != nil {
+				return nil, err
+			}
+			t.OriginalAmount, t.FXFee = &orig, fee
+		}
+		if card != nil {
+			if err := s.releaseCard(ctx, tx, card, released, log); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Points are earned on the amount without the FX fee
+	pointsEarned := earnedPoints(captured, fee, mult)
+	promos, bonus, err := s.capturePromotions(ctx, tx, t, captured, authorized, log)
+	if err != nil {
+		return nil, err
+	}
+	pointChange := pointsEarned + bonus - pointsRedeemed
+
+	if err := s.holdCredit(ctx, tx, t.UserID, t.CardID, -authorized); err != nil {
+		return nil, err
+	}
+	// An installment purchase reserves the full amount and posts only the
+	// first installment to the balance
+	balanceChange := captured
+	if plan != nil {
+		balanceChange = 0
+	}
+	if _, err := s.Users.UpdateBalanceAndPoints(ctx, tx, t.UserID, balanceChange, pointChange); err != nil {
+		return nil, err
+	}
+	if err := s.Transactions.Capture(ctx, tx, t.TransactionID, captured, pointChange); err != nil {
+		return nil, err
+	}
+	t.Amount, t.PointChange, t.Status = captured, pointChange, "Paid"
+	if plan != nil {
+		if err := s.settleInstallments(ctx, tx, t, plan, log); err != nil {
+			return nil, err
+		}
+	} else if t.CardID != nil {
+		if err := s.Cards.UpdateBalance(ctx, tx, *t.CardID, captured); err != nil {
+			return nil, err
+		}
+	}
+
+	if pointsRedeemed > 0 {
+		if err := s.Points.Insert(ctx, tx, t.UserID, int64(t.TransactionID), -pointsRedeemed, "Redeemed"); err != nil {
+			return nil, err
+		}
+	}
+	if pointsEarned > 0 {
+		reason := fmt.Sprintf("Earned (%s x%g)", t.Merchant, mult)
+		if err := s.Points.Insert(ctx, tx, t.UserID, int64(t.TransactionID), pointsEarned, reason); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range promos {
+		if a.Points > 0 {
+			if err := s.Repos.Promotions.InsertPoints(ctx, tx, t.UserID, int64(t.TransactionID), a); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.emitTransactionEvent(ctx, tx, log, EventTransactionSettled, transactionEvent(t)); err != nil {
+		return nil, err
+	}
+	// Settled spend moved, so the tier may too; it applies from the next payment
+	if err := s.refreshTier(ctx, tx, user, log); err != nil {
+		return nil, err
+	}
+
+	log.Info(fmt.Sprintf("Capture successful: $%.2f captured, %d points earned, %d bonus points.", captured, pointsEarned, bonus))
+	return &CaptureResult{
+		TransactionID: int64(t.TransactionID), AuthorizedAmount: authorized, CapturedAmount: captured, ReleasedAmount: released,
+		PointsEarned: pointsEarned, BonusPoints: bonus, PointsRedeemed: pointsRedeemed,
+	}, nil
+}
+
+// ---- INCREMENTAL AUTHORIZATION (merchant) ----
+// Raises the authorized amount of a Pending transaction (e.g. hotel or car
+// rental extras). The increment is in the billing currency, carries no FX
+// fee and must fit the same limits and card controls as a new payment.
+func (s *TransactionService) IncrementAuthorization(ctx context.Context, req IncrementRequest) (*AuthorizationResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyTxID, req.TransactionID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: INCREMENT Transaction: %d, +$%.2f", req.TransactionID, req.Amount))
+
+		inc := round2(req.Amount)
+		if inc <= 0 {
+			return nil, Failf(utils.CodeInvalidAuthAmount, "increment must be positive")
+		}
+		user, t, err := s.lockAuthorization(ctx, tx, req.TransactionID, req.Merchant)
+		if err != nil {
+			return nil, err
+		}
+		if t.Status != "Pending" {
+			return nil, Failf(utils.CodeTxInvalidStatus, "Cannot increment transaction with status: %s", t.Status)
+		}
+		if authorizationExpired(t, time.Now()) {
+			return nil, Failf(utils.CodeAuthExpired, "authorization expired at %s", t.AuthExpiresAt.Format(time.RFC3339))
+		}
+		if err := s.checkAccountStatus(ctx, tx, user, log); err != nil {
+			return nil, err
+		}
+		var card *models.Card
+		if t.CardID != nil {
+			if card, err = s.Cards.GetByIDForUpdate(ctx, tx, *t.CardID); err != nil {
+				return nil, err
+			}
+			if card.Status != "Active" {
+				return nil, Failf(utils.CodeCardInactive, "card %d is %s", card.CardID, card.Status)
+			}
+		}
+		plan, err := s.lockInstallmentPlan(ctx, tx, int64(t.TransactionID))
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil {
+			return nil, Failf(utils.CodeInvalidAuthAmount, "installment purchases cannot be incremented")
+		}
+
+		limit := effectiveLimit(user, time.Now())
+		if creditUsed(user.Balance, user.InstallmentReserved, user.AuthHold)+inc > limit {
+			return nil, Fail(utils.CodeInsufficientCredit)
+		}
+		if card != nil {
+			if creditUsed(card.Balance, card.InstallmentReserved, card.AuthHold)+inc > card.CreditLimit {
+				log.Info(fmt.Sprintf("[CARD] FAIL: card credit used $%.2f + $%.2f exceeds card limit $%.2f.", creditUsed(card.Balance, card.InstallmentReserved, card.AuthHold), inc, card.CreditLimit))
+				return nil, Fail(utils.CodeCardLimitExceeded)
+			}
+			if err := checkCardControls(card, t.Merchant, inc, log); err != nil {
+				return nil, err
+			}
+		}
+
+		mult := rewardRate(t)
+		// Promotions are not re-evaluated; the bonus granted at authorization stays
+		bonus, err := s.promotionPoints(ctx, tx, t.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+		authorized := round2(t.Amount + inc)
+		pointChange := earnedPoints(authorized, t.FXFee, mult) + bonus - t.PointsRedeemed
+
+		if err := s.Transactions.IncrementAuthorization(ctx, tx, t.TransactionID, inc, pointChange); err != nil {
+			return nil, err
+		}
+		if err := s.holdCredit(ctx, tx, t.UserID, t.CardID, inc); err != nil {
+			return nil, err
+		}
+		if card != nil {
+			if err := s.consumeCard(ctx, tx, card, inc, log); err != nil {
+				return nil, err
+			}
+		}
+		log.Info(fmt.Sprintf("[AUTH] Authorization raised $%.2f -> $%.2f.", t.Amount, authorized))
+		t.Amount, t.PointChange = authorized, pointChange
+		if err := s.emitTransactionEvent(ctx, tx, log, EventTransactionIncremented, transactionEvent(t)); err != nil {
+			return nil, err
+		}
+		return &AuthorizationResult{TransactionID: int64(t.TransactionID), AuthorizedAmount: authorized}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "increment", err, steps)
+	}
+	res := anyRes.(*AuthorizationResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("authorization incremented", "authorized_amount", res.AuthorizedAmount)
+	return res, nil
+}
+
+// ExpireAuthorization releases the hold of a Pending transaction past
+// auth_expires_at (see repo.ListExpiredAuthorizations). Transactions that
+// are no longer Pending are left alone, so concurrent sweepers are harmless.
+func (s *TransactionService) ExpireAuthorization(ctx context.Context, txID int64) error {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyTxID, txID)
+	_, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: EXPIRE authorization, Transaction: %d", txID))
+
+		_, t, err := s.lockAuthorization(ctx, tx, int(txID), "")
+		if err != nil {
+			return nil, err
+		}
+		if t.Status != "Pending" {
+			log.Info(fmt.Sprintf("Transaction is '%s', skipping.", t.Status))
+			return nil, nil
+		}
+		if err := s.Transactions.UpdateStatus(ctx, tx, t.TransactionID, "Expired"); err != nil {
+			return nil, err
+		}
+		t.Status = "Expired"
+		if err := s.releaseAuthorization(ctx, tx, t, log); err != nil {
+			return nil, err
+		}
+		return nil, s.emitTransactionEvent(ctx, tx, log, EventTransactionExpired, transactionEvent(t))
+	})
+	if err != nil {
+		utils.LoggerFrom(ctx).Error("authorization expiry failed", "error", err, "steps", steps)
+		return err
+	}
+	utils.LoggerFrom(ctx).Debug("authorization expired", "steps", steps)
+	return nil
+}
+
+// lockAuthorization locks the owner of transaction txID, then the
+// transaction, in the usual user -> transaction order. A non-empty merchant
+// must match the transaction's merchant; the owner never changes, so it is
+// checked before taking any lock.
+func (s *TransactionService) lockAuthorization(ctx context.Context, tx pgx.Tx, txID int, merchant string) (*models.User, *models.Transaction, error) {
+	owner, err := s.Transactions.GetByID(ctx, tx, txID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) && merchant != "" {
+			return nil, nil, Fail(utils.CodeTxNotFound)
+		}
+		return nil, nil, err
+	}
+	if merchant != "" && owner.Merchant != merchant {
+		return nil, nil, Fail(utils.CodeTxForbidden)
+	}
+	user, err := s.lockUser(ctx, tx, owner.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	t, err := s.Transactions.GetByIDForUpdate(ctx, tx, txID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, t, nil
+}
+
+// authorizationExpired reports whether the hold of Pending t is past
+// auth_expires_at at now.
+func authorizationExpired(t *models.Transaction, now time.Time) bool {
+	return t.AuthExpiresAt != nil && !now.Before(*t.AuthExpiresAt)
+}
+
+// releaseAuthorization releases the hold of an uncaptured transaction t,
+// takes it back out of the card's spent total and cancels its installment
+// plan, if any. The user row must already be locked.
+func (s *TransactionService) releaseAuthorization(ctx context.Context, tx pgx.Tx, t *models.Transaction, log *utils.TxLogger) error {
+	log.Info(fmt.Sprintf("[AUTH] Releasing hold of $%.2f.", t.Amount))
+	if err := s.holdCredit(ctx, tx, t.UserID, t.CardID, -t.Amount); err != nil {
+		return err
+	}
+	if t.CardID != nil {
+		card, err := s.Cards.GetByIDForUpdate(ctx, tx, *t.CardID)
+		if err != nil {
+			return err
+		}
+		if err := s.releaseCard(ctx, tx, card, t.Amount, log); err != nil {
+			return err
+		}
+	}
+	plan, err := s.lockInstallmentPlan(ctx, tx, int64(t.TransactionID))
+	if err != nil || plan == nil {
+		return err
+	}
+	_, _, err = s.cancelInstallments(ctx, tx, plan, log)
+	return err
+}
+
+// holdCredit adds amount (negative to release) to the user's and card's held credit.
+func (s *TransactionService) holdCredit(ctx context.Context, tx pgx.Tx, userID int, cardID *int64, amount float64) error {
+	if err := s.Users.UpdateAuthHold(ctx, tx, userID, amount); err != nil {
+		return err
+	}
+	if cardID != nil {
+		return s.Cards.UpdateAuthHold(ctx, tx, *cardID, amount)
+	}
+	return nil
+}
+
+// earnedPoints is floor((amount - fxFee) * mult), the points a purchase earns.
+func earnedPoints(amount, fxFee, mult float64) int {
+	return int(math.Floor(round2(amount-fxFee) * mult))
+}
+
+// AuthorizationSweeper expires uncaptured authorizations every Interval.
+type AuthorizationSweeper struct {
+	Pool     *pgxpool.Pool
+	Svc      *TransactionService
+	Interval time.Duration
+	Batch    int
+}
+
+func (sw *AuthorizationSweeper) Run(ctx context.Context) {
+	logger := utils.LoggerFrom(ctx).With("component", "authorization_sweeper")
+	ticker := time.NewTicker(sw.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := sw.Svc.Transactions.ListExpiredAuthorizations(ctx, sw.Pool, sw.Batch)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("list expired authorizations failed", "error", err)
+				}
+				continue
+			}
+			for _, txID := range expired {
+				_ = sw.Svc.ExpireAuthorization(ctx, txID)
+			}
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var cardTypes = map[string]bool{"Primary": true, "Virtual": true, "Supplementary": true}
+
+// cardTransitions: cardholder action -> allowed current statuses -> new status.
+// Blocked (lost/stolen) can only be changed by an admin.
+var cardTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	"freeze":      {from: []string{"Active"}, to: "Frozen"},
+	"unfreeze":    {from: []string{"Frozen"}, to: "Active"},
+	"close":       {from: []string{"Active", "Frozen"}, to: "Closed"},
+	"report_lost": {from: []string{"Active", "Frozen"}, to: "Blocked"},
+}
+
+// cardBIN is the issuer prefix of generated card numbers (test range).
+const cardBIN = "489537"
+
+const cardValidityYears = 3
+
+// Reasons of the system closing a card on its virtual card controls.
+const (
+	reasonSingleUseConsumed = "single-use card consumed"
+	reasonAmountCapReached  = "amount cap reached"
+)
+
+// IssueCardRequest is the input of IssueCard. CreditLimit 0 means "same as
+// the account limit". SingleUse, AmountCap and MerchantLock are only allowed
+// on Virtual cards.
+type IssueCardRequest struct {
+	UserID       int
+	CardType     string
+	CreditLimit  float64
+	SingleUse    bool
+	AmountCap    *float64
+	MerchantLock string
+}
+
+type IssueCardResult struct {
+	Card *models.Card `json:"card"`
+	// PAN is only ever returned here; the DB keeps a random token and last4.
+	PAN   string       `json:"pan"`
+	Steps []utils.Step `json:"steps,omitempty"`
+}
+
+type CardResult struct {
+	Card  *models.Card `json:"card"`
+	Steps []utils.Step `json:"steps,omitempty"`
+}
+
+// ---- ISSUE ----
+func (s *TransactionService) IssueCard(ctx context.Context, req IssueCardRequest) (*IssueCardResult, error) {
+	userID, cardType, creditLimit := req.UserID, req.CardType, req.CreditLimit
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: ISSUE %s card, User: %d", cardType, userID))
+
+		if !cardTypes[cardType] {
+			return nil, Failf(utils.CodeInvalidCard, "unknown card type %q", cardType)
+		}
+		hasControls := req.SingleUse || req.AmountCap != nil || req.MerchantLock != ""
+		if hasControls && cardType != "Virtual" {
+			return nil, Failf(utils.CodeInvalidCard, "single_use, amount_cap and merchant_lock are only for Virtual cards")
+		}
+		if req.AmountCap != nil && *req.AmountCap <= 0 {
+			return nil, Failf(utils.CodeInvalidCard, "amount_cap must be positive")
+		}
+		if _, ok := merchantRates[req.MerchantLock]; req.MerchantLock != "" && !ok {
+			return nil, Failf(utils.CodeInvalidCard, "merchant_lock %q is not a registered merchant", req.MerchantLock)
+		}
+
+		user, err := s.lockUser(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkAccountStatus(ctx, tx, user, log); err != nil {
+			return nil, err
+		}
+		if creditLimit == 0 {
+			creditLimit = user.CreditLimit
+		}
+		if creditLimit < 0 || creditLimit > user.CreditLimit {
+			return nil, Failf(utils.CodeInvalidCard, "card limit %.2f outside account limit %.2f", creditLimit, user.CreditLimit)
+		}
+
+		if cardType == "Primary" {
+			if _, err := s.Cards.GetPrimaryForUpdate(ctx, tx, userID); err == nil {
+				return nil, Fail(utils.CodePrimaryCardExists)
+			} else if !errors.Is(err, pgx.ErrNoRows) {
+				return nil, err
+			}
+		}
+
+		pan, err := generatePAN()
+		if err != nil {
+			return nil, err
+		}
+		token, err := newPANToken()
+		if err != nil {
+			return nil, err
+		}
+		card, err := s.Cards.Create(ctx, tx, &models.Card{
+			UserID: userID, CardType: cardType, PANToken: token, Last4: pan[len(pan)-4:], ExpiresAt: cardExpiry(time.Now()), CreditLimit: creditLimit,
+			SingleUse: req.SingleUse, AmountCap: req.AmountCap, MerchantLock: req.MerchantLock,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Info(fmt.Sprintf("Card %d issued (**** %s), limit $%.2f within account limit $%.2f.", card.CardID, card.Last4, card.CreditLimit, user.CreditLimit))
+		if hasControls {
+			log.Info(fmt.Sprintf("Card %d controls: %s.", card.CardID, describeCardControls(card)))
+		}
+		return &IssueCardResult{Card: card, PAN: pan}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "card.issue", err, steps)
+	}
+	res := anyRes.(*IssueCardResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("card issued", "card_id", res.Card.CardID, "card_type", cardType)
+	return res, nil
+}
+
+func (s *TransactionService) ListCards(ctx context.Context, userID int) ([]models.Card, error) {
+	return s.Cards.ListByUserID(ctx, s.Pool, userID)
+}
+
+// ---- FREEZE / UNFREEZE / CLOSE / REPORT LOST ----
+// reason is recorded in the status audit; empty means "cardholder <action>".
+func (s *TransactionService) ChangeCardStatus(ctx context.Context, userID int, cardID int64, action, reason string) (*CardResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID, "card_id", cardID)
+	tr, ok := cardTransitions[action]
+	if !ok {
+		return nil, Failf(utils.CodeValidationFailed, "unknown card action %q", action)
+	}
+	if reason == "" {
+		reason = "cardholder " + action
+	}
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: CARD %s, Card: %d", action, cardID))
+
+		card, err := s.lockUserCard(ctx, tx, userID, cardID)
+		if err != nil {
+			return nil, err
+		}
+		allowed := false
+		for _, st := range tr.from {
+			if card.Status == st {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return nil, Failf(utils.CodeCardInvalidStatus, "cannot %s card with status: %s", action, card.Status)
+		}
+		if err := s.setCardStatus(ctx, tx, card, tr.to, reason, ActorCardholder, log); err != nil {
+			return nil, err
+		}
+		return &CardResult{Card: card}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "card."+action, err, steps)
+	}
+	res := anyRes.(*CardResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("card status changed", "status", res.Card.Status, "actor", ActorCardholder)
+	return res, nil
+}
+
+// lockUserCard locks a card and checks it belongs to userID.
+func (s *TransactionService) lockUserCard(ctx context.Context, tx pgx.Tx, userID int, cardID int64) (*models.Card, error) {
+	card, err := s.Cards.GetByIDForUpdate(ctx, tx, cardID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, Fail(utils.CodeCardNotFound)
+		}
+		return nil, err
+	}
+	if card.UserID != userID {
+		return nil, Fail(utils.CodeCardForbidden)
+	}
+	return card, nil
+}
+
+// lockPaymentCard resolves the card a payment is charged to: the requested
+// card, else the user's open primary card. nil means an account-only payment
+// (user without cards). Must be called after the user row is locked.
+func (s *TransactionService) lockPaymentCard(ctx context.Context, tx pgx.Tx, userID int, cardID *int64, log *utils.TxLogger) (*models.Card, error) {
+	var card *models.Card
+	var err error
+	if cardID != nil {
+		card, err = s.lockUserCard(ctx, tx, userID, *cardID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		card, err = s.Cards.GetPrimaryForUpdate(ctx, tx, userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Info("[CARD] No card given and no primary card: account-only payment.")
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if card.Status != "Active" {
+		return nil, Failf(utils.CodeCardInactive, "card %d is %s", card.CardID, card.Status)
+	}
+	if time.Now().After(card.ExpiresAt.AddDate(0, 0, 1)) {
+		return nil, Fail(utils.CodeCardExpired)
+	}
+	log.Info(fmt.Sprintf("[CARD] Charging %s card %d (**** %s): balance $%.2f / limit $%.2f.", card.CardType, card.CardID, card.Last4, card.Balance, card.CreditLimit))
+	return card, nil
+}
+
+// checkCardControls validates a payment of amount at merchant against the
+// card's virtual card controls.
+func checkCardControls(card *models.Card, merchant string, amount float64, log *utils.TxLogger) error {
+	if card.MerchantLock != "" && card.MerchantLock != merchant {
+		log.Info(fmt.Sprintf("[CARD] FAIL: card %d is locked to %s.", card.CardID, card.MerchantLock))
+		return Failf(utils.CodeCardMerchantLocked, "card is locked to %s", card.MerchantLock)
+	}
+	if card.AmountCap != nil && card.Spent+amount > *card.AmountCap {
+		log.Info(fmt.Sprintf("[CARD] FAIL: spent $%.2f + $%.2f exceeds card cap $%.2f.", card.Spent, amount, *card.AmountCap))
+		return Failf(utils.CodeCardCapExceeded, "remaining cap $%.2f", *card.AmountCap-card.Spent)
+	}
+	return nil
+}
+
+// consumeCard records an authorized amount against the card and closes
+// single-use cards and cards whose amount cap is used up.
+func (s *TransactionService) consumeCard(ctx context.Context, tx pgx.Tx, card *models.Card, amount float64, log *utils.TxLogger) error {
+	if err := s.Cards.AddSpend(ctx, tx, card.CardID, amount); err != nil {
+		return err
+	}
+	card.Spent += amount
+	switch {
+	case card.SingleUse:
+		return s.setCardStatus(ctx, tx, card, "Closed", reasonSingleUseConsumed, ActorSystem, log)
+	case card.AmountCap != nil && card.Spent >= *card.AmountCap-0.005:
+		return s.setCardStatus(ctx, tx, card, "Closed", reasonAmountCapReached, ActorSystem, log)
+	}
+	return nil
+}
+
+// releaseCard takes an uncaptured amount back out of the locked card's
+// spent total. A card its controls closed is reopened when the release
+// undoes that: a single-use card with nothing spent, or a capped card back
+// under its cap. Closed is otherwise final, so the latest change of a
+// Closed card tells why it closed.
+func (s *TransactionService) releaseCard(ctx context.Context, tx pgx.Tx, card *models.Card, amount float64, log *utils.TxLogger) error {
+	if err := s.Cards.AddSpend(ctx, tx, card.CardID, -amount); err != nil {
+		return err
+	}
+	card.Spent -= amount
+	if card.Status != "Closed" {
+		return nil
+	}
+	last, err := s.StatusChanges.LastForCard(ctx, tx, card.CardID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if last.Actor != ActorSystem {
+		return nil
+	}
+	switch {
+	case last.Reason == reasonSingleUseConsumed && card.Spent < 0.005,
+		last.Reason == reasonAmountCapReached && card.AmountCap != nil && card.Spent < *card.AmountCap-0.005:
+		return s.setCardStatus(ctx, tx, card, last.OldStatus, "authorization released", ActorSystem, log)
+	}
+	return nil
+}
+
+func describeCardControls(card *models.Card) string {
+	parts := make([]string, 0, 3)
+	if card.SingleUse {
+		parts = append(parts, "single-use")
+	}
+	if card.AmountCap != nil {
+		parts = append(parts, fmt.Sprintf("cap $%.2f", *card.AmountCap))
+	}
+	if card.MerchantLock != "" {
+		parts = append(parts, "locked to "+card.MerchantLock)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cardExpiry is the last day of the month cardValidityYears from now.
+func cardExpiry(now time.Time) time.Time {
+	firstOfMonth := time.Date(now.Year()+cardValidityYears, now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return firstOfMonth.AddDate(0, 1, -1)
+}
+
+// generatePAN returns a random 16-digit Luhn-valid number in cardBIN.
+func generatePAN() (string, error) {
+	digits := []byte(cardBIN)
+	for len(digits) < 15 {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digits = append(digits, byte('0'+n.Int64()))
+	}
+	return string(append(digits, luhnCheckDigit(digits))), nil
+}
+
+func luhnCheckDigit(digits []byte) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func newPANToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "tok_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/repo"
+)
+
+// putVirtualCard issues a virtual card with the given controls next to the
+// user's primary card.
+func putVirtualCard(t *testing.T, mem *repo.Memory, userID int, singleUse bool, amountCap *float64) *models.Card {
+	t.Helper()
+	c, err := mem.Repos().Cards.Create(context.Background(), nil, &models.Card{
+		UserID: userID, CardType: "Virtual", PANToken: "vtok", Last4: "1111", ExpiresAt: time.Now().AddDate(3, 0, 0),
+		CreditLimit: 5000, SingleUse: singleUse, AmountCap: amountCap,
+	})
+	if err != nil {
+		t.Fatalf("create virtual card: %v", err)
+	}
+	return c
+}
+
+func cardByID(t *testing.T, s *TransactionService, cardID int64) models.Card {
+	t.Helper()
+	cards, err := s.Cards.ListByUserID(context.Background(), nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cards {
+		if c.CardID == cardID {
+			return c
+		}
+	}
+	t.Fatalf("card %d not found", cardID)
+	return models.Card{}
+}
+
+func TestReleasedAuthorizationRestoresCardControls(t *testing.T) {
+	capped := 100.0
+	release := map[string]func(s *TransactionService, mem *repo.Memory, txID int64) error{
+		"void": func(s *TransactionService, mem *repo.Memory, txID int64) error {
+			_, err := s.VoidTransaction(context.Background(), 1, int(txID))
+			return err
+		},
+		"expiry": func(s *TransactionService, mem *repo.Memory, txID int64) error {
+			mem.Now = func() time.Time { return time.Now().Add(s.Capture.AuthExpiry + time.Minute) }
+			expired, err := s.Transactions.ListExpiredAuthorizations(context.Background(), nil, 10)
+			if err != nil || len(expired) != 1 || expired[0] != txID {
+				t.Fatalf("expired = %v %v", expired, err)
+			}
+			return s.ExpireAuthorization(context.Background(), txID)
+		},
+	}
+	cases := []struct {
+		name      string
+		singleUse bool
+		cap       *float64
+	}{
+		{"amount cap", false, &capped},
+		{"single use", true, nil},
+	}
+	for _, c := range cases {
+		for how, fn := range release {
+			t.Run(c.name+"/"+how, func(t *testing.T) {
+				s, mem := newMemoryService(t)
+				putCard(t, mem, 1, 5000)
+				card := putVirtualCard(t, mem, 1, c.singleUse, c.cap)
+
+				res, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 100, Merchant: "Amazon", CardID: &card.CardID})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := cardByID(t, s, card.CardID); got.Status != "Closed" || got.Spent != 100 {
+					t.Fatalf("card after authorization = %+v", got)
+				}
+
+				if err := fn(s, mem, res.TransactionID); err != nil {
+					t.Fatal(err)
+				}
+				got := cardByID(t, s, card.CardID)
+				if got.Status != "Active" || got.Spent != 0 || got.AuthHold != 0 {
+					t.Fatalf("card after release = %+v", got)
+				}
+				// The limit is usable again
+				if _, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 100, Merchant: "Amazon", CardID: &card.CardID}); err != nil {
+					t.Fatalf("payment after release: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestPartialCaptureReopensCappedCard(t *testing.T) {
+	s, mem := newMemoryService(t)
+	putCard(t, mem, 1, 5000)
+	capped := 100.0
+	card := putVirtualCard(t, mem, 1, false, &capped)
+
+	res, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 100, Merchant: "Amazon", CardID: &card.CardID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := 60.0
+	if _, err := s.CaptureTransaction(context.Background(), CaptureRequest{Merchant: "Amazon", TransactionID: int(res.TransactionID), Amount: &amount}); err != nil {
+		t.Fatal(err)
+	}
+	if got := cardByID(t, s, card.CardID); got.Status != "Active" || got.Spent != 60 {
+		t.Fatalf("card after partial capture = %+v", got)
+	}
+}
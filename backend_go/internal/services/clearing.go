@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Clearing exception types.
+const (
+	ClearingInvalid        = "invalid"         // the record could not be parsed
+	ClearingUnmatched      = "unmatched"       // no open authorization of that merchant
+	ClearingAmountMismatch = "amount_mismatch" // presented amount differs from the authorization
+	ClearingDuplicate      = "duplicate"       // presented twice, or already settled
+	ClearingRejected       = "rejected"        // matched, but the capture was refused
+)
+
+// Presentment is one clearing file record: the acquirer presents Amount
+// (billing currency) of authorization TransactionID for settlement.
+type Presentment struct {
+	Line          int
+	TransactionID int
+	Merchant      string
+	Amount        float64
+}
+
+// ClearingException is a record that was not settled.
+type ClearingException struct {
+	Presentment
+	Type   string
+	Reason string
+}
+
+// ClearingReport summarises one clearing file.
+type ClearingReport struct {
+	Records       int
+	Settled       int
+	SettledAmount float64
+	Exceptions    []ClearingException
+}
+
+func (r *ClearingReport) except(p Presentment, typ, format string, args ...any) {
+	r.Exceptions = append(r.Exceptions, ClearingException{Presentment: p, Type: typ, Reason: fmt.Sprintf(format, args...)})
+}
+
+// ClearPresentments settles the matched records in file order through the
+// same capture path as merchant captures. A record matches a Pending
+// purchase with the same id and merchant whose authorized amount equals the
+// presented amount; with allowPartial a smaller amount is a partial capture.
+// Everything else becomes an exception. Only database failures abort.
+func (s *TransactionService) ClearPresentments(ctx context.Context, records []Presentment, allowPartial bool) (*ClearingReport, error) {
+	report := &ClearingReport{Records: len(records)}
+	seen := make(map[int]int, len(records))
+	for _, p := range records {
+		if line, ok := seen[p.TransactionID]; ok {
+			report.except(p, ClearingDuplicate, "also presented on line %d", line)
+			continue
+		}
+		seen[p.TransactionID] = p.Line
+
+		t, err := s.Transactions.GetByID(ctx, s.Pool, p.TransactionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			report.except(p, ClearingUnmatched, "no transaction %d", p.TransactionID)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		switch {
+		case t.SourceTransactionID != nil:
+			report.except(p, ClearingUnmatched, "transaction %d is a refund", p.TransactionID)
+			continue
+		case t.Merchant != p.Merchant:
+			report.except(p, ClearingUnmatched, "transaction %d belongs to %s", p.TransactionID, t.Merchant)
+			continue
+		case t.Status == "Voided" || t.Status == "Expired":
+			report.except(p, ClearingUnmatched, "authorization is %s", t.Status)
+			continue
+		case t.Status != "Pending":
+			report.except(p, ClearingDuplicate, "already settled (%s)", t.Status)
+			continue
+		case authorizationExpired(t, time.Now()):
+			report.except(p, ClearingUnmatched, "authorization expired at %s", t.AuthExpiresAt.Format(time.RFC3339))
+			continue
+		}
+		amount := round2(p.Amount)
+		if amount > t.Amount || (amount < t.Amount && !allowPartial) {
+			report.except(p, ClearingAmountMismatch, "presented %.2f, authorized %.2f", amount, t.Amount)
+			continue
+		}
+
+		res, err := s.CaptureTransaction(ctx, CaptureRequest{Merchant: p.Merchant, TransactionID: p.TransactionID, Amount: &amount})
+		if err != nil {
+			te, ok := asTxError(err)
+			if !ok || te.Code == utils.CodeInternalError {
+				return report, err
+			}
+			reason := te.Msg
+			if te.Detail != "" {
+				reason = te.Detail
+			}
+			report.except(p, ClearingRejected, "%s: %s", te.Code, reason)
+			continue
+		}
+		report.Settled++
+		report.SettledAmount = round2(report.SettledAmount + res.CapturedAmount)
+	}
+	return report, nil
+}
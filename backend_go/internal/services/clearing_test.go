@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClearPresentments(t *testing.T) {
+	s, _ := newMemoryService(t)
+	auth := func(amount float64, merchant string) int {
+		res, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: amount, Merchant: merchant})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(res.TransactionID)
+	}
+	a, b, c := auth(40, "Amazon"), auth(25, "Steam"), auth(60, "7-11")
+	settled := pay(t, s, PaymentRequest{UserID: 1, Amount: 15, Merchant: "Amazon"})
+
+	records := []Presentment{
+		{Line: 1, TransactionID: a, Merchant: "Amazon", Amount: 40},
+		{Line: 2, TransactionID: a, Merchant: "Amazon", Amount: 40},
+		{Line: 3, TransactionID: int(settled.TransactionID), Merchant: "Amazon", Amount: 15},
+		{Line: 4, TransactionID: b, Merchant: "Amazon", Amount: 25},
+		{Line: 5, TransactionID: c, Merchant: "7-11", Amount: 59.99},
+		{Line: 6, TransactionID: 999, Merchant: "7-11", Amount: 1},
+	}
+	report, err := s.ClearPresentments(context.Background(), records, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Records != 6 || report.Settled != 1 || report.SettledAmount != 40 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := map[int]string{
+		2: ClearingDuplicate,
+		3: ClearingDuplicate,
+		4: ClearingUnmatched,
+		5: ClearingAmountMismatch,
+		6: ClearingUnmatched,
+	}
+	if len(report.Exceptions) != len(want) {
+		t.Fatalf("exceptions = %+v", report.Exceptions)
+	}
+	for _, e := range report.Exceptions {
+		if want[e.Line] != e.Type {
+			t.Errorf("line %d: %s (%s), want %s", e.Line, e.Type, e.Reason, want[e.Line])
+		}
+	}
+	if tr := getTransaction(t, s, int64(a)); tr.Status != "Paid" {
+		t.Fatalf("line 1 not settled: %+v", tr)
+	}
+
+	// A second file presenting the same record again is a duplicate too
+	report, err = s.ClearPresentments(context.Background(), records[:1], false)
+	if err != nil || report.Settled != 0 || len(report.Exceptions) != 1 || report.Exceptions[0].Type != ClearingDuplicate {
+		t.Fatalf("re-presented file: %+v %v", report, err)
+	}
+
+	// With allowPartial a smaller amount is a partial capture
+	report, err = s.ClearPresentments(context.Background(), records[4:5], true)
+	if err != nil || report.Settled != 1 || report.SettledAmount != 59.99 {
+		t.Fatalf("partial: %+v %v", report, err)
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const creditLimitListLimit = 100
+
+// CreditLimitChangeRequest is the input of RequestCreditLimitChange: either
+// NewLimit (permanent increase/decrease) or Boost + BoostUntil (temporary).
+type CreditLimitChangeRequest struct {
+	UserID     int
+	NewLimit   *float64
+	Boost      *float64
+	BoostUntil *time.Time
+	Reason     string
+}
+
+type CreditLimitRequestResult struct {
+	Request *models.CreditLimitRequest `json:"request"`
+	User    *models.User               `json:"user,omitempty"` // set once applied
+	Steps   []utils.Step               `json:"steps,omitempty"`
+}
+
+// effectiveLimit is the credit limit payments are checked against: the
+// permanent limit plus a temporary boost that has not expired yet.
+func effectiveLimit(u *models.User, now time.Time) float64 {
+	if u.TempLimitBoostUntil != nil && now.Before(*u.TempLimitBoostUntil) {
+		return u.CreditLimit + u.TempLimitBoost
+	}
+	return u.CreditLimit
+}
+
+// ---- REQUEST (cardholder) ----
+func (s *TransactionService) RequestCreditLimitChange(ctx context.Context, req CreditLimitChangeRequest) (*CreditLimitRequestResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, req.UserID)
+	if (req.NewLimit == nil) == (req.Boost == nil) {
+		return nil, Failf(utils.CodeInvalidCreditRequest, "exactly one of new_limit or boost is required")
+	}
+	if req.NewLimit != nil && *req.NewLimit < 0 {
+		return nil, Failf(utils.CodeInvalidCreditRequest, "new_limit must not be negative")
+	}
+	if req.Boost != nil && (*req.Boost <= 0 || req.BoostUntil == nil || !req.BoostUntil.After(time.Now())) {
+		return nil, Failf(utils.CodeInvalidCreditRequest, "boost must be positive with a future boost_until")
+	}
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: CREDIT LIMIT request, User: %d", req.UserID))
+
+		user, err := s.lockUser(ctx, tx, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user.Status == "Closed" {
+			return nil, Fail(utils.CodeAccountClosed)
+		}
+		r := &models.CreditLimitRequest{UserID: req.UserID, CurrentLimit: user.CreditLimit, Reason: req.Reason}
+		if req.NewLimit != nil {
+			if *req.NewLimit == user.CreditLimit {
+				return nil, Failf(utils.CodeInvalidCreditRequest, "new_limit equals the current limit")
+			}
+			r.ChangeType, r.RequestedLimit = "Permanent", req.NewLimit
+			log.Info(fmt.Sprintf("Requesting limit change $%.2f -> $%.2f.", user.CreditLimit, *req.NewLimit))
+		} else {
+			r.ChangeType, r.BoostAmount, r.BoostUntil = "TemporaryBoost", req.Boost, req.BoostUntil
+			log.Info(fmt.Sprintf("Requesting temporary boost +$%.2f until %s.", *req.Boost, req.BoostUntil.Format(time.RFC3339)))
+		}
+		created, err := s.CreditLimits.CreateRequest(ctx, tx, r)
+		if err != nil {
+			return nil, err
+		}
+		return &CreditLimitRequestResult{Request: created}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "credit_limit.request", err, steps)
+	}
+	res := anyRes.(*CreditLimitRequestResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("credit limit change requested", "request_id", res.Request.RequestID, "change_type", res.Request.ChangeType)
+	return res, nil
+}
+
+// ---- APPROVE / REJECT (admin) ----
+func (s *TransactionService) DecideCreditLimitRequest(ctx context.Context, requestID int64, approve bool, reason string) (*CreditLimitRequestResult, error) {
+	ctx = utils.WithLogFields(ctx, "request_id", requestID)
+	status := "Rejected"
+	if approve {
+		status = "Approved"
+	}
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: CREDIT LIMIT request %d -> %s", requestID, status))
+
+		r, err := s.lockCreditLimitRequest(ctx, tx, requestID)
+		if err != nil {
+			return nil, err
+		}
+		if r.Status != "Pending" {
+			return nil, Failf(utils.CodeCreditRequestInvalidStatus, "request is %s", r.Status)
+		}
+		if err := s.CreditLimits.DecideRequest(ctx, tx, requestID, status, reason); err != nil {
+			return nil, err
+		}
+		r.Status, r.DecisionReason = status, reason
+		return &CreditLimitRequestResult{Request: r}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "credit_limit.decide", err, steps)
+	}
+	res := anyRes.(*CreditLimitRequestResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("credit limit request decided", "status", status)
+	return res, nil
+}
+
+// ---- APPLY (admin) ----
+// Applies an Approved request under the same user row lock as payments. A
+// decrease below the current balance is applied as-is; payments are then
+// refused until the balance is back under the limit.
+func (s *TransactionService) ApplyCreditLimitRequest(ctx context.Context, requestID int64) (*CreditLimitRequestResult, error) {
+	ctx = utils.WithLogFields(ctx, "request_id", requestID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: CREDIT LIMIT apply request %d", requestID))
+
+		r, err := s.lockCreditLimitRequest(ctx, tx, requestID)
+		if err != nil {
+			return nil, err
+		}
+		if r.Status != "Approved" {
+			return nil, Failf(utils.CodeCreditRequestInvalidStatus, "request is %s", r.Status)
+		}
+		user, err := s.lockUser(ctx, tx, r.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		change := models.CreditLimitChange{
+			UserID: r.UserID, RequestID: &r.RequestID, OldLimit: user.CreditLimit, NewLimit: user.CreditLimit,
+			OldBoost: user.TempLimitBoost, NewBoost: user.TempLimitBoost, BoostUntil: user.TempLimitBoostUntil,
+			Reason: r.Reason, Actor: ActorAdmin,
+		}
+		switch r.ChangeType {
+		case "Permanent":
+			change.NewLimit = *r.RequestedLimit
+			if err := s.Users.UpdateCreditLimit(ctx, tx, user.UserID, change.NewLimit); err != nil {
+				return nil, err
+			}
+			user.CreditLimit = change.NewLimit
+			log.Info(fmt.Sprintf("Credit limit $%.2f -> $%.2f.", change.OldLimit, change.NewLimit))
+			if user.Balance > user.CreditLimit {
+				log.Info(fmt.Sprintf("Balance $%.2f is above the new limit; new payments are blocked until it is repaid.", user.Balance))
+			}
+		default: // TemporaryBoost
+			if !r.BoostUntil.After(time.Now()) {
+				return nil, Failf(utils.CodeCreditRequestInvalidStatus, "boost expired at %s", r.BoostUntil.Format(time.RFC3339))
+			}
+			change.NewBoost, change.BoostUntil = *r.BoostAmount, r.BoostUntil
+			if err := s.Users.UpdateLimitBoost(ctx, tx, user.UserID, change.NewBoost, change.BoostUntil); err != nil {
+				return nil, err
+			}
+			user.TempLimitBoost, user.TempLimitBoostUntil = change.NewBoost, change.BoostUntil
+			log.Info(fmt.Sprintf("Temporary boost +$%.2f until %s (effective limit $%.2f).", change.NewBoost, change.BoostUntil.Format(time.RFC3339), effectiveLimit(user, time.Now())))
+		}
+		if err := s.CreditLimits.InsertChange(ctx, tx, change); err != nil {
+			return nil, err
+		}
+		if err := s.CreditLimits.MarkRequestApplied(ctx, tx, requestID); err != nil {
+			return nil, err
+		}
+		if err := s.emitAccountEvent(ctx, tx, log, EventAccountCreditLimitChanged, models.AccountEvent{UserID: user.UserID, CreditLimit: &change}); err != nil {
+			return nil, err
+		}
+		r.Status = "Applied"
+		return &CreditLimitRequestResult{Request: r, User: user}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "credit_limit.apply", err, steps)
+	}
+	res := anyRes.(*CreditLimitRequestResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("credit limit change applied", utils.LogKeyUserID, res.Request.UserID, "credit_limit", res.User.CreditLimit)
+	return res, nil
+}
+
+// ListCreditLimitRequests filters by user (userID > 0) and/or status.
+func (s *TransactionService) ListCreditLimitRequests(ctx context.Context, userID int, status string) ([]models.CreditLimitRequest, error) {
+	return s.CreditLimits.ListRequests(ctx, s.Pool, userID, status, creditLimitListLimit)
+}
+
+func (s *TransactionService) CreditLimitHistory(ctx context.Context, userID int) ([]models.CreditLimitChange, error) {
+	return s.CreditLimits.ListHistory(ctx, s.Pool, userID, creditLimitListLimit)
+}
+
+func (s *TransactionService) lockCreditLimitRequest(ctx context.Context, tx pgx.Tx, requestID int64) (*models.CreditLimitRequest, error) {
+	r, err := s.CreditLimits.GetRequestForUpdate(ctx, tx, requestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, Fail(utils.CodeCreditRequestNotFound)
+		}
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+)
+
+func TestCreditLimitRequestLifecycle(t *testing.T) {
+	s, _ := newMemoryService(t)
+	ctx := context.Background()
+	newLimit := 15000.0
+
+	req, err := s.RequestCreditLimitChange(ctx, CreditLimitChangeRequest{UserID: 1, NewLimit: &newLimit, Reason: "raise"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := req.Request.RequestID
+	if req.Request.Status != "Pending" || req.Request.CurrentLimit != 10000 {
+		t.Fatalf("request = %+v", req.Request)
+	}
+
+	// Only Approved requests are applied
+	_, err = s.ApplyCreditLimitRequest(ctx, id)
+	wantCode(t, err, utils.CodeCreditRequestInvalidStatus)
+	if _, err := s.DecideCreditLimitRequest(ctx, id, true, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.DecideCreditLimitRequest(ctx, id, false, "again")
+	wantCode(t, err, utils.CodeCreditRequestInvalidStatus)
+
+	res, err := s.ApplyCreditLimitRequest(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.User.CreditLimit != 15000 || getUser(t, s, 1).CreditLimit != 15000 {
+		t.Fatalf("user after apply = %+v", res.User)
+	}
+	reqs, err := s.ListCreditLimitRequests(ctx, 1, "")
+	if err != nil || len(reqs) != 1 || reqs[0].Status != "Applied" {
+		t.Fatalf("requests = %+v %v", reqs, err)
+	}
+	hist, err := s.CreditLimitHistory(ctx, 1)
+	if err != nil || len(hist) != 1 || hist[0].OldLimit != 10000 || hist[0].NewLimit != 15000 {
+		t.Fatalf("history = %+v %v", hist, err)
+	}
+	_, err = s.DecideCreditLimitRequest(ctx, 99, true, "")
+	wantCode(t, err, utils.CodeCreditRequestNotFound)
+}
+
+func TestCreditLimitBoostRaisesEffectiveLimit(t *testing.T) {
+	s, mem := newMemoryService(t)
+	mem.PutUser(models.User{UserID: 1, CreditLimit: 10000, Balance: 9500})
+	putCard(t, mem, 1, 20000)
+	ctx := context.Background()
+	boost, until := 2000.0, time.Now().Add(24*time.Hour)
+
+	_, err := s.ProcessPayment(ctx, PaymentRequest{UserID: 1, Amount: 1000, Merchant: "Amazon"})
+	wantCode(t, err, utils.CodeInsufficientCredit)
+
+	req, err := s.RequestCreditLimitChange(ctx, CreditLimitChangeRequest{UserID: 1, Boost: &boost, BoostUntil: &until})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DecideCreditLimitRequest(ctx, req.Request.RequestID, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ApplyCreditLimitRequest(ctx, req.Request.RequestID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ProcessPayment(ctx, PaymentRequest{UserID: 1, Amount: 1000, Merchant: "Amazon"}); err != nil {
+		t.Fatalf("payment within the boosted limit: %v", err)
+	}
+}
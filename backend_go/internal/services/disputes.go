@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	EventDisputeOpened   = "dispute.opened"
+	EventDisputeResolved = "dispute.resolved"
+)
+
+// ActorMerchant responds to disputes (DisputeEvents.actor).
+const ActorMerchant = "merchant"
+
+var disputeReasonCodes = map[string]bool{
+	"fraud":                true,
+	"not_received":         true,
+	"not_as_described":     true,
+	"duplicate":            true,
+	"credit_not_processed": true,
+	"other":                true,
+}
+
+const disputeListLimit = 100
+
+// DisputeConfig controls the dispute lifecycle.
+type DisputeConfig struct {
+	// FilingWindow is how long after the purchase a dispute can be opened
+	FilingWindow time.Duration
+	// ResponseWindow is the merchant's deadline; an Open dispute past it is Won
+	ResponseWindow time.Duration
+}
+
+func DefaultDisputeConfig() DisputeConfig {
+	return DisputeConfig{FilingWindow: 120 * 24 * time.Hour, ResponseWindow: 7 * 24 * time.Hour}
+}
+
+// OpenDisputeRequest is the input of OpenDispute.
+type OpenDisputeRequest struct {
+	UserID        int
+	TransactionID int
+	ReasonCode    string
+	Description   string
+}
+
+type DisputeResult struct {
+	Dispute *models.Dispute `json:"dispute"`
+	Steps   []utils.Step    `json:"steps,omitempty"`
+}
+
+// ---- OPEN (cardholder) ----
+// The cardholder gets a provisional credit right away: the amount comes off
+// the balance and the transaction's points are reversed, exactly as a refund
+// would, and the transaction is Disputed until the dispute is resolved.
+func (s *TransactionService) OpenDispute(ctx context.Context, req OpenDisputeRequest) (*DisputeResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, req.UserID, utils.LogKeyTxID, req.TransactionID)
+	if !disputeReasonCodes[req.ReasonCode] {
+		return nil, Failf(utils.CodeInvalidDispute, "unknown reason_code %q", req.ReasonCode)
+	}
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: DISPUTE open, Target Transaction: %d", req.TransactionID))
+
+		user, err := s.lockUser(ctx, tx, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user.Status == "Closed" {
+			return nil, Fail(utils.CodeAccountClosed)
+		}
+		t, err := s.Transactions.GetByIDForUpdate(ctx, tx, req.TransactionID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, Fail(utils.CodeTxNotFound)
+			}
+			return nil, err
+		}
+		if t.UserID != req.UserID {
+			return nil, Fail(utils.CodeTxForbidden)
+		}
+		if t.Status != "Paid" {
+			return nil, Failf(utils.CodeTxInvalidStatus, "Cannot dispute transaction with status: %s", t.Status)
+		}
+		if time.Since(t.CreatedAt) > s.Disputes.FilingWindow {
+			return nil, Failf(utils.CodeInvalidDispute, "filing window of %s has passed", s.Disputes.FilingWindow)
+		}
+		// A Lost dispute returns the transaction to Paid; it cannot be
+		// disputed again for a second provisional credit
+		resolved, err := s.Repos.Disputes.HasResolved(ctx, tx, int64(t.TransactionID))
+		if err != nil {
+			return nil, err
+		}
+		if resolved {
+			return nil, Failf(utils.CodeInvalidDispute, "transaction %d already had a dispute resolved", t.TransactionID)
+		}
+		if user.CurrentPoints < t.PointChange {
+			return nil, Fail(utils.CodeInsufficientPoints)
+		}
+
+		if err := s.Transactions.UpdateStatus(ctx, tx, req.TransactionID, "Disputed"); err != nil {
+			return nil, err
+		}
+		t.Status = "Disputed"
+
+		creditPoints := -t.PointChange
+		log.Info(fmt.Sprintf("[DISPUTE] Provisional credit: -$%.2f balance, %+d points.", t.Amount, creditPoints))
+		if _, err := s.Users.UpdateBalanceAndPoints(ctx, tx, t.UserID, -t.Amount, creditPoints); err != nil {
+			return nil, err
+		}
+		if t.CardID != nil {
+			if err := s.Cards.UpdateBalance(ctx, tx, *t.CardID, -t.Amount); err != nil {
+				return nil, err
+			}
+		}
+		if creditPoints != 0 {
+			if err := s.Points.Insert(ctx, tx, t.UserID, int64(t.TransactionID), creditPoints, "Dispute Provisional Credit"); err != nil {
+				return nil, err
+			}
+		}
+
+		d, err := s.Repos.Disputes.Create(ctx, tx, &models.Dispute{
+			TransactionID: int64(t.TransactionID), UserID: t.UserID, CardID: t.CardID,
+			ReasonCode: req.ReasonCode, Description: req.Description, Amount: t.Amount, PointChange: creditPoints,
+		}, s.Disputes.ResponseWindow.Seconds())
+		if err != nil {
+			return nil, err
+		}
+		if err := s.recordDisputeEvent(ctx, tx, d, "", req.Description, ActorCardholder, log); err != nil {
+			return nil, err
+		}
+		log.Info(fmt.Sprintf("[DISPUTE] Dispute %d opened (%s); merchant must respond by %s.", d.DisputeID, d.ReasonCode, d.RespondBy.Format(time.RFC3339)))
+		if err := s.emitDisputeEvent(ctx, tx, log, EventDisputeOpened, t, d); err != nil {
+			return nil, err
+		}
+		return &DisputeResult{Dispute: d}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "dispute.open", err, steps)
+	}
+	res := anyRes.(*DisputeResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("dispute opened", "dispute_id", res.Dispute.DisputeID, "reason_code", res.Dispute.ReasonCode)
+	return res, nil
+}
+
+// ---- MERCHANT RESPONSE ----
+// Accepting the dispute resolves it as Won; contesting it moves it to
+// UnderReview for an administrator to decide. Only the merchant of the
+// disputed sale can respond.
+func (s *TransactionService) RespondToDispute(ctx context.Context, merchant string, disputeID int64, accept bool, note string) (*DisputeResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyMerchant, merchant)
+	return s.updateDispute(ctx, disputeID, "dispute.respond", func(ctx context.Context, tx pgx.Tx, d *models.Dispute, t *models.Transaction, log *utils.TxLogger) error {
+		if t.Merchant != merchant {
+			return Failf(utils.CodeTxForbidden, "dispute %d is not on a sale of %s", d.DisputeID, merchant)
+		}
+		if d.Status != "Open" {
+			return Failf(utils.CodeDisputeInvalidStatus, "dispute is %s", d.Status)
+		}
+		if err := s.Repos.Disputes.SetMerchantResponse(ctx, tx, d.DisputeID, note); err != nil {
+			return err
+		}
+		d.MerchantResponse = note
+		if accept {
+			log.Info("[DISPUTE] Merchant accepted the dispute.")
+			return s.resolveDispute(ctx, tx, d, t, true, note, ActorMerchant, log)
+		}
+		log.Info("[DISPUTE] Merchant contested the dispute.")
+		if err := s.Repos.Disputes.UpdateStatus(ctx, tx, d.DisputeID, "UnderReview", ""); err != nil {
+			return err
+		}
+		return s.recordDisputeEvent(ctx, tx, d, "UnderReview", note, ActorMerchant, log)
+	})
+}
+
+// ---- RESOLVE (admin) ----
+func (s *TransactionService) ResolveDispute(ctx context.Context, disputeID int64, won bool, reason string) (*DisputeResult, error) {
+	return s.updateDispute(ctx, disputeID, "dispute.resolve", func(ctx context.Context, tx pgx.Tx, d *models.Dispute, t *models.Transaction, log *utils.TxLogger) error {
+		if d.Status != "Open" && d.Status != "UnderReview" {
+			return Failf(utils.CodeDisputeInvalidStatus, "dispute is %s", d.Status)
+		}
+		return s.resolveDispute(ctx, tx, d, t, won, reason, ActorAdmin, log)
+	})
+}
+
+// ExpireDispute resolves an Open dispute whose merchant response window has
+// passed (see DisputeRepo.ListExpired) in the cardholder's favour. Disputes
+// that are no longer Open are left alone, so concurrent sweepers are harmless.
+func (s *TransactionService) ExpireDispute(ctx context.Context, disputeID int64) (*DisputeResult, error) {
+	return s.updateDispute(ctx, disputeID, "dispute.expire", func(ctx context.Context, tx pgx.Tx, d *models.Dispute, t *models.Transaction, log *utils.TxLogger) error {
+		if d.Status != "Open" {
+			log.Info(fmt.Sprintf("Dispute is %s, skipping.", d.Status))
+			return nil
+		}
+		reason := fmt.Sprintf("no merchant response by %s", d.RespondBy.Format(time.RFC3339))
+		return s.resolveDispute(ctx, tx, d, t, true, reason, ActorSystem, log)
+	})
+}
+
+func (s *TransactionService) GetDispute(ctx context.Context, disputeID int64) (*models.Dispute, error) {
+	d, err := s.Repos.Disputes.GetByID(ctx, s.Pool, disputeID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, Fail(utils.CodeDisputeNotFound)
+		}
+		return nil, err
+	}
+	if d.Events, err = s.Repos.Disputes.ListEvents(ctx, s.Pool, disputeID); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListDisputes filters by user (userID > 0) and/or status.
+func (s *TransactionService) ListDisputes(ctx context.Context, userID int, status string) ([]models.Dispute, error) {
+	return s.Repos.Disputes.List(ctx, s.Pool, userID, status, disputeListLimit)
+}
+
+// updateDispute locks user, transaction and dispute (in that order, as
+// refunds do) and runs fn on the locked rows.
+func (s *TransactionService) updateDispute(ctx context.Context, disputeID int64, op string, fn func(ctx context.Context, tx pgx.Tx, d *models.Dispute, t *models.Transaction, log *utils.TxLogger) error) (*DisputeResult, error) {
+	ctx = utils.WithLogFields(ctx, "dispute_id", disputeID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: %s, Dispute: %d", op, disputeID))
+
+		d, err := s.Repos.Disputes.GetByID(ctx, tx, disputeID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, Fail(utils.CodeDisputeNotFound)
+			}
+			return nil, err
+		}
+		if _, err := s.lockUser(ctx, tx, d.UserID); err != nil {
+			return nil, err
+		}
+		t, err := s.Transactions.GetByIDForUpdate(ctx, tx, int(d.TransactionID))
+		if err != nil {
+			return nil, err
+		}
+		if d, err = s.Repos.Disputes.GetByIDForUpdate(ctx, tx, disputeID); err != nil {
+			return nil, err
+		}
+		if err := fn(ctx, tx, d, t, log); err != nil {
+			return nil, err
+		}
+		return &DisputeResult{Dispute: d}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, op, err, steps)
+	}
+	res := anyRes.(*DisputeResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("dispute updated", "op", op, "status", res.Dispute.Status)
+	return res, nil
+}
+
+// resolveDispute finalizes (won) or reverses (lost) the provisional credit.
+// A won installment purchase also cancels the remaining schedule: the
+// provisional credit covered the full amount, so the unposted part moves
+// from the reservation to the balance.
+func (s *TransactionService) resolveDispute(ctx context.Context, tx pgx.Tx, d *models.Dispute, t *models.Transaction, won bool, reason, actor string, log *utils.TxLogger) error {
+	status, txStatus := "Lost", "Paid"
+	if won {
+		status, txStatus = "Won", "ChargedBack"
+		plan, err := s.lockInstallmentPlan(ctx, tx, d.TransactionID)
+		if err != nil {
+			return err
+		}
+		if plan != nil {
+			if err := s.reverseSettledPurchase(ctx, tx, t, 0, log); err != nil {
+				return err
+			}
+		}
+		log.Info("[DISPUTE] Won: provisional credit is final.")
+	} else {
+		log.Info(fmt.Sprintf("[DISPUTE] Lost: reversing provisional credit (+$%.2f balance, %+d points).", d.Amount, -d.PointChange))
+		if _, err := s.Users.UpdateBalanceAndPoints(ctx, tx, d.UserID, d.Amount, -d.PointChange); err != nil {
+			return err
+		}
+		if d.CardID != nil {
+			if err := s.Cards.UpdateBalance(ctx, tx, *d.CardID, d.Amount); err != nil {
+				return err
+			}
+		}
+		if d.PointChange != 0 {
+			if err := s.Points.Insert(ctx, tx, d.UserID, d.TransactionID, -d.PointChange, "Dispute Lost"); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.Transactions.UpdateStatus(ctx, tx, t.TransactionID, txStatus); err != nil {
+		return err
+	}
+	t.Status = txStatus
+	if err := s.Repos.Disputes.UpdateStatus(ctx, tx, d.DisputeID, status, reason); err != nil {
+		return err
+	}
+	if err := s.recordDisputeEvent(ctx, tx, d, status, reason, actor, log); err != nil {
+		return err
+	}
+	d.ResolutionReason = reason
+	return s.emitDisputeEvent(ctx, tx, log, EventDisputeResolved, t, d)
+}
+
+// recordDisputeEvent appends the transition d.Status -> status and updates d.
+// An empty status records the opening of d.
+func (s *TransactionService) recordDisputeEvent(ctx context.Context, tx pgx.Tx, d *models.Dispute, status, note, actor string, log *utils.TxLogger) error {
+	from := d.Status
+	if status == "" {
+		from, status = "", d.Status
+	}
+	if err := s.Repos.Disputes.InsertEvent(ctx, tx, models.DisputeEvent{
+		DisputeID: d.DisputeID, OldStatus: from, NewStatus: status, Note: note, Actor: actor,
+	}); err != nil {
+		return err
+	}
+	if from != "" {
+		log.Info(fmt.Sprintf("[DISPUTE] Dispute %d: %s -> %s by %s.", d.DisputeID, from, status, actor))
+	}
+	d.Status = status
+	return nil
+}
+
+func (s *TransactionService) emitDisputeEvent(ctx context.Context, tx pgx.Tx, log *utils.TxLogger, eventType string, t *models.Transaction, d *models.Dispute) error {
+	ev := transactionEvent(t)
+	ev.Dispute = &models.DisputeRef{DisputeID: d.DisputeID, Status: d.Status, ReasonCode: d.ReasonCode}
+	return s.emitTransactionEvent(ctx, tx, log, eventType, ev)
+}
+
+// DisputeSweeper resolves Open disputes past their merchant response window
+// every Interval.
+type DisputeSweeper struct {
+	Pool     *pgxpool.Pool
+	Svc      *TransactionService
+	Interval time.Duration
+	Batch    int
+}
+
+func (sw *DisputeSweeper) Run(ctx context.Context) {
+	logger := utils.LoggerFrom(ctx).With("component", "dispute_sweeper")
+	ticker := time.NewTicker(sw.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := sw.Svc.Repos.Disputes.ListExpired(ctx, sw.Pool, sw.Batch)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("list expired disputes failed", "error", err)
+				}
+				continue
+			}
+			for _, d := range expired {
+				_, _ = sw.Svc.ExpireDispute(ctx, d.DisputeID)
+			}
+		}
+	}
+}
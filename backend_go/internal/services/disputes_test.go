@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+)
+
+func openDispute(t *testing.T, s *TransactionService, txID int64) *models.Dispute {
+	t.Helper()
+	res, err := s.OpenDispute(context.Background(), OpenDisputeRequest{UserID: 1, TransactionID: int(txID), ReasonCode: "not_received"})
+	if err != nil {
+		t.Fatalf("open dispute on %d: %v", txID, err)
+	}
+	return res.Dispute
+}
+
+func disputeStatuses(t *testing.T, s *TransactionService, disputeID int64) []string {
+	t.Helper()
+	d, err := s.GetDispute(context.Background(), disputeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, e := range d.Events {
+		out = append(out, e.OldStatus+">"+e.NewStatus)
+	}
+	return out
+}
+
+func TestDisputeProvisionalCredit(t *testing.T) {
+	s, mem := newMemoryService(t)
+	putCard(t, mem, 1, 5000)
+	res := pay(t, s, PaymentRequest{UserID: 1, Amount: 80, Merchant: "Steam"})
+
+	d := openDispute(t, s, res.TransactionID)
+	if d.Status != "Open" || d.Amount != 80 || d.PointChange != -160 {
+		t.Fatalf("dispute = %+v", d)
+	}
+	if tr := getTransaction(t, s, res.TransactionID); tr.Status != "Disputed" {
+		t.Fatalf("transaction status = %s", tr.Status)
+	}
+	if u := getUser(t, s, 1); u.Balance != 0 || u.CurrentPoints != 1000 {
+		t.Fatalf("user after provisional credit = %+v", u)
+	}
+	if c := getCard(t, s, 1); c.Balance != 0 {
+		t.Fatalf("card after provisional credit = %+v", c)
+	}
+
+	// One active dispute per transaction, and only Paid ones
+	_, err := s.OpenDispute(context.Background(), OpenDisputeRequest{UserID: 1, TransactionID: int(res.TransactionID), ReasonCode: "fraud"})
+	wantCode(t, err, utils.CodeTxInvalidStatus)
+	_, err = s.OpenDispute(context.Background(), OpenDisputeRequest{UserID: 1, TransactionID: int(res.TransactionID), ReasonCode: "bogus"})
+	wantCode(t, err, utils.CodeInvalidDispute)
+}
+
+func TestDisputeStateMachine(t *testing.T) {
+	cases := []struct {
+		name     string
+		resolve  func(s *TransactionService, d *models.Dispute) (*DisputeResult, error)
+		status   string
+		txStatus string
+		balance  float64
+		events   []string
+	}{
+		{
+			name: "merchant accepts",
+			resolve: func(s *TransactionService, d *models.Dispute) (*DisputeResult, error) {
+				return s.RespondToDispute(context.Background(), "Steam", d.DisputeID, true, "refund issued")
+			},
+			status: "Won", txStatus: "ChargedBack", balance: 0,
+			events: []string{">Open", "Open>Won"},
+		},
+		{
+			name: "merchant contests, admin rules for the merchant",
+			resolve: func(s *TransactionService, d *models.Dispute) (*DisputeResult, error) {
+				if _, err := s.RespondToDispute(context.Background(), "Steam", d.DisputeID, false, "delivered"); err != nil {
+					return nil, err
+				}
+				return s.ResolveDispute(context.Background(), d.DisputeID, false, "proof of delivery")
+			},
+			status: "Lost", txStatus: "Paid", balance: 80,
+			events: []string{">Open", "Open>UnderReview", "UnderReview>Lost"},
+		},
+		{
+			name: "no merchant response",
+			resolve: func(s *TransactionService, d *models.Dispute) (*DisputeResult, error) {
+				return s.ExpireDispute(context.Background(), d.DisputeID)
+			},
+			status: "Won", txStatus: "ChargedBack", balance: 0,
+			events: []string{">Open", "Open>Won"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, mem := newMemoryService(t)
+			res := pay(t, s, PaymentRequest{UserID: 1, Amount: 80, Merchant: "Steam"})
+			d := openDispute(t, s, res.TransactionID)
+
+			if c.name == "no merchant response" {
+				expired, _ := s.Repos.Disputes.ListExpired(context.Background(), nil, 10)
+				if len(expired) != 0 {
+					t.Fatalf("expired before the response window: %+v", expired)
+				}
+				mem.Now = func() time.Time { return time.Now().Add(s.Disputes.ResponseWindow + time.Minute) }
+				expired, _ = s.Repos.Disputes.ListExpired(context.Background(), nil, 10)
+				if len(expired) != 1 || expired[0].DisputeID != d.DisputeID {
+					t.Fatalf("expired = %+v", expired)
+				}
+			}
+
+			r, err := c.resolve(s, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Dispute.Status != c.status {
+				t.Fatalf("dispute status = %s, want %s", r.Dispute.Status, c.status)
+			}
+			if tr := getTransaction(t, s, res.TransactionID); tr.Status != c.txStatus {
+				t.Fatalf("transaction status = %s, want %s", tr.Status, c.txStatus)
+			}
+			wantPoints := 1000
+			if c.status == "Lost" {
+				wantPoints = 1160
+			}
+			if u := getUser(t, s, 1); u.Balance != c.balance || u.CurrentPoints != wantPoints {
+				t.Fatalf("user = %+v", u)
+			}
+			if got := disputeStatuses(t, s, d.DisputeID); !slices.Equal(got, c.events) {
+				t.Fatalf("events = %v, want %v", got, c.events)
+			}
+
+			// Resolved disputes are final
+			_, err = s.ResolveDispute(context.Background(), d.DisputeID, true, "again")
+			wantCode(t, err, utils.CodeDisputeInvalidStatus)
+			_, err = s.RespondToDispute(context.Background(), "Steam", d.DisputeID, true, "again")
+			wantCode(t, err, utils.CodeDisputeInvalidStatus)
+		})
+	}
+}
+
+func TestDisputeLostCannotBeReopened(t *testing.T) {
+	s, _ := newMemoryService(t)
+	res := pay(t, s, PaymentRequest{UserID: 1, Amount: 80, Merchant: "Steam"})
+	d := openDispute(t, s, res.TransactionID)
+	if _, err := s.ResolveDispute(context.Background(), d.DisputeID, false, "proof of delivery"); err != nil {
+		t.Fatal(err)
+	}
+	if tr := getTransaction(t, s, res.TransactionID); tr.Status != "Paid" {
+		t.Fatalf("transaction status = %s", tr.Status)
+	}
+
+	// No second provisional credit on the same purchase
+	_, err := s.OpenDispute(context.Background(), OpenDisputeRequest{UserID: 1, TransactionID: int(res.TransactionID), ReasonCode: "fraud"})
+	wantCode(t, err, utils.CodeInvalidDispute)
+	if u := getUser(t, s, 1); u.Balance != 80 || u.CurrentPoints != 1160 {
+		t.Fatalf("user after rejected dispute = %+v", u)
+	}
+	if tr := getTransaction(t, s, res.TransactionID); tr.Status != "Paid" {
+		t.Fatalf("transaction status = %s", tr.Status)
+	}
+	if ds, _ := s.Repos.Disputes.List(context.Background(), nil, 1, "", 10); len(ds) != 1 {
+		t.Fatalf("disputes = %+v", ds)
+	}
+}
+
+func TestDisputeResponseScopedToMerchant(t *testing.T) {
+	s, _ := newMemoryService(t)
+	res := pay(t, s, PaymentRequest{UserID: 1, Amount: 80, Merchant: "Steam"})
+	d := openDispute(t, s, res.TransactionID)
+
+	_, err := s.RespondToDispute(context.Background(), "Amazon", d.DisputeID, true, "")
+	wantCode(t, err, utils.CodeTxForbidden)
+	if got, _ := s.GetDispute(context.Background(), d.DisputeID); got.Status != "Open" {
+		t.Fatalf("status = %s", got.Status)
+	}
+}
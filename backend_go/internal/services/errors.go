@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"backend_go/internal/utils"
+)
+
+// ---- TxError with HTTP + Code ----
+// Code/Msg/HTTP come from the public error catalogue (utils.LookupError);
+// Detail is an internal explanation only exposed in debug verbosity.
+type TxError struct {
+	HTTP   int
+	Code   string
+	Msg    string
+	Detail string
+	Steps  []utils.Step
+}
+
+func (e *TxError) Error() string {
+	if e.Detail != "" {
+		return e.Msg + ": " + e.Detail
+	}
+	return e.Msg
+}
+
+func NewTxError(httpStatus int, code, msg string) *TxError {
+	return &TxError{HTTP: httpStatus, Code: code, Msg: msg}
+}
+
+// Fail builds a TxError for a catalogued error code.
+func Fail(code string) *TxError {
+	spec := utils.LookupError(code)
+	return &TxError{HTTP: spec.HTTP, Code: spec.Code, Msg: spec.Message}
+}
+
+// Failf is Fail with an internal detail message.
+func Failf(code, format string, args ...any) *TxError {
+	te := Fail(code)
+	te.Detail = fmt.Sprintf(format, args...)
+	return te
+}
+
+func asTxError(err error) (*TxError, bool) {
+	var te *TxError
+	if errors.As(err, &te) {
+		return te, true
+	}
+	return nil, false
+}
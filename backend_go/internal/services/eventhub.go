@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"backend_go/internal/models"
+	"backend_go/internal/repo"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const accountEventsChannelPrefix = "account-events:user:"
+
+// EventHub fans committed outbox events out to SSE subscribers on every
+// backend instance: publishers PUBLISH to Redis, and each instance keeps a
+// single PSUBSCRIBE that dispatches to its local subscribers.
+type EventHub struct {
+	Redis *redis.Client
+	Pool  *pgxpool.Pool
+
+	mu   sync.Mutex
+	subs map[int]map[chan models.EventEnvelope]struct{}
+}
+
+func NewEventHub(rdb *redis.Client, pool *pgxpool.Pool) *EventHub {
+	return &EventHub{Redis: rdb, Pool: pool, subs: make(map[int]map[chan models.EventEnvelope]struct{})}
+}
+
+func accountEventsChannel(userID int) string {
+	return accountEventsChannelPrefix + strconv.Itoa(userID)
+}
+
+// Publish broadcasts committed events. Failures are only logged: subscribers
+// recover missed events from the outbox via Last-Event-ID.
+func (h *EventHub) Publish(ctx context.Context, events []models.EventEnvelope) {
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err == nil {
+			err = h.Redis.Publish(ctx, accountEventsChannel(e.UserID), b).Err()
+		}
+		if err != nil {
+			utils.LoggerFrom(ctx).Warn("publish account event failed", "event_id", e.ID, "error", err)
+		}
+	}
+}
+
+// Run consumes the Redis subscription until ctx is cancelled.
+func (h *EventHub) Run(ctx context.Context) {
+	logger := utils.LoggerFrom(ctx).With("component", "event_hub")
+	ps := h.Redis.PSubscribe(ctx, accountEventsChannelPrefix+"*")
+	defer ps.Close()
+
+	ch := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			userID, err := strconv.Atoi(strings.TrimPrefix(msg.Channel, accountEventsChannelPrefix))
+			if err != nil {
+				continue
+			}
+			var e models.EventEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				logger.Warn("bad account event payload", "channel", msg.Channel, "error", err)
+				continue
+			}
+			h.dispatch(userID, e)
+		}
+	}
+}
+
+func (h *EventHub) dispatch(userID int, e models.EventEnvelope) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[userID] {
+		select {
+		case ch <- e:
+		default:
+			// Slow consumer: drop; it can resume with Last-Event-ID.
+		}
+	}
+}
+
+// Subscribe registers a local subscriber for userID. The returned cancel
+// func must be called to unsubscribe.
+func (h *EventHub) Subscribe(userID int) (<-chan models.EventEnvelope, func()) {
+	ch := make(chan models.EventEnvelope, 64)
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[chan models.EventEnvelope]struct{})
+	}
+	h.subs[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs[userID], ch)
+		if len(h.subs[userID]) == 0 {
+			delete(h.subs, userID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// EventsSince replays the user's outbox events after lastEventID (for resume).
+func (h *EventHub) EventsSince(ctx context.Context, userID int, lastEventID int64, limit int) ([]models.EventEnvelope, error) {
+	return repo.ListOutboxEventsForUser(ctx, h.Pool, userID, lastEventID, limit)
+}
+
+// ---- per-transaction collection of events to publish after COMMIT ----
+
+type pendingEventsCtxKey struct{}
+
+type pendingEvents struct {
+	events []models.EventEnvelope
+}
+
+func withPendingEvents(ctx context.Context) (context.Context, *pendingEvents) {
+	p := &pendingEvents{}
+	return context.WithValue(ctx, pendingEventsCtxKey{}, p), p
+}
+
+func addPendingEvent(ctx context.Context, e *models.EventEnvelope) {
+	if p, ok := ctx.Value(pendingEventsCtxKey{}).(*pendingEvents); ok {
+		p.events = append(p.events, *e)
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"backend_go/internal/models"
+	"backend_go/internal/repo"
+	"backend_go/internal/utils"
+)
+
+// Transaction lifecycle event types written to the outbox.
+const (
+	EventTransactionAuthorized = "transaction.authorized"
+	EventTransactionSettled    = "transaction.settled"
+	EventTransactionVoided     = "transaction.voided"
+	EventTransactionRefunded   = "transaction.refunded"
+)
+
+// Account event types: points, status and limit changes outside transactions.
+const (
+	EventPointsTransferred         = "points.transferred"
+	EventPointsRedeemed            = "points.redeemed"
+	EventAccountStatusChanged      = "account.status_changed"
+	EventAccountCreditLimitChanged = "account.credit_limit_changed"
+	EventCardStatusChanged         = "card.status_changed"
+)
+
+var knownEventTypes = map[string]bool{
+	EventTransactionAuthorized:  true,
+	EventTransactionSettled:     true,
+	EventTransactionVoided:      true,
+	EventTransactionRefunded:    true,
+	EventTransactionIncremented: true,
+	EventTransactionExpired:     true,
+	EventInstallmentPosted:      true,
+	EventDisputeOpened:          true,
+	EventDisputeResolved:        true,
+
+	EventPointsTransferred:         true,
+	EventPointsRedeemed:            true,
+	EventAccountStatusChanged:      true,
+	EventAccountCreditLimitChanged: true,
+	EventCardStatusChanged:         true,
+}
+
+func transactionEvent(t *models.Transaction) models.TransactionEvent {
+	ev := models.TransactionEvent{
+		TransactionID: int64(t.TransactionID),
+		UserID:        t.UserID,
+		Amount:        t.Amount,
+		Status:        t.Status,
+		PointChange:   t.PointChange,
+		Merchant:      t.Merchant,
+		CardID:        t.CardID,
+
+		OriginalAmount:   t.OriginalAmount,
+		OriginalCurrency: t.OriginalCurrency,
+	}
+	if t.SourceTransactionID != nil {
+		src := int64(*t.SourceTransactionID)
+		ev.SourceTransactionID = &src
+	}
+	return ev
+}
+
+// emitTransactionEvent writes an outbox row. It must be called with the same
+// tx as the state change it describes so both commit or roll back together.
+// The event also carries the account snapshot as seen inside the tx, and is
+// published to live subscribers once withTransaction commits.
+func (s *TransactionService) emitTransactionEvent(ctx context.Context, q repo.Querier, log *utils.TxLogger, eventType string, ev models.TransactionEvent) error {
+	snap, err := s.accountSnapshot(ctx, q, ev.UserID)
+	if err != nil {
+		return err
+	}
+	ev.Account = snap
+	if err := s.queueEvent(ctx, q, eventType, ev.UserID, &ev.TransactionID, ev); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("[OUTBOX] %s queued for transaction %d.", eventType, ev.TransactionID))
+	return nil
+}
+
+// emitAccountEvent is emitTransactionEvent for changes without a
+// transaction; the outbox row has no transaction_id.
+func (s *TransactionService) emitAccountEvent(ctx context.Context, q repo.Querier, log *utils.TxLogger, eventType string, ev models.AccountEvent) error {
+	snap, err := s.accountSnapshot(ctx, q, ev.UserID)
+	if err != nil {
+		return err
+	}
+	ev.Account = snap
+	if err := s.queueEvent(ctx, q, eventType, ev.UserID, nil, ev); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("[OUTBOX] %s queued for user %d.", eventType, ev.UserID))
+	return nil
+}
+
+func (s *TransactionService) accountSnapshot(ctx context.Context, q repo.Querier, userID int) (*models.AccountSnapshot, error) {
+	u, err := s.Users.GetByID(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AccountSnapshot{Balance: u.Balance, CurrentPoints: u.CurrentPoints}, nil
+}
+
+func (s *TransactionService) queueEvent(ctx context.Context, q repo.Querier, eventType string, userID int, txID *int64, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	e, err := s.Outbox.Insert(ctx, q, eventType, userID, txID, payload)
+	if err != nil {
+		return err
+	}
+	addPendingEvent(ctx, e)
+	return nil
+}
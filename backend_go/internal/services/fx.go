@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+)
+
+// ErrUnknownCurrency is returned by a RateProvider that has no rate for a currency.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// RateProvider supplies FX rates: Rate(from, to) is the price of one unit
+// of from in to.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// FXConfig controls foreign-currency payments.
+type FXConfig struct {
+	BillingCurrency string
+	// FeeRate is the foreign transaction fee as a fraction of the converted amount (0.015 = 1.5%)
+	FeeRate float64
+	// RefundAtCurrentRate converts refunds at today's rate instead of the rate of the purchase
+	RefundAtCurrentRate bool
+}
+
+func DefaultFXConfig() FXConfig {
+	return FXConfig{BillingCurrency: "USD", FeeRate: 0.015}
+}
+
+// FileRateProvider reads rates from a local JSON file and reloads it when
+// the file changes:
+//
+//	{"base": "USD", "rates": {"USD": 1, "EUR": 1.085, "JPY": 0.0067}}
+//
+// Each rate is the value of one unit of the currency in base.
+type FileRateProvider struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	rates   map[string]float64
+}
+
+type rateFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (p *FileRateProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	rates, err := p.load()
+	if err != nil {
+		return 0, err
+	}
+	f, ok := rates[from]
+	if !ok || f <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, from)
+	}
+	t, ok := rates[to]
+	if !ok || t <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+	}
+	return f / t, nil
+}
+
+func (p *FileRateProvider) load() (map[string]float64, error) {
+	st, err := os.Stat(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rates != nil && st.ModTime().Equal(p.modTime) {
+		return p.rates, nil
+	}
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	var rf rateFile
+	if err := json.Unmarshal(b, &rf); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", p.Path, err)
+	}
+	if rf.Rates == nil {
+		rf.Rates = make(map[string]float64)
+	}
+	if rf.Base != "" {
+		rf.Rates[rf.Base] = 1
+	}
+	p.rates, p.modTime = rf.Rates, st.ModTime()
+	return p.rates, nil
+}
+
+// round2 rounds a money amount to cents.
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// fxQuote is the conversion of a foreign-currency payment into the billing currency.
+type fxQuote struct {
+	Currency       string
+	OriginalAmount float64
+	Rate           float64
+	Billed         float64 // converted amount, before the fee
+	Fee            float64
+}
+
+func (s *TransactionService) billingCurrency() string {
+	if s.FX.BillingCurrency == "" {
+		return DefaultFXConfig().BillingCurrency
+	}
+	return s.FX.BillingCurrency
+}
+
+// quoteFX converts amount in currency to the billing currency. It returns
+// nil for payments already in the billing currency.
+func (s *TransactionService) quoteFX(ctx context.Context, currency string, amount float64, log *utils.TxLogger) (*fxQuote, error) {
+	billing := s.billingCurrency()
+	if currency == "" || currency == billing {
+		return nil, nil
+	}
+	if !currencyCode.MatchString(currency) {
+		return nil, Failf(utils.CodeUnsupportedCurrency, "invalid currency code %q", currency)
+	}
+	rate, err := s.fxRate(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	q := &fxQuote{Currency: currency, OriginalAmount: amount, Rate: rate, Billed: round2(amount * rate)}
+	q.Fee = round2(q.Billed * s.FX.FeeRate)
+	log.Info(fmt.Sprintf("[FX] %.2f %s x %.8f = %.2f %s, foreign transaction fee %.2f (%.2f%%).", amount, currency, rate, q.Billed, billing, q.Fee, s.FX.FeeRate*100))
+	return q, nil
+}
+
+// fxRate is the current currency -> billing currency rate, rounded to the
+// precision stored in Transactions.fx_rate.
+func (s *TransactionService) fxRate(ctx context.Context, currency string) (float64, error) {
+	if s.Rates == nil {
+		return 0, Failf(utils.CodeFXRateUnavailable, "no rate provider configured")
+	}
+	rate, err := s.Rates.Rate(ctx, currency, s.billingCurrency())
+	if errors.Is(err, ErrUnknownCurrency) {
+		return 0, Failf(utils.CodeUnsupportedCurrency, "%v", err)
+	}
+	if err != nil {
+		return 0, Failf(utils.CodeFXRateUnavailable, "%v", err)
+	}
+	return math.Round(rate*1e8) / 1e8, nil
+}
+
+// refundFX returns the billing amount to refund for a foreign-currency
+// transaction t and the rate used. At the original rate that is t.Amount;
+// at the current rate the conversion difference is added (fee and points
+// discount of the purchase are kept as they were).
+func (s *TransactionService) refundFX(ctx context.Context, t *models.Transaction, log *utils.TxLogger) (float64, float64, error) {
+	if !s.FX.RefundAtCurrentRate {
+		log.Info(fmt.Sprintf("[FX] Refunding at the original rate %.8f.", *t.FXRate))
+		return t.Amount, *t.FXRate, nil
+	}
+	rate, err := s.fxRate(ctx, *t.OriginalCurrency)
+	if err != nil {
+		return 0, 0, err
+	}
+	diff := round2(*t.OriginalAmount*rate) - round2(*t.OriginalAmount**t.FXRate)
+	log.Info(fmt.Sprintf("[FX] Refunding at the current rate %.8f (purchase rate %.8f): %+.2f %s.", rate, *t.FXRate, diff, s.billingCurrency()))
+	return round2(t.Amount + diff), rate, nil
+}
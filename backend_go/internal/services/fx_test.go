@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"backend_go/internal/utils"
+)
+
+// staticRates values one unit of each currency in USD.
+type staticRates map[string]float64
+
+func (r staticRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	if r[from] == 0 || r[to] == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, from)
+	}
+	return r[from] / r[to], nil
+}
+
+func TestQuoteFXRounding(t *testing.T) {
+	s := &TransactionService{FX: DefaultFXConfig(), Rates: staticRates{"USD": 1, "EUR": 1.0853, "JPY": 0.006712345678}}
+	cases := []struct {
+		currency          string
+		amount            float64
+		rate, billed, fee float64
+	}{
+		// 19.99 x 1.0853 = 21.695147; fee 1.5% of 21.70 = 0.3255
+		{"EUR", 19.99, 1.0853, 21.70, 0.33},
+		// rates keep 8 decimals, as Transactions.fx_rate does
+		{"JPY", 1500, 0.00671235, 10.07, 0.15},
+		{"EUR", 0.01, 1.0853, 0.01, 0},
+	}
+	for _, c := range cases {
+		q, err := s.quoteFX(context.Background(), c.currency, c.amount, utils.NewTxLogger())
+		if err != nil {
+			t.Fatalf("%s %.2f: %v", c.currency, c.amount, err)
+		}
+		if q.Rate != c.rate || q.Billed != c.billed || q.Fee != c.fee {
+			t.Errorf("%s %.2f: rate %v billed %v fee %v, want %v %v %v", c.currency, c.amount, q.Rate, q.Billed, q.Fee, c.rate, c.billed, c.fee)
+		}
+	}
+
+	if q, err := s.quoteFX(context.Background(), "USD", 10, utils.NewTxLogger()); q != nil || err != nil {
+		t.Fatalf("billing currency quoted: %+v %v", q, err)
+	}
+	_, err := s.quoteFX(context.Background(), "GBP", 10, utils.NewTxLogger())
+	wantCode(t, err, utils.CodeUnsupportedCurrency)
+	_, err = s.quoteFX(context.Background(), "EURO", 10, utils.NewTxLogger())
+	wantCode(t, err, utils.CodeUnsupportedCurrency)
+}
+
+func TestForeignCurrencyPaymentAndRefund(t *testing.T) {
+	rates := staticRates{"USD": 1, "EUR": 1.0853}
+	for _, current := range []bool{false, true} {
+		s, _ := newMemoryService(t)
+		s.Rates = rates
+		s.FX.RefundAtCurrentRate = current
+
+		res := pay(t, s, PaymentRequest{UserID: 1, Amount: 19.99, Merchant: "7-11", Currency: "eur"})
+		// 21.70 converted + 0.33 fee; points on the converted amount only
+		if res.FinalAmount != 22.03 || res.FXFee != 0.33 || res.PointsEarned != 21 || res.OriginalCurrency != "EUR" {
+			t.Fatalf("payment = %+v", res)
+		}
+
+		// EUR rises: 19.99 x 1.1 = 21.99, 0.29 more than at the purchase rate
+		s.Rates = staticRates{"USD": 1, "EUR": 1.1}
+		r, err := s.RefundTransaction(context.Background(), 1, int(res.TransactionID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		refund := getTransaction(t, s, r.RefundTransactionID)
+		want, wantRate := -22.03, 1.0853
+		if current {
+			want, wantRate = -22.32, 1.1
+		}
+		if refund.Amount != want || refund.FXRate == nil || *refund.FXRate != wantRate || *refund.OriginalAmount != -19.99 || refund.FXFee != -0.33 {
+			t.Errorf("current=%v: refund = %+v", current, refund)
+		}
+		if u := getUser(t, s, 1); round2(u.Balance) != round2(22.03+want) {
+			t.Errorf("current=%v: balance = %v", current, u.Balance)
+		}
+	}
+}
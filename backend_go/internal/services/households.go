@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend_go/internal/models"
+	"backend_go/internal/repo"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type HouseholdResult struct {
+	Household *models.Household `json:"household"`
+	Steps     []utils.Step      `json:"steps,omitempty"`
+}
+
+// lockHousehold locks the household row; lock users first (user -> household).
+func (s *TransactionService) lockHousehold(ctx context.Context, tx pgx.Tx, householdID int64) (*models.Household, error) {
+	h, err := s.Households.GetByIDForUpdate(ctx, tx, householdID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, Fail(utils.CodeHouseholdNotFound)
+	}
+	return h, err
+}
+
+// lockUserHousehold locks the household the user belongs to.
+func (s *TransactionService) lockUserHousehold(ctx context.Context, tx pgx.Tx, userID int) (*models.Household, error) {
+	id, err := s.Households.GetUserHouseholdID(ctx, tx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, Failf(utils.CodeHouseholdMembership, "user %d is not in a household", userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.lockHousehold(ctx, tx, id)
+}
+
+// householdOp runs fn in a transaction and returns the household with its members.
+func (s *TransactionService) householdOp(ctx context.Context, op string, fn func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (int64, error)) (*HouseholdResult, error) {
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		id, err := fn(ctx, tx, log)
+		if err != nil {
+			return nil, err
+		}
+		return s.getHousehold(ctx, tx, id)
+	})
+	if err != nil {
+		return nil, txFailure(ctx, op, err, steps)
+	}
+	return &HouseholdResult{Household: anyRes.(*models.Household), Steps: steps}, nil
+}
+
+func (s *TransactionService) getHousehold(ctx context.Context, q repo.Querier, householdID int64) (*models.Household, error) {
+	h, err := s.Households.GetByID(ctx, q, householdID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, Fail(utils.CodeHouseholdNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if h.Members, err = s.Households.ListMembers(ctx, q, householdID); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *TransactionService) GetHousehold(ctx context.Context, householdID int64) (*models.Household, error) {
+	return s.getHousehold(ctx, s.Pool, householdID)
+}
+
+// addMember fails with HOUSEHOLD_MEMBERSHIP if the user is in a household.
+func (s *TransactionService) addMember(ctx context.Context, tx pgx.Tx, householdID int64, userID int) error {
+	err := s.Households.AddMember(ctx, tx, householdID, userID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return Failf(utils.CodeHouseholdMembership, "user %d is already in a household", userID)
+	}
+	return err
+}
+
+// CreateHousehold opens a household owned (and joined) by ownerUserID.
+func (s *TransactionService) CreateHousehold(ctx context.Context, ownerUserID int, name string) (*HouseholdResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, ownerUserID)
+	res, err := s.householdOp(ctx, "household.create", func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (int64, error) {
+		log.Raw(fmt.Sprintf("> Processing: CREATE household, Owner: %d", ownerUserID))
+		name = strings.TrimSpace(name)
+		if name == "" || len(name) > 50 {
+			return 0, Fail(utils.CodeInvalidHousehold)
+		}
+		if _, err := s.lockUser(ctx, tx, ownerUserID); err != nil {
+			return 0, err
+		}
+		h, err := s.Households.Create(ctx, tx, name, ownerUserID)
+		if err != nil {
+			return 0, err
+		}
+		return h.HouseholdID, s.addMember(ctx, tx, h.HouseholdID, ownerUserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	utils.LoggerFrom(ctx).Info("household created", "household_id", res.Household.HouseholdID)
+	return res, nil
+}
+
+// AddHouseholdMember adds userID; only the owner can add members.
+func (s *TransactionService) AddHouseholdMember(ctx context.Context, householdID int64, ownerUserID, userID int) (*HouseholdResult, error) {
+	return s.householdOp(ctx, "household.add_member", func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (int64, error) {
+		log.Raw(fmt.Sprintf("> Processing: ADD member %d, Household: %d", userID, householdID))
+		if _, err := s.lockUser(ctx, tx, userID); err != nil {
+			return 0, err
+		}
+		h, err := s.lockHousehold(ctx, tx, householdID)
+		if err != nil {
+			return 0, err
+		}
+		if h.OwnerUserID != ownerUserID {
+			return 0, Failf(utils.CodeHouseholdMembership, "only the owner can add members")
+		}
+		return householdID, s.addMember(ctx, tx, householdID, userID)
+	})
+}
+
+// RemoveHouseholdMember removes userID, by the owner or by the member
+// leaving. Points already in the pool stay with the household.
+func (s *TransactionService) RemoveHouseholdMember(ctx context.Context, householdID int64, actingUserID, userID int) (*HouseholdResult, error) {
+	return s.householdOp(ctx, "household.remove_member", func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (int64, error) {
+		log.Raw(fmt.Sprintf("> Processing: REMOVE member %d, Household: %d", userID, householdID))
+		h, err := s.lockHousehold(ctx, tx, householdID)
+		if err != nil {
+			return 0, err
+		}
+		if actingUserID != h.OwnerUserID && actingUserID != userID {
+			return 0, Failf(utils.CodeHouseholdMembership, "only the owner or the member can remove a member")
+		}
+		if userID == h.OwnerUserID {
+			return 0, Failf(utils.CodeHouseholdMembership, "the owner cannot leave the household")
+		}
+		ok, err := s.Households.RemoveMember(ctx, tx, householdID, userID)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, Failf(utils.CodeHouseholdMembership, "user %d is not a member", userID)
+		}
+		return householdID, nil
+	})
+}
+
+// ContributeToHousehold moves a member's points into the household pool.
+// It counts against the member's transfer limits like a transfer.
+func (s *TransactionService) ContributeToHousehold(ctx context.Context, householdID int64, userID, points int, note string) (*TransferResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: CONTRIBUTE %d pts, User %d -> Household %d", points, userID, householdID))
+
+		if points <= 0 {
+			return nil, Failf(utils.CodeInvalidTransfer, "points must be positive")
+		}
+		note, err := validTransferNote(note)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.lockUser(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkAccountStatus(ctx, tx, user, log); err != nil {
+			return nil, err
+		}
+		h, err := s.lockUserHousehold(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if h.HouseholdID != householdID {
+			return nil, Failf(utils.CodeHouseholdMembership, "user %d is not a member of household %d", userID, householdID)
+		}
+		available, err := s.availablePoints(ctx, tx, user)
+		if err != nil {
+			return nil, err
+		}
+		if points > available {
+			return nil, Failf(utils.CodeInsufficientPoints, "requested %d pts, %d available", points, available)
+		}
+		if s.Risk != nil {
+			if err := s.Risk.EvaluatePointsTransfer(ctx, tx, userID, nil, points, log); err != nil {
+				return nil, err
+			}
+		}
+
+		t, err := s.Transfers.Create(ctx, tx, userID, nil, &householdID, points, note)
+		if err != nil {
+			return nil, err
+		}
+		u, err := s.Users.UpdateBalanceAndPoints(ctx, tx, userID, 0, -points)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := s.Households.AddPoolPoints(ctx, tx, householdID, points)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Transfers.InsertPoints(ctx, tx, userID, t.TransferID, -points, fmt.Sprintf("Household %d pool", householdID)); err != nil {
+			return nil, err
+		}
+		if err := s.emitAccountEvent(ctx, tx, log, EventPointsTransferred, models.AccountEvent{UserID: userID, Transfer: t}); err != nil {
+			return nil, err
+		}
+		log.Info(fmt.Sprintf("[HOUSEHOLD] %d pts into the pool: user %d pts, pool %d pts.", points, u.CurrentPoints, pool))
+		return &TransferResult{Transfer: t, FromPoints: u.CurrentPoints, ToPoints: pool}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "household.contribute", err, steps)
+	}
+	res := anyRes.(*TransferResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("household contribution", "household_id", householdID, "points", points)
+	return res, nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/repo"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const EventInstallmentPosted = "installment.posted"
+
+var installmentTerms = map[int]bool{3: true, 6: true, 12: true}
+
+// InstallmentConfig controls installment purchases.
+type InstallmentConfig struct {
+	// MinAmount is the smallest billed amount that can be split
+	MinAmount float64
+	// Cycle is the billing cycle; installment n is due (n-1) cycles after settlement
+	Cycle time.Duration
+}
+
+func DefaultInstallmentConfig() InstallmentConfig {
+	return InstallmentConfig{MinAmount: 300, Cycle: 30 * 24 * time.Hour}
+}
+
+// creditUsed is what counts against a credit limit: posted balance, the
+// unposted installments of settled installment purchases and open
+// authorizations.
+func creditUsed(balance, reserved, held float64) float64 {
+	return balance + reserved + held
+}
+
+// newInstallmentPlan splits total into term installments; the last one
+// absorbs the rounding remainder. Due dates are relative to now and are
+// shifted to the settlement time when the purchase settles.
+func (s *TransactionService) newInstallmentPlan(txID int64, userID int, cardID *int64, total float64, term int, now time.Time) (*models.InstallmentPlan, error) {
+	if !installmentTerms[term] {
+		return nil, Failf(utils.CodeInvalidInstallments, "term must be 3, 6 or 12 months, got %d", term)
+	}
+	if total < s.Installments.MinAmount {
+		return nil, Failf(utils.CodeInvalidInstallments, "amount $%.2f is below the installment minimum $%.2f", total, s.Installments.MinAmount)
+	}
+	p := &models.InstallmentPlan{TransactionID: txID, UserID: userID, CardID: cardID, TotalAmount: total, Term: term}
+	each := round2(total / float64(term))
+	for seq := 1; seq <= term; seq++ {
+		amt := each
+		if seq == term {
+			amt = round2(total - each*float64(term-1))
+		}
+		p.Installments = append(p.Installments, models.Installment{
+			Seq: seq, Amount: amt, DueAt: now.Add(time.Duration(seq-1) * s.Installments.Cycle),
+		})
+	}
+	return p, nil
+}
+
+// lockInstallmentPlan returns the plan of purchase txID, or nil if it has none.
+func (s *TransactionService) lockInstallmentPlan(ctx context.Context, tx pgx.Tx, txID int64) (*models.InstallmentPlan, error) {
+	p, err := s.Repos.Installments.GetByTransactionForUpdate(ctx, tx, txID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// settleInstallments reserves the full purchase amount against the credit
+// limit, re-bases the schedule on the settlement time and posts installment 1.
+// The user row must already be locked.
+func (s *TransactionService) settleInstallments(ctx context.Context, tx pgx.Tx, t *models.Transaction, plan *models.InstallmentPlan, log *utils.TxLogger) error {
+	if err := s.Users.UpdateBalanceAndReserved(ctx, tx, t.UserID, 0, t.Amount); err != nil {
+		return err
+	}
+	if t.CardID != nil {
+		if err := s.Cards.UpdateBalanceAndReserved(ctx, tx, *t.CardID, 0, t.Amount); err != nil {
+			return err
+		}
+	}
+	log.Info(fmt.Sprintf("[INSTALLMENT] Reserved $%.2f for a %d-month plan.", t.Amount, plan.Term))
+	if err := s.Repos.Installments.Rebase(ctx, tx, plan.PlanID, s.Installments.Cycle); err != nil {
+		return err
+	}
+	return s.postInstallment(ctx, tx, plan, &plan.Installments[0], log)
+}
+
+// postInstallment moves one installment from reserved to balance.
+func (s *TransactionService) postInstallment(ctx context.Context, tx pgx.Tx, plan *models.InstallmentPlan, inst *models.Installment, log *utils.TxLogger) error {
+	if err := s.Users.UpdateBalanceAndReserved(ctx, tx, plan.UserID, inst.Amount, -inst.Amount); err != nil {
+		return err
+	}
+	if plan.CardID != nil {
+		if err := s.Cards.UpdateBalanceAndReserved(ctx, tx, *plan.CardID, inst.Amount, -inst.Amount); err != nil {
+			return err
+		}
+	}
+	if err := s.Repos.Installments.UpdateInstallmentStatus(ctx, tx, inst.InstallmentID, "Posted"); err != nil {
+		return err
+	}
+	inst.Status = "Posted"
+	plan.PostedCount++
+	if plan.PostedCount == plan.Term {
+		plan.Status = "Completed"
+	}
+	if err := s.Repos.Installments.UpdatePlan(ctx, tx, plan.PlanID, plan.PostedCount, plan.Status); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("[INSTALLMENT] Posted %d/%d: $%.2f (plan %d %s).", inst.Seq, plan.Term, inst.Amount, plan.PlanID, plan.Status))
+	return s.emitTransactionEvent(ctx, tx, log, EventInstallmentPosted, models.TransactionEvent{
+		TransactionID: plan.TransactionID, UserID: plan.UserID, Amount: inst.Amount, Status: "Paid", CardID: plan.CardID,
+		Installment: &models.InstallmentRef{PlanID: plan.PlanID, Seq: inst.Seq, Term: plan.Term},
+	})
+}
+
+// cancelInstallments cancels the Scheduled installments of plan and returns
+// the posted and cancelled (unposted) totals.
+func (s *TransactionService) cancelInstallments(ctx context.Context, tx pgx.Tx, plan *models.InstallmentPlan, log *utils.TxLogger) (posted, unposted float64, err error) {
+	for i := range plan.Installments {
+		inst := &plan.Installments[i]
+		switch inst.Status {
+		case "Posted":
+			posted += inst.Amount
+		case "Scheduled":
+			if err := s.Repos.Installments.UpdateInstallmentStatus(ctx, tx, inst.InstallmentID, "Cancelled"); err != nil {
+				return 0, 0, err
+			}
+			inst.Status = "Cancelled"
+			unposted += inst.Amount
+		}
+	}
+	plan.Status = "Cancelled"
+	if err := s.Repos.Installments.UpdatePlan(ctx, tx, plan.PlanID, plan.PostedCount, plan.Status); err != nil {
+		return 0, 0, err
+	}
+	log.Info(fmt.Sprintf("[INSTALLMENT] Plan %d cancelled: $%.2f posted, $%.2f unposted.", plan.PlanID, round2(posted), round2(unposted)))
+	return round2(posted), round2(unposted), nil
+}
+
+// reverseSettledPurchase undoes the money movement of a settled purchase t:
+// the balance changes by balanceChange (e.g. -t.Amount). For an installment
+// purchase the unposted part is released from the reservation instead of
+// the balance and the rest of the schedule is cancelled.
+func (s *TransactionService) reverseSettledPurchase(ctx context.Context, tx pgx.Tx, t *models.Transaction, balanceChange float64, log *utils.TxLogger) error {
+	plan, err := s.lockInstallmentPlan(ctx, tx, int64(t.TransactionID))
+	if err != nil {
+		return err
+	}
+	reservedChange := 0.0
+	if plan != nil {
+		_, unposted, err := s.cancelInstallments(ctx, tx, plan, log)
+		if err != nil {
+			return err
+		}
+		balanceChange, reservedChange = round2(balanceChange+unposted), -unposted
+	}
+	log.Info(fmt.Sprintf("Restoring Balance: %+.2f", balanceChange))
+	if err := s.Users.UpdateBalanceAndReserved(ctx, tx, t.UserID, balanceChange, reservedChange); err != nil {
+		return err
+	}
+	if t.CardID != nil {
+		if err := s.Cards.UpdateBalanceAndReserved(ctx, tx, *t.CardID, balanceChange, reservedChange); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PostDueInstallment posts one due installment. Safe to run concurrently:
+// the user row is locked first (same order as payments) and the installment
+// is re-checked under lock.
+func (s *TransactionService) PostDueInstallment(ctx context.Context, due repo.DueInstallment) error {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, due.UserID, utils.LogKeyTxID, due.TransactionID)
+	_, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: INSTALLMENT %d of Transaction %d", due.InstallmentID, due.TransactionID))
+
+		if _, err := s.lockUser(ctx, tx, due.UserID); err != nil {
+			return nil, err
+		}
+		plan, err := s.lockInstallmentPlan(ctx, tx, due.TransactionID)
+		if err != nil || plan == nil || plan.Status != "Active" {
+			return nil, err
+		}
+		for i := range plan.Installments {
+			if inst := &plan.Installments[i]; inst.InstallmentID == due.InstallmentID {
+				if inst.Status != "Scheduled" {
+					log.Info(fmt.Sprintf("Installment already %s, skipping.", inst.Status))
+					return nil, nil
+				}
+				return nil, s.postInstallment(ctx, tx, plan, inst, log)
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		utils.LoggerFrom(ctx).Error("installment posting failed", "installment_id", due.InstallmentID, "error", err, "steps", steps)
+		return err
+	}
+	utils.LoggerFrom(ctx).Debug("installment posted", "installment_id", due.InstallmentID, "steps", steps)
+	return nil
+}
+
+func (s *TransactionService) ListInstallmentPlans(ctx context.Context, userID int) ([]models.InstallmentPlan, error) {
+	return s.Repos.Installments.ListByUserID(ctx, s.Pool, userID)
+}
+
+// InstallmentBiller posts due installments every Interval.
+type InstallmentBiller struct {
+	Pool     *pgxpool.Pool
+	Svc      *TransactionService
+	Interval time.Duration
+	Batch    int
+}
+
+func (b *InstallmentBiller) Run(ctx context.Context) {
+	logger := utils.LoggerFrom(ctx).With("component", "installment_biller")
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			due, err := b.Svc.Repos.Installments.ListDue(ctx, b.Pool, b.Batch)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("list due installments failed", "error", err)
+				}
+				continue
+			}
+			for _, d := range due {
+				_ = b.Svc.PostDueInstallment(ctx, d)
+			}
+		}
+	}
+}
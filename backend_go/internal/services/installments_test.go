@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend_go/internal/utils"
+)
+
+func TestNewInstallmentPlanSchedule(t *testing.T) {
+	s := &TransactionService{Installments: DefaultInstallmentConfig()}
+	now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		total float64
+		term  int
+		each  float64
+		last  float64
+	}{
+		{1000, 3, 333.33, 333.34},
+		{1000, 12, 83.33, 83.37},
+		{300, 6, 50, 50},
+		{999.99, 6, 166.67, 166.64},
+	}
+	for _, c := range cases {
+		p, err := s.newInstallmentPlan(1, 1, nil, c.total, c.term, now)
+		if err != nil {
+			t.Fatalf("%.2f / %d: %v", c.total, c.term, err)
+		}
+		if len(p.Installments) != c.term {
+			t.Fatalf("%.2f / %d: %d installments", c.total, c.term, len(p.Installments))
+		}
+		sum := 0.0
+		for i, inst := range p.Installments {
+			want := c.each
+			if i == c.term-1 {
+				want = c.last
+			}
+			if inst.Seq != i+1 || inst.Amount != want || !inst.DueAt.Equal(now.Add(time.Duration(i)*s.Installments.Cycle)) {
+				t.Errorf("%.2f / %d: installment %d = %+v, want $%.2f", c.total, c.term, i+1, inst, want)
+			}
+			sum += inst.Amount
+		}
+		if round2(sum) != c.total {
+			t.Errorf("%.2f / %d: installments sum to %.2f", c.total, c.term, sum)
+		}
+	}
+
+	_, err := s.newInstallmentPlan(1, 1, nil, 1000, 4, now)
+	wantCode(t, err, utils.CodeInvalidInstallments)
+	_, err = s.newInstallmentPlan(1, 1, nil, 299.99, 3, now)
+	wantCode(t, err, utils.CodeInvalidInstallments)
+}
+
+func TestInstallmentPurchasePostsMonthly(t *testing.T) {
+	s, mem := newMemoryService(t)
+	putCard(t, mem, 1, 5000)
+	res := pay(t, s, PaymentRequest{UserID: 1, Amount: 1000, Merchant: "Amazon", Installments: 3})
+
+	// Settlement posts installment 1 and reserves the rest
+	u := getUser(t, s, 1)
+	if u.Balance != 333.33 || round2(u.InstallmentReserved) != 666.67 || u.AuthHold != 0 {
+		t.Fatalf("user after settlement = %+v", u)
+	}
+	if c := getCard(t, s, 1); c.Balance != 333.33 || round2(c.InstallmentReserved) != 666.67 {
+		t.Fatalf("card after settlement = %+v", c)
+	}
+
+	due, err := s.Repos.Installments.ListDue(context.Background(), nil, 10)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("due before the next cycle: %+v %v", due, err)
+	}
+	mem.Now = func() time.Time { return time.Now().Add(s.Installments.Cycle + time.Minute) }
+	due, err = s.Repos.Installments.ListDue(context.Background(), nil, 10)
+	if err != nil || len(due) != 1 || due[0].TransactionID != res.TransactionID {
+		t.Fatalf("due after one cycle: %+v %v", due, err)
+	}
+	if err := s.PostDueInstallment(context.Background(), due[0]); err != nil {
+		t.Fatal(err)
+	}
+	// Posting twice is a no-op
+	if err := s.PostDueInstallment(context.Background(), due[0]); err != nil {
+		t.Fatal(err)
+	}
+	if u := getUser(t, s, 1); u.Balance != 666.66 || round2(u.InstallmentReserved) != 333.34 {
+		t.Fatalf("user after installment 2 = %+v", u)
+	}
+
+	// Refunding cancels installment 3 and credits the posted part
+	if _, err := s.RefundTransaction(context.Background(), 1, int(res.TransactionID)); err != nil {
+		t.Fatal(err)
+	}
+	if u := getUser(t, s, 1); round2(u.Balance) != 0 || round2(u.InstallmentReserved) != 0 {
+		t.Fatalf("user after refund = %+v", u)
+	}
+	plans, _ := s.Repos.Installments.ListByUserID(context.Background(), nil, 1)
+	if len(plans) != 1 || plans[0].Status != "Cancelled" || plans[0].PostedCount != 2 || plans[0].Installments[2].Status != "Cancelled" {
+		t.Fatalf("plan after refund = %+v", plans)
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/repo"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MerchantService manages merchant API accounts and their reports. Refunds
+// go through TransactionService.MerchantRefund.
+type MerchantService struct {
+	Pool *pgxpool.Pool
+}
+
+// newMerchantKey returns an API key, its SHA-256 (what is stored) and its
+// display prefix.
+func newMerchantKey() (key, hash, prefix string, err error) {
+	buf := make([]byte, 24)
+	if _, err := crand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key = "mk_" + hex.EncodeToString(buf)
+	return key, hashMerchantKey(key), key[:11], nil
+}
+
+func hashMerchantKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create opens an account for a registered merchant. The API key is only
+// returned here and by RotateKey.
+func (s *MerchantService) Create(ctx context.Context, name string) (*models.Merchant, error) {
+	if _, ok := merchantRates[name]; !ok {
+		return nil, Failf(utils.CodeInvalidMerchant, "unknown merchant %q", name)
+	}
+	key, hash, prefix, err := newMerchantKey()
+	if err != nil {
+		return nil, err
+	}
+	m, err := repo.CreateMerchant(ctx, s.Pool, name, hash, prefix)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, Failf(utils.CodeMerchantExists, "merchant %q already has an account", name)
+		}
+		return nil, err
+	}
+	m.APIKey = key
+	utils.LoggerFrom(ctx).Info("merchant account created", utils.LogKeyMerchant, name, "merchant_id", m.MerchantID)
+	return m, nil
+}
+
+func (s *MerchantService) List(ctx context.Context) ([]models.Merchant, error) {
+	return repo.ListMerchants(ctx, s.Pool)
+}
+
+// RotateKey issues a new API key; the old one stops working immediately.
+func (s *MerchantService) RotateKey(ctx context.Context, merchantID int64) (*models.Merchant, error) {
+	key, hash, prefix, err := newMerchantKey()
+	if err != nil {
+		return nil, err
+	}
+	m, err := repo.UpdateMerchantKey(ctx, s.Pool, merchantID, hash, prefix)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, Fail(utils.CodeMerchantNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.APIKey = key
+	utils.LoggerFrom(ctx).Info("merchant key rotated", utils.LogKeyMerchant, m.Name, "merchant_id", m.MerchantID)
+	return m, nil
+}
+
+func (s *MerchantService) SetActive(ctx context.Context, merchantID int64, active bool) (*models.Merchant, error) {
+	m, err := repo.SetMerchantActive(ctx, s.Pool, merchantID, active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, Fail(utils.CodeMerchantNotFound)
+	}
+	return m, err
+}
+
+// Authenticate returns the active merchant owning key, or nil if there is none.
+func (s *MerchantService) Authenticate(ctx context.Context, key string) (*models.Merchant, error) {
+	m, err := repo.GetMerchantByKeyHash(ctx, s.Pool, hashMerchantKey(key))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return m, err
+}
+
+// ListTransactions lists the merchant's transactions created in [from, to)
+// (either may be nil), optionally only those with status.
+func (s *MerchantService) ListTransactions(ctx context.Context, merchant, status string, from, to *time.Time, limit int) ([]models.Transaction, error) {
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, Failf(utils.CodeInvalidReportDate, "from %s is after to %s", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	return repo.ListMerchantTransactions(ctx, s.Pool, merchant, status, from, to, limit)
+}
+
+// SettlementReport aggregates the merchant's sales, refunds and chargebacks
+// of one calendar day into the amount payable to the merchant.
+func (s *MerchantService) SettlementReport(ctx context.Context, merchant string, day time.Time) (*models.SettlementReport, error) {
+	lines, err := repo.ListSettlementLines(ctx, s.Pool, merchant, day)
+	if err != nil {
+		return nil, err
+	}
+	r := &models.SettlementReport{Merchant: merchant, Date: day.Format(time.DateOnly), Lines: lines}
+	for _, l := range lines {
+		switch l.Type {
+		case "sale":
+			r.SalesCount++
+			r.GrossSales += l.Amount
+		case "refund":
+			r.RefundCount++
+			r.Refunds += l.Amount
+		case "chargeback":
+			r.ChargebackCount++
+			r.Chargebacks += l.Amount
+		}
+	}
+	r.GrossSales, r.Refunds, r.Chargebacks = round2(r.GrossSales), round2(r.Refunds), round2(r.Chargebacks)
+	r.NetPayable = round2(r.GrossSales - r.Refunds - r.Chargebacks)
+	return r, nil
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// merchantCategories groups merchants for category promotions.
+var merchantCategories = map[string]string{
+	"7-11":        "convenience",
+	"Steam":       "gaming",
+	"Apple Store": "electronics",
+	"Amazon":      "online",
+}
+
+// Promotion kinds.
+const (
+	PromoCampaign      = "campaign"       // one merchant, time-boxed
+	PromoCategory      = "category"       // every merchant of a category
+	PromoFirstPurchase = "first_purchase" // the user's first purchase (optionally at one merchant)
+)
+
+// PromotionConfig controls bonus points.
+type PromotionConfig struct {
+	// MonthlyCap caps the bonus points of all promotions per user and
+	// calendar month; 0 disables the global cap
+	MonthlyCap int
+}
+
+func DefaultPromotionConfig() PromotionConfig {
+	return PromotionConfig{MonthlyCap: 10000}
+}
+
+// CreatePromotionRequest is the input of CreatePromotion.
+type CreatePromotionRequest struct {
+	Name           string
+	Kind           string
+	Merchant       string
+	Category       string
+	Multiplier     *float64
+	BonusPoints    *int
+	UserMonthlyCap *int
+	Stackable      bool
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+}
+
+func (s *TransactionService) CreatePromotion(ctx context.Context, req CreatePromotionRequest) (*models.Promotion, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 40 {
+		return nil, Failf(utils.CodeInvalidPromotion, "name must be 1-40 characters")
+	}
+	switch req.Kind {
+	case PromoCampaign:
+		if _, ok := merchantRates[req.Merchant]; !ok {
+			return nil, Failf(utils.CodeInvalidPromotion, "campaign needs a registered merchant, got %q", req.Merchant)
+		}
+		if req.StartsAt == nil || req.EndsAt == nil {
+			return nil, Failf(utils.CodeInvalidPromotion, "campaign needs starts_at and ends_at")
+		}
+	case PromoCategory:
+		known := false
+		for _, c := range merchantCategories {
+			known = known || c == req.Category
+		}
+		if !known {
+			return nil, Failf(utils.CodeInvalidPromotion, "unknown category %q", req.Category)
+		}
+	case PromoFirstPurchase:
+		if _, ok := merchantRates[req.Merchant]; req.Merchant != "" && !ok {
+			return nil, Failf(utils.CodeInvalidPromotion, "unknown merchant %q", req.Merchant)
+		}
+	default:
+		return nil, Failf(utils.CodeInvalidPromotion, "kind must be campaign, category or first_purchase, got %q", req.Kind)
+	}
+	if req.Multiplier == nil && req.BonusPoints == nil {
+		return nil, Failf(utils.CodeInvalidPromotion, "give a multiplier and/or bonus_points")
+	}
+	if (req.Multiplier != nil && (*req.Multiplier <= 0 || *req.Multiplier > 100)) || (req.BonusPoints != nil && *req.BonusPoints <= 0) {
+		return nil, Failf(utils.CodeInvalidPromotion, "multiplier must be in (0, 100] and bonus_points positive")
+	}
+	if req.UserMonthlyCap != nil && *req.UserMonthlyCap <= 0 {
+		return nil, Failf(utils.CodeInvalidPromotion, "user_monthly_cap must be positive")
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.StartsAt.Before(*req.EndsAt) {
+		return nil, Failf(utils.CodeInvalidPromotion, "starts_at must be before ends_at")
+	}
+	p, err := s.Repos.Promotions.Create(ctx, s.Pool, &models.Promotion{
+		Name: req.Name, Kind: req.Kind, Merchant: req.Merchant, Category: req.Category,
+		Multiplier: req.Multiplier, BonusPoints: req.BonusPoints, UserMonthlyCap: req.UserMonthlyCap,
+		Stackable: req.Stackable, StartsAt: req.StartsAt, EndsAt: req.EndsAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	utils.LoggerFrom(ctx).Info("promotion created", "promotion_id", p.PromotionID, "kind", p.Kind)
+	return p, nil
+}
+
+// ListPromotions lists all promotions, or only those running now.
+func (s *TransactionService) ListPromotions(ctx context.Context, runningOnly bool) ([]models.Promotion, error) {
+	return s.Repos.Promotions.List(ctx, s.Pool, runningOnly)
+}
+
+func (s *TransactionService) DeactivatePromotion(ctx context.Context, promotionID int64) (*models.Promotion, error) {
+	p, err := s.Repos.Promotions.Deactivate(ctx, s.Pool, promotionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, Fail(utils.CodePromotionNotFound)
+	}
+	return p, err
+}
+
+// evaluatePromotions returns the bonuses a purchase earns on top of the
+// baseEarned merchant-rate points for pointsBase. Stackable promotions add
+// up; an exclusive one applies alone, and whichever of the two gives more
+// points wins. Per-promotion and global monthly caps trim the result. The
+// user row must already be locked, which serializes the cap accounting.
+func (s *TransactionService) evaluatePromotions(ctx context.Context, tx pgx.Tx, userID int, merchant string, pointsBase float64, baseEarned int, log *utils.TxLogger) ([]models.AppliedPromotion, error) {
+	promos, err := s.Repos.Promotions.List(ctx, tx, true)
+	if err != nil || len(promos) == 0 {
+		return nil, err
+	}
+	used, err := s.Repos.Promotions.MonthlyPoints(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var firstPurchase *bool
+
+	var stackable []models.AppliedPromotion
+	var exclusive *models.AppliedPromotion
+	stackTotal := 0
+	for _, p := range promos {
+		switch p.Kind {
+		case PromoCampaign:
+			if p.Merchant != merchant {
+				continue
+			}
+		case PromoCategory:
+			if p.Category != merchantCategories[merchant] {
+				continue
+			}
+		case PromoFirstPurchase:
+			if p.Merchant != "" && p.Merchant != merchant {
+				continue
+			}
+			if firstPurchase == nil {
+				has, err := s.Transactions.HasPurchase(ctx, tx, userID)
+				if err != nil {
+					return nil, err
+				}
+				first := !has
+				firstPurchase = &first
+			}
+			if !*firstPurchase {
+				continue
+			}
+		}
+		points := 0
+		if p.Multiplier != nil {
+			points += max(0, int(math.Floor(pointsBase**p.Multiplier))-baseEarned)
+		}
+		if p.BonusPoints != nil {
+			points += *p.BonusPoints
+		}
+		if p.UserMonthlyCap != nil {
+			if left := *p.UserMonthlyCap - used[p.PromotionID]; points > left {
+				log.Info(fmt.Sprintf("[PROMO] %q capped: %d of %d monthly bonus points left.", p.Name, max(left, 0), *p.UserMonthlyCap))
+				points = max(left, 0)
+			}
+		}
+		if points == 0 {
+			continue
+		}
+		a := models.AppliedPromotion{PromotionID: p.PromotionID, Name: p.Name, Points: points}
+		if p.Stackable {
+			stackable = append(stackable, a)
+			stackTotal += points
+		} else if exclusive == nil || points > exclusive.Points {
+			exclusive = &a
+		}
+	}
+	applied := stackable
+	if exclusive != nil && exclusive.Points > stackTotal {
+		log.Info(fmt.Sprintf("[PROMO] Exclusive %q (%d pts) beats %d stackable pts.", exclusive.Name, exclusive.Points, stackTotal))
+		applied = []models.AppliedPromotion{*exclusive}
+	}
+
+	if s.Promotions.MonthlyCap > 0 {
+		left := s.Promotions.MonthlyCap
+		for _, pts := range used {
+			left -= pts
+		}
+		kept := applied[:0]
+		for _, a := range applied {
+			if a.Points > left {
+				log.Info(fmt.Sprintf("[PROMO] Monthly bonus cap %d reached: %q trimmed %d -> %d pts.", s.Promotions.MonthlyCap, a.Name, a.Points, max(left, 0)))
+				a.Points = max(left, 0)
+			}
+			if a.Points > 0 {
+				kept = append(kept, a)
+				left -= a.Points
+			}
+		}
+		applied = kept
+	}
+	for _, a := range applied {
+		log.Info(fmt.Sprintf("[PROMO] %q: +%d bonus pts.", a.Name, a.Points))
+	}
+	return applied, nil
+}
+
+// capturePromotions scales the bonuses of purchase t to the captured share
+// and returns them with their total; the caller writes the Points rows.
+func (s *TransactionService) capturePromotions(ctx context.Context, tx pgx.Tx, t *models.Transaction, captured, authorized float64, log *utils.TxLogger) ([]models.AppliedPromotion, int, error) {
+	applied, err := s.Repos.Promotions.ListGranted(ctx, tx, int64(t.TransactionID))
+	if err != nil {
+		return nil, 0, err
+	}
+	total := 0
+	for i := range applied {
+		a := &applied[i]
+		if captured < authorized {
+			scaled := int(math.Floor(float64(a.Points) * captured / authorized))
+			if err := s.Repos.Promotions.UpdateGranted(ctx, tx, int64(t.TransactionID), a.PromotionID, scaled); err != nil {
+				return nil, 0, err
+			}
+			log.Info(fmt.Sprintf("[PROMO] Partial capture: %q %d -> %d pts.", a.Name, a.Points, scaled))
+			a.Points = scaled
+		}
+		total += a.Points
+	}
+	return applied, total, nil
+}
+
+// promotionPoints is the bonus currently granted to purchase t.
+func (s *TransactionService) promotionPoints(ctx context.Context, tx pgx.Tx, txID int) (int, error) {
+	applied, err := s.Repos.Promotions.ListGranted(ctx, tx, int64(txID))
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, a := range applied {
+		total += a.Points
+	}
+	return total, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEvaluatePromotions(t *testing.T) {
+	ptr := func(n int) *int { return &n }
+	triple := 3.0
+	// The payments are 10 minutes apart (past the duplicate window) and
+	// must share one calendar month
+	base := time.Now()
+	if base.Add(30*time.Minute).Month() != base.Month() {
+		base = base.Add(time.Hour)
+	}
+	start, end := base.Add(-time.Hour), base.Add(time.Hour)
+	steamx3 := func(stackable bool) CreatePromotionRequest {
+		return CreatePromotionRequest{Name: "Steam x3", Kind: PromoCampaign, Merchant: "Steam", Multiplier: &triple, Stackable: stackable, StartsAt: &start, EndsAt: &end}
+	}
+	gaming := func(bonus int, stackable bool, cap *int) CreatePromotionRequest {
+		return CreatePromotionRequest{Name: "Gaming", Kind: PromoCategory, Category: "gaming", BonusPoints: &bonus, Stackable: stackable, UserMonthlyCap: cap}
+	}
+
+	// Every payment is $100 at Steam (x2): 200 base points; x3 adds 100
+	cases := []struct {
+		name       string
+		promos     []CreatePromotionRequest
+		monthlyCap int
+		// bonus points and promotion count of each payment
+		bonus []int
+		count []int
+	}{
+		{
+			name:   "stackable promotions add up",
+			promos: []CreatePromotionRequest{gaming(50, true, nil), steamx3(true)},
+			bonus:  []int{150}, count: []int{2},
+		},
+		{
+			name:   "exclusive beats a smaller stack",
+			promos: []CreatePromotionRequest{gaming(50, true, nil), {Name: "Big bonus", Kind: PromoCategory, Category: "gaming", BonusPoints: ptr(300)}},
+			bonus:  []int{300}, count: []int{1},
+		},
+		{
+			name:   "stack beats a smaller exclusive",
+			promos: []CreatePromotionRequest{gaming(50, true, nil), steamx3(true), {Name: "Small bonus", Kind: PromoCategory, Category: "gaming", BonusPoints: ptr(120)}},
+			bonus:  []int{150}, count: []int{2},
+		},
+		{
+			name:   "best of two exclusives",
+			promos: []CreatePromotionRequest{gaming(50, false, nil), steamx3(false)},
+			bonus:  []int{100}, count: []int{1},
+		},
+		{
+			name:   "other merchants earn nothing",
+			promos: []CreatePromotionRequest{{Name: "Amazon x3", Kind: PromoCampaign, Merchant: "Amazon", Multiplier: &triple, Stackable: true, StartsAt: &start, EndsAt: &end}},
+			bonus:  []int{0}, count: []int{0},
+		},
+		{
+			name:   "first purchase only once",
+			promos: []CreatePromotionRequest{{Name: "Welcome", Kind: PromoFirstPurchase, BonusPoints: ptr(500)}},
+			bonus:  []int{500, 0}, count: []int{1, 0},
+		},
+		{
+			name:   "per-promotion monthly cap",
+			promos: []CreatePromotionRequest{gaming(50, true, ptr(80))},
+			bonus:  []int{50, 30, 0}, count: []int{1, 1, 0},
+		},
+		{
+			name:       "global monthly cap trims in order",
+			promos:     []CreatePromotionRequest{gaming(50, true, nil), steamx3(true)},
+			monthlyCap: 200,
+			// 150, then 50 left: the first promotion takes it all
+			bonus: []int{150, 50, 0}, count: []int{2, 1, 0},
+		},
+		{
+			name:       "global cap applies to the winning exclusive",
+			promos:     []CreatePromotionRequest{{Name: "Big bonus", Kind: PromoCategory, Category: "gaming", BonusPoints: ptr(300)}},
+			monthlyCap: 400,
+			bonus:      []int{300, 100}, count: []int{1, 1},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, mem := newMemoryService(t)
+			mem.Now = func() time.Time { return base }
+			s.Promotions.MonthlyCap = c.monthlyCap
+			for _, p := range c.promos {
+				if _, err := s.CreatePromotion(context.Background(), p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i, want := range c.bonus {
+				at := base.Add(time.Duration(i) * 10 * time.Minute)
+				mem.Now = func() time.Time { return at }
+				res := pay(t, s, PaymentRequest{UserID: 1, Amount: 100, Merchant: "Steam"})
+				if res.PointsEarned != 200 || res.BonusPoints != want || len(res.Promotions) != c.count[i] {
+					t.Fatalf("payment %d: earned %d, bonus %d %+v; want bonus %d from %d promotions", i+1, res.PointsEarned, res.BonusPoints, res.Promotions, want, c.count[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPartialCaptureScalesPromotions(t *testing.T) {
+	s, mem := newMemoryService(t)
+	putCard(t, mem, 1, 5000)
+	bonus := 50
+	triple := 3.0
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	for _, p := range []CreatePromotionRequest{
+		{Name: "Gaming", Kind: PromoCategory, Category: "gaming", BonusPoints: &bonus, Stackable: true},
+		{Name: "Steam x3", Kind: PromoCampaign, Merchant: "Steam", Multiplier: &triple, Stackable: true, StartsAt: &start, EndsAt: &end},
+	} {
+		if _, err := s.CreatePromotion(context.Background(), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 100, Merchant: "Steam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BonusPoints != 150 {
+		t.Fatalf("authorization bonus = %d", res.BonusPoints)
+	}
+	amount := 60.0
+	capRes, err := s.CaptureTransaction(context.Background(), CaptureRequest{Merchant: "Steam", TransactionID: int(res.TransactionID), Amount: &amount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// floor(50 * 0.6) + floor(100 * 0.6)
+	if capRes.PointsEarned != 120 || capRes.BonusPoints != 90 {
+		t.Fatalf("capture = %+v", capRes)
+	}
+	if u := getUser(t, s, 1); u.CurrentPoints != 1000+120+90 {
+		t.Fatalf("user after capture = %+v", u)
+	}
+	// The scaled grants count against the monthly caps
+	used, err := s.Repos.Promotions.MonthlyPoints(context.Background(), nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, pts := range used {
+		total += pts
+	}
+	if total != 90 {
+		t.Fatalf("monthly promotion points = %v", used)
+	}
+}
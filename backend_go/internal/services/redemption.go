@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Points-only redemption kinds.
+const (
+	RedeemStatementCredit = "statement_credit"
+	RedeemCatalogue       = "catalogue"
+)
+
+// RedemptionConfig sets how many points buy $1 of discount or credit.
+type RedemptionConfig struct {
+	// TierRates overrides LoyaltyTier.PointsPerDollar by tier name
+	TierRates map[string]int
+	// MerchantRates sets the rate at one merchant; the cheaper of the
+	// merchant and the tier rate applies
+	MerchantRates map[string]int
+}
+
+// tiers returns loyaltyTiers with the configured redemption rates.
+func (s *TransactionService) tiers() []LoyaltyTier {
+	out := make([]LoyaltyTier, len(loyaltyTiers))
+	copy(out, loyaltyTiers)
+	for i := range out {
+		if r, ok := s.Redemption.TierRates[out[i].Name]; ok && r > 0 {
+			out[i].PointsPerDollar = r
+		}
+	}
+	return out
+}
+
+// pointsPerDollar is the redemption rate of a tier at merchant ("" for
+// redemptions outside a purchase).
+func (s *TransactionService) pointsPerDollar(tier LoyaltyTier, merchant string) int {
+	rate := tier.PointsPerDollar
+	if r, ok := s.Redemption.TierRates[tier.Name]; ok && r > 0 {
+		rate = r
+	}
+	if r, ok := s.Redemption.MerchantRates[merchant]; ok && r > 0 && r < rate {
+		rate = r
+	}
+	return rate
+}
+
+// redeemCents converts up to budget points at rate (points per $1) into
+// whole cents, at most maxCents. points is what those cents cost, rounded
+// up to the next point, so it never exceeds budget.
+func redeemCents(budget, rate, maxCents int) (points, cents int) {
+	if budget <= 0 || rate <= 0 || maxCents <= 0 {
+		return 0, 0
+	}
+	cents = min(budget*100/rate, maxCents)
+	points = (cents*rate + 99) / 100
+	return points, cents
+}
+
+// availablePoints is what the user can redeem or give away: current points
+// minus the points Pending authorizations will redeem at capture.
+func (s *TransactionService) availablePoints(ctx context.Context, tx pgx.Tx, u *models.User) (int, error) {
+	held, err := s.Transactions.PendingRedeemedPoints(ctx, tx, u.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return max(u.CurrentPoints-held, 0), nil
+}
+
+// toCents converts a dollar amount to whole cents.
+func toCents(amount float64) int {
+	return int(math.Round(amount * 100))
+}
+
+// CatalogueItem is a reward bought with points only.
+type CatalogueItem struct {
+	SKU    string `json:"sku"`
+	Name   string `json:"name"`
+	Points int    `json:"points"`
+}
+
+var rewardCatalogue = []CatalogueItem{
+	{SKU: "coffee-voucher", Name: "Coffee voucher", Points: 500},
+	{SKU: "movie-ticket", Name: "Movie ticket", Points: 1200},
+	{SKU: "lounge-pass", Name: "Airport lounge pass", Points: 4000},
+	{SKU: "gift-card-50", Name: "$50 gift card", Points: 5500},
+}
+
+func (s *TransactionService) ListCatalogue() []CatalogueItem {
+	return rewardCatalogue
+}
+
+// RedeemRequest is the input of RedeemPoints: Points for a statement
+// credit, Item (a catalogue SKU) for a catalogue redemption. FromHousehold
+// spends the pool of the user's household instead of the user's points.
+type RedeemRequest struct {
+	UserID        int
+	Kind          string
+	Points        int
+	Item          string
+	FromHousehold bool
+}
+
+type RedemptionResult struct {
+	Redemption      *models.Redemption `json:"redemption"`
+	PointsPerDollar int                `json:"pointsPerDollar,omitempty"`
+	RemainingPoints int                `json:"remainingPoints"` // of the user, or of the pool with FromHousehold
+	Balance         float64            `json:"balance"`
+	Steps           []utils.Step       `json:"steps,omitempty"`
+}
+
+// ---- POINTS-ONLY REDEMPTION ----
+// Spends points without a purchase: a statement credit lowers the balance
+// by the points' cash value at the tier rate; a catalogue item costs its
+// listed points.
+func (s *TransactionService) RedeemPoints(ctx context.Context, req RedeemRequest) (*RedemptionResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, req.UserID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: REDEEM %s, User: %d", req.Kind, req.UserID))
+
+		user, err := s.lockUser(ctx, tx, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkAccountStatus(ctx, tx, user, log); err != nil {
+			return nil, err
+		}
+		var available int
+		var householdID *int64
+		if req.FromHousehold {
+			h, err := s.lockUserHousehold(ctx, tx, req.UserID)
+			if err != nil {
+				return nil, err
+			}
+			householdID, available = &h.HouseholdID, h.PoolPoints
+			log.Info(fmt.Sprintf("[HOUSEHOLD] Redeeming from household %d pool (%d pts).", h.HouseholdID, h.PoolPoints))
+		} else if available, err = s.availablePoints(ctx, tx, user); err != nil {
+			return nil, err
+		}
+
+		res := &RedemptionResult{}
+		var points, cents int
+		var item, reason string
+		switch req.Kind {
+		case RedeemStatementCredit:
+			if req.Points <= 0 {
+				return nil, Failf(utils.CodeInvalidRedemption, "points must be positive")
+			}
+			if req.Points > available {
+				return nil, Failf(utils.CodeInsufficientPoints, "requested %d pts, %d available", req.Points, available)
+			}
+			rate := s.pointsPerDollar(tierByName(user.Tier), "")
+			points, cents = redeemCents(req.Points, rate, math.MaxInt32)
+			if cents == 0 {
+				return nil, Failf(utils.CodeInvalidRedemption, "%d pts is worth less than $0.01 at %d pts = $1", req.Points, rate)
+			}
+			if float64(cents)/100 > user.Balance {
+				return nil, Failf(utils.CodeInvalidRedemption, "statement credit $%.2f exceeds the balance $%.2f", float64(cents)/100, user.Balance)
+			}
+			res.PointsPerDollar = rate
+			reason = "Redeemed: statement credit"
+			log.Info(fmt.Sprintf("[Points Redemption] %d pts -> $%.2f statement credit (%d pts = $1).", points, float64(cents)/100, rate))
+		case RedeemCatalogue:
+			var found *CatalogueItem
+			for i := range rewardCatalogue {
+				if rewardCatalogue[i].SKU == req.Item {
+					found = &rewardCatalogue[i]
+				}
+			}
+			if found == nil {
+				return nil, Failf(utils.CodeInvalidRedemption, "unknown catalogue item %q", req.Item)
+			}
+			if found.Points > available {
+				return nil, Failf(utils.CodeInsufficientPoints, "%s costs %d pts, %d available", found.SKU, found.Points, available)
+			}
+			points, item = found.Points, found.SKU
+			reason = "Redeemed: " + found.SKU
+			log.Info(fmt.Sprintf("[Points Redemption] %d pts -> %s.", points, found.Name))
+		default:
+			return nil, Failf(utils.CodeInvalidRedemption, "kind must be statement_credit or catalogue, got %q", req.Kind)
+		}
+
+		credit := float64(cents) / 100
+		r, err := s.Redemptions.Create(ctx, tx, req.UserID, req.Kind, item, points, credit, householdID)
+		if err != nil {
+			return nil, err
+		}
+		if householdID != nil {
+			// The pool has no Points ledger of its own; Redemptions records the spend
+			pool, err := s.Households.AddPoolPoints(ctx, tx, *householdID, -points)
+			if err != nil {
+				return nil, err
+			}
+			u, err := s.Users.UpdateBalanceAndPoints(ctx, tx, req.UserID, -credit, 0)
+			if err != nil {
+				return nil, err
+			}
+			res.Redemption, res.RemainingPoints, res.Balance = r, pool, u.Balance
+		} else {
+			u, err := s.Users.UpdateBalanceAndPoints(ctx, tx, req.UserID, -credit, -points)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.Redemptions.InsertPoints(ctx, tx, r, reason); err != nil {
+				return nil, err
+			}
+			res.Redemption, res.RemainingPoints, res.Balance = r, u.CurrentPoints, u.Balance
+		}
+		if err := s.emitAccountEvent(ctx, tx, log, EventPointsRedeemed, models.AccountEvent{UserID: req.UserID, Redemption: r}); err != nil {
+			return nil, err
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "redeem", err, steps)
+	}
+	res := anyRes.(*RedemptionResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("points redeemed", "kind", req.Kind, "points", res.Redemption.Points, "credit", res.Redemption.CreditAmount)
+	return res, nil
+}
+
+func (s *TransactionService) ListRedemptions(ctx context.Context, userID int) ([]models.Redemption, error) {
+	return s.Redemptions.ListByUserID(ctx, s.Pool, userID)
+}
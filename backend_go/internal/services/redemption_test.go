@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+)
+
+func TestRedeemCents(t *testing.T) {
+	cases := []struct {
+		budget, rate, maxCents int
+		points, cents          int
+	}{
+		{1000, 100, math.MaxInt32, 1000, 1000},
+		{150, 100, math.MaxInt32, 150, 150},
+		// 55 pts at 90 pts = $1 is 61 cents, which cost 54.9 -> 55 pts
+		{55, 90, math.MaxInt32, 55, 61},
+		{7, 70, math.MaxInt32, 7, 10},
+		{1000, 100, 500, 500, 500},
+		{1000, 90, 500, 450, 500},
+		{1, 101, math.MaxInt32, 0, 0},
+		{0, 100, 500, 0, 0},
+		{100, 0, 500, 0, 0},
+	}
+	for _, c := range cases {
+		points, cents := redeemCents(c.budget, c.rate, c.maxCents)
+		if points != c.points || cents != c.cents {
+			t.Errorf("redeemCents(%d, %d, %d) = %d pts, %d cents; want %d, %d", c.budget, c.rate, c.maxCents, points, cents, c.points, c.cents)
+		}
+		if points > c.budget {
+			t.Errorf("redeemCents(%d, %d, %d) spends %d pts over budget", c.budget, c.rate, c.maxCents, points)
+		}
+	}
+}
+
+func TestPaymentPartialRedemption(t *testing.T) {
+	cases := []struct {
+		name   string
+		req    PaymentRequest
+		points int
+		final  float64
+	}{
+		{"points count", PaymentRequest{RedeemPoints: 300}, 300, 47},
+		{"amount cap", PaymentRequest{UsePoints: true, MaxRedeemAmount: 2.5}, 250, 47.5},
+		{"count under the amount cap", PaymentRequest{RedeemPoints: 100, MaxRedeemAmount: 2.5}, 100, 49},
+		{"a cent stays cash", PaymentRequest{UsePoints: true, Amount: 5}, 499, 0.01},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, _ := newMemoryService(t)
+			req := c.req
+			req.UserID, req.Merchant = 1, "7-11"
+			if req.Amount == 0 {
+				req.Amount = 50
+			}
+			res := pay(t, s, req)
+			if res.PointsRedeemed != c.points || res.FinalAmount != c.final {
+				t.Fatalf("result = %+v", res)
+			}
+			if u := getUser(t, s, 1); u.CurrentPoints != 1000-c.points+res.PointsEarned || u.Balance != c.final {
+				t.Fatalf("user = %+v", u)
+			}
+		})
+	}
+
+	s, _ := newMemoryService(t)
+	_, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 50, Merchant: "7-11", RedeemPoints: 1001})
+	wantCode(t, err, utils.CodeInsufficientPoints)
+}
+
+func TestRedeemPoints(t *testing.T) {
+	cases := []struct {
+		name    string
+		rates   map[string]int
+		owed    float64 // starting balance, 100 if 0
+		req     RedeemRequest
+		code    string
+		points  int
+		credit  float64
+		balance float64
+	}{
+		{name: "statement credit", req: RedeemRequest{Kind: RedeemStatementCredit, Points: 550}, points: 550, credit: 5.5, balance: 94.5},
+		{name: "configured tier rate", rates: map[string]int{"Standard": 90}, req: RedeemRequest{Kind: RedeemStatementCredit, Points: 100}, points: 100, credit: 1.11, balance: 98.89},
+		{name: "catalogue", req: RedeemRequest{Kind: RedeemCatalogue, Item: "coffee-voucher"}, points: 500, balance: 100},
+		{name: "more than available", req: RedeemRequest{Kind: RedeemStatementCredit, Points: 1001}, code: utils.CodeInsufficientPoints},
+		{name: "credit above the balance", owed: 5, req: RedeemRequest{Kind: RedeemStatementCredit, Points: 1000}, code: utils.CodeInvalidRedemption},
+		{name: "worth less than a cent", req: RedeemRequest{Kind: RedeemStatementCredit, Points: 0}, code: utils.CodeInvalidRedemption},
+		{name: "catalogue item too dear", req: RedeemRequest{Kind: RedeemCatalogue, Item: "lounge-pass"}, code: utils.CodeInsufficientPoints},
+		{name: "unknown item", req: RedeemRequest{Kind: RedeemCatalogue, Item: "yacht"}, code: utils.CodeInvalidRedemption},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, mem := newMemoryService(t)
+			owed := c.owed
+			if owed == 0 {
+				owed = 100
+			}
+			mem.PutUser(models.User{UserID: 1, CreditLimit: 10000, Balance: owed, CurrentPoints: 1000})
+			s.Redemption.TierRates = c.rates
+			req := c.req
+			req.UserID = 1
+
+			res, err := s.RedeemPoints(context.Background(), req)
+			if c.code != "" {
+				wantCode(t, err, c.code)
+				if got := mem.PointsOf(1); len(got) != 0 {
+					t.Fatalf("points ledger after a rejected redemption = %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := res.Redemption
+			if r.Points != c.points || r.CreditAmount != c.credit || res.RemainingPoints != 1000-c.points || res.Balance != c.balance {
+				t.Fatalf("result = %+v, redemption = %+v", res, r)
+			}
+			if u := getUser(t, s, 1); u.CurrentPoints != 1000-c.points || u.Balance != c.balance {
+				t.Fatalf("user = %+v", u)
+			}
+			got := mem.PointsOf(1)
+			if len(got) != 1 || got[0].ChangeAmount != -c.points || got[0].RedemptionID != r.RedemptionID {
+				t.Fatalf("points ledger = %+v", got)
+			}
+			if list, _ := s.ListRedemptions(context.Background(), 1); len(list) != 1 || list[0].RedemptionID != r.RedemptionID {
+				t.Fatalf("redemptions = %+v", list)
+			}
+		})
+	}
+}
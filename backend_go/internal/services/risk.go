@@ -3,7 +3,6 @@ package service
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"time"
 
 	"backend_go/internal/repo"
@@ -20,6 +19,14 @@ type RiskRules struct {
 	DuplicateWindowSQL string
 	RefundLimit        int64
 	RefundWindowSQL    string
+	// RefundFreeze is how long the account is frozen once RefundLimit is reached
+	RefundFreeze time.Duration
+	// Points transfers per sender in TransferWindowSQL (count and points),
+	// and the distinct senders one recipient may receive from
+	TransferLimit       int64
+	TransferPointsLimit int64
+	TransferFanInLimit  int64
+	TransferWindowSQL   string
 }
 
 func DefaultRules(loadtest bool) RiskRules {
@@ -42,34 +49,33 @@ func DefaultRules(loadtest bool) RiskRules {
 		DuplicateWindowSQL: "5 minutes",
 		RefundLimit:        3,
 		RefundWindowSQL:    "24 hours",
+		RefundFreeze:       24 * time.Hour,
+
+		TransferLimit:       5,
+		TransferPointsLimit: 50000,
+		TransferFanInLimit:  5,
+		TransferWindowSQL:   "24 hours",
 	}
 }
 
 type RiskEngine struct {
-	Redis *redis.Client
-	Rules RiskRules
+	Redis        *redis.Client // nil skips the velocity check (tests)
+	Rules        RiskRules
+	Transactions repo.TransactionRepo
+	Transfers    repo.TransferRepo
 }
 
-func (r *RiskEngine) EvaluatePaymentRisk(ctx context.Context, q repo.Querier, userID int, amount float64, merchant string, log *utils.TxLogger) error {
-	log.Info(fmt.Sprintf("[RISK] Starting Risk Evaluation for User %d...", userID))
-
-	// Amount bounds
-	if amount > r.Rules.MaxAmount {
-		log.Info(fmt.Sprintf("[RISK] FAIL: Amount $%.2f exceeds limit $%.2f.", amount, r.Rules.MaxAmount))
-		return NewTxError(http.StatusBadRequest, "RISK_AMOUNT_TOO_HIGH", "Transaction amount exceeds maximum limit")
+// checkVelocity counts the user's payments in the velocity window via Redis.
+func (r *RiskEngine) checkVelocity(ctx context.Context, userID int, log *utils.TxLogger) error {
+	if r.Redis == nil {
+		log.Info("[RISK] SKIP: Velocity check (no Redis).")
+		return nil
 	}
-	if amount < r.Rules.MinAmount {
-		log.Info(fmt.Sprintf("[RISK] FAIL: Amount $%.2f is below minimum $%.2f.", amount, r.Rules.MinAmount))
-		return NewTxError(http.StatusBadRequest, "RISK_AMOUNT_TOO_LOW", "Transaction amount is too low")
-	}
-	log.Info("[RISK] PASS: Amount limits check.")
-
-	// Velocity check via Redis
 	velocityKey := fmt.Sprintf("risk:velocity:user:%d", userID)
 	count, err := r.Redis.Incr(ctx, velocityKey).Result()
 	if err != nil {
 		log.Info(fmt.Sprintf("[RISK] ERROR: redis incr failed: %v", err))
-		return NewTxError(http.StatusServiceUnavailable, "REDIS_UNAVAILABLE", "Risk system temporarily unavailable")
+		return Fail(utils.CodeRedisUnavailable)
 	}
 	if count == 1 {
 		if err := r.Redis.Expire(ctx, velocityKey, r.Rules.VelocityWindow).Err(); err != nil {
@@ -78,39 +84,39 @@ func (r *RiskEngine) EvaluatePaymentRisk(ctx context.Context, q repo.Querier, us
 	}
 	if count > r.Rules.VelocityLimit {
 		log.Info(fmt.Sprintf("[RISK] FAIL: Velocity limit reached (Redis: %d tx in window).", count))
-		return NewTxError(http.StatusTooManyRequests, "RISK_VELOCITY_LIMIT", "Too many transactions in short period")
+		return Fail(utils.CodeRiskVelocityLimit)
 	}
 	log.Info(fmt.Sprintf("[RISK] PASS: Velocity check (Redis: %d/%d).", count, r.Rules.VelocityLimit))
+	return nil
+}
+
+func (r *RiskEngine) EvaluatePaymentRisk(ctx context.Context, q repo.Querier, userID int, amount float64, merchant string, log *utils.TxLogger) error {
+	log.Info(fmt.Sprintf("[RISK] Starting Risk Evaluation for User %d...", userID))
 
-	// Refund abuse (DB)
-	var refundCount int64
-	refundSQL := fmt.Sprintf(
-		`SELECT COUNT(*) FROM Transactions WHERE user_id = $1 AND status = 'Refunded' AND created_at > NOW() - INTERVAL '%s'`,
-		r.Rules.RefundWindowSQL,
-	)
-	if err := q.QueryRow(ctx, refundSQL, userID).Scan(&refundCount); err != nil {
-		log.Info(fmt.Sprintf("[RISK] ERROR: refund count query failed: %v", err))
-		return NewTxError(http.StatusInternalServerError, "INTERNAL_ERROR", "Internal Server Error")
+	// Amount bounds
+	if amount > r.Rules.MaxAmount {
+		log.Info(fmt.Sprintf("[RISK] FAIL: Amount $%.2f exceeds limit $%.2f.", amount, r.Rules.MaxAmount))
+		return Fail(utils.CodeRiskAmountTooHigh)
 	}
-	if refundCount >= r.Rules.RefundLimit {
-		log.Info(fmt.Sprintf("[RISK] FAIL: User has %d refunds in 24h. Account temporarily frozen.", refundCount))
-		return NewTxError(http.StatusForbidden, "RISK_REFUND_ABUSE", "Account temporarily frozen due to excessive refunds")
+	if amount < r.Rules.MinAmount {
+		log.Info(fmt.Sprintf("[RISK] FAIL: Amount $%.2f is below minimum $%.2f.", amount, r.Rules.MinAmount))
+		return Fail(utils.CodeRiskAmountTooLow)
+	}
+	log.Info("[RISK] PASS: Amount limits check.")
+
+	if err := r.checkVelocity(ctx, userID, log); err != nil {
+		return err
 	}
-	log.Info(fmt.Sprintf("[RISK] PASS: Refund history check (%d refunds in 24h).", refundCount))
 
 	// Duplicate transaction check (DB)
-	var dupCount int64
-	dupSQL := fmt.Sprintf(
-		`SELECT COUNT(*) FROM Transactions WHERE user_id = $1 AND merchant = $2 AND amount = $3 AND created_at > NOW() - INTERVAL '%s'`,
-		r.Rules.DuplicateWindowSQL,
-	)
-	if err := q.QueryRow(ctx, dupSQL, userID, merchant, amount).Scan(&dupCount); err != nil {
+	dupCount, err := r.Transactions.CountRecentDuplicates(ctx, q, userID, merchant, amount, r.Rules.DuplicateWindowSQL)
+	if err != nil {
 		log.Info(fmt.Sprintf("[RISK] ERROR: duplicate count query failed: %v", err))
-		return NewTxError(http.StatusInternalServerError, "INTERNAL_ERROR", "Internal Server Error")
+		return Fail(utils.CodeInternalError)
 	}
 	if dupCount > 1 {
 		log.Info("[RISK] FAIL: Duplicate transaction detected.")
-		return NewTxError(http.StatusConflict, "RISK_DUPLICATE", "Potential duplicate transaction detected")
+		return Fail(utils.CodeRiskDuplicate)
 	}
 
 	log.Info("[RISK] PASS: Duplicate transaction check.")
@@ -118,21 +124,47 @@ func (r *RiskEngine) EvaluatePaymentRisk(ctx context.Context, q repo.Querier, us
 	return nil
 }
 
-func (r *RiskEngine) EvaluateRefundRisk(ctx context.Context, q repo.Querier, userID int, log *utils.TxLogger) error {
-	// Refund abuse (DB)
-	var refundCount int64
-	refundSQL := fmt.Sprintf(
-		`SELECT COUNT(*) FROM Transactions WHERE user_id = $1 AND status = 'Refunded' AND created_at > NOW() - INTERVAL '%s'`,
-		r.Rules.RefundWindowSQL,
-	)
-	if err := q.QueryRow(ctx, refundSQL, userID).Scan(&refundCount); err != nil {
-		log.Info(fmt.Sprintf("[RISK] ERROR: refund count query failed: %v", err))
-		return NewTxError(http.StatusInternalServerError, "INTERNAL_ERROR", "Internal Server Error")
-	}
-	if refundCount > r.Rules.RefundLimit {
-		log.Info(fmt.Sprintf("[RISK] FAIL: User has %d refunds in 24h. Refund blocked.", refundCount))
-		return NewTxError(http.StatusForbidden, "RISK_REFUND_ABUSE", "Account temporarily frozen due to excessive refunds")
-	}
-	log.Info(fmt.Sprintf("[RISK] PASS: Refund check (%d/%d in 24h).", refundCount, r.Rules.RefundLimit))
+// RefundAbuse runs after a refund is written: it reports whether the user has
+// now reached RefundLimit refunds in the window. The caller then freezes the
+// account for RefundFreeze, so payments only check the account status.
+func (r *RiskEngine) RefundAbuse(ctx context.Context, q repo.Querier, userID int, log *utils.TxLogger) (bool, error) {
+	refundCount, err := r.Transactions.CountRecentRefunds(ctx, q, userID, r.Rules.RefundWindowSQL)
+	if err != nil {
+		return false, err
+	}
+	if refundCount >= r.Rules.RefundLimit {
+		log.Info(fmt.Sprintf("[RISK] ALERT: User has %d refunds in %s. Freezing account for %s.", refundCount, r.Rules.RefundWindowSQL, r.Rules.RefundFreeze))
+		return true, nil
+	}
+	log.Info(fmt.Sprintf("[RISK] PASS: Refund check (%d/%d in %s).", refundCount, r.Rules.RefundLimit, r.Rules.RefundWindowSQL))
+	return false, nil
+}
+
+// EvaluatePointsTransfer checks the sender's daily transfer limits and, for
+// user-to-user transfers, how many distinct senders the recipient already
+// received from (many accounts feeding one is a points-farming pattern).
+func (r *RiskEngine) EvaluatePointsTransfer(ctx context.Context, q repo.Querier, fromUserID int, toUserID *int, points int, log *utils.TxLogger) error {
+	count, sent, err := r.Transfers.CountRecent(ctx, q, fromUserID, r.Rules.TransferWindowSQL)
+	if err != nil {
+		return err
+	}
+	if count+1 > r.Rules.TransferLimit || sent+int64(points) > r.Rules.TransferPointsLimit {
+		log.Info(fmt.Sprintf("[RISK] FAIL: Transfer limit (%d transfers, %d pts in %s; limits %d / %d pts).", count, sent, r.Rules.TransferWindowSQL, r.Rules.TransferLimit, r.Rules.TransferPointsLimit))
+		return Failf(utils.CodeTransferLimit, "%d transfers and %d pts sent in %s", count, sent, r.Rules.TransferWindowSQL)
+	}
+	log.Info(fmt.Sprintf("[RISK] PASS: Transfer limit (%d/%d, %d+%d/%d pts).", count+1, r.Rules.TransferLimit, sent, points, r.Rules.TransferPointsLimit))
+
+	if toUserID == nil {
+		return nil
+	}
+	senders, err := r.Transfers.CountRecentSenders(ctx, q, *toUserID, fromUserID, r.Rules.TransferWindowSQL)
+	if err != nil {
+		return err
+	}
+	if senders+1 > r.Rules.TransferFanInLimit {
+		log.Info(fmt.Sprintf("[RISK] FAIL: Recipient %d already received from %d other users in %s.", *toUserID, senders, r.Rules.TransferWindowSQL))
+		return Fail(utils.CodeRiskTransferFanIn)
+	}
+	log.Info(fmt.Sprintf("[RISK] PASS: Transfer fan-in (%d/%d senders).", senders+1, r.Rules.TransferFanInLimit))
 	return nil
 }
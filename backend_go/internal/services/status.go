@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Who changed an account or card status (StatusChanges.actor).
+const (
+	ActorCardholder = "cardholder"
+	ActorAdmin      = "admin"
+	ActorSystem     = "system"
+)
+
+// statuses an administrator may set on accounts and cards
+var adminStatuses = map[string]bool{"Active": true, "Frozen": true, "Blocked": true, "Closed": true}
+
+const statusHistoryLimit = 100
+
+type AccountResult struct {
+	User  *models.User `json:"user"`
+	Steps []utils.Step `json:"steps,omitempty"`
+}
+
+// ---- ACCOUNT STATUS (cardholder) ----
+// A cardholder can freeze their own account and lift a freeze they set
+// themselves; freezes by an admin or the system need an admin (or expiry).
+func (s *TransactionService) ChangeAccountStatus(ctx context.Context, userID int, action, reason string) (*AccountResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID)
+	if action != "freeze" && action != "unfreeze" {
+		return nil, Failf(utils.CodeValidationFailed, "unknown account action %q", action)
+	}
+	if reason == "" {
+		reason = "cardholder " + action
+	}
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: ACCOUNT %s, User: %d", action, userID))
+
+		user, err := s.lockUser(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		to := "Frozen"
+		if action == "unfreeze" {
+			if user.Status != "Frozen" {
+				return nil, Failf(utils.CodeAccountInvalidStatus, "cannot unfreeze account with status: %s", user.Status)
+			}
+			if user.StatusChangedBy != ActorCardholder {
+				return nil, Failf(utils.CodeAccountInvalidStatus, "account was frozen by %s", user.StatusChangedBy)
+			}
+			to = "Active"
+		} else if user.Status != "Active" {
+			return nil, Failf(utils.CodeAccountInvalidStatus, "cannot freeze account with status: %s", user.Status)
+		}
+		if err := s.setAccountStatus(ctx, tx, user, to, reason, ActorCardholder, nil, log); err != nil {
+			return nil, err
+		}
+		return &AccountResult{User: user}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "account."+action, err, steps)
+	}
+	res := anyRes.(*AccountResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("account status changed", "status", res.User.Status, "actor", ActorCardholder)
+	return res, nil
+}
+
+// ---- ACCOUNT STATUS (admin) ----
+// frozenUntil makes a Frozen status temporary; it is ignored for other statuses.
+func (s *TransactionService) AdminSetAccountStatus(ctx context.Context, userID int, status, reason string, frozenUntil *time.Time) (*AccountResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID)
+	if !adminStatuses[status] {
+		return nil, Failf(utils.CodeValidationFailed, "unknown account status %q", status)
+	}
+	if status != "Frozen" {
+		frozenUntil = nil
+	} else if frozenUntil != nil && !frozenUntil.After(time.Now()) {
+		return nil, Failf(utils.CodeValidationFailed, "frozen_until must be in the future")
+	}
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: ADMIN account status -> %s, User: %d", status, userID))
+
+		user, err := s.lockUser(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if user.Status == "Closed" {
+			return nil, Failf(utils.CodeAccountInvalidStatus, "account is Closed")
+		}
+		if err := s.setAccountStatus(ctx, tx, user, status, reason, ActorAdmin, frozenUntil, log); err != nil {
+			return nil, err
+		}
+		return &AccountResult{User: user}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "admin.account_status", err, steps)
+	}
+	res := anyRes.(*AccountResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("account status changed", "status", res.User.Status, "actor", ActorAdmin)
+	return res, nil
+}
+
+// ---- CARD STATUS (admin) ----
+// An admin may move a card between any statuses except out of Closed.
+func (s *TransactionService) AdminSetCardStatus(ctx context.Context, cardID int64, status, reason string) (*CardResult, error) {
+	ctx = utils.WithLogFields(ctx, "card_id", cardID)
+	if !adminStatuses[status] {
+		return nil, Failf(utils.CodeValidationFailed, "unknown card status %q", status)
+	}
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: ADMIN card status -> %s, Card: %d", status, cardID))
+
+		card, err := s.Cards.GetByIDForUpdate(ctx, tx, cardID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, Fail(utils.CodeCardNotFound)
+			}
+			return nil, err
+		}
+		if card.Status == "Closed" {
+			return nil, Failf(utils.CodeCardInvalidStatus, "card is Closed")
+		}
+		// A Blocked primary card may already have a replacement
+		if card.CardType == "Primary" && card.Status == "Blocked" && (status == "Active" || status == "Frozen") {
+			if _, err := s.Cards.GetPrimaryForUpdate(ctx, tx, card.UserID); err == nil {
+				return nil, Fail(utils.CodePrimaryCardExists)
+			} else if !errors.Is(err, pgx.ErrNoRows) {
+				return nil, err
+			}
+		}
+		if err := s.setCardStatus(ctx, tx, card, status, reason, ActorAdmin, log); err != nil {
+			return nil, err
+		}
+		return &CardResult{Card: card}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "admin.card_status", err, steps)
+	}
+	res := anyRes.(*CardResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("card status changed", "status", res.Card.Status, "actor", ActorAdmin)
+	return res, nil
+}
+
+func (s *TransactionService) StatusHistory(ctx context.Context, userID int) ([]models.StatusChange, error) {
+	return s.StatusChanges.ListByUserID(ctx, s.Pool, userID, statusHistoryLimit)
+}
+
+// checkAccountStatus rejects payments and refunds on a non-Active account.
+// An expired temporary freeze is lifted here, so no background job is needed.
+// user must be locked.
+func (s *TransactionService) checkAccountStatus(ctx context.Context, tx pgx.Tx, user *models.User, log *utils.TxLogger) error {
+	if user.Status == "Frozen" && user.FrozenUntil != nil && !time.Now().Before(*user.FrozenUntil) {
+		log.Info(fmt.Sprintf("[STATUS] Temporary freeze expired at %s.", user.FrozenUntil.Format(time.RFC3339)))
+		if err := s.setAccountStatus(ctx, tx, user, "Active", "temporary freeze expired", ActorSystem, nil, log); err != nil {
+			return err
+		}
+	}
+	switch user.Status {
+	case "Active":
+		log.Info("[STATUS] PASS: Account is Active.")
+		return nil
+	case "Frozen":
+		// System freezes come from refund abuse; keep the established risk code for them
+		if user.StatusChangedBy == ActorSystem {
+			log.Info(fmt.Sprintf("[STATUS] FAIL: Account frozen by the risk engine (%s).", user.StatusReason))
+			return accountStatusError(utils.CodeRiskRefundAbuse, user)
+		}
+		log.Info(fmt.Sprintf("[STATUS] FAIL: Account frozen by %s (%s).", user.StatusChangedBy, user.StatusReason))
+		return accountStatusError(utils.CodeAccountFrozen, user)
+	case "Blocked":
+		log.Info(fmt.Sprintf("[STATUS] FAIL: Account blocked (%s).", user.StatusReason))
+		return accountStatusError(utils.CodeAccountBlocked, user)
+	default:
+		log.Info(fmt.Sprintf("[STATUS] FAIL: Account is %s.", user.Status))
+		return accountStatusError(utils.CodeAccountClosed, user)
+	}
+}
+
+func accountStatusError(code string, user *models.User) *TxError {
+	if user.FrozenUntil != nil {
+		return Failf(code, "%s until %s", user.StatusReason, user.FrozenUntil.Format(time.RFC3339))
+	}
+	return Failf(code, "%s", user.StatusReason)
+}
+
+// freezeForRefundAbuse temporarily freezes the account after RiskEngine.RefundAbuse fired.
+func (s *TransactionService) freezeForRefundAbuse(ctx context.Context, tx pgx.Tx, user *models.User, log *utils.TxLogger) error {
+	until := time.Now().Add(s.Risk.Rules.RefundFreeze)
+	reason := fmt.Sprintf("excessive refunds (%d in %s)", s.Risk.Rules.RefundLimit, s.Risk.Rules.RefundWindowSQL)
+	return s.setAccountStatus(ctx, tx, user, "Frozen", reason, ActorSystem, &until, log)
+}
+
+// setAccountStatus updates the locked user row and appends the audit record.
+func (s *TransactionService) setAccountStatus(ctx context.Context, tx pgx.Tx, user *models.User, status, reason, actor string, frozenUntil *time.Time, log *utils.TxLogger) error {
+	if err := s.Users.UpdateStatus(ctx, tx, user.UserID, status, reason, actor, frozenUntil); err != nil {
+		return err
+	}
+	change := models.StatusChange{
+		UserID: user.UserID, OldStatus: user.Status, NewStatus: status, Reason: reason, Actor: actor,
+	}
+	if err := s.StatusChanges.Insert(ctx, tx, change); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("[STATUS] Account %d: %s -> %s by %s (%s).", user.UserID, user.Status, status, actor, reason))
+	user.Status, user.StatusReason, user.StatusChangedBy, user.FrozenUntil = status, reason, actor, frozenUntil
+	return s.emitAccountEvent(ctx, tx, log, EventAccountStatusChanged, models.AccountEvent{UserID: user.UserID, StatusChange: &change})
+}
+
+// setCardStatus updates the locked card row and appends the audit record.
+func (s *TransactionService) setCardStatus(ctx context.Context, tx pgx.Tx, card *models.Card, status, reason, actor string, log *utils.TxLogger) error {
+	if err := s.Cards.UpdateStatus(ctx, tx, card.CardID, status); err != nil {
+		return err
+	}
+	cardID := card.CardID
+	change := models.StatusChange{
+		UserID: card.UserID, CardID: &cardID, OldStatus: card.Status, NewStatus: status, Reason: reason, Actor: actor,
+	}
+	if err := s.StatusChanges.Insert(ctx, tx, change); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Card %d: %s -> %s by %s (%s).", card.CardID, card.Status, status, actor, reason))
+	card.Status = status
+	return s.emitAccountEvent(ctx, tx, log, EventCardStatusChanged, models.AccountEvent{UserID: card.UserID, StatusChange: &change})
+}
+
+func (s *TransactionService) lockUser(ctx context.Context, tx pgx.Tx, userID int) (*models.User, error) {
+	user, err := s.Users.GetByIDForUpdate(ctx, tx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, Fail(utils.CodeUserNotFound)
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LoyaltyTier is a cardholder level reached by rolling 12-month settled spend.
+type LoyaltyTier struct {
+	Name     string  `json:"name"`
+	MinSpend float64 `json:"min_spend"`
+	// EarnMultiplier scales the merchant rate
+	EarnMultiplier float64 `json:"earn_multiplier"`
+	// PointsPerDollar is the redemption rate: points for a $1 discount
+	PointsPerDollar int `json:"points_per_dollar"`
+}
+
+// loyaltyTiers is ordered by MinSpend; the first tier is the default.
+var loyaltyTiers = []LoyaltyTier{
+	{Name: "Standard", MinSpend: 0, EarnMultiplier: 1, PointsPerDollar: 100},
+	{Name: "Silver", MinSpend: 5000, EarnMultiplier: 1.25, PointsPerDollar: 90},
+	{Name: "Gold", MinSpend: 15000, EarnMultiplier: 1.5, PointsPerDollar: 80},
+	{Name: "Platinum", MinSpend: 40000, EarnMultiplier: 2, PointsPerDollar: 70},
+}
+
+// tierForSpend returns the highest tier spend qualifies for.
+func tierForSpend(spend float64) LoyaltyTier {
+	tier := loyaltyTiers[0]
+	for _, t := range loyaltyTiers[1:] {
+		if spend >= t.MinSpend {
+			tier = t
+		}
+	}
+	return tier
+}
+
+// tierByName falls back to the default tier for unknown names.
+func tierByName(name string) LoyaltyTier {
+	for _, t := range loyaltyTiers {
+		if t.Name == name {
+			return t
+		}
+	}
+	return loyaltyTiers[0]
+}
+
+// rewardRate is the points per $1 a transaction earns: the rate fixed at
+// authorization, or the bare merchant rate for transactions without one.
+func rewardRate(t *models.Transaction) float64 {
+	if t.RewardMultiplier > 0 {
+		return t.RewardMultiplier
+	}
+	if mult := merchantRates[t.Merchant]; mult > 0 {
+		return mult
+	}
+	return 1
+}
+
+// refreshTier recomputes the user's tier from the rolling settled spend.
+// The user row must be locked.
+func (s *TransactionService) refreshTier(ctx context.Context, tx pgx.Tx, u *models.User, log *utils.TxLogger) error {
+	spend, err := s.Transactions.RollingSettledSpend(ctx, tx, u.UserID)
+	if err != nil {
+		return err
+	}
+	spend = round2(spend)
+	tier := tierForSpend(spend)
+	if err := s.Users.UpdateTier(ctx, tx, u.UserID, tier.Name, spend); err != nil {
+		return err
+	}
+	if tier.Name != u.Tier {
+		log.Info(fmt.Sprintf("[TIER] User %d: %s -> %s (12-month spend $%.2f).", u.UserID, u.Tier, tier.Name, spend))
+		utils.LoggerFrom(ctx).Info("loyalty tier changed", utils.LogKeyUserID, u.UserID, "from", u.Tier, "to", tier.Name, "spend", spend)
+	}
+	u.Tier, u.TierSpend = tier.Name, spend
+	return nil
+}
+
+// RefreshTier recomputes one user's tier (tier review job).
+func (s *TransactionService) RefreshTier(ctx context.Context, userID int) error {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID)
+	_, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		u, err := s.lockUser(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.refreshTier(ctx, tx, u, log)
+	})
+	if err != nil {
+		utils.LoggerFrom(ctx).Error("tier refresh failed", "error", err, "steps", steps)
+		return err
+	}
+	return nil
+}
+
+// TierStatus is the user's tier and the progress towards the next one.
+type TierStatus struct {
+	UserID      int         `json:"user_id"`
+	Tier        LoyaltyTier `json:"tier"`
+	Spend       float64     `json:"rolling_spend"`
+	EvaluatedAt *time.Time  `json:"evaluated_at,omitempty"`
+
+	// Next tier; omitted at the top tier
+	NextTier    *LoyaltyTier  `json:"next_tier,omitempty"`
+	SpendToNext float64       `json:"spend_to_next,omitempty"`
+	ProgressPct float64       `json:"progress_pct"`
+	Tiers       []LoyaltyTier `json:"tiers"`
+}
+
+// GetTierStatus reports the stored tier with the live rolling spend, so
+// progress includes purchases settled since the last evaluation.
+func (s *TransactionService) GetTierStatus(ctx context.Context, userID int) (*TierStatus, error) {
+	u, err := s.Users.GetByID(ctx, s.Pool, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, Fail(utils.CodeUserNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	spend, err := s.Transactions.RollingSettledSpend(ctx, s.Pool, userID)
+	if err != nil {
+		return nil, err
+	}
+	spend = round2(spend)
+	tiers := s.tiers()
+	tier := tierByName(u.Tier)
+	tier.PointsPerDollar = s.pointsPerDollar(tier, "")
+	st := &TierStatus{UserID: userID, Tier: tier, Spend: spend, EvaluatedAt: u.TierEvaluatedAt, ProgressPct: 100, Tiers: tiers}
+	for i := 0; i < len(tiers)-1; i++ {
+		if tiers[i].Name != tier.Name {
+			continue
+		}
+		next := tiers[i+1]
+		st.NextTier = &next
+		st.SpendToNext = round2(math.Max(next.MinSpend-spend, 0))
+		pct := (spend - tier.MinSpend) / (next.MinSpend - tier.MinSpend) * 100
+		st.ProgressPct = math.Floor(math.Min(math.Max(pct, 0), 100)*10) / 10
+	}
+	return st, nil
+}
+
+// TierReviewer re-evaluates tiers every Interval for users not evaluated
+// within MaxAge, so tiers also drop as old spend leaves the 12-month window.
+type TierReviewer struct {
+	Pool     *pgxpool.Pool
+	Svc      *TransactionService
+	Interval time.Duration
+	MaxAge   time.Duration
+	Batch    int
+}
+
+func (tr *TierReviewer) Run(ctx context.Context) {
+	logger := utils.LoggerFrom(ctx).With("component", "tier_reviewer")
+	ticker := time.NewTicker(tr.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			users, err := tr.Svc.Users.ListForTierReview(ctx, tr.Pool, tr.MaxAge.Seconds(), tr.Batch)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("list users for tier review failed", "error", err)
+				}
+				continue
+			}
+			for _, userID := range users {
+				_ = tr.Svc.RefreshTier(ctx, userID)
+			}
+		}
+	}
+}
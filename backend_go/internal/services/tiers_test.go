@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"backend_go/internal/models"
+)
+
+func TestTierForSpend(t *testing.T) {
+	cases := []struct {
+		spend float64
+		want  string
+	}{
+		{0, "Standard"},
+		{4999.99, "Standard"},
+		{5000, "Silver"},
+		{14999.99, "Silver"},
+		{15000, "Gold"},
+		{39999.99, "Gold"},
+		{40000, "Platinum"},
+		{1e6, "Platinum"},
+	}
+	for _, c := range cases {
+		if got := tierForSpend(c.spend).Name; got != c.want {
+			t.Errorf("tierForSpend(%.2f) = %s, want %s", c.spend, got, c.want)
+		}
+	}
+	if got := tierByName("Diamond").Name; got != "Standard" {
+		t.Errorf("unknown tier falls back to %s", got)
+	}
+}
+
+func TestTierFollowsRollingSpend(t *testing.T) {
+	s, mem := newMemoryService(t)
+	now := time.Now()
+	captured := now.AddDate(0, -11, 0)
+	mem.PutTransaction(models.Transaction{TransactionID: 1, UserID: 1, Amount: 6000, Merchant: "Amazon", Status: "Paid", CreatedAt: captured, CapturedAt: &captured})
+	// Refunds and open authorizations are not settled spend
+	mem.PutTransaction(models.Transaction{TransactionID: 2, UserID: 1, Amount: 9000, Merchant: "Amazon", Status: "Refunded"})
+	mem.PutTransaction(models.Transaction{TransactionID: 3, UserID: 1, Amount: 9000, Merchant: "Amazon", Status: "Pending"})
+
+	if due, _ := s.Users.ListForTierReview(context.Background(), nil, time.Hour.Seconds(), 10); !slices.Equal(due, []int{1}) {
+		t.Fatalf("due before the first review = %v", due)
+	}
+	if err := s.RefreshTier(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if u := getUser(t, s, 1); u.Tier != "Silver" || u.TierSpend != 6000 || u.TierEvaluatedAt == nil {
+		t.Fatalf("user after review = %+v", u)
+	}
+	if due, _ := s.Users.ListForTierReview(context.Background(), nil, time.Hour.Seconds(), 10); len(due) != 0 {
+		t.Fatalf("due right after the review = %v", due)
+	}
+
+	st, err := s.GetTierStatus(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Tier.Name != "Silver" || st.NextTier == nil || st.NextTier.Name != "Gold" || st.SpendToNext != 9000 || st.ProgressPct != 10 {
+		t.Fatalf("tier status = %+v", st)
+	}
+
+	// Silver earns x1.25 on the merchant rate: Steam x2.5
+	if res := pay(t, s, PaymentRequest{UserID: 1, Amount: 100, Merchant: "Steam"}); res.PointsEarned != 250 {
+		t.Fatalf("Silver points earned = %d", res.PointsEarned)
+	}
+
+	// Two months on, the old purchase has left the 12-month window
+	mem.Now = func() time.Time { return now.AddDate(0, 2, 0) }
+	if due, _ := s.Users.ListForTierReview(context.Background(), nil, time.Hour.Seconds(), 10); !slices.Equal(due, []int{1}) {
+		t.Fatalf("due after two months = %v", due)
+	}
+	if err := s.RefreshTier(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if u := getUser(t, s, 1); u.Tier != "Standard" || u.TierSpend != 100 {
+		t.Fatalf("user after the spend expired = %+v", u)
+	}
+}
@@ -4,9 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math"
-	"net/http"
+	"strings"
 	"time"
 
 	"backend_go/internal/models"
@@ -19,7 +18,23 @@ import (
 
 type TransactionService struct {
 	Pool *pgxpool.Pool
-	Risk *RiskEngine
+	// Storage of the payment paths: repo.Postgres() or a repo.Memory
+	repo.Repos
+	// DB starts the transactions of withTransaction; nil uses Pool
+	DB repo.Beginner
+
+	Risk   *RiskEngine
+	Events *EventHub // optional: live account event fan-out
+
+	// Foreign-currency payments; without Rates only billing-currency payments work
+	Rates RateProvider
+	FX    FXConfig
+
+	Installments InstallmentConfig
+	Disputes     DisputeConfig
+	Capture      CaptureConfig
+	Promotions   PromotionConfig
+	Redemption   RedemptionConfig
 }
 
 var merchantRates = map[string]float64{
@@ -29,80 +44,113 @@ var merchantRates = map[string]float64{
 	"Amazon":      1.5,
 }
 
+// PaymentRequest is the input of ProcessPayment.
+type PaymentRequest struct {
+	UserID    int
+	Amount    float64
+	Merchant  string
+	UsePoints bool
+	// CardID is optional; without it the user's primary card is charged.
+	CardID *int64
+	// Currency of Amount; empty means the billing currency.
+	Currency string
+	// Installments is the term in months (3, 6 or 12); 0 means pay in full.
+	Installments int
+	// With UsePoints: RedeemPoints caps the points spent (0: all available)
+	// and MaxRedeemAmount the discount in the billing currency (0: no cap).
+	RedeemPoints    int
+	MaxRedeemAmount float64
+}
+
 type TxResult struct {
-	TransactionID  int64    `json:"transactionId"`
-	FinalAmount    float64  `json:"finalAmount"`
-	PointsEarned   int      `json:"pointsEarned"`
-	PointsRedeemed int      `json:"pointsRedeemed"`
-	Logs           []string `json:"logs"`
+	TransactionID  int64   `json:"transactionId"`
+	CardID         *int64  `json:"cardId,omitempty"`
+	FinalAmount    float64 `json:"finalAmount"`
+	PointsEarned   int     `json:"pointsEarned"`
+	PointsRedeemed int     `json:"pointsRedeemed"`
+
+	// Promotion bonuses on top of PointsEarned, credited at capture
+	BonusPoints int                       `json:"bonusPoints,omitempty"`
+	Promotions  []models.AppliedPromotion `json:"promotions,omitempty"`
+
+	// Foreign-currency payments: FinalAmount is in the billing currency and includes FXFee
+	OriginalAmount   float64 `json:"originalAmount,omitempty"`
+	OriginalCurrency string  `json:"originalCurrency,omitempty"`
+	FXRate           float64 `json:"fxRate,omitempty"`
+	FXFee            float64 `json:"fxFee,omitempty"`
+
+	// Installment purchases: FinalAmount is split over Installments months
+	InstallmentPlanID int64 `json:"installmentPlanId,omitempty"`
+	Installments      int   `json:"installments,omitempty"`
+
+	Steps []utils.Step `json:"steps,omitempty"`
 }
 
 type VoidResult struct {
-	Success        bool     `json:"success"`
-	VoidedAmount   float64  `json:"voidedAmount"`
-	RestoredPoints int      `json:"restoredPoints"`
-	Logs           []string `json:"logs,omitempty"`
+	Success        bool         `json:"success"`
+	VoidedAmount   float64      `json:"voidedAmount"`
+	RestoredPoints int          `json:"restoredPoints"`
+	Steps          []utils.Step `json:"steps,omitempty"`
 }
 
 type RefundResult struct {
-	RefundTransactionID int64    `json:"refundTransactionId"`
-	Logs                []string `json:"logs"`
-}
-
-// ---- New TxError with HTTP + Code ----
-type TxError struct {
-	HTTP int
-	Code string
-	Msg  string
-	Logs []string
-}
-
-func (e *TxError) Error() string { return e.Msg }
-
-func NewTxError(httpStatus int, code, msg string) *TxError {
-	return &TxError{HTTP: httpStatus, Code: code, Msg: msg}
+	RefundTransactionID int64        `json:"refundTransactionId"`
+	Steps               []utils.Step `json:"steps,omitempty"`
 }
 
-func asTxError(err error) (*TxError, bool) {
-	var te *TxError
-	if errors.As(err, &te) {
-		return te, true
+// txFailure turns an error from withTransaction into the *TxError returned to
+// callers: business errors keep their code, anything else becomes INTERNAL_ERROR.
+// The step trace is attached and the outcome is logged with the request fields.
+func txFailure(ctx context.Context, op string, err error, steps []utils.Step) *TxError {
+	logger := utils.LoggerFrom(ctx).With("op", op)
+	if te, ok := asTxError(err); ok {
+		te.Steps = steps
+		logger.Warn("transaction rejected", utils.LogKeyErrorCode, te.Code, "reason", te.Error())
+		return te
 	}
-	return nil, false
+	logger.Error("transaction failed", utils.LogKeyErrorCode, "INTERNAL_ERROR", "error", err)
+	ie := Fail(utils.CodeInternalError)
+	ie.Steps = steps
+	return ie
 }
 
 // ---- Transaction wrapper ----
-func (s *TransactionService) withTransaction(ctx context.Context, fn func(tx pgx.Tx, log *utils.TxLogger) (any, error)) (any, []string, error) {
-	conn, err := s.Pool.Acquire(ctx)
-	if err != nil {
-		return nil, nil, err
+// withTransaction runs fn inside BEGIN/COMMIT. The ctx handed to fn carries the
+// TxLogger, so every statement executed with it is traced by repo.StepTracer,
+// and collects outbox events which are published after COMMIT.
+func (s *TransactionService) withTransaction(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error)) (any, []utils.Step, error) {
+	var db repo.Beginner = s.Pool
+	if s.DB != nil {
+		db = s.DB
 	}
-	defer conn.Release()
 
 	log := utils.NewTxLogger()
-	log.SQL("START TRANSACTION;")
-	tx, err := conn.Begin(ctx)
+	ctx = utils.WithTxLogger(ctx, log)
+	ctx, pending := withPendingEvents(ctx)
+	tx, err := db.Begin(ctx)
 	if err != nil {
-		return nil, log.Logs, err
+		return nil, log.Steps, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	res, err := fn(tx, log)
+	res, err := fn(ctx, tx, log)
 	if err != nil {
-		log.SQL("ROLLBACK; -- Error occurred")
+		log.Info("Error occurred, rolling back.")
 		_ = tx.Rollback(ctx)
-		return nil, log.Logs, err
+		return nil, log.Steps, err
 	}
-	log.SQL("COMMIT;")
 	if err := tx.Commit(ctx); err != nil {
-		return nil, log.Logs, err
+		return nil, log.Steps, err
 	}
-	return res, log.Logs, nil
+	if s.Events != nil && len(pending.events) > 0 {
+		s.Events.Publish(ctx, pending.events)
+	}
+	return res, log.Steps, nil
 }
 
 // ---- Query APIs ----
 func (s *TransactionService) GetUserDetails(ctx context.Context, userID int) (*models.User, error) {
-	u, err := repo.GetUserByID(ctx, s.Pool, userID)
+	u, err := s.Users.GetByID(ctx, s.Pool, userID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			// handlers.go 目前用字串比對 User not found 做 404，所以保留
@@ -114,35 +162,59 @@ func (s *TransactionService) GetUserDetails(ctx context.Context, userID int) (*m
 }
 
 func (s *TransactionService) GetTransactionHistory(ctx context.Context, userID int) ([]models.Transaction, error) {
-	return repo.GetTransactionsByUserID(ctx, s.Pool, userID)
+	return s.Transactions.ListByUserID(ctx, s.Pool, userID)
 }
 
 // ---- PAY ----
-func (s *TransactionService) ProcessPayment(ctx context.Context, userID int, amount float64, merchant string, usePoints bool) (*TxResult, error) {
-	anyRes, logs, err := s.withTransaction(ctx, func(tx pgx.Tx, log *utils.TxLogger) (any, error) {
-		log.Raw(fmt.Sprintf("\n> Processing: PAY at %s, User: %d, Total: $%.2f\n", merchant, userID, amount))
+func (s *TransactionService) ProcessPayment(ctx context.Context, req PaymentRequest) (*TxResult, error) {
+	userID, amount, merchant := req.UserID, req.Amount, req.Merchant
+	usePoints := req.UsePoints || req.RedeemPoints > 0 || req.MaxRedeemAmount > 0
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		if currency != "" {
+			log.Raw(fmt.Sprintf("> Processing: PAY at %s, User: %d, Total: %.2f %s", merchant, userID, amount, currency))
+		} else {
+			log.Raw(fmt.Sprintf("> Processing: PAY at %s, User: %d, Total: $%.2f", merchant, userID, amount))
+		}
 
 		// Merchant whitelist
 		mult, ok := merchantRates[merchant]
 		if !ok {
-			return nil, NewTxError(http.StatusBadRequest, "INVALID_MERCHANT", "Invalid merchant")
+			return nil, Fail(utils.CodeInvalidMerchant)
 		}
 
-		// Risk
-		if err := s.Risk.EvaluatePaymentRisk(ctx, tx, userID, amount, merchant, log); err != nil {
+		// Foreign currency: everything below works on the converted amount
+		fx, err := s.quoteFX(ctx, currency, amount, log)
+		if err != nil {
 			return nil, err
 		}
+		if fx != nil {
+			amount = fx.Billed
+		}
 
-		// Lock user row
+		// Lock user row; account and card status are checked before risk so a
+		// frozen account does not consume velocity budget.
 		log.Info(fmt.Sprintf("[PAY] Starting transaction logic for User %d.", userID))
-		log.SQL(fmt.Sprintf("SELECT * FROM Users WHERE user_id = %d FOR UPDATE;", userID))
+		user, err := s.lockUser(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkAccountStatus(ctx, tx, user, log); err != nil {
+			return nil, err
+		}
 
-		var user models.User
-		row := tx.QueryRow(ctx, `SELECT user_id, username, balance, current_points, credit_limit FROM Users WHERE user_id=$1 FOR UPDATE`, userID)
-		if err := row.Scan(&user.UserID, &user.Username, &user.Balance, &user.CurrentPoints, &user.CreditLimit); err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, NewTxError(http.StatusNotFound, "USER_NOT_FOUND", "User not found")
-			}
+		card, err := s.lockPaymentCard(ctx, tx, userID, req.CardID, log)
+		if err != nil {
+			return nil, err
+		}
+		var cardID *int64
+		if card != nil {
+			cardID = &card.CardID
+		}
+
+		// Risk
+		if err := s.Risk.EvaluatePaymentRisk(ctx, tx, userID, amount, merchant, log); err != nil {
 			return nil, err
 		}
 
@@ -150,17 +222,41 @@ func (s *TransactionService) ProcessPayment(ctx context.Context, userID int, amo
 		pointsRedeemed := 0
 		discountAmount := 0.0
 
-		// Points redemption: 100 pts = $1
-		if usePoints && user.CurrentPoints >= 100 {
-			log.Info(fmt.Sprintf("[Points Redemption] User has %d pts. Calculating discount...", user.CurrentPoints))
-			maxDiscount := math.Min(float64(user.CurrentPoints/100), math.Floor(finalAmount))
-			if maxDiscount > 0 {
-				pointsRedeemed = int(maxDiscount) * 100
-				discountAmount = float64(int(maxDiscount))
-				finalAmount = finalAmount - discountAmount
+		// Loyalty tier: scales the merchant rate and sets the redemption rate
+		tier := tierByName(user.Tier)
+		rate := s.pointsPerDollar(tier, merchant)
+		if tier.EarnMultiplier != 1 {
+			log.Info(fmt.Sprintf("[TIER] %s: earn x%g.", tier.Name, tier.EarnMultiplier))
+			mult = math.Round(mult*tier.EarnMultiplier*1000) / 1000
+		}
+
+		// Points redemption: rate pts = $1, in whole cents. Without a points
+		// count every available point is offered; at least $0.01 stays cash
+		// so the authorization can be captured.
+		if usePoints {
+			budget, err := s.availablePoints(ctx, tx, user)
+			if err != nil {
+				return nil, err
+			}
+			if req.RedeemPoints > 0 {
+				if req.RedeemPoints > budget {
+					return nil, Failf(utils.CodeInsufficientPoints, "requested %d pts, %d available", req.RedeemPoints, budget)
+				}
+				budget = req.RedeemPoints
+			}
+			maxCents := toCents(finalAmount) - 1
+			if req.MaxRedeemAmount > 0 {
+				maxCents = min(maxCents, toCents(req.MaxRedeemAmount))
+			}
+			log.Info(fmt.Sprintf("[Points Redemption] User has %d pts, offering %d at %d pts = $1. Calculating discount...", user.CurrentPoints, budget, rate))
+			points, cents := redeemCents(budget, rate, maxCents)
+			if cents > 0 {
+				pointsRedeemed = points
+				discountAmount = float64(cents) / 100
+				finalAmount = round2(finalAmount - discountAmount)
 				log.Info(fmt.Sprintf("Redeeming %d pts for $%.2f discount.", pointsRedeemed, discountAmount))
 			} else {
-				log.Info("Points insufficient for minimum $1 discount or amount is too small.")
+				log.Info("Points insufficient for a $0.01 discount or amount is too small.")
 			}
 		} else {
 			log.Info("No points redemption applied.")
@@ -168,283 +264,344 @@ func (s *TransactionService) ProcessPayment(ctx context.Context, userID int, amo
 
 		log.Info(fmt.Sprintf("Final Payment: $%.2f - $%.2f (Points) = $%.2f (Cash)", amount, discountAmount, finalAmount))
 
-		// Credit limit check
-		if (user.Balance + finalAmount) > user.CreditLimit {
-			return nil, NewTxError(http.StatusConflict, "INSUFFICIENT_CREDIT", "Insufficient credit")
+		// Points are earned on the purchase only, not on the FX fee
+		pointsBase := finalAmount
+		if fx != nil && fx.Fee > 0 {
+			finalAmount = round2(finalAmount + fx.Fee)
+			log.Info(fmt.Sprintf("[FX] Adding foreign transaction fee $%.2f: billed $%.2f.", fx.Fee, finalAmount))
 		}
 
-		pointsEarned := int(math.Floor(finalAmount * mult))
-		log.Info(fmt.Sprintf("[Rewards] Merchant: %s (x%g). Points Earned: floor(%.2f)*%g = %d.", merchant, mult, finalAmount, mult, pointsEarned))
-		netPointChange := pointsEarned - pointsRedeemed
-
-		// 1. Create Transaction with 'Pending' status
-		// We do NOT update user balance or points yet. This happens at settlement.
-		log.SQL(fmt.Sprintf(
-			"INSERT INTO Transactions (user_id, amount, status, point_change, merchant, source_transaction_id) VALUES (%d, %.2f, 'Pending', %d, '%s', NULL) RETURNING transaction_id;",
-			userID, finalAmount, netPointChange, merchant,
-		))
+		// Credit limit check (permanent limit + unexpired temporary boost);
+		// unposted installments and open authorizations count against it
+		limit := effectiveLimit(user, time.Now())
+		used := creditUsed(user.Balance, user.InstallmentReserved, user.AuthHold)
+		if used > limit {
+			log.Info(fmt.Sprintf("[PAY] FAIL: credit used $%.2f is already above the credit limit $%.2f.", used, limit))
+			return nil, Failf(utils.CodeInsufficientCredit, "balance above credit limit")
+		}
+		if (used + finalAmount) > limit {
+			return nil, Fail(utils.CodeInsufficientCredit)
+		}
+		if card != nil && creditUsed(card.Balance, card.InstallmentReserved, card.AuthHold)+finalAmount > card.CreditLimit {
+			log.Info(fmt.Sprintf("[CARD] FAIL: card credit used $%.2f + $%.2f exceeds card limit $%.2f.", creditUsed(card.Balance, card.InstallmentReserved, card.AuthHold), finalAmount, card.CreditLimit))
+			return nil, Fail(utils.CodeCardLimitExceeded)
+		}
+		if card != nil {
+			if err := checkCardControls(card, merchant, finalAmount, log); err != nil {
+				return nil, err
+			}
+		}
 
-		newTxID, err := repo.CreateTransactionReturningID(ctx, tx, userID, finalAmount, "Pending", netPointChange, merchant, nil)
+		pointsEarned := int(math.Floor(pointsBase * mult))
+		log.Info(fmt.Sprintf("[Rewards] Merchant: %s (x%g). Points Earned: floor(%.2f)*%g = %d.", merchant, mult, pointsBase, mult, pointsEarned))
+		promos, err := s.evaluatePromotions(ctx, tx, userID, merchant, pointsBase, pointsEarned, log)
 		if err != nil {
 			return nil, err
 		}
+		bonusPoints := 0
+		for _, a := range promos {
+			bonusPoints += a.Points
+		}
+		netPointChange := pointsEarned + bonusPoints - pointsRedeemed
 
-		log.Info(fmt.Sprintf("Transaction %d created (Pending). Settlement in 10s.", newTxID))
-		return &TxResult{TransactionID: newTxID, FinalAmount: finalAmount, PointsEarned: pointsEarned, PointsRedeemed: pointsRedeemed}, nil
-	})
-
-	if err != nil {
-		// preserve TxError (risk/business) with logs
-		if te, ok := asTxError(err); ok {
-			te.Logs = logs
-			return nil, te
-		}
-		// unknown/internal
-		ie := NewTxError(http.StatusInternalServerError, "INTERNAL_ERROR", "Internal Server Error")
-		ie.Logs = logs
-		return nil, ie
-	}
-
-	res := anyRes.(*TxResult)
-	res.Logs = logs
-
-	// Start background settlement
-	go s.SettleTransaction(res.TransactionID)
-
-	return res, nil
-}
-
-// SettleTransaction waits 10s then finalizes the transaction
-func (s *TransactionService) SettleTransaction(txID int64) {
-	time.Sleep(10 * time.Second)
-
-	ctx := context.Background()
-
-	// Reuse withTransaction for consistent logging and transaction management
-	_, logs, err := s.withTransaction(ctx, func(tx pgx.Tx, log *utils.TxLogger) (any, error) {
-		log.Raw(fmt.Sprintf("\n> Processing: SETTLE Transaction: %d\n", txID))
-
-		// 1. Lock Transaction
-		t, err := repo.GetTransactionByIDForUpdate(ctx, tx, int(txID))
+		// 1. Create Transaction with 'Pending' status and hold the amount
+		// We do NOT update user balance or points yet. This happens at capture.
+		newTxID, err := s.Transactions.Create(ctx, tx, userID, finalAmount, "Pending", netPointChange, merchant, nil, cardID)
 		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				log.Info("Transaction not found during settlement.")
-				return nil, nil
-			}
 			return nil, err
 		}
-
-		if t.Status != "Pending" {
-			log.Info(fmt.Sprintf("Transaction %d is '%s', skipping settlement.", txID, t.Status))
-			return nil, nil
-		}
-
-		// 2. Lock User & Check Limit
-
-		user, err := repo.GetUserByID(ctx, tx, t.UserID)
+		expiresAt, err := s.Transactions.SetAuthorization(ctx, tx, newTxID, s.Capture.AuthExpiry.Seconds(), pointsRedeemed, mult)
 		if err != nil {
 			return nil, err
 		}
-
-		if user.Balance+t.Amount > user.CreditLimit {
-			log.Info(fmt.Sprintf("Insufficient credit (Bal: %.2f + Amt: %.2f > Lim: %.2f). Voiding.", user.Balance, t.Amount, user.CreditLimit))
-			if err := repo.UpdateTransactionStatus(ctx, tx, int(txID), "Voided"); err != nil {
+		for _, a := range promos {
+			if err := s.Repos.Promotions.Grant(ctx, tx, newTxID, a); err != nil {
 				return nil, err
 			}
-			return nil, nil // Commit the Voided status
-		}
-
-		// 3. Apply Changes
-		mult := merchantRates[t.Merchant]
-		if mult == 0 {
-			mult = 1
 		}
-		pointsEarned := int(math.Floor(t.Amount * mult))
-		pointsRedeemed := pointsEarned - t.PointChange
-
-		log.SQL(fmt.Sprintf("UPDATE Users SET balance += %.2f, points += %d", t.Amount, t.PointChange))
-		if _, err := repo.UpdateUserBalanceAndPoints(ctx, tx, t.UserID, t.Amount, t.PointChange); err != nil {
+		if err := s.holdCredit(ctx, tx, userID, cardID, finalAmount); err != nil {
 			return nil, err
 		}
-
-		// 4. Insert Points Logs
-		if pointsRedeemed > 0 {
-			log.SQL(fmt.Sprintf("INSERT INTO Points (Redeemed: -%d)", pointsRedeemed))
-			if _, err := tx.Exec(ctx, `INSERT INTO Points (user_id, transaction_id, change_amount, reason) VALUES ($1,$2,$3,$4)`, t.UserID, t.TransactionID, -pointsRedeemed, "Redeemed"); err != nil {
+		ev := models.TransactionEvent{
+			TransactionID: newTxID, UserID: userID, Amount: finalAmount, Status: "Pending", PointChange: netPointChange, Merchant: merchant, CardID: cardID,
+		}
+		res := &TxResult{TransactionID: newTxID, CardID: cardID, FinalAmount: finalAmount, PointsEarned: pointsEarned, BonusPoints: bonusPoints, Promotions: promos, PointsRedeemed: pointsRedeemed}
+		if fx != nil {
+			if err := s.Transactions.SetFX(ctx, tx, newTxID, fx.OriginalAmount, fx.Currency, fx.Rate, fx.Fee); err != nil {
 				return nil, err
 			}
+			ev.OriginalAmount, ev.OriginalCurrency = &fx.OriginalAmount, &fx.Currency
+			res.OriginalAmount, res.OriginalCurrency, res.FXRate, res.FXFee = fx.OriginalAmount, fx.Currency, fx.Rate, fx.Fee
 		}
-		if pointsEarned > 0 {
-			reason := fmt.Sprintf("Earned (%s x%g)", t.Merchant, mult)
-			log.SQL(fmt.Sprintf("INSERT INTO Points (Earned: +%d)", pointsEarned))
-			if _, err := tx.Exec(ctx, `INSERT INTO Points (user_id, transaction_id, change_amount, reason) VALUES ($1,$2,$3,$4)`, t.UserID, t.TransactionID, pointsEarned, reason); err != nil {
+		if req.Installments != 0 {
+			p, err := s.newInstallmentPlan(newTxID, userID, cardID, finalAmount, req.Installments, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			plan, err := s.Repos.Installments.Create(ctx, tx, p)
+			if err != nil {
 				return nil, err
 			}
+			log.Info(fmt.Sprintf("[INSTALLMENT] Plan %d: %d x $%.2f, first installment posts at settlement.", plan.PlanID, plan.Term, plan.Installments[0].Amount))
+			res.InstallmentPlanID, res.Installments = plan.PlanID, plan.Term
 		}
-
-		// 5. Update Status
-		log.SQL("UPDATE Transactions SET status='Paid'")
-		if err := repo.UpdateTransactionStatus(ctx, tx, int(txID), "Paid"); err != nil {
+		if card != nil {
+			if err := s.consumeCard(ctx, tx, card, finalAmount, log); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.emitTransactionEvent(ctx, tx, log, EventTransactionAuthorized, ev); err != nil {
 			return nil, err
 		}
 
-		log.Info("Settlement successful.")
-		return nil, nil
+		if s.Capture.AutoCaptureDelay > 0 {
+			log.Info(fmt.Sprintf("Transaction %d authorized (Pending). Auto-capture in %s.", newTxID, s.Capture.AutoCaptureDelay))
+		} else {
+			log.Info(fmt.Sprintf("Transaction %d authorized (Pending). Awaiting merchant capture until %s.", newTxID, expiresAt.Format(time.RFC3339)))
+		}
+		return res, nil
 	})
 
 	if err != nil {
-		log.Printf("[SETTLE] Error settling tx %d: %v", txID, err)
-		for _, l := range logs {
-			log.Println(l)
-		}
+		return nil, txFailure(ctx, "pay", err, steps)
+	}
+
+	res := anyRes.(*TxResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("payment authorized", utils.LogKeyTxID, res.TransactionID, "amount", res.FinalAmount, "merchant", merchant)
+
+	// Demo mode: capture in full after AutoCaptureDelay unless the merchant does
+	if s.Capture.AutoCaptureDelay > 0 {
+		go s.SettleTransaction(res.TransactionID)
 	}
+
+	return res, nil
 }
 
 // ---- VOID ----
 func (s *TransactionService) VoidTransaction(ctx context.Context, userID int, targetTxID int) (*VoidResult, error) {
-	anyRes, logs, err := s.withTransaction(ctx, func(tx pgx.Tx, log *utils.TxLogger) (any, error) {
-		log.Raw(fmt.Sprintf("\n> Processing: VOID, Target Transaction: %d\n", targetTxID))
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID, utils.LogKeyTxID, targetTxID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: VOID, Target Transaction: %d", targetTxID))
 
-		t, err := repo.GetTransactionByIDForUpdate(ctx, tx, targetTxID)
+		// Lock order: user before transaction and installment plan (same as
+		// capture, refund and the biller)
+		if _, err := s.lockUser(ctx, tx, userID); err != nil {
+			return nil, err
+		}
+		t, err := s.Transactions.GetByIDForUpdate(ctx, tx, targetTxID)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, NewTxError(http.StatusNotFound, "TX_NOT_FOUND", "Transaction not found")
+				return nil, Fail(utils.CodeTxNotFound)
 			}
 			return nil, err
 		}
 		if t.UserID != userID {
-			return nil, NewTxError(http.StatusForbidden, "TX_FORBIDDEN", "Unauthorized access")
+			return nil, Fail(utils.CodeTxForbidden)
 		}
 
 		if t.Status == "Pending" {
-			// Voiding a pending transaction: just update status, no money movement
+			// Voiding a pending transaction: release the hold, no balance/points movement
 			log.Info("Voiding PENDING transaction. No balance/points reverted.")
-			if err := repo.UpdateTransactionStatus(ctx, tx, targetTxID, "Voided"); err != nil {
+			if err := s.Transactions.UpdateStatus(ctx, tx, targetTxID, "Voided"); err != nil {
+				return nil, err
+			}
+			t.Status = "Voided"
+			if err := s.releaseAuthorization(ctx, tx, t, log); err != nil {
+				return nil, err
+			}
+			if err := s.emitTransactionEvent(ctx, tx, log, EventTransactionVoided, transactionEvent(t)); err != nil {
 				return nil, err
 			}
 			return &VoidResult{Success: true, VoidedAmount: 0, RestoredPoints: 0}, nil
 		} else if t.Status == "Paid" {
 			// Existing logic for Paid
-			log.SQL(fmt.Sprintf("UPDATE Transactions SET status='Voided' WHERE transaction_id=%d;", targetTxID))
-			if err := repo.UpdateTransactionStatus(ctx, tx, targetTxID, "Voided"); err != nil {
+			if err := s.Transactions.UpdateStatus(ctx, tx, targetTxID, "Voided"); err != nil {
 				return nil, err
 			}
 
-		log.Info(fmt.Sprintf("Restoring Balance: +$%.2f", t.Amount))
-		if _, err := tx.Exec(ctx, `UPDATE Users SET balance = balance + $1 WHERE user_id=$2`, t.Amount, userID); err != nil {
+			if err := s.reverseSettledPurchase(ctx, tx, t, -t.Amount, log); err != nil {
 				return nil, err
 			}
 
 			reversePointChange := -1 * t.PointChange
 			if reversePointChange != 0 {
 				log.Info(fmt.Sprintf("Restoring Points: %d", reversePointChange))
-				if _, err := tx.Exec(ctx, `UPDATE Users SET current_points = current_points + $1 WHERE user_id=$2`, reversePointChange, userID); err != nil {
+				if err := s.Users.AddPoints(ctx, tx, userID, reversePointChange); err != nil {
 					return nil, err
 				}
-				if _, err := tx.Exec(ctx, `INSERT INTO Points (user_id, transaction_id, change_amount, reason) VALUES ($1,$2,$3,$4)`, userID, targetTxID, reversePointChange, "Void Reversal"); err != nil {
+				if err := s.Points.Insert(ctx, tx, userID, int64(targetTxID), reversePointChange, "Void Reversal"); err != nil {
 					return nil, err
 				}
 			}
+			t.Status = "Voided"
+			if err := s.emitTransactionEvent(ctx, tx, log, EventTransactionVoided, transactionEvent(t)); err != nil {
+				return nil, err
+			}
 			return &VoidResult{Success: true, VoidedAmount: t.Amount, RestoredPoints: reversePointChange}, nil
 		} else {
-			return nil, NewTxError(http.StatusConflict, "TX_INVALID_STATUS", fmt.Sprintf("Cannot void transaction with status: %s", t.Status))
+			return nil, Failf(utils.CodeTxInvalidStatus, "Cannot void transaction with status: %s", t.Status)
 		}
 	})
 
 	if err != nil {
-		if te, ok := asTxError(err); ok {
-			te.Logs = logs
-			return nil, te
-		}
-		ie := NewTxError(http.StatusInternalServerError, "INTERNAL_ERROR", "Internal Server Error")
-		ie.Logs = logs
-		return nil, ie
+		return nil, txFailure(ctx, "void", err, steps)
 	}
 
 	res := anyRes.(*VoidResult)
-	res.Logs = logs
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("transaction voided", "voided_amount", res.VoidedAmount)
 	return res, nil
 }
 
 // ---- REFUND ----
 func (s *TransactionService) RefundTransaction(ctx context.Context, userID int, targetTxID int) (*RefundResult, error) {
-	anyRes, logs, err := s.withTransaction(ctx, func(tx pgx.Tx, log *utils.TxLogger) (any, error) {
-		log.Raw(fmt.Sprintf("\n> Processing: REFUND, Target Transaction: %d\n", targetTxID))
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, userID, utils.LogKeyTxID, targetTxID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: REFUND, Target Transaction: %d", targetTxID))
 
-		//Check refund abuse
-		if err := s.Risk.EvaluateRefundRisk(ctx, tx, userID, log); err != nil {
+		u, err := s.lockUser(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkAccountStatus(ctx, tx, u, log); err != nil {
 			return nil, err
 		}
 
-		t, err := repo.GetTransactionByIDForUpdate(ctx, tx, targetTxID)
+		t, err := s.Transactions.GetByIDForUpdate(ctx, tx, targetTxID)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, NewTxError(http.StatusNotFound, "TX_NOT_FOUND", "Transaction not found")
+				return nil, Fail(utils.CodeTxNotFound)
 			}
 			return nil, err
 		}
 		if t.UserID != userID {
-			return nil, NewTxError(http.StatusForbidden, "TX_FORBIDDEN", "Unauthorized access")
+			return nil, Fail(utils.CodeTxForbidden)
 		}
-		if t.Status != "Paid" {
-			return nil, NewTxError(http.StatusConflict, "TX_INVALID_STATUS", fmt.Sprintf("Cannot refund transaction with status: %s", t.Status))
+		res, err := s.refund(ctx, tx, u, t, log)
+		if err != nil {
+			return nil, err
 		}
 
-		u, err := repo.GetUserByID(ctx, tx, userID)
+		// Refund abuse freezes the account for later payments; this refund still goes through
+		abuse, err := s.Risk.RefundAbuse(ctx, tx, userID, log)
 		if err != nil {
 			return nil, err
 		}
-
-		if u.CurrentPoints < t.PointChange {
-			return nil, NewTxError(http.StatusConflict, "INSUFFICIENT_POINTS", "Insufficient points to rollback transaction")
+		if abuse {
+			if err := s.freezeForRefundAbuse(ctx, tx, u, log); err != nil {
+				return nil, err
+			}
 		}
 
-		if err := repo.UpdateTransactionStatus(ctx, tx, targetTxID, "Refunded"); err != nil {
-			return nil, err
-		}
+		return res, nil
+	})
 
-		refundAmount := -t.Amount
-		refundPoints := -t.PointChange
-		src := int64(targetTxID)
+	if err != nil {
+		return nil, txFailure(ctx, "refund", err, steps)
+	}
 
-		log.SQL(fmt.Sprintf(
-			"INSERT INTO Transactions (user_id, amount, status, point_change, merchant, source_transaction_id) VALUES (%d, %.2f, 'Refunded', %d, '%s', %d) RETURNING transaction_id;",
-			userID, refundAmount, refundPoints, t.Merchant, targetTxID,
-		))
+	res := anyRes.(*RefundResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("transaction refunded", "refund_transaction_id", res.RefundTransactionID)
+	return res, nil
+}
 
-		refundTxID, err := repo.CreateTransactionReturningID(ctx, tx, userID, refundAmount, "Refunded", refundPoints, t.Merchant, &src)
+// MerchantRefund refunds a sale on behalf of its merchant. Unlike a
+// cardholder refund it is not blocked by the account status and does not
+// count towards refund abuse.
+func (s *TransactionService) MerchantRefund(ctx context.Context, merchant string, targetTxID int) (*RefundResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyTxID, targetTxID, utils.LogKeyMerchant, merchant)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: MERCHANT REFUND by %s, Target Transaction: %d", merchant, targetTxID))
+
+		// Locks go user -> transaction like cardholder refunds; the owner never changes
+		owner, err := s.Transactions.GetByID(ctx, tx, targetTxID)
 		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, Fail(utils.CodeTxNotFound)
+			}
 			return nil, err
 		}
-
-		if _, err := tx.Exec(ctx,
-			`UPDATE Users SET balance = balance + $1, current_points = current_points + $2 WHERE user_id=$3`,
-			refundAmount, refundPoints, userID,
-		); err != nil {
+		if owner.Merchant != merchant {
+			return nil, Failf(utils.CodeTxForbidden, "transaction %d is not a sale of %s", targetTxID, merchant)
+		}
+		u, err := s.lockUser(ctx, tx, owner.UserID)
+		if err != nil {
 			return nil, err
 		}
-
-		if _, err := tx.Exec(ctx,
-			`INSERT INTO Points (user_id, transaction_id, change_amount, reason) VALUES ($1,$2,$3,$4)`,
-			userID, refundTxID, refundPoints, "Refund",
-		); err != nil {
+		t, err := s.Transactions.GetByIDForUpdate(ctx, tx, targetTxID)
+		if err != nil {
 			return nil, err
 		}
-
-		return &RefundResult{RefundTransactionID: refundTxID}, nil
+		return s.refund(ctx, tx, u, t, log)
 	})
 
 	if err != nil {
-		if te, ok := asTxError(err); ok {
-			te.Logs = logs
-			return nil, te
-		}
-		ie := NewTxError(http.StatusInternalServerError, "INTERNAL_ERROR", "Internal Server Error")
-		ie.Logs = logs
-		return nil, ie
+		return nil, txFailure(ctx, "merchant_refund", err, steps)
 	}
 
 	res := anyRes.(*RefundResult)
-	res.Logs = logs
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("transaction refunded by merchant", "refund_transaction_id", res.RefundTransactionID)
 	return res, nil
 }
+
+// refund reverses the Paid purchase t into a new Refunded transaction.
+// The user and t must already be locked.
+func (s *TransactionService) refund(ctx context.Context, tx pgx.Tx, u *models.User, t *models.Transaction, log *utils.TxLogger) (*RefundResult, error) {
+	if t.Status != "Paid" {
+		return nil, Failf(utils.CodeTxInvalidStatus, "Cannot refund transaction with status: %s", t.Status)
+	}
+
+	if u.CurrentPoints < t.PointChange {
+		return nil, Fail(utils.CodeInsufficientPoints)
+	}
+
+	if err := s.Transactions.UpdateStatus(ctx, tx, t.TransactionID, "Refunded"); err != nil {
+		return nil, err
+	}
+
+	refundAmount := -t.Amount
+	refundPoints := -t.PointChange
+	src := int64(t.TransactionID)
+	var refundRate float64
+	if t.OriginalCurrency != nil {
+		amt, rate, err := s.refundFX(ctx, t, log)
+		if err != nil {
+			return nil, err
+		}
+		refundAmount, refundRate = -amt, rate
+	}
+
+	refundTxID, err := s.Transactions.Create(ctx, tx, t.UserID, refundAmount, "Refunded", refundPoints, t.Merchant, &src, t.CardID)
+	if err != nil {
+		return nil, err
+	}
+	ev := models.TransactionEvent{
+		TransactionID: refundTxID, UserID: t.UserID, Amount: refundAmount, Status: "Refunded", PointChange: refundPoints, Merchant: t.Merchant, SourceTransactionID: &src, CardID: t.CardID,
+	}
+	if t.OriginalCurrency != nil {
+		origRefund := -*t.OriginalAmount
+		if err := s.Transactions.SetFX(ctx, tx, refundTxID, origRefund, *t.OriginalCurrency, refundRate, -t.FXFee); err != nil {
+			return nil, err
+		}
+		ev.OriginalAmount, ev.OriginalCurrency = &origRefund, t.OriginalCurrency
+	}
+
+	if err := s.reverseSettledPurchase(ctx, tx, t, refundAmount, log); err != nil {
+		return nil, err
+	}
+	if err := s.Users.AddPoints(ctx, tx, t.UserID, refundPoints); err != nil {
+		return nil, err
+	}
+
+	if err := s.Points.Insert(ctx, tx, t.UserID, refundTxID, refundPoints, "Refund"); err != nil {
+		return nil, err
+	}
+
+	if err := s.emitTransactionEvent(ctx, tx, log, EventTransactionRefunded, ev); err != nil {
+		return nil, err
+	}
+	return &RefundResult{RefundTransactionID: refundTxID}, nil
+}
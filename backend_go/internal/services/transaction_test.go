@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/repo"
+	"backend_go/internal/utils"
+)
+
+// newMemoryService returns a service on a Memory store holding user 1 with
+// a $10,000 limit and 1,000 points, and no card.
+func newMemoryService(t *testing.T) (*TransactionService, *repo.Memory) {
+	t.Helper()
+	mem := repo.NewMemory()
+	repos := mem.Repos()
+	mem.PutUser(models.User{UserID: 1, CreditLimit: 10000, CurrentPoints: 1000})
+	return &TransactionService{
+		Repos:        repos,
+		DB:           mem,
+		Risk:         &RiskEngine{Rules: DefaultRules(false), Transactions: repos.Transactions, Transfers: repos.Transfers},
+		FX:           DefaultFXConfig(),
+		Installments: DefaultInstallmentConfig(),
+		Disputes:     DefaultDisputeConfig(),
+		Capture:      CaptureConfig{AuthExpiry: time.Hour},
+		Promotions:   DefaultPromotionConfig(),
+	}, mem
+}
+
+// putCard issues a primary card to the user.
+func putCard(t *testing.T, mem *repo.Memory, userID int, limit float64) *models.Card {
+	t.Helper()
+	c, err := mem.Repos().Cards.Create(context.Background(), nil, &models.Card{
+		UserID: userID, CardType: "Primary", PANToken: "tok", Last4: "4242", ExpiresAt: time.Now().AddDate(3, 0, 0), CreditLimit: limit,
+	})
+	if err != nil {
+		t.Fatalf("create card: %v", err)
+	}
+	return c
+}
+
+func getUser(t *testing.T, s *TransactionService, userID int) *models.User {
+	t.Helper()
+	u, err := s.Users.GetByID(context.Background(), nil, userID)
+	if err != nil {
+		t.Fatalf("get user %d: %v", userID, err)
+	}
+	return u
+}
+
+func getTransaction(t *testing.T, s *TransactionService, txID int64) *models.Transaction {
+	t.Helper()
+	tr, err := s.Transactions.GetByID(context.Background(), nil, int(txID))
+	if err != nil {
+		t.Fatalf("get transaction %d: %v", txID, err)
+	}
+	return tr
+}
+
+func getCard(t *testing.T, s *TransactionService, userID int) models.Card {
+	t.Helper()
+	cards, err := s.Cards.ListByUserID(context.Background(), nil, userID)
+	if err != nil || len(cards) != 1 {
+		t.Fatalf("list cards: %v %v", cards, err)
+	}
+	return cards[0]
+}
+
+// pay authorizes and captures a purchase in full.
+func pay(t *testing.T, s *TransactionService, req PaymentRequest) *TxResult {
+	t.Helper()
+	res, err := s.ProcessPayment(context.Background(), req)
+	if err != nil {
+		t.Fatalf("pay %+v: %v", req, err)
+	}
+	if _, err := s.CaptureTransaction(context.Background(), CaptureRequest{Merchant: req.Merchant, TransactionID: int(res.TransactionID)}); err != nil {
+		t.Fatalf("capture %d: %v", res.TransactionID, err)
+	}
+	return res
+}
+
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var te *TxError
+	if !errors.As(err, &te) || te.Code != code {
+		t.Fatalf("error = %v, want %s", err, code)
+	}
+}
+
+func eventTypes(mem *repo.Memory, userID int) []string {
+	var out []string
+	for _, e := range mem.EventsOf(userID) {
+		out = append(out, e.Type)
+	}
+	return out
+}
+
+func TestPaymentAndCapture(t *testing.T) {
+	s, mem := newMemoryService(t)
+	card := putCard(t, mem, 1, 5000)
+	bonus := 50
+	if _, err := s.CreatePromotion(context.Background(), CreatePromotionRequest{
+		Name: "Gaming week", Kind: PromoCategory, Category: "gaming", BonusPoints: &bonus, Stackable: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 100, Merchant: "Steam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.CardID == nil || *res.CardID != card.CardID || res.PointsEarned != 200 || res.BonusPoints != 50 {
+		t.Fatalf("result = %+v", res)
+	}
+	if tr := getTransaction(t, s, res.TransactionID); tr.Status != "Pending" || tr.PointChange != 250 {
+		t.Fatalf("authorized transaction = %+v", tr)
+	}
+	if u := getUser(t, s, 1); u.AuthHold != 100 || u.Balance != 0 || u.CurrentPoints != 1000 {
+		t.Fatalf("user after authorization = %+v", u)
+	}
+	if c := getCard(t, s, 1); c.AuthHold != 100 || c.Spent != 100 {
+		t.Fatalf("card after authorization = %+v", c)
+	}
+
+	capRes, err := s.CaptureTransaction(context.Background(), CaptureRequest{Merchant: "Steam", TransactionID: int(res.TransactionID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capRes.CapturedAmount != 100 || capRes.PointsEarned != 200 || capRes.BonusPoints != 50 {
+		t.Fatalf("capture = %+v", capRes)
+	}
+	if u := getUser(t, s, 1); u.AuthHold != 0 || u.Balance != 100 || u.CurrentPoints != 1250 || u.TierSpend != 100 {
+		t.Fatalf("user after capture = %+v", u)
+	}
+	if c := getCard(t, s, 1); c.AuthHold != 0 || c.Balance != 100 {
+		t.Fatalf("card after capture = %+v", c)
+	}
+	points := mem.PointsOf(1)
+	if len(points) != 2 || points[0].ChangeAmount != 200 || points[1].ChangeAmount != 50 || points[1].PromotionID == 0 {
+		t.Fatalf("points ledger = %+v", points)
+	}
+	if got := eventTypes(mem, 1); len(got) != 2 || got[0] != EventTransactionAuthorized || got[1] != EventTransactionSettled {
+		t.Fatalf("events = %v", got)
+	}
+}
+
+func TestPaymentRollsBackOnFailure(t *testing.T) {
+	s, mem := newMemoryService(t)
+	putCard(t, mem, 1, 50)
+
+	_, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 80, Merchant: "7-11"})
+	wantCode(t, err, utils.CodeCardLimitExceeded)
+	if txs, _ := s.Transactions.ListByUserID(context.Background(), nil, 1); len(txs) != 0 {
+		t.Fatalf("transactions = %+v", txs)
+	}
+	if u := getUser(t, s, 1); u.AuthHold != 0 {
+		t.Fatalf("hold kept after rollback: %+v", u)
+	}
+	if got := mem.EventsOf(1); len(got) != 0 {
+		t.Fatalf("events = %+v", got)
+	}
+}
+
+func TestPaymentRedeemsPointsNetOfPendingAuthorizations(t *testing.T) {
+	s, _ := newMemoryService(t)
+
+	res, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 50, Merchant: "7-11", UsePoints: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1,000 pts at 100 pts = $1
+	if res.PointsRedeemed != 1000 || res.FinalAmount != 40 {
+		t.Fatalf("result = %+v", res)
+	}
+	second, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 20, Merchant: "7-11", UsePoints: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.PointsRedeemed != 0 || second.FinalAmount != 20 {
+		t.Fatalf("points held by the Pending authorization were redeemed again: %+v", second)
+	}
+}
+
+func TestVoidPending(t *testing.T) {
+	s, mem := newMemoryService(t)
+	putCard(t, mem, 1, 5000)
+	res, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 400, Merchant: "Amazon", Installments: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := s.VoidTransaction(context.Background(), 1, int(res.TransactionID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Success || v.VoidedAmount != 0 {
+		t.Fatalf("void = %+v", v)
+	}
+	if tr := getTransaction(t, s, res.TransactionID); tr.Status != "Voided" {
+		t.Fatalf("status = %s", tr.Status)
+	}
+	if u := getUser(t, s, 1); u.AuthHold != 0 || u.Balance != 0 || u.InstallmentReserved != 0 {
+		t.Fatalf("user = %+v", u)
+	}
+	if c := getCard(t, s, 1); c.AuthHold != 0 {
+		t.Fatalf("card = %+v", c)
+	}
+	plans, _ := s.Repos.Installments.ListByUserID(context.Background(), nil, 1)
+	if len(plans) != 1 || plans[0].Status != "Cancelled" {
+		t.Fatalf("plans = %+v", plans)
+	}
+
+	_, err = s.VoidTransaction(context.Background(), 1, int(res.TransactionID))
+	wantCode(t, err, utils.CodeTxInvalidStatus)
+}
+
+func TestVoidPaid(t *testing.T) {
+	s, mem := newMemoryService(t)
+	putCard(t, mem, 1, 5000)
+	res := pay(t, s, PaymentRequest{UserID: 1, Amount: 30, Merchant: "Apple Store"})
+
+	mem.PutUser(models.User{UserID: 2, CreditLimit: 10000})
+	_, err := s.VoidTransaction(context.Background(), 2, int(res.TransactionID))
+	wantCode(t, err, utils.CodeTxForbidden)
+
+	v, err := s.VoidTransaction(context.Background(), 1, int(res.TransactionID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.VoidedAmount != 30 || v.RestoredPoints != -90 {
+		t.Fatalf("void = %+v", v)
+	}
+	if u := getUser(t, s, 1); u.Balance != 0 || u.CurrentPoints != 1000 {
+		t.Fatalf("user = %+v", u)
+	}
+	if c := getCard(t, s, 1); c.Balance != 0 {
+		t.Fatalf("card = %+v", c)
+	}
+	points := mem.PointsOf(1)
+	if last := points[len(points)-1]; last.ChangeAmount != -90 || last.Reason != "Void Reversal" {
+		t.Fatalf("points ledger = %+v", points)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	s, mem := newMemoryService(t)
+	putCard(t, mem, 1, 5000)
+	res := pay(t, s, PaymentRequest{UserID: 1, Amount: 60, Merchant: "Amazon"})
+
+	r, err := s.RefundTransaction(context.Background(), 1, int(res.TransactionID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refund := getTransaction(t, s, r.RefundTransactionID)
+	if refund.Status != "Refunded" || refund.Amount != -60 || refund.PointChange != -90 || refund.SourceTransactionID == nil || *refund.SourceTransactionID != int(res.TransactionID) {
+		t.Fatalf("refund transaction = %+v", refund)
+	}
+	if tr := getTransaction(t, s, res.TransactionID); tr.Status != "Refunded" {
+		t.Fatalf("original status = %s", tr.Status)
+	}
+	if u := getUser(t, s, 1); u.Balance != 0 || u.CurrentPoints != 1000 || u.Status != "Active" {
+		t.Fatalf("user = %+v", u)
+	}
+	if c := getCard(t, s, 1); c.Balance != 0 {
+		t.Fatalf("card = %+v", c)
+	}
+
+	_, err = s.RefundTransaction(context.Background(), 1, int(res.TransactionID))
+	wantCode(t, err, utils.CodeTxInvalidStatus)
+}
+
+func TestRefundAbuseFreezesAccount(t *testing.T) {
+	s, mem := newMemoryService(t)
+	limit := int(s.Risk.Rules.RefundLimit)
+	for i := 0; i < limit; i++ {
+		res := pay(t, s, PaymentRequest{UserID: 1, Amount: float64(10 + i), Merchant: "7-11"})
+		if _, err := s.RefundTransaction(context.Background(), 1, int(res.TransactionID)); err != nil {
+			t.Fatalf("refund %d: %v", i+1, err)
+		}
+	}
+
+	u := getUser(t, s, 1)
+	if u.Status != "Frozen" || u.StatusChangedBy != ActorSystem || u.FrozenUntil == nil {
+		t.Fatalf("user after %d refunds = %+v", limit, u)
+	}
+	changes, _ := s.StatusChanges.ListByUserID(context.Background(), nil, 1, 10)
+	if len(changes) != 1 || changes[0].NewStatus != "Frozen" {
+		t.Fatalf("status changes = %+v", changes)
+	}
+	if got := eventTypes(mem, 1); got[len(got)-1] != EventAccountStatusChanged {
+		t.Fatalf("events = %v", got)
+	}
+	_, err := s.ProcessPayment(context.Background(), PaymentRequest{UserID: 1, Amount: 5, Merchant: "7-11"})
+	wantCode(t, err, utils.CodeRiskRefundAbuse)
+}
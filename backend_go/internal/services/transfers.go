@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TransferRequest is the input of TransferPoints.
+type TransferRequest struct {
+	FromUserID int
+	ToUserID   int
+	Points     int
+	Note       string
+}
+
+type TransferResult struct {
+	Transfer *models.PointTransfer `json:"transfer"`
+	// Points left with the sender, and the recipient's or pool's new total
+	FromPoints int          `json:"fromPoints"`
+	ToPoints   int          `json:"toPoints"`
+	Steps      []utils.Step `json:"steps,omitempty"`
+}
+
+// lockUserPair locks both user rows in user_id order, so transfers between
+// the same users in opposite directions cannot deadlock.
+func (s *TransactionService) lockUserPair(ctx context.Context, tx pgx.Tx, a, b int) (*models.User, *models.User, error) {
+	first, second := a, b
+	if b < a {
+		first, second = b, a
+	}
+	u1, err := s.lockUser(ctx, tx, first)
+	if err != nil {
+		return nil, nil, err
+	}
+	u2, err := s.lockUser(ctx, tx, second)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first == a {
+		return u1, u2, nil
+	}
+	return u2, u1, nil
+}
+
+func validTransferNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if len(note) > 100 {
+		return "", Failf(utils.CodeInvalidTransfer, "note must be at most 100 characters")
+	}
+	return note, nil
+}
+
+// ---- POINTS TRANSFER ----
+// Moves points between two users in one transaction with a paired Points
+// row on each side.
+func (s *TransactionService) TransferPoints(ctx context.Context, req TransferRequest) (*TransferResult, error) {
+	ctx = utils.WithLogFields(ctx, utils.LogKeyUserID, req.FromUserID)
+	anyRes, steps, err := s.withTransaction(ctx, func(ctx context.Context, tx pgx.Tx, log *utils.TxLogger) (any, error) {
+		log.Raw(fmt.Sprintf("> Processing: TRANSFER %d pts, User %d -> User %d", req.Points, req.FromUserID, req.ToUserID))
+
+		if req.Points <= 0 {
+			return nil, Failf(utils.CodeInvalidTransfer, "points must be positive")
+		}
+		if req.FromUserID == req.ToUserID {
+			return nil, Failf(utils.CodeInvalidTransfer, "cannot transfer to the same user")
+		}
+		note, err := validTransferNote(req.Note)
+		if err != nil {
+			return nil, err
+		}
+
+		from, to, err := s.lockUserPair(ctx, tx, req.FromUserID, req.ToUserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkAccountStatus(ctx, tx, from, log); err != nil {
+			return nil, err
+		}
+		if to.Status == "Closed" || to.Status == "Blocked" {
+			return nil, Failf(utils.CodeInvalidTransfer, "recipient account is %s", to.Status)
+		}
+		available, err := s.availablePoints(ctx, tx, from)
+		if err != nil {
+			return nil, err
+		}
+		if req.Points > available {
+			return nil, Failf(utils.CodeInsufficientPoints, "requested %d pts, %d available", req.Points, available)
+		}
+		if s.Risk != nil {
+			if err := s.Risk.EvaluatePointsTransfer(ctx, tx, from.UserID, &to.UserID, req.Points, log); err != nil {
+				return nil, err
+			}
+		}
+
+		t, err := s.Transfers.Create(ctx, tx, from.UserID, &to.UserID, nil, req.Points, note)
+		if err != nil {
+			return nil, err
+		}
+		fromU, err := s.Users.UpdateBalanceAndPoints(ctx, tx, from.UserID, 0, -req.Points)
+		if err != nil {
+			return nil, err
+		}
+		toU, err := s.Users.UpdateBalanceAndPoints(ctx, tx, to.UserID, 0, req.Points)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Transfers.InsertPoints(ctx, tx, from.UserID, t.TransferID, -req.Points, fmt.Sprintf("Transfer to user %d", to.UserID)); err != nil {
+			return nil, err
+		}
+		if err := s.Transfers.InsertPoints(ctx, tx, to.UserID, t.TransferID, req.Points, fmt.Sprintf("Transfer from user %d", from.UserID)); err != nil {
+			return nil, err
+		}
+		// One event per side so both account streams see the change
+		for _, userID := range []int{from.UserID, to.UserID} {
+			if err := s.emitAccountEvent(ctx, tx, log, EventPointsTransferred, models.AccountEvent{UserID: userID, Transfer: t}); err != nil {
+				return nil, err
+			}
+		}
+		log.Info(fmt.Sprintf("[TRANSFER] %d pts moved. Sender %d pts, recipient %d pts.", req.Points, fromU.CurrentPoints, toU.CurrentPoints))
+		return &TransferResult{Transfer: t, FromPoints: fromU.CurrentPoints, ToPoints: toU.CurrentPoints}, nil
+	})
+	if err != nil {
+		return nil, txFailure(ctx, "points.transfer", err, steps)
+	}
+	res := anyRes.(*TransferResult)
+	res.Steps = steps
+	utils.LoggerFrom(ctx).Info("points transferred", "transfer_id", res.Transfer.TransferID, "to_user_id", req.ToUserID, "points", req.Points)
+	return res, nil
+}
+
+func (s *TransactionService) ListPointTransfers(ctx context.Context, userID int) ([]models.PointTransfer, error) {
+	return s.Transfers.ListByUserID(ctx, s.Pool, userID)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/utils"
+)
+
+func transfer(s *TransactionService, from, to, points int) (*TransferResult, error) {
+	return s.TransferPoints(context.Background(), TransferRequest{FromUserID: from, ToUserID: to, Points: points})
+}
+
+func TestTransferPoints(t *testing.T) {
+	s, mem := newMemoryService(t)
+	mem.PutUser(models.User{UserID: 2, CreditLimit: 10000, CurrentPoints: 50})
+
+	res, err := s.TransferPoints(context.Background(), TransferRequest{FromUserID: 2, ToUserID: 1, Points: 30, Note: " thanks "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.FromPoints != 20 || res.ToPoints != 1030 || res.Transfer.Note != "thanks" {
+		t.Fatalf("result = %+v", res)
+	}
+	id := res.Transfer.TransferID
+	from, to := mem.PointsOf(2), mem.PointsOf(1)
+	if len(from) != 1 || from[0].ChangeAmount != -30 || from[0].TransferID != id || len(to) != 1 || to[0].ChangeAmount != 30 || to[0].TransferID != id {
+		t.Fatalf("points ledger = %+v / %+v", from, to)
+	}
+	for _, userID := range []int{1, 2} {
+		if got := eventTypes(mem, userID); !slices.Equal(got, []string{EventPointsTransferred}) {
+			t.Fatalf("events of %d = %v", userID, got)
+		}
+	}
+	if list, _ := s.ListPointTransfers(context.Background(), 1); len(list) != 1 || list[0].TransferID != id {
+		t.Fatalf("transfers of the recipient = %+v", list)
+	}
+
+	_, err = transfer(s, 2, 1, 21)
+	wantCode(t, err, utils.CodeInsufficientPoints)
+	_, err = transfer(s, 1, 1, 10)
+	wantCode(t, err, utils.CodeInvalidTransfer)
+	_, err = transfer(s, 1, 2, 0)
+	wantCode(t, err, utils.CodeInvalidTransfer)
+	mem.PutUser(models.User{UserID: 3, Status: "Closed"})
+	_, err = transfer(s, 1, 3, 10)
+	wantCode(t, err, utils.CodeInvalidTransfer)
+	if u := getUser(t, s, 1); u.CurrentPoints != 1030 {
+		t.Fatalf("sender after rejected transfers = %+v", u)
+	}
+}
+
+func TestTransferLimits(t *testing.T) {
+	t.Run("count", func(t *testing.T) {
+		s, mem := newMemoryService(t)
+		mem.PutUser(models.User{UserID: 2, CreditLimit: 10000})
+		for i := range s.Risk.Rules.TransferLimit {
+			if _, err := transfer(s, 1, 2, 10); err != nil {
+				t.Fatalf("transfer %d: %v", i+1, err)
+			}
+		}
+		_, err := transfer(s, 1, 2, 10)
+		wantCode(t, err, utils.CodeTransferLimit)
+
+		// The window rolls over
+		mem.Now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+		if _, err := transfer(s, 1, 2, 10); err != nil {
+			t.Fatalf("transfer after the window: %v", err)
+		}
+	})
+	t.Run("points", func(t *testing.T) {
+		s, mem := newMemoryService(t)
+		mem.PutUser(models.User{UserID: 1, CreditLimit: 10000, CurrentPoints: 100000})
+		mem.PutUser(models.User{UserID: 2, CreditLimit: 10000})
+		if _, err := transfer(s, 1, 2, 40000); err != nil {
+			t.Fatal(err)
+		}
+		_, err := transfer(s, 1, 2, 10001)
+		wantCode(t, err, utils.CodeTransferLimit)
+		if _, err := transfer(s, 1, 2, 10000); err != nil {
+			t.Fatalf("transfer up to the points limit: %v", err)
+		}
+	})
+	t.Run("fan-in", func(t *testing.T) {
+		s, mem := newMemoryService(t)
+		limit := int(s.Risk.Rules.TransferFanInLimit)
+		for from := 2; from <= limit+2; from++ {
+			mem.PutUser(models.User{UserID: from, CreditLimit: 10000, CurrentPoints: 100})
+		}
+		for from := 2; from < limit+2; from++ {
+			if _, err := transfer(s, from, 1, 10); err != nil {
+				t.Fatalf("transfer from %d: %v", from, err)
+			}
+		}
+		_, err := transfer(s, limit+2, 1, 10)
+		wantCode(t, err, utils.CodeRiskTransferFanIn)
+		// A known sender is not a new one
+		if _, err := transfer(s, 2, 1, 10); err != nil {
+			t.Fatalf("repeat sender: %v", err)
+		}
+	})
+}
+
+func TestHouseholdPool(t *testing.T) {
+	s, mem := newMemoryService(t)
+	mem.PutUser(models.User{UserID: 2, CreditLimit: 10000, Balance: 50, CurrentPoints: 600})
+	mem.PutUser(models.User{UserID: 3, CreditLimit: 10000})
+	ctx := context.Background()
+
+	h, err := s.CreateHousehold(ctx, 1, " Family ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := h.Household.HouseholdID
+	if h.Household.Name != "Family" || len(h.Household.Members) != 1 {
+		t.Fatalf("household = %+v", h.Household)
+	}
+	if _, err := s.AddHouseholdMember(ctx, id, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.AddHouseholdMember(ctx, id, 2, 3)
+	wantCode(t, err, utils.CodeHouseholdMembership)
+	_, err = s.CreateHousehold(ctx, 2, "Another")
+	wantCode(t, err, utils.CodeHouseholdMembership)
+
+	if _, err := s.ContributeToHousehold(ctx, id, 1, 400, ""); err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.ContributeToHousehold(ctx, id, 2, 600, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.FromPoints != 0 || res.ToPoints != 1000 {
+		t.Fatalf("contribution = %+v", res)
+	}
+	_, err = s.ContributeToHousehold(ctx, id, 3, 10, "")
+	wantCode(t, err, utils.CodeHouseholdMembership)
+
+	// Any member spends the pool; the credit goes to that member's balance
+	r, err := s.RedeemPoints(ctx, RedeemRequest{UserID: 2, Kind: RedeemStatementCredit, Points: 700, FromHousehold: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RemainingPoints != 300 || r.Balance != 43 || r.Redemption.HouseholdID == nil || *r.Redemption.HouseholdID != id {
+		t.Fatalf("pool redemption = %+v", r)
+	}
+	_, err = s.RedeemPoints(ctx, RedeemRequest{UserID: 1, Kind: RedeemCatalogue, Item: "coffee-voucher", FromHousehold: true})
+	wantCode(t, err, utils.CodeInsufficientPoints)
+	// The pool has no ledger rows of its own
+	if got := mem.PointsOf(2); len(got) != 1 || got[0].ChangeAmount != -600 {
+		t.Fatalf("member ledger = %+v", got)
+	}
+
+	_, err = s.RemoveHouseholdMember(ctx, id, 2, 1)
+	wantCode(t, err, utils.CodeHouseholdMembership)
+	if _, err := s.RemoveHouseholdMember(ctx, id, 2, 2); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetHousehold(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PoolPoints != 300 || len(got.Members) != 1 || got.Members[0].UserID != 1 {
+		t.Fatalf("household after leaving = %+v", got)
+	}
+	// The former member can join another household
+	if _, err := s.CreateHousehold(ctx, 2, "Another"); err != nil {
+		t.Fatal(err)
+	}
+}
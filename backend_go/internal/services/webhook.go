@@ -0,0 +1,295 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"backend_go/internal/models"
+	"backend_go/internal/repo"
+	"backend_go/internal/utils"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Webhook request headers.
+const (
+	HeaderWebhookID        = "X-Webhook-Id"
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+// ---- Registration ----
+
+type WebhookService struct {
+	Pool *pgxpool.Pool
+	// AllowPrivateTargets accepts loopback / private / link-local hosts
+	// (local development only).
+	AllowPrivateTargets bool
+}
+
+// Register stores a webhook. An empty secret is generated; the secret is only
+// returned here, listing never exposes it.
+func (s *WebhookService) Register(ctx context.Context, rawURL, secret string, eventTypes []string) (*models.Webhook, error) {
+	if err := ValidateWebhookURL(rawURL, s.AllowPrivateTargets); err != nil {
+		return nil, err
+	}
+	for _, et := range eventTypes {
+		if !knownEventTypes[et] {
+			return nil, Failf(utils.CodeInvalidWebhook, "unknown event type %q", et)
+		}
+	}
+	if secret == "" {
+		buf := make([]byte, 24)
+		if _, err := crand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = "whsec_" + hex.EncodeToString(buf)
+	}
+	return repo.CreateWebhook(ctx, s.Pool, rawURL, secret, eventTypes)
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]models.Webhook, error) {
+	return repo.ListWebhooks(ctx, s.Pool)
+}
+
+func (s *WebhookService) Deactivate(ctx context.Context, webhookID int64) error {
+	ok, err := repo.DeactivateWebhook(ctx, s.Pool, webhookID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return Fail(utils.CodeWebhookNotFound)
+	}
+	return nil
+}
+
+func (s *WebhookService) DeadLetters(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	return repo.ListDeadDeliveries(ctx, s.Pool, limit)
+}
+
+// Retry puts a dead-lettered delivery back into the queue.
+func (s *WebhookService) Retry(ctx context.Context, deliveryID int64) error {
+	ok, err := repo.RequeueDeadDelivery(ctx, s.Pool, deliveryID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return Fail(utils.CodeDeliveryNotFound)
+	}
+	return nil
+}
+
+// ValidateWebhookURL accepts absolute http(s) URLs without credentials whose
+// host is a DNS name or a public IP. Unless allowPrivate, localhost names and
+// loopback, private, link-local and unspecified addresses are rejected; names
+// resolving to them are stopped when dialing (see NewWebhookClient).
+func ValidateWebhookURL(rawURL string, allowPrivate bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return Failf(utils.CodeInvalidWebhook, "invalid url %q", rawURL)
+	}
+	if allowPrivate {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return Failf(utils.CodeInvalidWebhook, "url host %q is not public", u.Hostname())
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return Failf(utils.CodeInvalidWebhook, "url host %q is not public", u.Hostname())
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), internal to
+// the provider's network although net.IP.IsPrivate does not cover it.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || sharedAddressSpace.Contains(ip))
+}
+
+// NewWebhookClient returns the dispatcher's HTTP client. Unless allowPrivate,
+// every connection (including redirects) is refused when the resolved address
+// is not public, so a DNS name cannot point deliveries at internal services.
+func NewWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("webhook target %s is not a public address", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+	}
+}
+
+// SignWebhook returns the X-Webhook-Signature value: "t=<unix>,v1=<hex>" where
+// v1 = HMAC-SHA256(secret, "<unix>.<body>").
+func SignWebhook(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// ---- Dispatcher ----
+
+type WebhookConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	Timeout      time.Duration
+}
+
+func DefaultWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		PollInterval: 2 * time.Second,
+		BatchSize:    50,
+		MaxAttempts:  8,
+		BackoffBase:  5 * time.Second,
+		BackoffMax:   time.Hour,
+		Timeout:      5 * time.Second,
+	}
+}
+
+// WebhookDispatcher moves outbox events to registered webhooks.
+// Each tick it fans new outbox events out into per-webhook deliveries, then
+// sends the due ones; failures are retried with exponential backoff until
+// MaxAttempts, after which the delivery is dead-lettered.
+type WebhookDispatcher struct {
+	Pool   *pgxpool.Pool
+	Client *http.Client
+	Config WebhookConfig
+}
+
+// Run polls until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	logger := utils.LoggerFrom(ctx).With("component", "webhook_dispatcher")
+	ticker := time.NewTicker(d.Config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.tick(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("webhook dispatch failed", "error", err)
+			}
+		}
+	}
+}
+
+func (d *WebhookDispatcher) tick(ctx context.Context) error {
+	if _, err := repo.FanOutOutbox(ctx, d.Pool, d.Config.BatchSize); err != nil {
+		return err
+	}
+	lease := 2*d.Config.Timeout + 5*time.Second
+	due, err := repo.ClaimDueDeliveries(ctx, d.Pool, d.Config.BatchSize, lease)
+	if err != nil {
+		return err
+	}
+	for _, dd := range due {
+		d.deliver(ctx, dd)
+	}
+	return nil
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, dd repo.DueDelivery) {
+	logger := utils.LoggerFrom(ctx).With("delivery_id", dd.DeliveryID, "event_id", dd.EventID, "event_type", dd.EventType, "attempt", dd.Attempts)
+
+	statusCode, err := d.post(ctx, dd)
+	if err == nil {
+		if err := repo.MarkDeliveryDelivered(ctx, d.Pool, dd.DeliveryID, statusCode); err != nil {
+			logger.Error("mark webhook delivered failed", "error", err)
+		}
+		logger.Debug("webhook delivered", "status", statusCode)
+		return
+	}
+
+	var sc *int
+	if statusCode != 0 {
+		sc = &statusCode
+	}
+	dead := dd.Attempts >= d.Config.MaxAttempts
+	next := time.Now().Add(d.backoff(dd.Attempts))
+	if err := repo.MarkDeliveryFailed(ctx, d.Pool, dd.DeliveryID, sc, err.Error(), next, dead); err != nil {
+		logger.Error("mark webhook failed failed", "error", err)
+	}
+	if dead {
+		logger.Warn("webhook dead-lettered", "error", err)
+	} else {
+		logger.Info("webhook delivery failed, will retry", "error", err, "next_attempt_at", next)
+	}
+}
+
+func (d *WebhookDispatcher) post(ctx context.Context, dd repo.DueDelivery) (int, error) {
+	body, err := json.Marshal(models.EventEnvelope{ID: dd.EventID, Type: dd.EventType, UserID: dd.UserID, CreatedAt: dd.CreatedAt, Data: dd.Payload})
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.Config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dd.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookID, strconv.FormatInt(dd.EventID, 10))
+	req.Header.Set(HeaderWebhookEvent, dd.EventType)
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(dd.Secret, ts, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff: BackoffBase * 2^(attempt-1), capped at BackoffMax, with ±20% jitter.
+func (d *WebhookDispatcher) backoff(attempt int) time.Duration {
+	delay := d.Config.BackoffBase
+	for i := 1; i < attempt && delay < d.Config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > d.Config.BackoffMax {
+		delay = d.Config.BackoffMax
+	}
+	jitter := time.Duration(float64(delay) * (rand.Float64()*0.4 - 0.2))
+	return delay + jitter
+}
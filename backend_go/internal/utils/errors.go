@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Public error codes. Codes and messages are part of the API contract:
+// clients (e.g. the frontend toast mapping) switch on Code, so never rename
+// an existing one. Add new codes to errorCatalog as well.
+const (
+	CodeBadJSON          = "BAD_JSON"
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeInvalidUserID    = "INVALID_USER_ID"
+	CodeInvalidMerchant  = "INVALID_MERCHANT"
+
+	CodeUnsupportedCurrency = "UNSUPPORTED_CURRENCY"
+	CodeFXRateUnavailable   = "FX_RATE_UNAVAILABLE"
+
+	CodeInvalidInstallments = "INVALID_INSTALLMENTS"
+	CodeInvalidAuthAmount   = "INVALID_AUTH_AMOUNT"
+
+	CodeUserNotFound = "USER_NOT_FOUND"
+	CodeTxNotFound   = "TX_NOT_FOUND"
+
+	CodeTxForbidden     = "TX_FORBIDDEN"
+	CodeTxInvalidStatus = "TX_INVALID_STATUS"
+	CodeAuthExpired     = "AUTH_EXPIRED"
+
+	CodeAccountFrozen        = "ACCOUNT_FROZEN"
+	CodeAccountBlocked       = "ACCOUNT_BLOCKED"
+	CodeAccountClosed        = "ACCOUNT_CLOSED"
+	CodeAccountInvalidStatus = "ACCOUNT_INVALID_STATUS"
+	CodeAdminForbidden       = "ADMIN_FORBIDDEN"
+
+	CodeInsufficientCredit = "INSUFFICIENT_CREDIT"
+	CodeInsufficientPoints = "INSUFFICIENT_POINTS"
+
+	CodeRiskAmountTooHigh = "RISK_AMOUNT_TOO_HIGH"
+	CodeRiskAmountTooLow  = "RISK_AMOUNT_TOO_LOW"
+	CodeRiskVelocityLimit = "RISK_VELOCITY_LIMIT"
+	CodeRiskRefundAbuse   = "RISK_REFUND_ABUSE"
+	CodeRiskDuplicate     = "RISK_DUPLICATE"
+
+	CodeInvalidCard        = "INVALID_CARD"
+	CodeCardNotFound       = "CARD_NOT_FOUND"
+	CodeCardForbidden      = "CARD_FORBIDDEN"
+	CodeCardInactive       = "CARD_INACTIVE"
+	CodeCardExpired        = "CARD_EXPIRED"
+	CodeCardLimitExceeded  = "CARD_LIMIT_EXCEEDED"
+	CodeCardInvalidStatus  = "CARD_INVALID_STATUS"
+	CodeCardMerchantLocked = "CARD_MERCHANT_LOCKED"
+	CodeCardCapExceeded    = "CARD_CAP_EXCEEDED"
+	CodePrimaryCardExists  = "PRIMARY_CARD_EXISTS"
+
+	CodeInvalidCreditRequest       = "INVALID_CREDIT_REQUEST"
+	CodeCreditRequestNotFound      = "CREDIT_REQUEST_NOT_FOUND"
+	CodeCreditRequestInvalidStatus = "CREDIT_REQUEST_INVALID_STATUS"
+
+	CodeInvalidDispute       = "INVALID_DISPUTE"
+	CodeDisputeNotFound      = "DISPUTE_NOT_FOUND"
+	CodeDisputeInvalidStatus = "DISPUTE_INVALID_STATUS"
+
+	CodeMerchantUnauthorized = "MERCHANT_UNAUTHORIZED"
+	CodeMerchantNotFound     = "MERCHANT_NOT_FOUND"
+	CodeMerchantExists       = "MERCHANT_EXISTS"
+	CodeInvalidReportDate    = "INVALID_REPORT_DATE"
+
+	CodeInvalidPromotion  = "INVALID_PROMOTION"
+	CodePromotionNotFound = "PROMOTION_NOT_FOUND"
+
+	CodeInvalidRedemption = "INVALID_REDEMPTION"
+
+	CodeInvalidTransfer     = "INVALID_TRANSFER"
+	CodeTransferLimit       = "TRANSFER_LIMIT_EXCEEDED"
+	CodeRiskTransferFanIn   = "RISK_TRANSFER_FAN_IN"
+	CodeInvalidHousehold    = "INVALID_HOUSEHOLD"
+	CodeHouseholdNotFound   = "HOUSEHOLD_NOT_FOUND"
+	CodeHouseholdMembership = "HOUSEHOLD_MEMBERSHIP"
+
+	CodeInvalidWebhook   = "INVALID_WEBHOOK"
+	CodeWebhookNotFound  = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound = "DELIVERY_NOT_FOUND"
+
+	CodeRedisUnavailable = "REDIS_UNAVAILABLE"
+	CodeInternalError    = "INTERNAL_ERROR"
+)
+
+// ErrorSpec is one entry of the public error catalogue.
+type ErrorSpec struct {
+	Code        string `json:"code"`
+	HTTP        int    `json:"http"`
+	Message     string `json:"message"`
+	Description string `json:"description"`
+}
+
+var errorCatalog = map[string]ErrorSpec{
+	CodeBadJSON:          {CodeBadJSON, http.StatusBadRequest, "Invalid JSON body", "The request body is not valid JSON or has unknown fields."},
+	CodeValidationFailed: {CodeValidationFailed, http.StatusBadRequest, "Validation failed", "A required field is missing or out of range."},
+	CodeInvalidUserID:    {CodeInvalidUserID, http.StatusBadRequest, "Invalid user ID format", "The user id path parameter must be a positive integer."},
+	CodeInvalidMerchant:  {CodeInvalidMerchant, http.StatusBadRequest, "Invalid merchant", "The merchant is not in the merchant registry."},
+
+	CodeUnsupportedCurrency: {CodeUnsupportedCurrency, http.StatusBadRequest, "Unsupported currency", "currency must be an ISO 4217 code the rate provider has a rate for."},
+	CodeFXRateUnavailable:   {CodeFXRateUnavailable, http.StatusServiceUnavailable, "FX rate temporarily unavailable", "The rate provider could not be read; billing-currency payments still work."},
+
+	CodeInvalidInstallments: {CodeInvalidInstallments, http.StatusBadRequest, "Invalid installment plan", "installments must be 3, 6 or 12 and the billed amount at least the installment minimum."},
+	CodeInvalidAuthAmount:   {CodeInvalidAuthAmount, http.StatusBadRequest, "Invalid capture or increment amount", "Captures must be > 0 and <= the authorized amount, increments positive; installment purchases are captured in full and cannot be incremented."},
+
+	CodeUserNotFound: {CodeUserNotFound, http.StatusNotFound, "User not found", "No user exists with the given id."},
+	CodeTxNotFound:   {CodeTxNotFound, http.StatusNotFound, "Transaction not found", "No transaction exists with the given id."},
+
+	CodeTxForbidden:     {CodeTxForbidden, http.StatusForbidden, "Unauthorized access", "The transaction belongs to another user."},
+	CodeTxInvalidStatus: {CodeTxInvalidStatus, http.StatusConflict, "Transaction status does not allow this operation", "e.g. voiding a Refunded transaction or refunding a Pending one."},
+	CodeAuthExpired:     {CodeAuthExpired, http.StatusConflict, "Authorization has expired", "The hold is past auth_expires_at and can no longer be captured or incremented."},
+
+	CodeAccountFrozen:        {CodeAccountFrozen, http.StatusForbidden, "Account is frozen", "The account was frozen by the cardholder or an administrator."},
+	CodeAccountBlocked:       {CodeAccountBlocked, http.StatusForbidden, "Account is blocked", "The account was blocked by an administrator."},
+	CodeAccountClosed:        {CodeAccountClosed, http.StatusForbidden, "Account is closed", "The account is closed; no new payments or refunds."},
+	CodeAccountInvalidStatus: {CodeAccountInvalidStatus, http.StatusConflict, "Account status does not allow this operation", "e.g. unfreezing an account frozen by an administrator, or reopening a Closed account."},
+	CodeAdminForbidden:       {CodeAdminForbidden, http.StatusForbidden, "Admin access required", "The admin API needs a valid X-Admin-Token header (disabled when ADMIN_TOKEN is unset)."},
+
+	CodeInsufficientCredit: {CodeInsufficientCredit, http.StatusConflict, "Insufficient credit", "balance + amount would exceed the credit limit."},
+	CodeInsufficientPoints: {CodeInsufficientPoints, http.StatusConflict, "Insufficient points", "The user has already spent the points earned by the transaction being rolled back, or has fewer available points (net of Pending redemptions) than needed."},
+
+	CodeRiskAmountTooHigh: {CodeRiskAmountTooHigh, http.StatusBadRequest, "Transaction amount exceeds maximum limit", "Risk rule: amount above the per-transaction maximum."},
+	CodeRiskAmountTooLow:  {CodeRiskAmountTooLow, http.StatusBadRequest, "Transaction amount is too low", "Risk rule: amount below the per-transaction minimum."},
+	CodeRiskVelocityLimit: {CodeRiskVelocityLimit, http.StatusTooManyRequests, "Too many transactions in short period", "Risk rule: too many payments in the velocity window."},
+	CodeRiskRefundAbuse:   {CodeRiskRefundAbuse, http.StatusForbidden, "Account temporarily frozen due to excessive refunds", "Risk rule: too many refunds in the refund window."},
+	CodeRiskDuplicate:     {CodeRiskDuplicate, http.StatusConflict, "Potential duplicate transaction detected", "Risk rule: same merchant and amount within the duplicate window."},
+
+	CodeInvalidCard:        {CodeInvalidCard, http.StatusBadRequest, "Invalid card request", "card_type must be Primary, Virtual or Supplementary, credit_limit within the account limit, and controls only on Virtual cards."},
+	CodeCardNotFound:       {CodeCardNotFound, http.StatusNotFound, "Card not found", "No card exists with the given id."},
+	CodeCardForbidden:      {CodeCardForbidden, http.StatusForbidden, "Card belongs to another user", "The card_id is not on the requesting user's account."},
+	CodeCardInactive:       {CodeCardInactive, http.StatusForbidden, "Card is not active", "The card is frozen, blocked (lost/stolen) or closed."},
+	CodeCardExpired:        {CodeCardExpired, http.StatusForbidden, "Card has expired", "The card is past its expiry date."},
+	CodeCardLimitExceeded:  {CodeCardLimitExceeded, http.StatusConflict, "Card limit exceeded", "card balance + amount would exceed the card's own limit."},
+	CodeCardInvalidStatus:  {CodeCardInvalidStatus, http.StatusConflict, "Card status does not allow this operation", "e.g. unfreezing an Active card, freezing a Closed one or changing a Blocked card as cardholder."},
+	CodeCardMerchantLocked: {CodeCardMerchantLocked, http.StatusForbidden, "Card is locked to another merchant", "The virtual card can only be used at its merchant_lock merchant."},
+	CodeCardCapExceeded:    {CodeCardCapExceeded, http.StatusConflict, "Card amount cap exceeded", "spent + amount would exceed the virtual card's amount_cap."},
+	CodePrimaryCardExists:  {CodePrimaryCardExists, http.StatusConflict, "User already has a primary card", "Close the existing primary card before issuing a new one."},
+
+	CodeInvalidCreditRequest:       {CodeInvalidCreditRequest, http.StatusBadRequest, "Invalid credit limit request", "Give either new_limit (>= 0, different from the current limit) or a positive boost with a future boost_until."},
+	CodeCreditRequestNotFound:      {CodeCreditRequestNotFound, http.StatusNotFound, "Credit limit request not found", "No credit limit request exists with the given id."},
+	CodeCreditRequestInvalidStatus: {CodeCreditRequestInvalidStatus, http.StatusConflict, "Credit limit request status does not allow this operation", "Only Pending requests can be approved or rejected and only Approved ones applied."},
+
+	CodeInvalidDispute:       {CodeInvalidDispute, http.StatusBadRequest, "Invalid dispute", "reason_code must be a known reason code and the transaction within the filing window."},
+	CodeDisputeNotFound:      {CodeDisputeNotFound, http.StatusNotFound, "Dispute not found", "No dispute exists with the given id."},
+	CodeDisputeInvalidStatus: {CodeDisputeInvalidStatus, http.StatusConflict, "Dispute status does not allow this operation", "Merchants can only respond to Open disputes; only Open or UnderReview disputes can be resolved."},
+
+	CodeMerchantUnauthorized: {CodeMerchantUnauthorized, http.StatusUnauthorized, "Merchant authentication required", "The merchant API needs the X-Merchant-Key of an active merchant account."},
+	CodeMerchantNotFound:     {CodeMerchantNotFound, http.StatusNotFound, "Merchant not found", "No merchant account exists with the given id."},
+	CodeMerchantExists:       {CodeMerchantExists, http.StatusConflict, "Merchant account already exists", "Each merchant has one account; rotate its key instead."},
+	CodeInvalidReportDate:    {CodeInvalidReportDate, http.StatusBadRequest, "Invalid report date", "Dates must be YYYY-MM-DD and from must not be after to."},
+
+	CodeInvalidPromotion:  {CodeInvalidPromotion, http.StatusBadRequest, "Invalid promotion", "Check kind, merchant or category, multiplier/bonus_points, caps and the starts_at/ends_at window."},
+	CodePromotionNotFound: {CodePromotionNotFound, http.StatusNotFound, "Promotion not found", "No promotion exists with the given id."},
+
+	CodeInvalidRedemption: {CodeInvalidRedemption, http.StatusBadRequest, "Invalid redemption", "Give kind statement_credit with points worth at least $0.01 and at most the balance, or kind catalogue with a catalogue item."},
+
+	CodeInvalidTransfer:     {CodeInvalidTransfer, http.StatusBadRequest, "Invalid points transfer", "Points must be positive and the recipient an Active account other than the sender."},
+	CodeTransferLimit:       {CodeTransferLimit, http.StatusTooManyRequests, "Points transfer limit reached", "Risk rule: too many transfers or points sent in the transfer window."},
+	CodeRiskTransferFanIn:   {CodeRiskTransferFanIn, http.StatusForbidden, "Points transfer blocked by risk rules", "Risk rule: the recipient already received points from too many users in the transfer window."},
+	CodeInvalidHousehold:    {CodeInvalidHousehold, http.StatusBadRequest, "Invalid household", "The name must be 1-50 characters."},
+	CodeHouseholdNotFound:   {CodeHouseholdNotFound, http.StatusNotFound, "Household not found", "No household exists with the given id."},
+	CodeHouseholdMembership: {CodeHouseholdMembership, http.StatusConflict, "Household membership does not allow this operation", "A user belongs to at most one household; only the owner manages members and the owner cannot leave."},
+
+	CodeInvalidWebhook:   {CodeInvalidWebhook, http.StatusBadRequest, "Invalid webhook", "The url must be absolute http(s) to a public host without credentials and event_types must be known event types."},
+	CodeWebhookNotFound:  {CodeWebhookNotFound, http.StatusNotFound, "Webhook not found", "No webhook exists with the given id."},
+	CodeDeliveryNotFound: {CodeDeliveryNotFound, http.StatusNotFound, "Delivery not found", "No dead-lettered delivery exists with the given id."},
+
+	CodeRedisUnavailable: {CodeRedisUnavailable, http.StatusServiceUnavailable, "Risk system temporarily unavailable", "The risk engine could not reach Redis; retry later."},
+	CodeInternalError:    {CodeInternalError, http.StatusInternalServerError, "Internal Server Error", "Unexpected server error; see server logs by request_id."},
+}
+
+// LookupError returns the catalogue entry for code, or INTERNAL_ERROR.
+func LookupError(code string) ErrorSpec {
+	if spec, ok := errorCatalog[code]; ok {
+		return spec
+	}
+	return errorCatalog[CodeInternalError]
+}
+
+// ErrorCatalog lists all public error codes sorted by code.
+func ErrorCatalog() []ErrorSpec {
+	out := make([]ErrorSpec, 0, len(errorCatalog))
+	for _, spec := range errorCatalog {
+		out = append(out, spec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
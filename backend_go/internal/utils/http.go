@@ -6,9 +6,10 @@ import (
 )
 
 type APIError struct {
-	Code  string   `json:"code,omitempty"`
-	Error string   `json:"error"`
-	Logs  []string `json:"logs,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error"`
+	Detail string `json:"detail,omitempty"`
+	Steps  []Step `json:"steps,omitempty"`
 }
 
 func WriteJSON(w http.ResponseWriter, status int, v any) {
@@ -1,21 +1,87 @@
 package utils
 
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// StepType classifies a TxLogger entry so the frontend can style it.
+type StepType string
+
+const (
+	StepInfo StepType = "info"
+	StepSQL  StepType = "sql"
+	StepRaw  StepType = "raw"
+)
+
+// Step is one human-readable line of the transaction trace returned to the client.
+// SQL steps additionally carry the structured Query trace.
+type Step struct {
+	Type    StepType    `json:"type"`
+	Message string      `json:"message"`
+	Query   *QueryTrace `json:"query,omitempty"`
+}
+
+// QueryTrace describes one statement actually executed against Postgres.
+// Args are already rendered (and redacted) for display.
+type QueryTrace struct {
+	SQL          string   `json:"sql"`
+	Args         []string `json:"args,omitempty"`
+	RowsAffected int64    `json:"rowsAffected"`
+	DurationMs   float64  `json:"durationMs"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// TxLogger collects the step trace of a single DB transaction.
+// It is meant for the frontend / demos; operational logging goes through slog.
 type TxLogger struct {
-	Logs []string
+	Steps []Step
 }
 
 func NewTxLogger() *TxLogger {
-	return &TxLogger{Logs: make([]string, 0, 64)}
+	return &TxLogger{Steps: make([]Step, 0, 64)}
 }
 
 func (l *TxLogger) Info(msg string) {
-	l.Logs = append(l.Logs, "[INFO] "+msg)
+	l.Steps = append(l.Steps, Step{Type: StepInfo, Message: msg})
 }
 
 func (l *TxLogger) SQL(msg string) {
-	l.Logs = append(l.Logs, "[SQL] "+msg)
+	l.Steps = append(l.Steps, Step{Type: StepSQL, Message: msg})
 }
 
 func (l *TxLogger) Raw(msg string) {
-	l.Logs = append(l.Logs, msg)
+	l.Steps = append(l.Steps, Step{Type: StepRaw, Message: msg})
+}
+
+// Query records an executed statement, see repo.StepTracer.
+func (l *TxLogger) Query(q QueryTrace) {
+	var b strings.Builder
+	b.WriteString(q.SQL)
+	if len(q.Args) > 0 {
+		b.WriteString(" -- args: [")
+		b.WriteString(strings.Join(q.Args, ", "))
+		b.WriteString("]")
+	}
+	if q.Error != "" {
+		fmt.Fprintf(&b, " -- error: %s", q.Error)
+	} else {
+		fmt.Fprintf(&b, " -- %d row(s)", q.RowsAffected)
+	}
+	fmt.Fprintf(&b, ", %.2fms", q.DurationMs)
+	l.Steps = append(l.Steps, Step{Type: StepSQL, Message: b.String(), Query: &q})
+}
+
+type txLoggerCtxKey struct{}
+
+// WithTxLogger attaches l to ctx so the pgx tracer can append SQL steps to it.
+func WithTxLogger(ctx context.Context, l *TxLogger) context.Context {
+	return context.WithValue(ctx, txLoggerCtxKey{}, l)
+}
+
+// TxLoggerFrom returns the TxLogger attached to ctx, or nil.
+func TxLoggerFrom(ctx context.Context) *TxLogger {
+	l, _ := ctx.Value(txLoggerCtxKey{}).(*TxLogger)
+	return l
 }
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Structured log field keys shared by middlewares, controllers and services.
+const (
+	LogKeyRequestID = "request_id"
+	LogKeyUserID    = "user_id"
+	LogKeyTxID      = "transaction_id"
+	LogKeyErrorCode = "error_code"
+	LogKeyMerchant  = "merchant"
+)
+
+// NewLogger builds the process logger.
+// level: debug | info | warn | error (default info)
+// format: json | text (default json)
+func NewLogger(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLogLevel(level)}
+	if strings.EqualFold(format, "text") {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+func ParseLogLevel(s string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+type loggerCtxKey struct{}
+
+// WithLogger stores a (usually field-enriched) logger in ctx.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, l)
+}
+
+// LoggerFrom returns the logger stored in ctx, or slog.Default().
+func LoggerFrom(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerCtxKey{}).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// WithLogFields returns a ctx whose logger carries the extra fields.
+func WithLogFields(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, LoggerFrom(ctx).With(args...))
+}
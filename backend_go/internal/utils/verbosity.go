@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"context"
+	"strings"
+)
+
+// Verbosity controls how much internal detail API responses expose.
+//   - debug:      step trace (SQL, risk checks) and error details are returned
+//   - production: only the public code/message from the error catalogue
+type Verbosity string
+
+const (
+	VerbosityDebug      Verbosity = "debug"
+	VerbosityProduction Verbosity = "production"
+)
+
+// ParseVerbosity accepts debug/demo and production/prod, defaulting to def.
+func ParseVerbosity(s string, def Verbosity) Verbosity {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug", "demo":
+		return VerbosityDebug
+	case "production", "prod":
+		return VerbosityProduction
+	default:
+		return def
+	}
+}
+
+type verbosityCtxKey struct{}
+
+func WithVerbosity(ctx context.Context, v Verbosity) context.Context {
+	return context.WithValue(ctx, verbosityCtxKey{}, v)
+}
+
+// VerbosityFrom returns the request verbosity; without one set it is
+// production, so debug output is always an explicit opt-in.
+func VerbosityFrom(ctx context.Context) Verbosity {
+	if v, ok := ctx.Value(verbosityCtxKey{}).(Verbosity); ok {
+		return v
+	}
+	return VerbosityProduction
+}
+
+// ShowSteps reports whether the step trace may be included in the response.
+func ShowSteps(ctx context.Context) bool {
+	return VerbosityFrom(ctx) == VerbosityDebug
+}
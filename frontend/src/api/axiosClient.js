@@ -44,10 +44,13 @@ const CODE_TO_TOAST = {
   // Permission / status
   TX_FORBIDDEN: { title: '無權限', message: '你沒有權限操作這筆交易。', type: 'error' },
   TX_INVALID_STATUS: { title: '操作不允許', message: '此交易狀態不允許執行此操作。', type: 'error' },
+  ACCOUNT_FROZEN: { title: '帳戶已凍結', message: '帳戶目前為凍結狀態，無法付款或退款。', type: 'warning' },
+  ACCOUNT_BLOCKED: { title: '帳戶已停權', message: '帳戶已被管理員停權，請聯絡客服。', type: 'error' },
+  ACCOUNT_CLOSED: { title: '帳戶已關閉', message: '帳戶已關閉，無法進行交易。', type: 'error' },
 
   // Business
   INSUFFICIENT_CREDIT: { title: '交易失敗', message: '可用額度不足，請降低金額或先還款。', type: 'error' },
-  INSUFFICIENT_POINTS: { title: '交易失敗', message: '點數不足，無法回滾此交易的點數。', type: 'error' },
+  INSUFFICIENT_POINTS: { title: '交易失敗', message: '可用點數不足（已折抵的點數或授權中預計折抵的點數不可再使用）。', type: 'error' },
 
   // Internal
   INTERNAL_ERROR: { title: '系統錯誤', message: '伺服器忙碌或發生錯誤，請稍後再試。', type: 'error' },
@@ -120,7 +123,7 @@ apiClient.interceptors.response.use(
     }
 
     // Debug logs（可選）
-    if (data?.logs?.length) console.debug('API logs:', data.logs)
+    if (data?.steps?.length) console.debug('API steps:', data.steps)
     console.error('API Error:', data || error.message)
 
     return Promise.reject(error)
@@ -27,5 +27,10 @@ export default {
     refundTx(payload) {
     // payload: { user_id, target_transaction_id }
         return apiClient.post('/transactions/refund', payload)
+    },
+
+    // 6. 帳戶事件串流 (SSE) URL，給 EventSource 使用
+    userEventsUrl(userId) {
+        return `${apiClient.defaults.baseURL}/users/${userId}/events`
     }
 }
\ No newline at end of file